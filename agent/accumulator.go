@@ -12,6 +12,12 @@ var (
 	NErrors = selfstat.Register("agent", "gather_errors", map[string]string{})
 )
 
+// errorRecorder is implemented by the MetricMakers keeping track of the
+// errors of their plugin, ie, RunningInput
+type errorRecorder interface {
+	RecordError(err error)
+}
+
 type MetricMaker interface {
 	Name() string
 	MakeMetric(
@@ -105,6 +111,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if r, ok := ac.maker.(errorRecorder); ok {
+		r.RecordError(err)
+	}
 	//TODO suppress/throttle consecutive duplicate errors?
 	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
 }
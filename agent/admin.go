@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// The timeouts of the admin API, its responses are small and its clients,
+// e.g. the liveness probes, should not hold connections
+const (
+	adminReadTimeout  = 10 * time.Second
+	adminWriteTimeout = 10 * time.Second
+	adminIdleTimeout  = time.Minute
+)
+
+// AdminServer is the HTTP admin and health API of the agent, enabled by
+// admin_listen in [agent]. All the endpoints are read only and answer JSON:
+//
+//	/live     the process is up
+//	/ready    the plugins are started, 503 otherwise
+//	/plugins  the loaded plugins
+//	/inputs   last gather time and last error of the inputs
+//	/outputs  buffer fill, write latency and failures of the outputs
+//	/stats    the internal statistics, as gathered by inputs.internal
+//	/info     the dcai identity of the agent, as printed by telegraf --info
+type AdminServer struct {
+	ready    int32
+	listener net.Listener
+	server   *http.Server
+
+	mu     sync.Mutex
+	config *config.Config
+	info   map[string]string
+}
+
+// NewAdminServer returns the admin server of the agent running c
+func NewAdminServer(c *config.Config) *AdminServer {
+	s := &AdminServer{config: c}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", s.serveLive)
+	mux.HandleFunc("/ready", s.serveReady)
+	mux.HandleFunc("/plugins", s.servePlugins)
+	mux.HandleFunc("/inputs", s.serveInputs)
+	mux.HandleFunc("/outputs", s.serveOutputs)
+	mux.HandleFunc("/stats", s.serveStats)
+	mux.HandleFunc("/info", s.serveInfo)
+	s.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  adminReadTimeout,
+		WriteTimeout: adminWriteTimeout,
+		IdleTimeout:  adminIdleTimeout,
+	}
+	return s
+}
+
+// Start listens on address and serves the API in the background
+func (s *AdminServer) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	log.Printf("I! Started the admin API on %s\n", listener.Addr().String())
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! Admin API stopped: %s\n", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the API listens on
+func (s *AdminServer) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close stops the API
+func (s *AdminServer) Close() error {
+	s.SetReady(false)
+	return s.server.Close()
+}
+
+// SetConfig replaces the config reported by the API, ie, once it is
+// reloaded
+func (s *AdminServer) SetConfig(c *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = c
+	s.info = nil
+}
+
+func (s *AdminServer) getConfig() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// SetReady sets the readiness reported by /ready
+func (s *AdminServer) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&s.ready, v)
+}
+
+// Ready returns the readiness reported by /ready
+func (s *AdminServer) Ready() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
+func (s *AdminServer) serveLive(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *AdminServer) serveReady(w http.ResponseWriter, r *http.Request) {
+	if !s.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+type pluginsStatus struct {
+	Inputs      []string `json:"inputs"`
+	Processors  []string `json:"processors"`
+	Aggregators []string `json:"aggregators"`
+	Outputs     []string `json:"outputs"`
+}
+
+func (s *AdminServer) servePlugins(w http.ResponseWriter, r *http.Request) {
+	c := s.getConfig()
+	p := pluginsStatus{
+		Inputs:      []string{},
+		Processors:  []string{},
+		Aggregators: []string{},
+		Outputs:     []string{},
+	}
+	for _, input := range c.Inputs {
+		p.Inputs = append(p.Inputs, input.Name())
+	}
+	for _, processor := range c.Processors {
+		p.Processors = append(p.Processors, "processors."+processor.Name)
+	}
+	for _, aggregator := range c.Aggregators {
+		p.Aggregators = append(p.Aggregators, aggregator.Name())
+	}
+	for _, output := range c.Outputs {
+		p.Outputs = append(p.Outputs, "outputs."+output.Name)
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+type inputStatus struct {
+	Name               string     `json:"name"`
+	LastGather         *time.Time `json:"last_gather,omitempty"`
+	LastGatherDuration string     `json:"last_gather_duration,omitempty"`
+	LastError          string     `json:"last_error,omitempty"`
+	LastErrorTime      *time.Time `json:"last_error_time,omitempty"`
+	Errors             int64      `json:"errors"`
+}
+
+func (s *AdminServer) serveInputs(w http.ResponseWriter, r *http.Request) {
+	c := s.getConfig()
+	inputs := []inputStatus{}
+	for _, input := range c.Inputs {
+		st := input.Status()
+		is := inputStatus{
+			Name:      input.Name(),
+			LastError: st.LastError,
+			Errors:    input.GatherErrors.Get(),
+		}
+		if !st.LastGather.IsZero() {
+			is.LastGather = &st.LastGather
+			is.LastGatherDuration = st.LastGatherDuration.String()
+		}
+		if !st.LastErrorTime.IsZero() {
+			is.LastErrorTime = &st.LastErrorTime
+		}
+		inputs = append(inputs, is)
+	}
+	writeJSON(w, http.StatusOK, inputs)
+}
+
+type outputStatus struct {
+	Name              string     `json:"name"`
+	BufferSize        int        `json:"buffer_size"`
+	BufferLimit       int        `json:"buffer_limit"`
+	MetricsWritten    int64      `json:"metrics_written"`
+	LastWrite         *time.Time `json:"last_write,omitempty"`
+	LastWriteDuration string     `json:"last_write_duration,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	LastErrorTime     *time.Time `json:"last_error_time,omitempty"`
+	Errors            int64      `json:"errors"`
+}
+
+func (s *AdminServer) serveOutputs(w http.ResponseWriter, r *http.Request) {
+	c := s.getConfig()
+	outputs := []outputStatus{}
+	for _, output := range c.Outputs {
+		st := output.Status()
+		ost := outputStatus{
+			Name:           "outputs." + output.Name,
+			BufferSize:     st.BufferSize,
+			BufferLimit:    st.BufferLimit,
+			MetricsWritten: output.MetricsWritten.Get(),
+			LastError:      st.LastError,
+			Errors:         output.WriteErrors.Get(),
+		}
+		if !st.LastWrite.IsZero() {
+			ost.LastWrite = &st.LastWrite
+			ost.LastWriteDuration = st.LastWriteDuration.String()
+		}
+		if !st.LastErrorTime.IsZero() {
+			ost.LastErrorTime = &st.LastErrorTime
+		}
+		outputs = append(outputs, ost)
+	}
+	writeJSON(w, http.StatusOK, outputs)
+}
+
+type statMetric struct {
+	Name   string                 `json:"name"`
+	Tags   map[string]string      `json:"tags"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+func (s *AdminServer) serveStats(w http.ResponseWriter, r *http.Request) {
+	stats := []statMetric{}
+	for _, m := range selfstat.Metrics() {
+		stats = append(stats, statMetric{
+			Name:   m.Name(),
+			Tags:   m.Tags(),
+			Fields: m.Fields(),
+		})
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *AdminServer) serveInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	info := s.info
+	s.mu.Unlock()
+
+	if info == nil {
+		var err error
+		if info, err = agentInfo(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		s.mu.Lock()
+		s.info = info
+		s.mu.Unlock()
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// agentInfo returns the dcai identity of the agent, it only changes when the
+// config is reloaded
+func agentInfo() (map[string]string, error) {
+	da, err := dcai.GetDcaiAgent()
+	if err != nil {
+		return nil, err
+	}
+	ah, err := dcai.FetchAgentHostConfig(da.Agenttype, da.TelegrafConfig.Agent.DmidecodePath)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"saicluster_name":      da.GetSaiClusterName(),
+		"saicluster_domain_id": da.GetSaiClusterDomainId(),
+		"agenthost":            ah.Hostname(),
+		"agenthost_domain_id":  ah.DomainID(),
+		"agent_version":        da.GetAgentVersion(),
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! Unable to write the admin API response: %s\n", err)
+	}
+}
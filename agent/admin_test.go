@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAdminConfig() *config.Config {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs,
+		models.NewRunningInput(&validatedInput{}, &models.InputConfig{Name: "admin_ok"}),
+		models.NewRunningInput(&validatedInput{}, &models.InputConfig{Name: "admin_failing"}),
+	)
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("admin", &plainOutput{}, &models.OutputConfig{Name: "admin"}, 1000, 10000),
+	)
+	return c
+}
+
+func getAdmin(t *testing.T, s *AdminServer, path string, v interface{}) int {
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	if v != nil {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+	}
+	return w.Code
+}
+
+func TestAdminHealth(t *testing.T) {
+	s := NewAdminServer(newAdminConfig())
+
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/live", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, getAdmin(t, s, "/ready", nil))
+
+	s.SetReady(true)
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/ready", nil))
+	s.SetReady(false)
+	assert.Equal(t, http.StatusServiceUnavailable, getAdmin(t, s, "/ready", nil))
+}
+
+func TestAdminPlugins(t *testing.T) {
+	s := NewAdminServer(newAdminConfig())
+
+	var p pluginsStatus
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/plugins", &p))
+	assert.Equal(t, []string{"inputs.admin_ok", "inputs.admin_failing"}, p.Inputs)
+	assert.Equal(t, []string{}, p.Processors)
+	assert.Equal(t, []string{}, p.Aggregators)
+	assert.Equal(t, []string{"outputs.admin"}, p.Outputs)
+}
+
+func TestAdminInputs(t *testing.T) {
+	c := newAdminConfig()
+	s := NewAdminServer(c)
+
+	start := time.Unix(1500000000, 0).UTC()
+	c.Inputs[0].Gathered(start, time.Second)
+	// the stats are shared by the inputs of the same name
+	errors := c.Inputs[1].GatherErrors.Get()
+	NewAccumulator(c.Inputs[1], make(chan telegraf.Metric, 1)).AddError(fmt.Errorf("connection refused"))
+
+	var inputs []inputStatus
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/inputs", &inputs))
+	require.Len(t, inputs, 2)
+
+	assert.Equal(t, "inputs.admin_ok", inputs[0].Name)
+	require.NotNil(t, inputs[0].LastGather)
+	assert.True(t, start.Equal(*inputs[0].LastGather))
+	assert.Equal(t, "1s", inputs[0].LastGatherDuration)
+	assert.Empty(t, inputs[0].LastError)
+
+	assert.Equal(t, "inputs.admin_failing", inputs[1].Name)
+	assert.Nil(t, inputs[1].LastGather)
+	assert.Equal(t, "connection refused", inputs[1].LastError)
+	assert.NotNil(t, inputs[1].LastErrorTime)
+	assert.Equal(t, errors+1, inputs[1].Errors)
+}
+
+func TestAdminOutputs(t *testing.T) {
+	c := newAdminConfig()
+	s := NewAdminServer(c)
+
+	c.Outputs[0].AddMetric(testutil.TestMetric(1, "admin"))
+	c.Outputs[0].AddMetric(testutil.TestMetric(2, "admin"))
+
+	var outputs []outputStatus
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/outputs", &outputs))
+	require.Len(t, outputs, 1)
+	assert.Equal(t, "outputs.admin", outputs[0].Name)
+	assert.Equal(t, 2, outputs[0].BufferSize)
+	assert.Equal(t, 10000, outputs[0].BufferLimit)
+	assert.Nil(t, outputs[0].LastWrite)
+
+	require.NoError(t, c.Outputs[0].Write())
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/outputs", &outputs))
+	assert.Equal(t, 0, outputs[0].BufferSize)
+	assert.NotNil(t, outputs[0].LastWrite)
+	assert.NotEmpty(t, outputs[0].LastWriteDuration)
+}
+
+func TestAdminStats(t *testing.T) {
+	c := newAdminConfig()
+	s := NewAdminServer(c)
+
+	var stats []statMetric
+	assert.Equal(t, http.StatusOK, getAdmin(t, s, "/stats", &stats))
+
+	found := false
+	for _, m := range stats {
+		if m.Name == "internal_write" && m.Tags["output"] == "admin" {
+			found = true
+			assert.Contains(t, m.Fields, "buffer_limit")
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestAdminStart(t *testing.T) {
+	s := NewAdminServer(newAdminConfig())
+	require.NoError(t, s.Start("localhost:0"))
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.Addr() + "/live")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// a slow client does not hold the connection
+	assert.Equal(t, adminReadTimeout, s.server.ReadTimeout)
+	assert.Equal(t, adminWriteTimeout, s.server.WriteTimeout)
+	assert.Equal(t, adminIdleTimeout, s.server.IdleTimeout)
+}
@@ -17,9 +17,57 @@ import (
 	"github.com/influxdata/telegraf/selfstat"
 )
 
+// connectRetryDelay is the delay before retrying to connect to an output
+var connectRetryDelay = 15 * time.Second
+
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	admin *AdminServer
+
+	// mu guards Config, which Reload swaps while the plugins are running
+	mu sync.RWMutex
+	// reloadMu serializes Reload with the start and the shutdown of Run
+	reloadMu    sync.Mutex
+	running     bool
+	metricC     chan telegraf.Metric
+	aggC        chan telegraf.Metric
+	inputs      map[*models.RunningInput]*runner
+	aggregators map[*models.RunningAggregator]*runner
+	outputs     map[*models.RunningOutput]*runner
+	router      *runner
+}
+
+// runner is a goroutine of the agent running a plugin
+type runner struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+	// the service input, or the input to stop
+	service telegraf.StoppableInput
+}
+
+func newRunner() *runner {
+	return &runner{stop: make(chan struct{})}
+}
+
+// stopRunners stops the goroutines of runners at once, not their services
+func stopRunners(runners []*runner) {
+	for _, r := range runners {
+		close(r.stop)
+	}
+	for _, r := range runners {
+		r.wg.Wait()
+	}
+}
+
+// Stop stops the goroutine, then the service or stoppable input if any
+func (r *runner) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+	if r.service != nil {
+		r.service.Stop()
+	}
 }
 
 // NewAgent returns an Agent struct based off the given Config
@@ -28,54 +76,69 @@ func NewAgent(config *config.Config, nextver string, ver string, commit string,
 		Config: config,
 	}
 
-	//	if !a.Config.Agent.OmitHostname {
-	if a.Config.Agent.Hostname == "" {
+	da, err := dcai.NewDcaiAgent(a.Config, nextver, ver, commit, branch)
+	if err != nil {
+		return nil, err
+	}
+	if err := setHostTags(config, da); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// setHostTags sets the hostname of the agent and the agenthost tags of c
+func setHostTags(c *config.Config, da *dcai.DcaiAgent) error {
+	//	if !c.Agent.OmitHostname {
+	if c.Agent.Hostname == "" {
 		hostname, err := os.Hostname()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		a.Config.Agent.Hostname = hostname
+		c.Agent.Hostname = hostname
 	}
-	config.Tags["agenthost"] = a.Config.Agent.Hostname
+	c.Tags["agenthost"] = c.Agent.Hostname
 
-	da, err := dcai.NewDcaiAgent(a.Config, nextver, ver, commit, branch)
-	if err != nil {
-		return nil, err
-	}
 	ah, err := dcai.FetchAgentHostConfig(da.Agenttype, da.TelegrafConfig.Agent.DmidecodePath)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	config.Tags["agenthost_domain_id"] = ah.DomainID()
-	return a, nil
+	c.Tags["agenthost_domain_id"] = ah.DomainID()
+	return nil
 }
 
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
-			}
+		if err := connectOutput(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func connectOutput(o *models.RunningOutput) error {
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
+				o.Name, err.Error())
+			return err
 		}
+	}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
-		err := o.Output.Connect()
+	log.Printf("D! Attempting connection to output: %s\n", o.Name)
+	err := o.Output.Connect()
+	if err != nil {
+		log.Printf("E! Failed to connect to output %s, retrying in %s, "+
+			"error was '%s' \n", o.Name, connectRetryDelay, err)
+		time.Sleep(connectRetryDelay)
+		err = o.Output.Connect()
 		if err != nil {
-			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
-			}
+			return err
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	}
+	log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	return nil
 }
 
@@ -83,11 +146,44 @@ func (a *Agent) Connect() error {
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			ot.Stop()
-		}
+		err = closeOutput(o)
+	}
+	return err
+}
+
+// Reopen reopens the files of the outputs implementing telegraf.Reopener
+func (a *Agent) Reopen() {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, o := range a.Config.Outputs {
+		reopenOutput(o)
+	}
+}
+
+// reopenOutput reopens the files of the output if it implements
+// telegraf.Reopener
+func reopenOutput(o *models.RunningOutput) {
+	r, ok := o.Output.(telegraf.Reopener)
+	if !ok {
+		return
+	}
+	if err := r.Reopen(); err != nil {
+		log.Printf("E! Unable to reopen output %s: %s\n", o.Name, err)
+	}
+}
+
+// stopProcessor stops the goroutine of the processor, if it has one
+func stopProcessor(p *models.RunningProcessor) {
+	if s, ok := p.Processor.(telegraf.StoppableProcessor); ok {
+		s.Stop()
+	}
+}
+
+func closeOutput(o *models.RunningOutput) error {
+	err := o.Output.Close()
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		ot.Stop()
 	}
 	return err
 }
@@ -119,19 +215,21 @@ func (a *Agent) gatherer(
 		map[string]string{"input": input.Config.Name},
 	)
 
+	agentConfig := a.agentConfig()
 	acc := NewAccumulator(input, metricC)
-	acc.SetPrecision(a.Config.Agent.Precision.Duration,
-		a.Config.Agent.Interval.Duration)
+	acc.SetPrecision(agentConfig.Precision.Duration,
+		agentConfig.Interval.Duration)
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+		internal.RandomSleep(agentConfig.CollectionJitter.Duration, shutdown)
 
 		start := time.Now()
 		gatherWithTimeout(shutdown, input, acc, interval)
 		elapsed := time.Since(start)
+		input.Gathered(start, elapsed)
 
 		GatherTime.Incr(elapsed.Nanoseconds())
 
@@ -199,12 +297,23 @@ func (a *Agent) Test() error {
 		}
 	}()
 
+	// the inputs holding resources between the gathers release them
+	var stoppable []telegraf.StoppableInput
+	defer func() {
+		for _, s := range stoppable {
+			s.Stop()
+		}
+	}()
+
 	for _, input := range a.Config.Inputs {
 		if _, ok := input.Input.(telegraf.ServiceInput); ok {
 			fmt.Printf("\nWARNING: skipping plugin [[%s]]: service inputs not supported in --test mode\n",
 				input.Name())
 			continue
 		}
+		if s, ok := input.Input.(telegraf.StoppableInput); ok {
+			stoppable = append(stoppable, s)
+		}
 
 		acc := NewAccumulator(input, metricC)
 		acc.SetPrecision(a.Config.Agent.Precision.Duration,
@@ -236,31 +345,57 @@ func (a *Agent) Test() error {
 	return nil
 }
 
-// flush writes a list of metrics to all configured outputs
-func (a *Agent) flush() {
-	var wg sync.WaitGroup
-
-	wg.Add(len(a.Config.Outputs))
-	for _, o := range a.Config.Outputs {
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
-			}
-		}(o)
+// writeOutput writes the metrics buffered by output
+func writeOutput(output *models.RunningOutput) {
+	err := output.Write()
+	if err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n",
+			output.Name, err.Error())
 	}
+}
 
-	wg.Wait()
+// flushSettings returns the flush interval and jitter of output in c
+func flushSettings(c *config.Config, output *models.RunningOutput) (time.Duration, time.Duration) {
+	interval, jitter := c.Agent.FlushInterval.Duration, c.Agent.FlushJitter.Duration
+	if output.Config.FlushInterval > 0 {
+		interval = output.Config.FlushInterval
+	}
+	if output.Config.FlushJitter > 0 {
+		jitter = output.Config.FlushJitter
+	}
+	return interval, jitter
 }
 
-// flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
-	// Inelegant, but this sleep is to allow the Gather threads to run, so that
-	// the flusher will flush after metrics are collected.
-	time.Sleep(time.Millisecond * 300)
+// flusher writes the metrics buffered by output every flush interval and
+// whenever a batch is full, independently of the other outputs. It writes
+// them a last time when stopped.
+func (a *Agent) flusher(
+	shutdown chan struct{},
+	output *models.RunningOutput,
+	interval time.Duration,
+	jitter time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	batchReady := output.BatchReady()
 
+	for {
+		select {
+		case <-shutdown:
+			writeOutput(output)
+			return
+		case <-ticker.C:
+			internal.RandomSleep(jitter, shutdown)
+			writeOutput(output)
+		case <-batchReady:
+			writeOutput(output)
+		}
+	}
+}
+
+// route passes the metrics of the inputs and the aggregators through the
+// processors, then to the aggregators and the outputs
+func (a *Agent) route(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) {
 	// create an output metric channel and a gorouting that continuously passes
 	// each metric onto the output plugins & aggregators.
 	outMetricC := make(chan telegraf.Metric, 100)
@@ -277,6 +412,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				}
 				return
 			case m := <-outMetricC:
+				a.mu.RLock()
 				// if dropOriginal is set to true, then we will only send this
 				// metric to the aggregators, not the outputs.
 				var dropOriginal bool
@@ -296,6 +432,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 						}
 					}
 				}
+				a.mu.RUnlock()
 			}
 		}
 	}()
@@ -312,10 +449,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				}
 				return
 			case metric := <-aggC:
-				metrics := []telegraf.Metric{metric}
-				for _, processor := range a.Config.Processors {
-					metrics = processor.Apply(metrics...)
-				}
+				metrics := a.process(receiveBatch(metric, aggC)...)
 				for _, m := range metrics {
 					outMetricC <- m
 				}
@@ -323,36 +457,15 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
 	for {
 		select {
 		case <-shutdown:
-			log.Println("I! Hang on, flushing any cached metrics before shutdown")
-			// wait for outMetricC to get flushed before flushing outputs
+			// wait for outMetricC to get flushed before returning
 			wg.Wait()
-			a.flush()
-			return nil
-		case <-ticker.C:
-			go func() {
-				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
-					<-semaphore
-				default:
-					// skipping this flush because one is already happening
-					log.Println("W! Skipping a scheduled flush because there is" +
-						" already a flush ongoing.")
-				}
-			}()
+			return
 		case metric := <-metricC:
-			// NOTE potential bottleneck here as we put each metric through the
-			// processors serially.
-			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
-				mS = processor.Apply(mS...)
-			}
+			// the metrics queued meanwhile are processed at once
+			mS := a.process(receiveBatch(metric, metricC)...)
 			for _, m := range mS {
 				outMetricC <- m
 			}
@@ -360,18 +473,161 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 	}
 }
 
+// maxProcessBatch is the maximum number of metrics passed through the
+// processors at once
+const maxProcessBatch = 1000
+
+// receiveBatch returns metric and the metrics queued behind it in metricC
+func receiveBatch(metric telegraf.Metric, metricC chan telegraf.Metric) []telegraf.Metric {
+	batch := []telegraf.Metric{metric}
+	for len(batch) < maxProcessBatch {
+		select {
+		case m := <-metricC:
+			batch = append(batch, m)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// process passes a batch of metrics through the processors, in order
+func (a *Agent) process(metrics ...telegraf.Metric) []telegraf.Metric {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, processor := range a.Config.Processors {
+		metrics = processor.Apply(metrics...)
+	}
+	return metrics
+}
+
+// agentConfig returns the [agent] settings of the running config
+func (a *Agent) agentConfig() *config.AgentConfig {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config.Agent
+}
+
+// interval returns the gather interval of input
+func (a *Agent) interval(input *models.RunningInput) time.Duration {
+	// overwrite global interval if this plugin has it's own.
+	if input.Config.Interval != 0 {
+		return input.Config.Interval
+	}
+	return a.agentConfig().Interval.Duration
+}
+
+// startService starts the service of input, if it is a service input, its
+// gatherer is started by startGatherer
+func (a *Agent) startService(input *models.RunningInput) error {
+	r := newRunner()
+	if p, ok := input.Input.(telegraf.ServiceInput); ok {
+		acc := NewAccumulator(input, a.metricC)
+		// Service input plugins should set their own precision of their
+		// metrics.
+		acc.SetPrecision(time.Nanosecond, 0)
+		if err := p.Start(acc); err != nil {
+			return err
+		}
+		r.service = p
+	} else if s, ok := input.Input.(telegraf.StoppableInput); ok {
+		r.service = s
+	}
+	a.inputs[input] = r
+	return nil
+}
+
+func (a *Agent) startGatherer(input *models.RunningInput) {
+	r := a.inputs[input]
+	r.wg.Add(1)
+	go func(interval time.Duration) {
+		defer r.wg.Done()
+		a.gatherer(r.stop, input, interval, a.metricC)
+	}(a.interval(input))
+}
+
+func (a *Agent) stopInput(input *models.RunningInput) {
+	a.inputs[input].Stop()
+	delete(a.inputs, input)
+}
+
+func (a *Agent) startAggregator(agg *models.RunningAggregator, now time.Time) {
+	r := newRunner()
+	a.aggregators[agg] = r
+	agentConfig := a.agentConfig()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		acc := NewAccumulator(agg, a.aggC)
+		acc.SetPrecision(agentConfig.Precision.Duration,
+			agentConfig.Interval.Duration)
+		agg.Run(acc, now, r.stop)
+	}()
+}
+
+func (a *Agent) stopAggregator(agg *models.RunningAggregator) {
+	a.aggregators[agg].Stop()
+	delete(a.aggregators, agg)
+}
+
+// startOutput starts the flusher of output, with its settings in c
+func (a *Agent) startOutput(c *config.Config, output *models.RunningOutput) {
+	r := newRunner()
+	a.outputs[output] = r
+	interval, jitter := flushSettings(c, output)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		a.flusher(r.stop, output, interval, jitter)
+	}()
+}
+
+// stopOutput stops the flusher of output once it wrote its buffered metrics
+func (a *Agent) stopOutput(output *models.RunningOutput) {
+	a.outputs[output].Stop()
+	delete(a.outputs, output)
+}
+
+func (a *Agent) startRouter() {
+	r := newRunner()
+	a.router = r
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		a.route(r.stop, a.metricC, a.aggC)
+	}()
+}
+
 // Run runs the agent daemon, gathering every Interval
 func (a *Agent) Run(shutdown chan struct{}) error {
-	var wg sync.WaitGroup
-
 	log.Printf("I! Agent Config: Interval:%s, Quiet:%#v, Hostname:%#v, "+
 		"Flush Interval:%s \n",
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
+	if a.Config.Agent.AdminListen != "" {
+		admin := NewAdminServer(a.Config)
+		if err := admin.Start(a.Config.Agent.AdminListen); err != nil {
+			log.Printf("E! Unable to start the admin API on %s: %s\n",
+				a.Config.Agent.AdminListen, err)
+			return err
+		}
+		a.admin = admin
+	}
+	defer func() {
+		if a.admin != nil {
+			a.admin.Close()
+		}
+	}()
+
+	a.reloadMu.Lock()
 	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
-	aggC := make(chan telegraf.Metric, 100)
+	a.metricC = make(chan telegraf.Metric, 100)
+	a.aggC = make(chan telegraf.Metric, 100)
+	a.inputs = make(map[*models.RunningInput]*runner)
+	a.aggregators = make(map[*models.RunningAggregator]*runner)
+	a.outputs = make(map[*models.RunningOutput]*runner)
 
 	now := time.Now()
 
@@ -379,7 +635,7 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	inputs := a.Config.Inputs
 	if inputs != nil {
 		startAgentInput := inputs[0]
-		acc := NewAccumulator(startAgentInput, metricC)
+		acc := NewAccumulator(startAgentInput, a.metricC)
 		err := event.SendFirstAgentHeartbeat(acc)
 		if err != nil {
 			log.Printf("E! Send first agent heartbeat error %s\n", err.Error())
@@ -388,18 +644,14 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	// Start all ServicePlugins
 	for _, input := range a.Config.Inputs {
 		input.SetDefaultTags(a.Config.Tags)
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			acc := NewAccumulator(input, metricC)
-			// Service input plugins should set their own precision of their
-			// metrics.
-			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
-				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
-				return err
+		if err := a.startService(input); err != nil {
+			log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+				input.Name(), err.Error())
+			for input := range a.inputs {
+				a.stopInput(input)
 			}
-			defer p.Stop()
+			a.reloadMu.Unlock()
+			return err
 		}
 	}
 
@@ -409,40 +661,60 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := a.flusher(shutdown, metricC, aggC); err != nil {
-			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
-			close(shutdown)
-		}
-	}()
-
-	wg.Add(len(a.Config.Aggregators))
+	a.startRouter()
+	for _, output := range a.Config.Outputs {
+		a.startOutput(a.Config, output)
+	}
 	for _, aggregator := range a.Config.Aggregators {
-		go func(agg *models.RunningAggregator) {
-			defer wg.Done()
-			acc := NewAccumulator(agg, aggC)
-			acc.SetPrecision(a.Config.Agent.Precision.Duration,
-				a.Config.Agent.Interval.Duration)
-			agg.Run(acc, now, shutdown)
-		}(aggregator)
+		a.startAggregator(aggregator, now)
 	}
-
-	wg.Add(len(a.Config.Inputs))
 	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		// overwrite global interval if this plugin has it's own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
-		go func(in *models.RunningInput, interv time.Duration) {
-			defer wg.Done()
-			a.gatherer(shutdown, in, interv, metricC)
-		}(input, interval)
+		a.startGatherer(input)
+	}
+	a.running = true
+	a.reloadMu.Unlock()
+
+	if a.admin != nil {
+		a.admin.SetReady(true)
+	}
+
+	<-shutdown
+
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.running = false
+	if a.admin != nil {
+		a.admin.SetReady(false)
+	}
+
+	// stop the gatherers, the aggregators and the router at once, then the
+	// flushers once all the metrics are routed, then the outputs and the
+	// services
+	var runners []*runner
+	for _, r := range a.inputs {
+		runners = append(runners, r)
+	}
+	for _, r := range a.aggregators {
+		runners = append(runners, r)
 	}
+	runners = append(runners, a.router)
+	stopRunners(runners)
+	for _, p := range a.Config.Processors {
+		stopProcessor(p)
+	}
+
+	log.Println("I! Hang on, flushing any cached metrics before shutdown")
+	runners = runners[:0]
+	for _, r := range a.outputs {
+		runners = append(runners, r)
+	}
+	stopRunners(runners)
 
-	wg.Wait()
 	a.Close()
+	for _, r := range a.inputs {
+		if r.service != nil {
+			r.service.Stop()
+		}
+	}
 	return nil
 }
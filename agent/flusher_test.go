@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type metricInput struct{}
+
+func (i *metricInput) SampleConfig() string { return "" }
+func (i *metricInput) Description() string  { return "" }
+func (i *metricInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("gathered", map[string]interface{}{"value": 1}, nil)
+	return nil
+}
+
+// blockingOutput blocks its writes until it is released
+type blockingOutput struct {
+	recordingOutput
+	release chan struct{}
+}
+
+func (o *blockingOutput) Write(metrics []telegraf.Metric) error {
+	<-o.release
+	return o.recordingOutput.Write(metrics)
+}
+
+func countGathered(o *recordingOutput) int {
+	n := 0
+	for _, name := range o.Metrics() {
+		if name == "gathered" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFlusherPerOutput(t *testing.T) {
+	fast := &recordingOutput{}
+	slow := &blockingOutput{release: make(chan struct{})}
+
+	c := newReloadConfig()
+	addInput(c, "metric", &metricInput{}, "m1")
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("fast", fast, &models.OutputConfig{
+			Name:          "fast",
+			FlushInterval: 50 * time.Millisecond,
+		}, 0, 0),
+		// writes every metric, the batches are full at once
+		models.NewRunningOutput("slow", slow, &models.OutputConfig{
+			Name: "slow",
+		}, 1, 100),
+	)
+
+	_, stop := startAgent(t, c, func() bool { return countGathered(fast) >= 3 })
+	assert.Empty(t, slow.Metrics())
+
+	close(slow.release)
+	stop()
+	assert.True(t, countGathered(&slow.recordingOutput) >= 3)
+}
+
+func TestFlushSettings(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.FlushJitter.Duration = time.Second
+
+	o := models.NewRunningOutput("default", &recordingOutput{},
+		&models.OutputConfig{Name: "default"}, 0, 0)
+	interval, jitter := flushSettings(c, o)
+	assert.Equal(t, 10*time.Second, interval)
+	assert.Equal(t, time.Second, jitter)
+
+	o = models.NewRunningOutput("own", &recordingOutput{},
+		&models.OutputConfig{
+			Name:          "own",
+			FlushInterval: time.Minute,
+			FlushJitter:   5 * time.Second,
+		}, 0, 0)
+	interval, jitter = flushSettings(c, o)
+	assert.Equal(t, time.Minute, interval)
+	assert.Equal(t, 5*time.Second, jitter)
+}
+
+func TestReceiveBatch(t *testing.T) {
+	metricC := make(chan telegraf.Metric, 10)
+	metricC <- testutil.TestMetric(2, "queued")
+	metricC <- testutil.TestMetric(3, "queued")
+
+	batch := receiveBatch(testutil.TestMetric(1, "received"), metricC)
+	assert.Len(t, batch, 3)
+	assert.Equal(t, "received", batch[0].Name())
+	assert.Empty(t, metricC)
+}
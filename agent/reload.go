@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// Reload applies the config c to the running agent. The plugins are matched
+// by name and settings: the unchanged ones keep running, the outputs keeping
+// their buffered metrics, the removed ones are stopped, the outputs being
+// flushed a last time, and the new ones are started. The files of the
+// unchanged outputs are reopened, as SIGHUP is also sent by logrotate. The
+// dcai agent is initialized again when the dcai keys of [agent] changed.
+//
+// The running config is kept when an added output fails to connect.
+func (a *Agent) Reload(c *config.Config) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	if !a.running {
+		return fmt.Errorf("The agent is not running")
+	}
+	old := a.Config
+
+	da, err := dcai.ReloadDcaiAgent(c)
+	if err != nil {
+		return err
+	}
+	if err := setHostTags(c, da); err != nil {
+		dcai.ReloadDcaiAgent(old)
+		return err
+	}
+
+	// match the plugins of c with the running ones, c gets the running
+	// plugins left unchanged
+	keep := make(map[interface{}]bool)
+	var (
+		addedOutputs     []*models.RunningOutput
+		addedAggregators []*models.RunningAggregator
+	)
+
+	inputMatches := match(inputKeys(old), inputKeys(c))
+	if !sameInputSettings(old, c) {
+		inputMatches = match(nil, inputKeys(c))
+	}
+	for i, j := range inputMatches {
+		if j < 0 {
+			continue
+		}
+		c.Inputs[i] = old.Inputs[j]
+		keep[old.Inputs[j]] = true
+	}
+
+	for i, j := range match(outputKeys(old), outputKeys(c)) {
+		// the buffers are sized by these, they may come from [agent]
+		if j >= 0 && (c.Outputs[i].MetricBatchSize != old.Outputs[j].MetricBatchSize ||
+			c.Outputs[i].MetricBufferLimit != old.Outputs[j].MetricBufferLimit) {
+			j = -1
+		}
+		if j < 0 {
+			addedOutputs = append(addedOutputs, c.Outputs[i])
+			continue
+		}
+		c.Outputs[i] = old.Outputs[j]
+		keep[old.Outputs[j]] = true
+	}
+
+	aggregatorMatches := match(aggregatorKeys(old), aggregatorKeys(c))
+	if !sameInputSettings(old, c) {
+		aggregatorMatches = match(nil, aggregatorKeys(c))
+	}
+	for i, j := range aggregatorMatches {
+		if j < 0 {
+			addedAggregators = append(addedAggregators, c.Aggregators[i])
+			continue
+		}
+		c.Aggregators[i] = old.Aggregators[j]
+		keep[old.Aggregators[j]] = true
+	}
+
+	// the unchanged processors keep their state
+	for i, j := range match(processorKeys(old), processorKeys(c)) {
+		if j >= 0 {
+			c.Processors[i] = old.Processors[j]
+			keep[old.Processors[j]] = true
+		}
+	}
+
+	for i, o := range addedOutputs {
+		if err := connectOutput(o); err != nil {
+			for _, connected := range addedOutputs[:i] {
+				closeOutput(connected)
+			}
+			dcai.ReloadDcaiAgent(old)
+			return fmt.Errorf("Unable to connect to output %s: %s", o.Name, err)
+		}
+	}
+	for _, o := range addedOutputs {
+		a.startOutput(c, o)
+	}
+
+	// the removed inputs are stopped first, their last metrics being routed
+	// by the running config, and so are the services they listen with
+	var startedInputs, removedInputs, removedOutputs, removedAggregators int
+	for _, input := range old.Inputs {
+		if !keep[input] {
+			a.stopInput(input)
+			removedInputs++
+		}
+	}
+
+	started := c.Inputs[:0]
+	for _, input := range c.Inputs {
+		if keep[input] {
+			started = append(started, input)
+			continue
+		}
+		input.SetDefaultTags(c.Tags)
+		if err := a.startService(input); err != nil {
+			log.Printf("E! Service for input %s failed to start, it is "+
+				"disabled until the next reload: %s\n", input.Name(), err)
+			continue
+		}
+		started = append(started, input)
+		startedInputs++
+	}
+	c.Inputs = started
+
+	now := time.Now()
+	for _, aggregator := range addedAggregators {
+		a.startAggregator(aggregator, now)
+	}
+
+	a.mu.Lock()
+	a.Config = c
+	a.mu.Unlock()
+
+	// the removed processors are no longer applied once the config is swapped
+	for _, p := range old.Processors {
+		if !keep[p] {
+			stopProcessor(p)
+		}
+	}
+
+	for _, aggregator := range old.Aggregators {
+		if !keep[aggregator] {
+			a.stopAggregator(aggregator)
+			removedAggregators++
+		}
+	}
+	for _, o := range old.Outputs {
+		if keep[o] {
+			continue
+		}
+		// the last write error is logged, the remaining metrics are dropped
+		a.stopOutput(o)
+		closeOutput(o)
+		removedOutputs++
+	}
+	// the unchanged outputs keep their buffer when their flush settings change
+	for _, o := range c.Outputs {
+		if !keep[o] {
+			continue
+		}
+		reopenOutput(o)
+		oldInterval, oldJitter := flushSettings(old, o)
+		interval, jitter := flushSettings(c, o)
+		if oldInterval != interval || oldJitter != jitter {
+			a.stopOutput(o)
+			a.startOutput(c, o)
+		}
+	}
+	for _, input := range c.Inputs {
+		if !keep[input] {
+			a.startGatherer(input)
+		}
+	}
+
+	a.reloadAdmin(old, c)
+
+	log.Printf("I! Config reloaded, inputs: %d started, %d stopped; "+
+		"outputs: %d started, %d stopped; aggregators: %d started, %d stopped\n",
+		startedInputs, removedInputs,
+		len(addedOutputs), removedOutputs,
+		len(addedAggregators), removedAggregators)
+	return nil
+}
+
+// reloadAdmin restarts the admin API when admin_listen changed
+func (a *Agent) reloadAdmin(old *config.Config, c *config.Config) {
+	if old.Agent.AdminListen == c.Agent.AdminListen {
+		if a.admin != nil {
+			a.admin.SetConfig(c)
+		}
+		return
+	}
+
+	if a.admin != nil {
+		a.admin.Close()
+		a.admin = nil
+	}
+	if c.Agent.AdminListen == "" {
+		return
+	}
+	admin := NewAdminServer(c)
+	if err := admin.Start(c.Agent.AdminListen); err != nil {
+		log.Printf("E! Unable to start the admin API on %s: %s\n",
+			c.Agent.AdminListen, err)
+		return
+	}
+	admin.SetReady(true)
+	a.admin = admin
+}
+
+// sameInputSettings reports whether the [agent] settings and the global
+// tags shared by the inputs and the aggregators are unchanged, they are all
+// restarted otherwise
+func sameInputSettings(old *config.Config, c *config.Config) bool {
+	return reflect.DeepEqual(old.Tags, c.Tags) &&
+		old.Agent.Interval == c.Agent.Interval &&
+		old.Agent.RoundInterval == c.Agent.RoundInterval &&
+		old.Agent.Precision == c.Agent.Precision &&
+		old.Agent.CollectionJitter == c.Agent.CollectionJitter
+}
+
+// match returns, for each reloaded plugin, the index of the running plugin
+// with the same key or -1. A running plugin is matched once, so duplicated
+// plugins are matched in order.
+func match(running []string, reloaded []string) []int {
+	free := make(map[string][]int)
+	for i, key := range running {
+		free[key] = append(free[key], i)
+	}
+
+	matches := make([]int, len(reloaded))
+	for i, key := range reloaded {
+		matches[i] = -1
+		if indexes := free[key]; len(indexes) > 0 {
+			matches[i] = indexes[0]
+			free[key] = indexes[1:]
+		}
+	}
+	return matches
+}
+
+func pluginKey(name string, fingerprint string) string {
+	return name + "/" + fingerprint
+}
+
+func inputKeys(c *config.Config) []string {
+	keys := make([]string, len(c.Inputs))
+	for i, input := range c.Inputs {
+		keys[i] = pluginKey(input.Name(), input.Fingerprint)
+	}
+	return keys
+}
+
+func outputKeys(c *config.Config) []string {
+	keys := make([]string, len(c.Outputs))
+	for i, output := range c.Outputs {
+		keys[i] = pluginKey(output.Name, output.Fingerprint)
+	}
+	return keys
+}
+
+func aggregatorKeys(c *config.Config) []string {
+	keys := make([]string, len(c.Aggregators))
+	for i, aggregator := range c.Aggregators {
+		keys[i] = pluginKey(aggregator.Name(), aggregator.Fingerprint)
+	}
+	return keys
+}
+
+func processorKeys(c *config.Config) []string {
+	keys := make([]string, len(c.Processors))
+	for i, processor := range c.Processors {
+		keys[i] = pluginKey(processor.Name, processor.Fingerprint)
+	}
+	return keys
+}
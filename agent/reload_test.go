@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingInput struct {
+	gathers int32
+}
+
+func (i *countingInput) SampleConfig() string { return "" }
+func (i *countingInput) Description() string  { return "" }
+func (i *countingInput) Gather(acc telegraf.Accumulator) error {
+	atomic.AddInt32(&i.gathers, 1)
+	return nil
+}
+
+type serviceInput struct {
+	started int32
+	stopped int32
+}
+
+func (i *serviceInput) SampleConfig() string                  { return "" }
+func (i *serviceInput) Description() string                   { return "" }
+func (i *serviceInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *serviceInput) Start(acc telegraf.Accumulator) error {
+	atomic.AddInt32(&i.started, 1)
+	return nil
+}
+func (i *serviceInput) Stop() { atomic.AddInt32(&i.stopped, 1) }
+
+type stoppableInput struct {
+	countingInput
+	stopped int32
+}
+
+func (i *stoppableInput) Stop() { atomic.AddInt32(&i.stopped, 1) }
+
+type recordingOutput struct {
+	sync.Mutex
+	connectErr error
+	closed     bool
+	metrics    []string
+}
+
+func (o *recordingOutput) Connect() error       { return o.connectErr }
+func (o *recordingOutput) Description() string  { return "" }
+func (o *recordingOutput) SampleConfig() string { return "" }
+func (o *recordingOutput) Close() error {
+	o.Lock()
+	defer o.Unlock()
+	o.closed = true
+	return nil
+}
+func (o *recordingOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	for _, m := range metrics {
+		o.metrics = append(o.metrics, m.Name())
+	}
+	return nil
+}
+func (o *recordingOutput) Closed() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.closed
+}
+func (o *recordingOutput) Metrics() []string {
+	o.Lock()
+	defer o.Unlock()
+	return o.metrics
+}
+
+type reopeningOutput struct {
+	recordingOutput
+	reopened int32
+}
+
+func (o *reopeningOutput) Reopen() error {
+	atomic.AddInt32(&o.reopened, 1)
+	return nil
+}
+
+func newReloadConfig() *config.Config {
+	c := config.NewConfig()
+	c.Agent.Interval = internal.Duration{Duration: 100 * time.Millisecond}
+	c.Agent.RoundInterval = false
+	c.Agent.FlushInterval = internal.Duration{Duration: time.Hour}
+	return c
+}
+
+func addInput(c *config.Config, name string, input telegraf.Input, fingerprint string) {
+	ri := models.NewRunningInput(input, &models.InputConfig{Name: name})
+	ri.Fingerprint = fingerprint
+	c.Inputs = append(c.Inputs, ri)
+}
+
+func addOutput(c *config.Config, name string, output telegraf.Output, fingerprint string) {
+	ro := models.NewRunningOutput(name, output, &models.OutputConfig{Name: name},
+		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	ro.Fingerprint = fingerprint
+	c.Outputs = append(c.Outputs, ro)
+}
+
+// startAgent runs an agent on c until the returned function is called
+func startAgent(t *testing.T, c *config.Config, ready func() bool) (*Agent, func()) {
+	dcai.NewDcaiAgent(c, "1.5.0", "", "test", "")
+	a, err := NewAgent(c, "1.5.0", "", "test", "")
+	require.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		a.Run(shutdown)
+		close(done)
+	}()
+	for i := 0; i < 100 && !ready(); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.True(t, ready())
+
+	return a, func() {
+		close(shutdown)
+		<-done
+	}
+}
+
+func TestReload(t *testing.T) {
+	counter := &countingInput{}
+	service := &serviceInput{}
+	stoppable := &stoppableInput{}
+	kept := &reopeningOutput{}
+	removed := &reopeningOutput{}
+
+	c := newReloadConfig()
+	addInput(c, "counter", counter, "c1")
+	addInput(c, "service", service, "s1")
+	addInput(c, "stoppable", stoppable, "p1")
+	addOutput(c, "kept", kept, "o1")
+	addOutput(c, "removed", removed, "o2")
+
+	a, stop := startAgent(t, c, func() bool { return atomic.LoadInt32(&counter.gathers) > 0 })
+	keptOutput := c.Outputs[0]
+	keptOutput.AddMetric(testutil.TestMetric(1, "buffered"))
+
+	reloadedService := &serviceInput{}
+	added := &countingInput{}
+	reloaded := newReloadConfig()
+	addInput(reloaded, "counter", &countingInput{}, "c1")
+	addInput(reloaded, "service", reloadedService, "s2")
+	addInput(reloaded, "added", added, "a1")
+	addOutput(reloaded, "kept", &recordingOutput{}, "o1")
+	require.NoError(t, a.Reload(reloaded))
+
+	assert.True(t, a.Config == reloaded)
+	require.Len(t, reloaded.Inputs, 3)
+	assert.True(t, reloaded.Inputs[0] == c.Inputs[0], "unchanged input is kept")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&service.stopped))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reloadedService.started))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&stoppable.stopped))
+
+	require.Len(t, reloaded.Outputs, 1)
+	assert.True(t, reloaded.Outputs[0] == keptOutput, "unchanged output is kept")
+	// with the first agent heartbeat, if it is routed already
+	assert.True(t, keptOutput.Status().BufferSize >= 1)
+	assert.False(t, kept.Closed())
+	assert.True(t, removed.Closed())
+	// the files of the kept output may have been rotated
+	assert.Equal(t, int32(1), atomic.LoadInt32(&kept.reopened))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&removed.reopened))
+
+	for i := 0; i < 100 && atomic.LoadInt32(&added.gathers) == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NotZero(t, atomic.LoadInt32(&added.gathers))
+
+	stop()
+	// the first agent heartbeat may be written too
+	assert.Contains(t, kept.Metrics(), "buffered")
+	assert.True(t, kept.Closed())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reloadedService.stopped))
+}
+
+func TestReloadConnectError(t *testing.T) {
+	counter := &countingInput{}
+	c := newReloadConfig()
+	addInput(c, "counter", counter, "c1")
+	addOutput(c, "kept", &recordingOutput{}, "o1")
+
+	a, stop := startAgent(t, c, func() bool { return atomic.LoadInt32(&counter.gathers) > 0 })
+	defer stop()
+
+	reloaded := newReloadConfig()
+	addInput(reloaded, "counter", &countingInput{}, "c2")
+	addOutput(reloaded, "broken", &recordingOutput{connectErr: fmt.Errorf("connection refused")}, "o2")
+
+	defer func(delay time.Duration) { connectRetryDelay = delay }(connectRetryDelay)
+	connectRetryDelay = 0
+	assert.Error(t, a.Reload(reloaded))
+	assert.True(t, a.Config == c)
+}
+
+func TestReloadNotRunning(t *testing.T) {
+	a := &Agent{Config: newReloadConfig()}
+	assert.Error(t, a.Reload(newReloadConfig()))
+}
+
+func TestMatch(t *testing.T) {
+	assert.Equal(t, []int{1, -1, 0, -1},
+		match([]string{"cpu/a", "mem/a", "cpu/b"}, []string{"mem/a", "mem/b", "cpu/a", "cpu/a"}))
+	assert.Equal(t, []int{-1}, match(nil, []string{"cpu/a"}))
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// ValidationResult holds the problems found in one section of the config
+type ValidationResult struct {
+	Name     string
+	Errors   []string
+	Warnings []string
+}
+
+// ValidationReport is the outcome of Validate, one result per section
+type ValidationReport struct {
+	Results []*ValidationResult
+}
+
+// Validate checks a loaded config without starting any plugin: the agent
+// and dcai keys of [agent], the plugins implementing telegraf.Validator and
+// the filters of every plugin.
+func Validate(c *config.Config) *ValidationReport {
+	r := &ValidationReport{}
+
+	agent := &ValidationResult{Name: "agent"}
+	if c.Agent.Interval.Duration <= 0 {
+		agent.Errors = append(agent.Errors, fmt.Sprintf("interval must be positive, found %s", c.Agent.Interval.Duration))
+	}
+	if c.Agent.FlushInterval.Duration <= 0 {
+		agent.Errors = append(agent.Errors, fmt.Sprintf("flush_interval must be positive, found %s", c.Agent.FlushInterval.Duration))
+	}
+	if len(c.Inputs) == 0 {
+		agent.Errors = append(agent.Errors, "no inputs found")
+	}
+	if len(c.Outputs) == 0 {
+		agent.Errors = append(agent.Errors, "no outputs found")
+	}
+	for _, err := range dcai.ValidateConfig(c) {
+		agent.Errors = append(agent.Errors, err.Error())
+	}
+	r.Results = append(r.Results, agent)
+
+	for _, input := range c.Inputs {
+		r.Results = append(r.Results, validatePlugin(input.Name(), input.Input, &input.Config.Filter))
+	}
+	for _, processor := range c.Processors {
+		r.Results = append(r.Results, validatePlugin("processors."+processor.Name, processor.Processor, &processor.Config.Filter))
+	}
+	for _, aggregator := range c.Aggregators {
+		res := validatePlugin(aggregator.Name(), aggregator.Aggregator(), &aggregator.Config.Filter)
+		if aggregator.Config.Period <= 0 {
+			res.Errors = append(res.Errors, fmt.Sprintf("period must be positive, found %s", aggregator.Config.Period))
+		}
+		r.Results = append(r.Results, res)
+	}
+	for _, output := range c.Outputs {
+		r.Results = append(r.Results, validatePlugin("outputs."+output.Name, output.Output, &output.Config.Filter))
+	}
+
+	return r
+}
+
+func validatePlugin(name string, plugin interface{}, filter *models.Filter) *ValidationResult {
+	res := &ValidationResult{Name: name}
+	if v, ok := plugin.(telegraf.Validator); ok {
+		for _, err := range v.Validate() {
+			res.Errors = append(res.Errors, err.Error())
+		}
+	}
+	res.Warnings = append(res.Warnings, validateFilter(filter)...)
+	return res
+}
+
+// validateFilter warns about the filters that match nothing
+func validateFilter(f *models.Filter) []string {
+	var warnings []string
+	for _, p := range intersect(f.NamePass, f.NameDrop) {
+		warnings = append(warnings, fmt.Sprintf("namepass %q is also in namedrop, it matches nothing", p))
+	}
+	for _, p := range intersect(f.FieldPass, f.FieldDrop) {
+		warnings = append(warnings, fmt.Sprintf("fieldpass %q is also in fielddrop, it matches nothing", p))
+	}
+	for _, p := range intersect(f.TagInclude, f.TagExclude) {
+		warnings = append(warnings, fmt.Sprintf("taginclude %q is also in tagexclude, it matches nothing", p))
+	}
+	for _, tf := range f.TagPass {
+		if len(tf.Filter) == 0 {
+			warnings = append(warnings, fmt.Sprintf("tagpass %s has no value, it matches nothing", tf.Name))
+		}
+	}
+	for _, tf := range f.TagDrop {
+		for _, pass := range f.TagPass {
+			if tf.Name != pass.Name {
+				continue
+			}
+			for _, p := range intersect(pass.Filter, tf.Filter) {
+				warnings = append(warnings, fmt.Sprintf("tagpass %s=%q is also in tagdrop, it matches nothing", tf.Name, p))
+			}
+		}
+	}
+	return warnings
+}
+
+func intersect(a []string, b []string) []string {
+	var both []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				both = append(both, x)
+				break
+			}
+		}
+	}
+	return both
+}
+
+// Errors returns the number of errors of the report
+func (r *ValidationReport) Errors() int {
+	n := 0
+	for _, res := range r.Results {
+		n += len(res.Errors)
+	}
+	return n
+}
+
+// Warnings returns the number of warnings of the report
+func (r *ValidationReport) Warnings() int {
+	n := 0
+	for _, res := range r.Results {
+		n += len(res.Warnings)
+	}
+	return n
+}
+
+// Print writes the report, one line per section followed by its problems
+func (r *ValidationReport) Print(w io.Writer) {
+	for _, res := range r.Results {
+		status := "OK"
+		if len(res.Errors) > 0 {
+			status = "FAIL"
+		} else if len(res.Warnings) > 0 {
+			status = "WARN"
+		}
+		fmt.Fprintf(w, "[%-4s] %s\n", status, res.Name)
+		for _, e := range res.Errors {
+			fmt.Fprintf(w, "       E! %s\n", e)
+		}
+		for _, warning := range res.Warnings {
+			fmt.Fprintf(w, "       W! %s\n", warning)
+		}
+	}
+	fmt.Fprintf(w, "%d section(s) checked, %d error(s), %d warning(s)\n",
+		len(r.Results), r.Errors(), r.Warnings())
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validatedInput struct {
+	errs []error
+}
+
+func (i *validatedInput) SampleConfig() string                  { return "" }
+func (i *validatedInput) Description() string                   { return "" }
+func (i *validatedInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *validatedInput) Validate() []error                     { return i.errs }
+
+type plainOutput struct{}
+
+func (o *plainOutput) Connect() error                        { return nil }
+func (o *plainOutput) Close() error                          { return nil }
+func (o *plainOutput) Description() string                   { return "" }
+func (o *plainOutput) SampleConfig() string                  { return "" }
+func (o *plainOutput) Write(metrics []telegraf.Metric) error { return nil }
+
+func findResult(t *testing.T, r *ValidationReport, name string) *ValidationResult {
+	for _, res := range r.Results {
+		if res.Name == name {
+			return res
+		}
+	}
+	require.FailNow(t, "missing result", name)
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs,
+		models.NewRunningInput(
+			&validatedInput{errs: []error{fmt.Errorf("urls[0] has an empty address")}},
+			&models.InputConfig{Name: "broken"},
+		),
+		models.NewRunningInput(
+			&validatedInput{},
+			&models.InputConfig{
+				Name: "filtered",
+				Filter: models.Filter{
+					NamePass: []string{"cpu", "mem"},
+					NameDrop: []string{"cpu"},
+					TagPass:  []models.TagFilter{{Name: "cpu", Filter: []string{"cpu0"}}, {Name: "host"}},
+					TagDrop:  []models.TagFilter{{Name: "cpu", Filter: []string{"cpu0"}}},
+				},
+			},
+		),
+	)
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("plain", &plainOutput{}, &models.OutputConfig{Name: "plain"}, 0, 0))
+
+	r := Validate(c)
+	require.Len(t, r.Results, 4)
+	assert.Equal(t, "agent", r.Results[0].Name)
+
+	broken := findResult(t, r, "inputs.broken")
+	assert.Equal(t, []string{"urls[0] has an empty address"}, broken.Errors)
+	assert.Empty(t, broken.Warnings)
+
+	filtered := findResult(t, r, "inputs.filtered")
+	assert.Empty(t, filtered.Errors)
+	assert.Equal(t, []string{
+		`namepass "cpu" is also in namedrop, it matches nothing`,
+		"tagpass host has no value, it matches nothing",
+		`tagpass cpu="cpu0" is also in tagdrop, it matches nothing`,
+	}, filtered.Warnings)
+
+	plain := findResult(t, r, "outputs.plain")
+	assert.Empty(t, plain.Errors)
+	assert.Empty(t, plain.Warnings)
+
+	assert.True(t, r.Errors() >= 1)
+	assert.Equal(t, 3, r.Warnings())
+
+	var buf bytes.Buffer
+	r.Print(&buf)
+	assert.Contains(t, buf.String(), "[FAIL] inputs.broken\n       E! urls[0] has an empty address\n")
+	assert.Contains(t, buf.String(), "[WARN] inputs.filtered\n")
+	assert.Contains(t, buf.String(), "[OK  ] outputs.plain\n")
+}
+
+func TestValidateNoPlugins(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Interval.Duration = 0
+
+	r := Validate(c)
+	require.Len(t, r.Results, 1)
+	assert.Contains(t, r.Results[0].Errors, "no inputs found")
+	assert.Contains(t, r.Results[0].Errors, "no outputs found")
+	assert.Contains(t, r.Results[0].Errors, "interval must be positive, found 0s")
+}
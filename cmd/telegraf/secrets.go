@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal/secretstore"
+)
+
+const secretsUsage = `Usage:
+
+  telegraf [--secretstore <file>] [--secretstore-key-file <file>] secrets <command>
+
+The commands are:
+
+  list                  list the name/key of the stored secrets
+  set <name/key> [val]  add or replace a secret, read from stdin if val is omitted
+  delete <name/key>     delete a secret, or all the secrets of name
+
+Reference the secrets in the config within double quotes, ie,
+  password = "@secret:vcenter1/password"
+
+The key is read from $TELEGRAF_SECRETSTORE_KEY, or from the key file.
+`
+
+// runSecrets manages the secret store and returns the exit code
+func runSecrets(args []string) int {
+	if len(args) == 0 {
+		fmt.Print(secretsUsage)
+		return 1
+	}
+
+	key, err := secretstore.LoadKey(*fSecretStoreKeyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "E! %s\n", err)
+		return 1
+	}
+	store, err := secretstore.Open(*fSecretStore, key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "E! %s\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		for _, ref := range store.List() {
+			fmt.Println(ref)
+		}
+		return 0
+	case "set":
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Print(secretsUsage)
+			return 1
+		}
+		name, k, err := secretstore.ParseRef(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "E! %s\n", err)
+			return 1
+		}
+		var value string
+		if len(args) == 3 {
+			value = args[2]
+		} else {
+			// keep the secret out of the shell history
+			fmt.Fprintf(os.Stderr, "Value of %s/%s: ", name, k)
+			value, err = bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && value == "" {
+				fmt.Fprintf(os.Stderr, "E! Unable to read the secret: %s\n", err)
+				return 1
+			}
+			value = strings.TrimRight(value, "\r\n")
+		}
+		if err := store.Set(name, k, value); err != nil {
+			fmt.Fprintf(os.Stderr, "E! %s\n", err)
+			return 1
+		}
+	case "delete":
+		if len(args) != 2 {
+			fmt.Print(secretsUsage)
+			return 1
+		}
+		name, k := args[1], ""
+		if strings.Contains(args[1], "/") {
+			if name, k, err = secretstore.ParseRef(args[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "E! %s\n", err)
+				return 1
+			}
+		}
+		if err := store.Delete(name, k); err != nil {
+			fmt.Fprintf(os.Stderr, "E! %s\n", err)
+			return 1
+		}
+	default:
+		fmt.Print(secretsUsage)
+		return 1
+	}
+
+	if err := store.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "E! Unable to save %s: %s\n", *fSecretStore, err)
+		return 1
+	}
+	return 0
+}
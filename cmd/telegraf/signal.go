@@ -0,0 +1,11 @@
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignal makes the outputs reopen their files, as after logrotate
+var reopenSignal os.Signal = syscall.SIGUSR1
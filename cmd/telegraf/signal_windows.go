@@ -0,0 +1,8 @@
+// +build windows
+
+package main
+
+import "os"
+
+// reopenSignal is not available on windows
+var reopenSignal os.Signal
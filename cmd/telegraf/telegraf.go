@@ -32,6 +32,8 @@ var pprofAddr = flag.String("pprof-addr", "",
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fValidate = flag.Bool("validate", false,
+	"validate the configuration, print a report, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
@@ -56,6 +58,10 @@ var fUsage = flag.String("usage", "",
 	"print usage for a plugin, ie, 'telegraf --usage mysql'")
 var fService = flag.String("service", "",
 	"operate on the service")
+var fSecretStore = flag.String("secretstore", defaultSecretStore(),
+	"encrypted keystore resolving the @secret:name/key references")
+var fSecretStoreKeyFile = flag.String("secretstore-key-file", defaultSecretStoreKeyFile(),
+	"file holding the key of the secret store, overridden by $TELEGRAF_SECRETSTORE_KEY")
 
 var (
 	nextVersion = "1.5.0"
@@ -85,9 +91,11 @@ The commands & flags are:
   config              print out full sample configuration to stdout
   version             print the version to stdout
   info                print agent information to stdout
+  secrets             manage the secret store, ie, 'telegraf secrets list'
 
   --config <file>     configuration file to load
   --test              gather metrics once, print them to stdout, and exit
+  --validate          validate the configuration without collecting, exit 1 on errors
   --config-directory  directory containing additional *.conf files
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
@@ -95,6 +103,8 @@ The commands & flags are:
   --debug             print metrics as they're generated to stdout
   --pprof-addr        pprof address to listen on, format: localhost:6060 or :6060
   --quiet             run in quiet mode
+  --secretstore       encrypted keystore resolving the @secret:name/key references
+  --secretstore-key-file  file holding the key of the secret store
 
 Examples:
 
@@ -107,12 +117,18 @@ Examples:
   # run a single telegraf collection, outputing metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # check a config file before deploying it
+  telegraf --config telegraf.conf --validate
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf --config telegraf.conf --input-filter cpu:mem --output-filter influxdb
 
+  # store a password and reference it as "@secret:vcenter1/password"
+  telegraf secrets set vcenter1/password
+
   # run telegraf with pprof
   telegraf --config telegraf.conf --pprof-addr localhost:6060
 `
@@ -126,113 +142,151 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
-	reload := make(chan bool, 1)
-	reload <- true
-	for <-reload {
-		reload <- false
-
-		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
-
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
-		if int64(c.Agent.Interval.Duration) <= 0 {
-			log.Fatalf("E! Agent interval must be positive, found %s",
-				c.Agent.Interval.Duration)
-		}
+	ag, err := agent.NewAgent(c, nextVersion, version, commit, branch)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
-		if int64(c.Agent.FlushInterval.Duration) <= 0 {
-			log.Fatalf("E! Agent flush_interval must be positive; found %s",
-				c.Agent.Interval.Duration)
-		}
+	// Setup logging
+	logger.SetupLogging(
+		ag.Config.Agent.Debug || *fDebug,
+		ag.Config.Agent.Quiet || *fQuiet,
+		ag.Config.Agent.Logfile,
+	)
 
-		ag, err := agent.NewAgent(c, nextVersion, version, commit, branch)
+	if *fTest {
+		err = ag.Test()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
+		os.Exit(0)
+	}
 
-		// Setup logging
-		logger.SetupLogging(
-			ag.Config.Agent.Debug || *fDebug,
-			ag.Config.Agent.Quiet || *fQuiet,
-			ag.Config.Agent.Logfile,
-		)
-
-		if *fTest {
-			err = ag.Test()
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-			os.Exit(0)
-		}
-
-		err = ag.Connect()
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
+	err = ag.Connect()
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
-		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
-		go func() {
+	shutdown := make(chan struct{})
+	signals := make(chan os.Signal)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
+	if reopenSignal != nil {
+		signal.Notify(signals, reopenSignal)
+	}
+	go func() {
+		for {
 			select {
 			case sig := <-signals:
 				if sig == os.Interrupt {
 					close(shutdown)
+					return
 				}
 				if sig == syscall.SIGHUP {
 					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
-					close(shutdown)
+					reloadConfig(ag, inputFilters, outputFilters)
+				}
+				if reopenSignal != nil && sig == reopenSignal {
+					log.Printf("I! Reopening the output files\n")
+					ag.Reopen()
 				}
 			case <-stop:
 				close(shutdown)
+				return
 			}
-		}()
+		}
+	}()
 
-		log.Printf("I! Starting Telegraf %s\n", displayVersion())
-		log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-		log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
-		log.Printf("I! Tags enabled: %s", c.ListTags())
+	log.Printf("I! Starting Telegraf %s\n", displayVersion())
+	log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+	log.Printf("I! Tags enabled: %s", c.ListTags())
 
-		if *fPidfile != "" {
-			f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				log.Printf("E! Unable to create pidfile: %s", err)
-			} else {
-				fmt.Fprintf(f, "%d\n", os.Getpid())
-
-				f.Close()
-
-				defer func() {
-					err := os.Remove(*fPidfile)
-					if err != nil {
-						log.Printf("E! Unable to remove pidfile: %s", err)
-					}
-				}()
-			}
+	if *fPidfile != "" {
+		f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("E! Unable to create pidfile: %s", err)
+		} else {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+
+			f.Close()
+
+			defer func() {
+				err := os.Remove(*fPidfile)
+				if err != nil {
+					log.Printf("E! Unable to remove pidfile: %s", err)
+				}
+			}()
 		}
+	}
+
+	ag.Run(shutdown)
+}
+
+// reloadConfig applies the config files to the running agent, the running
+// config is kept when they are invalid
+func reloadConfig(ag *agent.Agent, inputFilters []string, outputFilters []string) {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err == nil {
+		err = ag.Reload(c)
+	}
+	if err != nil {
+		log.Printf("E! Unable to reload the config, keeping the running one: %s\n", err)
+		// the files may have been rotated by logrotate
+		ag.Reopen()
+		return
+	}
+
+	logger.SetupLogging(
+		c.Agent.Debug || *fDebug,
+		c.Agent.Quiet || *fQuiet,
+		c.Agent.Logfile,
+	)
+	log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+	log.Printf("I! Tags enabled: %s", c.ListTags())
+}
+
+// loadConfig loads and checks the config file and the config directory
+func loadConfig(inputFilters []string, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	c.SecretStore = *fSecretStore
+	c.SecretStoreKeyFile = *fSecretStoreKeyFile
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+
+	if int64(c.Agent.Interval.Duration) <= 0 {
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
+			c.Agent.Interval.Duration)
+	}
 
-		ag.Run(shutdown)
+	if int64(c.Agent.FlushInterval.Duration) <= 0 {
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
+			c.Agent.Interval.Duration)
 	}
+	return c, nil
 }
 
 func usageExit(rc int) {
@@ -266,6 +320,46 @@ func (p *program) Stop(s service.Service) error {
 	return nil
 }
 
+func defaultSecretStore() string {
+	if runtime.GOOS == "windows" {
+		return "C:\\Program Files\\Telegraf\\secrets.db"
+	}
+	return "/etc/telegraf/secrets.db"
+}
+
+func defaultSecretStoreKeyFile() string {
+	if runtime.GOOS == "windows" {
+		return "C:\\Program Files\\Telegraf\\secrets.key"
+	}
+	return "/etc/telegraf/secrets.key"
+}
+
+// validateConfig prints the validation report of the config and returns the
+// exit code, no plugin is started
+func validateConfig(inputFilters []string, outputFilters []string) int {
+	c := config.NewConfig()
+	c.InputFilters = inputFilters
+	c.OutputFilters = outputFilters
+	c.SecretStore = *fSecretStore
+	c.SecretStoreKeyFile = *fSecretStoreKeyFile
+
+	err := c.LoadConfig(*fConfig)
+	if err == nil && *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+	}
+	if err != nil {
+		fmt.Printf("[FAIL] config\n       E! %s\n", err)
+		return 1
+	}
+
+	report := agent.Validate(c)
+	report.Print(os.Stdout)
+	if report.Errors() > 0 {
+		return 1
+	}
+	return 0
+}
+
 func displayVersion() string {
 	if version == "" {
 		return fmt.Sprintf("v%s~%s", nextVersion, commit)
@@ -275,6 +369,8 @@ func displayVersion() string {
 
 func displayAgentInfo() {
 	c := config.NewConfig()
+	c.SecretStore = *fSecretStore
+	c.SecretStoreKeyFile = *fSecretStoreKeyFile
 	err := c.LoadConfig(*fConfig)
 	if err != nil {
 		log.Fatal("E! " + err.Error())
@@ -292,7 +388,7 @@ func displayAgentInfo() {
 	fmt.Printf("saicluster_domain_id=%s\n", da.GetSaiClusterDomainId())
 	fmt.Printf("agenthost=%s\n", ah.Hostname())
 	fmt.Printf("agenthost_domain_id=%s\n", ah.DomainID())
-	fmt.Printf("agent_version=Telegraf %s (git: %s %s)\n", displayVersion(), branch, commit)
+	fmt.Printf("agent_version=%s\n", da.GetAgentVersion())
 }
 
 func main() {
@@ -349,6 +445,8 @@ func main() {
 		case "info":
 			displayAgentInfo()
 			return
+		case "secrets":
+			os.Exit(runSecrets(args[1:]))
 		}
 	}
 
@@ -372,6 +470,8 @@ func main() {
 	case *fagentinfo:
 		displayAgentInfo()
 		return
+	case *fValidate:
+		os.Exit(validateConfig(inputFilters, outputFilters))
 	case *fSampleConfig:
 		config.PrintSampleConfig(
 			inputFilters,
@@ -417,6 +517,12 @@ func main() {
 			if *fConfigDirectory != "" {
 				(*svcConfig).Arguments = append((*svcConfig).Arguments, "-config-directory", *fConfigDirectory)
 			}
+			if *fSecretStore != defaultSecretStore() {
+				(*svcConfig).Arguments = append((*svcConfig).Arguments, "-secretstore", *fSecretStore)
+			}
+			if *fSecretStoreKeyFile != defaultSecretStoreKeyFile() {
+				(*svcConfig).Arguments = append((*svcConfig).Arguments, "-secretstore-key-file", *fSecretStoreKeyFile)
+			}
 			err := service.Control(s, *fService)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
@@ -50,6 +50,25 @@ func NewVcsaConnector(name string, url string, user string, pw string, insecure
 	return vcsa, nil
 }
 
+// ValidateUrls checks the [name, address, username, password] tuples of
+// the vCenters given to the vsphere inputs
+func ValidateUrls(urls [][]string) []error {
+	var errs []error
+	if len(urls) == 0 {
+		return []error{fmt.Errorf("No vCenter configured in urls")}
+	}
+	for i, u := range urls {
+		if len(u) != 4 {
+			errs = append(errs, fmt.Errorf("urls[%d] must be [name, address, username, password], got %d element(s)", i, len(u)))
+			continue
+		}
+		if u[1] == "" {
+			errs = append(errs, fmt.Errorf("urls[%d] has an empty address", i))
+		}
+	}
+	return errs
+}
+
 func (vcsa *VcsaConnector) ConnectVsphere() error {
 	var u *url.URL
 	var err error
@@ -0,0 +1,136 @@
+package vcsa
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+	"github.com/influxdata/telegraf/dcai/testutil/vcsim"
+	"github.com/influxdata/telegraf/dcai/topology/host/vmware/esxi"
+	"github.com/influxdata/telegraf/dcai/type"
+)
+
+func newSimulatorConnector(t *testing.T, topology vcsim.Topology) (*VcsaConnector, *vcsim.Simulator) {
+	sim, err := vcsim.New(topology)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vc, err := NewVcsaConnector("vcsim", sim.Address(), sim.Username(), sim.Password(), true)
+	if err != nil {
+		sim.Close()
+		t.Fatal(err)
+	}
+	if err := vc.ConnectVsphere(); err != nil {
+		sim.Close()
+		t.Fatal(err)
+	}
+	return vc, sim
+}
+
+func TestGetTopology(t *testing.T) {
+	topology := vcsim.DefaultTopology
+	vc, sim := newSimulatorConnector(t, topology)
+	defer sim.Close()
+	defer vc.DisconnectVsphere()
+
+	dcs, err := vc.GetTopology()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CompareVar(t, len(dcs), topology.Datacenter)
+
+	dc := dcs[0]
+	testutil.CompareVar(t, len(dc.Hosts), topology.Cluster*topology.ClusterHost+topology.Host)
+	testutil.CompareVar(t, len(dc.Clusters), topology.Cluster)
+	testutil.CompareVar(t, dc.Clusters[0].DomainID(), vcsim.VsanUuid)
+	testutil.CompareVar(t, dc.Clusters[0].ClusterType, dcaitype.ClustervSAN)
+	testutil.CompareVar(t, len(dc.Clusters[0].Hosts), topology.ClusterHost)
+
+	vms := 0
+	for i, hs := range sim.Hosts() {
+		var h *esxi.EsxiHostConfig
+		for _, hc := range dc.Hosts {
+			if hc.Hostname() == hs.Summary.Config.Name {
+				h = hc.(*esxi.EsxiHostConfig)
+			}
+		}
+		if h == nil {
+			t.Fatalf("host %s not found in topology", hs.Summary.Config.Name)
+		}
+		testutil.CompareVar(t, h.DomainID(), vcsim.HostUuid(i))
+
+		// local LUNs plus the shared SAN LUN
+		testutil.CompareVar(t, len(h.Disks), topology.LunsPerHost+1)
+		for j := 0; j < topology.LunsPerHost; j++ {
+			testutil.CompareVar(t, h.Disks[j].Name, vcsim.LunName(i, j))
+			testutil.CompareVar(t, h.Disks[j].WWN, vcsim.LunWWN(i, j))
+		}
+		testutil.CompareVar(t, h.Disks[0].Type, dcaitype.DiskTypeSSD)
+		testutil.CompareVar(t, h.Disks[1].Type, dcaitype.DiskTypeHDD)
+
+		testutil.CompareVar(t, len(h.Datastores), len(hs.Datastore))
+		for _, ds := range h.Datastores {
+			testutil.CompareVar(t, len(ds.Disks), 1)
+			testutil.CompareVar(t, ds.Disks[0].Name, vcsim.SharedLunName)
+		}
+
+		if hs.Parent.Type == "ClusterComputeResource" {
+			if h.Vsan != dc.Clusters[0] {
+				t.Errorf("host %s should be in vSAN cluster %s", h.Name, vcsim.VsanUuid)
+			}
+			testutil.CompareVar(t, len(h.VsanDiskgroups), 1)
+			testutil.CompareVar(t, h.VsanDiskgroups[0].DomainID(), vcsim.HostUuid(i))
+			testutil.CompareVar(t, h.VsanDiskgroups[0].CacheDisks[0].Name, vcsim.LunName(i, 0))
+			testutil.CompareVar(t, len(h.VsanDiskgroups[0].CapacityDisks), topology.LunsPerHost-1)
+		} else {
+			if h.Vsan != nil {
+				t.Errorf("standalone host %s should not be in a vSAN cluster", h.Name)
+			}
+			testutil.CompareVar(t, len(h.VsanDiskgroups), 0)
+		}
+
+		testutil.CompareVar(t, len(h.VMs), len(hs.Vm))
+		for _, vm := range h.VMs {
+			testutil.CompareVar(t, len(vm.Datastores), 1)
+			testutil.CompareVar(t, len(vm.Snapshots), topology.Snapshots)
+			testutil.CompareVar(t, vm.Snapshots[0].Name, "snapshot-0")
+			testutil.CompareVar(t, vm.Snapshots[0].DomainID(), "1")
+		}
+		vms += len(h.VMs)
+	}
+	testutil.CompareVar(t, vms, len(sim.VirtualMachines()))
+}
+
+func TestGetTopologyWithoutVsan(t *testing.T) {
+	topology := vcsim.DefaultTopology
+	topology.Vsan = false
+	topology.Snapshots = 0
+	vc, sim := newSimulatorConnector(t, topology)
+	defer sim.Close()
+	defer vc.DisconnectVsphere()
+
+	dcs, err := vc.GetTopology()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CompareVar(t, len(dcs[0].Clusters), 0)
+	for _, hc := range dcs[0].Hosts {
+		h := hc.(*esxi.EsxiHostConfig)
+		testutil.CompareVar(t, len(h.VsanDiskgroups), 0)
+		for _, vm := range h.VMs {
+			testutil.CompareVar(t, len(vm.Snapshots), 0)
+		}
+	}
+}
+
+func TestValidateUrls(t *testing.T) {
+	testutil.CompareVar(t, len(ValidateUrls(nil)), 1)
+	testutil.CompareVar(t, len(ValidateUrls([][]string{{"vc1", "10.0.0.1", "root", "vmware"}})), 0)
+
+	errs := ValidateUrls([][]string{
+		{"vc1", "10.0.0.1", "root"},
+		{"vc2", "", "root", "vmware"},
+	})
+	testutil.CompareVar(t, len(errs), 2)
+	testutil.CompareVar(t, errs[0].Error(), "urls[0] must be [name, address, username, password], got 3 element(s)")
+	testutil.CompareVar(t, errs[1].Error(), "urls[1] has an empty address")
+}
@@ -0,0 +1,374 @@
+package vcsa
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/influxdata/telegraf/dcai/topology/vsandiskgroup"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// the vSAN health service of vCenter, which govmomi does not bind, is
+// served over SOAP next to the vSphere API
+const (
+	vsanPath      = "/vsanHealth"
+	vsanNamespace = "vsan"
+	vsanVersion   = "6.6"
+)
+
+var (
+	// properties of HostVsanInternalSystem.QueryPhysicalVsanDisks
+	physicalVsanDiskProps = []string{"uuid", "lsom_objects_count", "disk_health"}
+
+	vsanClusterHealthSystem = types.ManagedObjectReference{
+		Type:  "VsanVcClusterHealthSystem",
+		Value: "vsan-cluster-health-system",
+	}
+)
+
+// vsanQueryVcClusterHealthSummary is the request of the cluster health
+// summary, the health checks are the cached ones unless FetchFromCache is
+// false
+type vsanQueryVcClusterHealthSummary struct {
+	This            types.ManagedObjectReference  `xml:"_this"`
+	Cluster         *types.ManagedObjectReference `xml:"cluster,omitempty"`
+	IncludeObjUuids *bool                         `xml:"includeObjUuids"`
+	FetchFromCache  *bool                         `xml:"fetchFromCache"`
+}
+
+type vsanQueryVcClusterHealthSummaryResponse struct {
+	Returnval vsanClusterHealthSummary `xml:"returnval"`
+}
+
+// vsanClusterHealthSummary holds the parts of the vSAN health summary of a
+// cluster which are collected
+type vsanClusterHealthSummary struct {
+	OverallHealth       string                          `xml:"overallHealth"`
+	Groups              []vsanClusterHealthGroup        `xml:"groups,omitempty"`
+	PhysicalDisksHealth []vsanPhysicalDiskHealthSummary `xml:"physicalDisksHealth,omitempty"`
+}
+
+type vsanClusterHealthGroup struct {
+	GroupId     string                  `xml:"groupId"`
+	GroupName   string                  `xml:"groupName"`
+	GroupHealth string                  `xml:"groupHealth"`
+	GroupTests  []vsanClusterHealthTest `xml:"groupTests,omitempty"`
+}
+
+type vsanClusterHealthTest struct {
+	TestId     string `xml:"testId"`
+	TestName   string `xml:"testName"`
+	TestHealth string `xml:"testHealth"`
+}
+
+// vsanPhysicalDiskHealthSummary is the health of the vSAN disks of a host
+type vsanPhysicalDiskHealthSummary struct {
+	Hostname string                   `xml:"hostname"`
+	Disks    []vsanPhysicalDiskHealth `xml:"disks,omitempty"`
+}
+
+// vsanPhysicalDiskHealth is the health of a vSAN disk, whose uuid is its
+// vSAN uuid
+type vsanPhysicalDiskHealth struct {
+	Name             string              `xml:"name"`
+	Uuid             string              `xml:"uuid"`
+	CongestionValue  *int32              `xml:"congestionValue"`
+	CongestionHealth string              `xml:"congestionHealth"`
+	ScsiDisk         *types.HostScsiDisk `xml:"scsiDisk,omitempty"`
+}
+
+type vsanQueryVcClusterHealthSummaryBody struct {
+	Req    *vsanQueryVcClusterHealthSummary         `xml:"urn:vsan VsanQueryVcClusterHealthSummary,omitempty"`
+	Res    *vsanQueryVcClusterHealthSummaryResponse `xml:"urn:vsan VsanQueryVcClusterHealthSummaryResponse,omitempty"`
+	Fault_ *soap.Fault                              `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *vsanQueryVcClusterHealthSummaryBody) Fault() *soap.Fault { return b.Fault_ }
+
+// physicalVsanDisk is one entry of the QueryPhysicalVsanDisks json, which
+// is keyed by the vSAN disk uuid
+type physicalVsanDisk struct {
+	LsomObjectsCount *int64 `json:"lsom_objects_count"`
+	DiskHealth       *struct {
+		HealthFlags int64 `json:"healthFlags"`
+	} `json:"disk_health"`
+}
+
+// GetVsanHealth returns the vSAN disk and disk group health of all vSAN
+// enabled hosts, and the health checks of their clusters. The disk
+// congestion and the health checks come from the vSAN health service, the
+// disks are reported without them when it cannot be queried.
+func (vcsa *VcsaConnector) GetVsanHealth() (*vsandiskgroup.VsanHealth, error) {
+	var (
+		hsmos []mo.HostSystem
+		ccmos []mo.ClusterComputeResource
+		hhs   []*vsandiskgroup.VsanHostHealth
+	)
+
+	err := vcsa.Retrieve(&vcsa.Client.ServiceContent.RootFolder, []string{"HostSystem"}, true, &hsmos)
+	if err != nil {
+		return nil, err
+	}
+	err = vcsa.Retrieve(&vcsa.Client.ServiceContent.RootFolder, []string{"ClusterComputeResource"}, true, &ccmos)
+	if err != nil {
+		return nil, err
+	}
+
+	// the vSAN cluster uuid is only known by the hosts of a cluster
+	clusterUuids := map[types.ManagedObjectReference]string{}
+
+	for _, hsmo := range hsmos {
+		if !isVsanEnabled(&hsmo) {
+			continue
+		}
+		hostName, hostUuid, ok := hostIdentity(&hsmo)
+		if !ok {
+			log.Printf("W! Skipping vSAN host %s without name or uuid\n", hsmo.Self.Value)
+			continue
+		}
+
+		// the disk group layout in the host config is still reported when
+		// the vSAN queries fail, with the disk health unknown
+		results, err := vcsa.queryDisksForVsan(&hsmo)
+		if err != nil {
+			log.Printf("W! Cannot query vSAN disks of host %s. %s", hostName, err)
+		}
+		physicalDisks, err := vcsa.queryPhysicalVsanDisks(&hsmo)
+		if err != nil {
+			log.Printf("D! Cannot query vSAN physical disks of host %s. %s", hostName, err)
+		}
+
+		clusterUuid := ""
+		if hsmo.Config.VsanHostConfig.ClusterInfo != nil {
+			clusterUuid = hsmo.Config.VsanHostConfig.ClusterInfo.Uuid
+		}
+		if hsmo.Parent != nil && hsmo.Parent.Type == "ClusterComputeResource" && clusterUuid != "" {
+			clusterUuids[*hsmo.Parent] = clusterUuid
+		}
+		var diskMaps []types.VsanHostDiskMapInfo
+		if hsmo.Config.VsanHostConfig.StorageInfo != nil {
+			diskMaps = hsmo.Config.VsanHostConfig.StorageInfo.DiskMapInfo
+		}
+
+		hhs = append(hhs, NewVsanHostHealth(hostName, hostUuid, clusterUuid, diskMaps, results, physicalDisks))
+	}
+
+	health := &vsandiskgroup.VsanHealth{Hosts: hhs}
+	for _, ccmo := range ccmos {
+		clusterUuid, ok := clusterUuids[ccmo.Self]
+		if !ok {
+			continue
+		}
+		summary, err := vcsa.queryVsanClusterHealth(ccmo.Self)
+		if err != nil {
+			log.Printf("W! Cannot query vSAN health of cluster %s. %s", ccmo.Name, err)
+			continue
+		}
+		health.Clusters = append(health.Clusters, newVsanClusterHealth(ccmo.Name, clusterUuid, summary, hhs))
+	}
+
+	return health, nil
+}
+
+// vsanClient returns a client of the vSAN health service sharing the
+// session of the vSphere client
+func (vcsa *VcsaConnector) vsanClient() *soap.Client {
+	sc := vcsa.Client.Client.NewServiceClient(vsanPath, vsanNamespace)
+	sc.Version = vsanVersion
+	return sc
+}
+
+func (vcsa *VcsaConnector) queryVsanClusterHealth(cluster types.ManagedObjectReference) (*vsanClusterHealthSummary, error) {
+	var reqBody, resBody vsanQueryVcClusterHealthSummaryBody
+
+	reqBody.Req = &vsanQueryVcClusterHealthSummary{
+		This:            vsanClusterHealthSystem,
+		Cluster:         &cluster,
+		IncludeObjUuids: types.NewBool(false),
+		FetchFromCache:  types.NewBool(true),
+	}
+	if err := vcsa.vsanClient().RoundTrip(*vcsa.ClientCtx, &reqBody, &resBody); err != nil {
+		return nil, err
+	}
+	if resBody.Res == nil {
+		return nil, fmt.Errorf("Empty vSAN health summary")
+	}
+	return &resBody.Res.Returnval, nil
+}
+
+// newVsanClusterHealth flattens the health checks of a cluster health
+// summary and sets the congestion of the disks of hhs from its disk health
+func newVsanClusterHealth(name string, uuid string, summary *vsanClusterHealthSummary, hhs []*vsandiskgroup.VsanHostHealth) *vsandiskgroup.VsanClusterHealth {
+	c := &vsandiskgroup.VsanClusterHealth{
+		Name:          name,
+		Uuid:          uuid,
+		OverallHealth: summary.OverallHealth,
+	}
+	for _, g := range summary.Groups {
+		for _, t := range g.GroupTests {
+			c.Checks = append(c.Checks, &vsandiskgroup.VsanHealthCheck{
+				GroupId:     g.GroupId,
+				GroupName:   g.GroupName,
+				GroupHealth: g.GroupHealth,
+				TestId:      t.TestId,
+				TestName:    t.TestName,
+				TestHealth:  t.TestHealth,
+			})
+		}
+	}
+
+	// the disks are known by their vSAN uuid, or by their canonical name
+	// when the vSAN queries of their host failed
+	disks := map[string]*vsandiskgroup.VsanDiskHealth{}
+	for _, hh := range hhs {
+		if hh.ClusterUuid != uuid {
+			continue
+		}
+		for _, d := range hh.Disks {
+			if d.VsanUuid != "" {
+				disks[d.VsanUuid] = d
+			}
+			disks[hh.HostName+"/"+d.Name] = d
+		}
+	}
+	for _, pdh := range summary.PhysicalDisksHealth {
+		for _, pd := range pdh.Disks {
+			d, ok := disks[pd.Uuid]
+			if !ok && pd.ScsiDisk != nil {
+				d, ok = disks[pdh.Hostname+"/"+pd.ScsiDisk.CanonicalName]
+			}
+			if !ok || pd.CongestionValue == nil {
+				continue
+			}
+			d.Congestion = int64(*pd.CongestionValue)
+			d.CongestionHealth = pd.CongestionHealth
+		}
+	}
+
+	return c
+}
+
+func isVsanEnabled(hsmo *mo.HostSystem) bool {
+	if hsmo.Config == nil || hsmo.Config.VsanHostConfig == nil {
+		return false
+	}
+	enabled := hsmo.Config.VsanHostConfig.Enabled
+	return enabled != nil && *enabled
+}
+
+// hostIdentity returns the name and the hardware uuid of a host, which are
+// not retrieved for a disconnected host
+func hostIdentity(hsmo *mo.HostSystem) (string, string, bool) {
+	if hsmo.Summary.Config.Name == "" || hsmo.Hardware == nil {
+		return "", "", false
+	}
+	return hsmo.Summary.Config.Name, hsmo.Hardware.SystemInfo.Uuid, true
+}
+
+func (vcsa *VcsaConnector) queryDisksForVsan(hsmo *mo.HostSystem) ([]types.VsanHostDiskResult, error) {
+	if hsmo.ConfigManager.VsanSystem == nil {
+		return nil, nil
+	}
+	req := types.QueryDisksForVsan{This: *hsmo.ConfigManager.VsanSystem}
+	res, err := methods.QueryDisksForVsan(*vcsa.ClientCtx, vcsa.Client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Returnval, nil
+}
+
+func (vcsa *VcsaConnector) queryPhysicalVsanDisks(hsmo *mo.HostSystem) (string, error) {
+	if hsmo.ConfigManager.VsanInternalSystem == nil {
+		return "", nil
+	}
+	req := types.QueryPhysicalVsanDisks{This: *hsmo.ConfigManager.VsanInternalSystem, Props: physicalVsanDiskProps}
+	res, err := methods.QueryPhysicalVsanDisks(*vcsa.ClientCtx, vcsa.Client.Client, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.Returnval, nil
+}
+
+func newVsanDiskHealth(d *types.HostScsiDisk) *vsandiskgroup.VsanDiskHealth {
+	return &vsandiskgroup.VsanDiskHealth{
+		Name:             d.CanonicalName,
+		WWN:              getDiskWwn(d.CanonicalName, d.Uuid),
+		IsSsd:            d.Ssd != nil && *d.Ssd,
+		OperationalState: d.OperationalState,
+		Components:       -1,
+		Congestion:       -1,
+	}
+}
+
+// NewVsanHostHealth joins the disk group layout of VsanHostConfig with the
+// QueryDisksForVsan results and the QueryPhysicalVsanDisks json
+func NewVsanHostHealth(hostName string, hostUuid string, clusterUuid string, diskMaps []types.VsanHostDiskMapInfo, results []types.VsanHostDiskResult, physicalDisks string) *vsandiskgroup.VsanHostHealth {
+	hh := &vsandiskgroup.VsanHostHealth{
+		HostName:    hostName,
+		HostUuid:    hostUuid,
+		ClusterUuid: clusterUuid,
+	}
+
+	pds := map[string]physicalVsanDisk{}
+	if physicalDisks != "" {
+		if err := json.Unmarshal([]byte(physicalDisks), &pds); err != nil {
+			log.Printf("W! Cannot parse vSAN physical disks of host %s. %s", hostName, err)
+		}
+	}
+
+	disks := map[string]*vsandiskgroup.VsanDiskHealth{}
+	getDisk := func(sd *types.HostScsiDisk) *vsandiskgroup.VsanDiskHealth {
+		if d, ok := disks[sd.CanonicalName]; ok {
+			return d
+		}
+		d := newVsanDiskHealth(sd)
+		disks[sd.CanonicalName] = d
+		hh.Disks = append(hh.Disks, d)
+		return d
+	}
+
+	for _, r := range results {
+		// disks not claimed by vSAN are eligible or ineligible
+		if r.State != "inUse" {
+			continue
+		}
+		d := getDisk(&r.Disk)
+		d.State = r.State
+		d.VsanUuid = r.VsanUuid
+		d.Degraded = r.Degraded != nil && *r.Degraded
+		if r.Error != nil {
+			d.Error = r.Error.LocalizedMessage
+		}
+		if pd, ok := pds[r.VsanUuid]; ok {
+			if pd.LsomObjectsCount != nil {
+				d.Components = *pd.LsomObjectsCount
+			}
+			if pd.DiskHealth != nil {
+				d.HealthFlags = pd.DiskHealth.HealthFlags
+			}
+		}
+	}
+
+	for _, dm := range diskMaps {
+		cache := getDisk(&dm.Mapping.Ssd)
+		cache.IsCache = true
+		dg := &vsandiskgroup.VsanDiskgroupHealth{
+			Uuid:      cache.VsanUuid,
+			CacheDisk: cache,
+			Mounted:   dm.Mounted,
+		}
+		for i := range dm.Mapping.NonSsd {
+			dg.CapacityDisks = append(dg.CapacityDisks, getDisk(&dm.Mapping.NonSsd[i]))
+		}
+		for _, d := range dg.Disks() {
+			d.DiskgroupUuid = dg.Uuid
+		}
+		hh.Diskgroups = append(hh.Diskgroups, dg)
+	}
+
+	return hh
+}
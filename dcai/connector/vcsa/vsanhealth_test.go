@@ -0,0 +1,203 @@
+package vcsa
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+	"github.com/influxdata/telegraf/dcai/testutil/vcsim"
+	"github.com/influxdata/telegraf/dcai/topology/vsandiskgroup"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var (
+	cacheDisk = types.HostScsiDisk{
+		ScsiLun: types.ScsiLun{
+			CanonicalName:    "naa.55cd2e404b7ee0e9",
+			OperationalState: []string{"ok"},
+		},
+		Ssd: types.NewBool(true),
+	}
+	capacityDisk1 = types.HostScsiDisk{
+		ScsiLun: types.ScsiLun{
+			CanonicalName:    "naa.5000c5005f50e6ab",
+			OperationalState: []string{"ok"},
+		},
+		Ssd: types.NewBool(false),
+	}
+	capacityDisk2 = types.HostScsiDisk{
+		ScsiLun: types.ScsiLun{
+			CanonicalName:    "naa.5000c5005f50e7cd",
+			OperationalState: []string{"lostCommunication"},
+		},
+		Ssd: types.NewBool(false),
+	}
+	localDisk = types.HostScsiDisk{
+		ScsiLun: types.ScsiLun{
+			CanonicalName:    "naa.50014ee60711e39c",
+			OperationalState: []string{"ok"},
+		},
+		Ssd: types.NewBool(false),
+	}
+
+	diskMaps = []types.VsanHostDiskMapInfo{
+		{
+			Mapping: types.VsanHostDiskMapping{
+				Ssd:    cacheDisk,
+				NonSsd: []types.HostScsiDisk{capacityDisk1, capacityDisk2},
+			},
+			Mounted: true,
+		},
+	}
+
+	diskResults = []types.VsanHostDiskResult{
+		{State: "inUse", Disk: cacheDisk, VsanUuid: "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01", Degraded: types.NewBool(false)},
+		{State: "inUse", Disk: capacityDisk1, VsanUuid: "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a02", Degraded: types.NewBool(true)},
+		{State: "inUse", Disk: capacityDisk2, VsanUuid: "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a03", Degraded: types.NewBool(false)},
+		{State: "ineligible", Disk: localDisk},
+	}
+
+	physicalDisks = `{
+  "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01": {"uuid": "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01", "lsom_objects_count": 0, "disk_health": {"healthFlags": 0}},
+  "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a02": {"uuid": "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a02", "lsom_objects_count": 12, "disk_health": {"healthFlags": 0}},
+  "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a03": {"uuid": "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a03", "lsom_objects_count": 7, "disk_health": {"healthFlags": 4}}
+}`
+)
+
+func TestNewVsanHostHealth(t *testing.T) {
+	hh := NewVsanHostHealth("esxi-01", "4c4c4544-0058-4b10-8032-b7c04f4a4832", "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5", diskMaps, diskResults, physicalDisks)
+
+	testutil.CompareVar(t, len(hh.Disks), 3)
+	testutil.CompareVar(t, len(hh.Diskgroups), 1)
+
+	cache := hh.Disks[0]
+	testutil.CompareVar(t, cache.WWN, "55cd2e404b7ee0e9")
+	testutil.CompareVar(t, cache.IsSsd, true)
+	testutil.CompareVar(t, cache.IsCache, true)
+	testutil.CompareVar(t, cache.DiskgroupUuid, "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01")
+	testutil.CompareVar(t, cache.Components, int64(0))
+	testutil.CompareVar(t, cache.Health(), vsandiskgroup.HealthHealthy)
+
+	degraded := hh.Disks[1]
+	testutil.CompareVar(t, degraded.WWN, "5000c5005f50e6ab")
+	testutil.CompareVar(t, degraded.IsCache, false)
+	testutil.CompareVar(t, degraded.Components, int64(12))
+	testutil.CompareVar(t, degraded.Health(), vsandiskgroup.HealthDegraded)
+
+	lost := hh.Disks[2]
+	testutil.CompareVar(t, lost.HealthFlags, int64(4))
+	testutil.CompareVar(t, lost.Health(), vsandiskgroup.HealthUnhealthy)
+
+	dg := hh.Diskgroups[0]
+	testutil.CompareVar(t, dg.Uuid, "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01")
+	testutil.CompareVar(t, dg.CacheDisk, cache)
+	testutil.CompareVar(t, len(dg.CapacityDisks), 2)
+	testutil.CompareVar(t, dg.Health(), vsandiskgroup.HealthUnhealthy)
+}
+
+func TestNewVsanHostHealthWithoutQueries(t *testing.T) {
+	hh := NewVsanHostHealth("esxi-01", "4c4c4544-0058-4b10-8032-b7c04f4a4832", "", diskMaps, nil, "")
+
+	testutil.CompareVar(t, len(hh.Disks), 3)
+	for _, d := range hh.Disks {
+		testutil.CompareVar(t, d.Components, int64(-1))
+		testutil.CompareVar(t, d.Health(), vsandiskgroup.HealthUnknown)
+	}
+	testutil.CompareVar(t, hh.Diskgroups[0].Health(), vsandiskgroup.HealthUnknown)
+}
+
+func TestHostIdentity(t *testing.T) {
+	// a disconnected host has neither its config summary nor its hardware
+	_, _, ok := hostIdentity(&mo.HostSystem{})
+	testutil.CompareVar(t, ok, false)
+
+	hsmo := &mo.HostSystem{
+		Summary: types.HostListSummary{Config: types.HostConfigSummary{Name: "esxi-01"}},
+	}
+	_, _, ok = hostIdentity(hsmo)
+	testutil.CompareVar(t, ok, false)
+
+	hsmo.Hardware = &types.HostHardwareInfo{SystemInfo: types.HostSystemInfo{Uuid: "4c4c4544-0058-4b10-8032-b7c04f4a4832"}}
+	name, uuid, ok := hostIdentity(hsmo)
+	testutil.CompareVar(t, ok, true)
+	testutil.CompareVar(t, name, "esxi-01")
+	testutil.CompareVar(t, uuid, "4c4c4544-0058-4b10-8032-b7c04f4a4832")
+}
+
+func TestGetVsanHealthSimulator(t *testing.T) {
+	vc, sim := newSimulatorConnector(t, vcsim.DefaultTopology)
+	defer sim.Close()
+	defer vc.DisconnectVsphere()
+
+	health, err := vc.GetVsanHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// vSAN is enabled on the clustered hosts only
+	testutil.CompareVar(t, len(health.Hosts), vcsim.DefaultTopology.Cluster*vcsim.DefaultTopology.ClusterHost)
+	for _, hh := range health.Hosts {
+		testutil.CompareVar(t, hh.ClusterUuid, vcsim.VsanUuid)
+		testutil.CompareVar(t, len(hh.Diskgroups), 1)
+		testutil.CompareVar(t, len(hh.Diskgroups[0].CapacityDisks), vcsim.DefaultTopology.LunsPerHost-1)
+
+		// the last local LUN is congested
+		dg := hh.Diskgroups[0]
+		testutil.CompareVar(t, dg.CacheDisk.Congestion, int64(0))
+		congested := dg.CapacityDisks[len(dg.CapacityDisks)-1]
+		testutil.CompareVar(t, congested.Congestion, int64(vcsim.VsanCongestion))
+		testutil.CompareVar(t, congested.CongestionHealth, "yellow")
+	}
+
+	testutil.CompareVar(t, len(health.Clusters), vcsim.DefaultTopology.Cluster)
+	c := health.Clusters[0]
+	testutil.CompareVar(t, c.Uuid, vcsim.VsanUuid)
+	testutil.CompareVar(t, c.OverallHealth, "yellow")
+	testutil.CompareVar(t, len(c.Checks), 3)
+	testutil.CompareVar(t, c.Checks[2].TestId, "com.vmware.vsan.health.test.physdiskcongestion")
+	testutil.CompareVar(t, c.Checks[2].GroupName, "Physical disk")
+}
+
+func TestNewVsanClusterHealth(t *testing.T) {
+	hh := NewVsanHostHealth("esxi-01", "4c4c4544-0058-4b10-8032-b7c04f4a4832", "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5", diskMaps, diskResults, physicalDisks)
+	congested, green := int32(150), int32(10)
+	summary := &vsanClusterHealthSummary{
+		OverallHealth: "red",
+		Groups: []vsanClusterHealthGroup{
+			{
+				GroupId:     "com.vmware.vsan.health.test.network",
+				GroupName:   "Network",
+				GroupHealth: "red",
+				GroupTests: []vsanClusterHealthTest{
+					{TestId: "com.vmware.vsan.health.test.hostdisconnected", TestName: "Hosts disconnected from VC", TestHealth: "red"},
+				},
+			},
+		},
+		PhysicalDisksHealth: []vsanPhysicalDiskHealthSummary{
+			{
+				Hostname: "esxi-01",
+				Disks: []vsanPhysicalDiskHealth{
+					// known by its vSAN uuid
+					{Uuid: "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01", CongestionValue: &green, CongestionHealth: "green"},
+					// known by its canonical name
+					{ScsiDisk: &capacityDisk1, CongestionValue: &congested, CongestionHealth: "red"},
+					// not in a disk group of the host
+					{Uuid: "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3aff", CongestionValue: &congested, CongestionHealth: "red"},
+				},
+			},
+		},
+	}
+
+	c := newVsanClusterHealth("cluster-01", "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5", summary, []*vsandiskgroup.VsanHostHealth{hh})
+	testutil.CompareVar(t, c.Name, "cluster-01")
+	testutil.CompareVar(t, c.OverallHealth, "red")
+	testutil.CompareVar(t, len(c.Checks), 1)
+	testutil.CompareVar(t, c.Checks[0].GroupId, "com.vmware.vsan.health.test.network")
+	testutil.CompareVar(t, c.Checks[0].TestHealth, "red")
+
+	testutil.CompareVar(t, hh.Disks[0].Congestion, int64(10))
+	testutil.CompareVar(t, hh.Disks[0].Health(), vsandiskgroup.HealthHealthy)
+	testutil.CompareVar(t, hh.Disks[1].Congestion, int64(150))
+	testutil.CompareVar(t, hh.Disks[1].CongestionHealth, "red")
+	testutil.CompareVar(t, hh.Disks[2].Congestion, int64(-1))
+}
@@ -3,13 +3,15 @@ package dcai
 import (
 	"fmt"
 	"log"
+	"runtime"
 
 	"github.com/influxdata/telegraf/dcai/connector/vcsa"
 	saicluster "github.com/influxdata/telegraf/dcai/sai/cluster"
 	"github.com/influxdata/telegraf/dcai/topology/cluster"
 	"github.com/influxdata/telegraf/dcai/topology/datacenter"
 	"github.com/influxdata/telegraf/dcai/topology/host"
-	"github.com/influxdata/telegraf/dcai/topology/host/linux"
+	_ "github.com/influxdata/telegraf/dcai/topology/host/all"
+	"github.com/influxdata/telegraf/dcai/topology/vsandiskgroup"
 	"github.com/influxdata/telegraf/dcai/type"
 	"github.com/influxdata/telegraf/dcai/util"
 	"github.com/influxdata/telegraf/internal/config"
@@ -26,16 +28,33 @@ type DcaiAgent struct {
 	version        string
 	commit         string
 	branch         string
+
+	// the dcai keys of [agent] the agent was initialized from
+	keys dcaiKeys
 }
 
-func FetchAgentHostConfig(t dcaitype.AgentType, dmidecodePath string) (host.HostConfig, error) {
-	if t == dcaitype.AgentLinux || t == dcaitype.AgentVMware {
-		return linux.NewLinuxHostConfig(dmidecodePath)
-	} else {
-		return nil, fmt.Errorf("Unsupported agent host type %s", t.String())
+type dcaiKeys struct {
+	saiClusterDomainId string
+	saiClusterName     string
+	agentType          string
+	dmidecodePath      string
+}
+
+func keysOf(config *config.Config) dcaiKeys {
+	return dcaiKeys{
+		saiClusterDomainId: config.Agent.SaiClusterDomainId,
+		saiClusterName:     config.Agent.SaiClusterName,
+		agentType:          config.Agent.AgentType,
+		dmidecodePath:      config.Agent.DmidecodePath,
 	}
 }
 
+// FetchAgentHostConfig creates the agent host config by the provider
+// registered for agent type t with host.Add
+func FetchAgentHostConfig(t dcaitype.AgentType, dmidecodePath string) (host.HostConfig, error) {
+	return host.New(t, dmidecodePath)
+}
+
 func FetchDefaultDatacenter(telegrafConfig *config.Config) (*datacenter.DatacenterConfig, error) {
 	var (
 		err error
@@ -85,41 +104,136 @@ func FetchVsphereTopology(vcsa *vcsa.VcsaConnector) ([]*datacenter.DatacenterCon
 	return vcsa.GetTopology()
 }
 
+func FetchVsanHealth(vcsa *vcsa.VcsaConnector) (*vsandiskgroup.VsanHealth, error) {
+	if vcsa == nil {
+		return nil, fmt.Errorf("null vcsa connector")
+	}
+
+	// connect and defer the disconnect
+	err := vcsa.ConnectVsphere()
+	if err != nil {
+		return nil, err
+	}
+	defer vcsa.DisconnectVsphere()
+
+	return vcsa.GetVsanHealth()
+}
+
 func NewDcaiAgent(config *config.Config, nextver string, ver string, commit string, branch string) (*DcaiAgent, error) {
 	if dcaiagent != nil {
 		log.Printf("W! dcaiagent instance already exists")
 		return dcaiagent, nil
 	}
 
+	dcaiagent = new(DcaiAgent)
+	dcaiagent.nextVersion = nextver
+	dcaiagent.version = ver
+	dcaiagent.commit = commit
+	dcaiagent.branch = branch
+	if err := dcaiagent.init(config); err != nil {
+		return nil, err
+	}
+	return dcaiagent, nil
+}
+
+// ReloadDcaiAgent points the dcai agent to a reloaded config. The agent is
+// initialized again when the dcai keys of [agent] changed, the running one
+// is kept when the new keys are invalid.
+func ReloadDcaiAgent(config *config.Config) (*DcaiAgent, error) {
+	if dcaiagent == nil {
+		return nil, fmt.Errorf("dcaiagent instance does not exist")
+	}
+
+	a := &DcaiAgent{
+		nextVersion: dcaiagent.nextVersion,
+		version:     dcaiagent.version,
+		commit:      dcaiagent.commit,
+		branch:      dcaiagent.branch,
+	}
+	if keysOf(config) == dcaiagent.keys {
+		a.Agenttype = dcaiagent.Agenttype
+		a.TelegrafConfig = config
+		a.keys = dcaiagent.keys
+		config.Agent.DmidecodePath = dcaiagent.TelegrafConfig.Agent.DmidecodePath
+	} else {
+		log.Printf("I! dcai settings changed, initializing the dcai agent again")
+		if err := a.init(config); err != nil {
+			return nil, err
+		}
+	}
+	dcaiagent = a
+	return dcaiagent, nil
+}
+
+func (a *DcaiAgent) init(config *config.Config) error {
+	a.keys = keysOf(config)
+
 	// translate agent type
 	var t dcaitype.AgentType
 	t = t.LookupCode(config.Agent.AgentType)
-	// default to linux agent type
+	// default to the agent type of the running OS
 	if t == dcaitype.AgentUnknown {
-		t = dcaitype.AgentLinux
+		if runtime.GOOS == "windows" {
+			t = dcaitype.AgentWindows
+		} else {
+			t = dcaitype.AgentLinux
+		}
 	}
 
-	dcaiagent = new(DcaiAgent)
-	dcaiagent.Agenttype = t
-	dcaiagent.TelegrafConfig = config
-	if config.Agent.DmidecodePath != "" {
-		err := util.CheckCmdPath(config.Agent.DmidecodePath)
-		if err != nil {
-			return nil, fmt.Errorf("Invalid dmidecode_path in config file")
+	a.Agenttype = t
+	a.TelegrafConfig = config
+	// the windows agent reads hardware information from WMI instead of dmidecode
+	if t != dcaitype.AgentWindows {
+		if config.Agent.DmidecodePath != "" {
+			err := util.CheckCmdPath(config.Agent.DmidecodePath)
+			if err != nil {
+				return fmt.Errorf("Invalid dmidecode_path in config file")
+			}
+		} else {
+			path, err := util.GetCmdPathInOsPath("dmidecode")
+			if err != nil {
+				return fmt.Errorf("Cannot find dmidecode in system path")
+			} else {
+				a.TelegrafConfig.Agent.DmidecodePath = path
+			}
 		}
-	} else {
-		path, err := util.GetCmdPathInOsPath("dmidecode")
-		if err != nil {
-			return nil, fmt.Errorf("Cannot find dmidecode in system path")
+	}
+	return nil
+}
+
+// ValidateConfig checks the dcai keys of the [agent] table, the checks
+// NewDcaiAgent and FetchAgentHostConfig would fail on at startup
+func ValidateConfig(config *config.Config) []error {
+	var (
+		errs []error
+		t    dcaitype.AgentType
+	)
+
+	t = t.LookupCode(config.Agent.AgentType)
+	if t == dcaitype.AgentUnknown {
+		if config.Agent.AgentType != "" {
+			errs = append(errs, fmt.Errorf("Unknown agent_type %s", config.Agent.AgentType))
+		}
+		if runtime.GOOS == "windows" {
+			t = dcaitype.AgentWindows
 		} else {
-			dcaiagent.TelegrafConfig.Agent.DmidecodePath = path
+			t = dcaitype.AgentLinux
 		}
 	}
-	dcaiagent.nextVersion = nextver
-	dcaiagent.version = ver
-	dcaiagent.commit = commit
-	dcaiagent.branch = branch
-	return dcaiagent, nil
+	if _, ok := host.HostConfigs[t]; !ok {
+		errs = append(errs, fmt.Errorf("Unsupported agent host type %s", t))
+	}
+
+	if t != dcaitype.AgentWindows {
+		dmidecode := config.Agent.DmidecodePath
+		if dmidecode == "" {
+			dmidecode = "dmidecode"
+		}
+		if err := util.ValidateCmd(dmidecode, true); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid dmidecode_path: %s", err))
+		}
+	}
+	return errs
 }
 
 func GetDcaiAgent() (*DcaiAgent, error) {
@@ -136,6 +250,16 @@ func (a *DcaiAgent) GetTelegrafVersion() string {
 	return a.version
 }
 
+// GetAgentVersion returns the version of the agent with its git branch and
+// commit, as reported by telegraf --info
+func (a *DcaiAgent) GetAgentVersion() string {
+	v := "v" + a.version
+	if a.version == "" {
+		v = fmt.Sprintf("v%s~%s", a.nextVersion, a.commit)
+	}
+	return fmt.Sprintf("Telegraf %s (git: %s %s)", v, a.branch, a.commit)
+}
+
 func (a *DcaiAgent) GetSaiClusterDomainId() string {
 	id := a.TelegrafConfig.Agent.SaiClusterDomainId
 	if id == "" {
@@ -95,6 +95,32 @@ func SendMetricsMonitoring(
 	return nil
 }
 
+// SendDiskError sends an error of a disk found in the kernel log, diskWWN is
+// empty when the error is not of a known disk
+func SendDiskError(
+	acc telegraf.Accumulator,
+	diskWWN string,
+	details string,
+	level dcaitype.LogLevel,
+) error {
+	d, err := dcai.GetDcaiAgent()
+	if err != nil {
+		return err
+	}
+
+	m, err := createSaiEventMetric(d, dcaitype.EventTypeDiskError, dcaitype.EventTitleDiskError, level, details)
+	if err != nil {
+		return err
+	}
+	if diskWWN != "" {
+		m.AddField("disk_domain_id", diskWWN)
+	}
+
+	acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+
+	return nil
+}
+
 func createSaiEventMetric(
 	d *dcai.DcaiAgent,
 	eventType dcaitype.EventType,
@@ -56,3 +56,23 @@ func TestSaiEventDataPoint(t *testing.T) {
 
 	assert.True(t, acc.HasMeasurement("sai_event"), "expected has measurement called sai_event")
 }
+
+func TestSaiEventDiskError(t *testing.T) {
+
+	var acc testutil.Accumulator
+
+	dcai.NewDcaiAgent(mockConfig, "1.5.0", "", "test", "")
+	err := SendDiskError(
+		&acc,
+		"5000c5005f50e6ab",
+		"sda: blk_update_request: I/O error, dev sda, sector 1234",
+		dcaitype.LogLevelError,
+	)
+	require.NoError(t, err)
+
+	require.True(t, acc.HasMeasurement("sai_event"), "expected has measurement called sai_event")
+	m := acc.Metrics[0]
+	assert.Equal(t, "5000c5005f50e6ab", m.Fields["disk_domain_id"])
+	assert.Equal(t, "Error", m.Fields["event_level"])
+	assert.Equal(t, "Disk Error", m.Fields["event_type"])
+}
@@ -0,0 +1,146 @@
+package disk
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	sysDevBlockPath   = "/sys/dev/block"
+	procMountInfoPath = "/proc/self/mountinfo"
+)
+
+func init() {
+	// the mounts of the host when telegraf runs in a container
+	if hostProc := os.Getenv("HOST_PROC"); hostProc != "" {
+		procMountInfoPath = filepath.Join(hostProc, "1", "mountinfo")
+	}
+}
+
+// GetDisksOfDevNumber maps a block device number, e.g. 8:1, to the sorted
+// kernel names of the disks holding it: the disk of a partition, or the disks
+// under a device mapper or md device. It returns nil when no disk is found.
+func GetDisksOfDevNumber(devnum string) []string {
+	// /sys/dev/block/8:1 -> ../../devices/.../block/sda/sda1
+	target, err := os.Readlink(filepath.Join(sysDevBlockPath, devnum))
+	if err != nil {
+		return nil
+	}
+	disks := getDisksOfBlockDevice(filepath.Base(target), make(map[string]bool))
+	sort.Strings(disks)
+	return disks
+}
+
+func getDisksOfBlockDevice(name string, seen map[string]bool) []string {
+	name = GetDiskOfPartition(name)
+	if seen[name] {
+		return nil
+	}
+	seen[name] = true
+
+	// dm-0 or md0 are stacked on the devices in slaves
+	slaves, err := ioutil.ReadDir(filepath.Join(sysBlockPath, name, "slaves"))
+	if err == nil && len(slaves) > 0 {
+		var disks []string
+		for _, slave := range slaves {
+			disks = append(disks, getDisksOfBlockDevice(slave.Name(), seen)...)
+		}
+		return disks
+	}
+	if _, err := os.Stat(filepath.Join(sysBlockPath, name)); err != nil {
+		return nil
+	}
+	return []string{name}
+}
+
+// GetDevNumberOfPath returns the number, e.g. 8:1, of the block device holding
+// the path, from the mount with the longest mount point containing it. The
+// anonymous devices of btrfs or overlay mounts are mapped to their source
+// device.
+func GetDevNumberOfPath(path string) (string, error) {
+	f, err := os.Open(procMountInfoPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	path = filepath.Clean(path)
+	var devnum, source, mountPoint string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		sep := 6
+		for sep < len(fields) && fields[sep] != "-" {
+			sep++
+		}
+		if sep+2 >= len(fields) {
+			continue
+		}
+
+		mp := unescapeMountInfo(fields[4])
+		if !containsPath(mp, path) || len(mp) < len(mountPoint) {
+			continue
+		}
+		devnum, source, mountPoint = fields[2], unescapeMountInfo(fields[sep+2]), mp
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if devnum == "" {
+		return "", fmt.Errorf("Cannot find the mount of %s", path)
+	}
+
+	if strings.HasPrefix(devnum, "0:") && strings.HasPrefix(source, "/dev/") {
+		if dev, err := getDevNumberOfNode(source); err == nil {
+			return dev, nil
+		}
+	}
+	return devnum, nil
+}
+
+// getDevNumberOfNode returns the number of a device node, e.g. /dev/sdb or
+// /dev/mapper/vg-data, from /sys/class/block/<name>/dev
+func getDevNumberOfNode(node string) (string, error) {
+	if target, err := filepath.EvalSymlinks(node); err == nil {
+		node = target
+	}
+	out, err := ioutil.ReadFile(filepath.Join(sysClassBlockPath, filepath.Base(node), "dev"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func containsPath(dir string, path string) bool {
+	return dir == "/" || path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// unescapeMountInfo decodes the octal escapes, e.g. \040 for a space, of the
+// paths in mountinfo
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			var c byte
+			if _, err := fmt.Sscanf(s[i+1:i+4], "%o", &c); err == nil {
+				b = append(b, c)
+				i += 3
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
@@ -0,0 +1,101 @@
+package disk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+func TestGetDisksOfDevNumber(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devnum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysBlockPath = filepath.Join(dir, "sys", "block")
+	sysClassBlockPath = filepath.Join(dir, "sys", "class", "block")
+	sysDevBlockPath = filepath.Join(dir, "sys", "dev", "block")
+	defer func() {
+		sysBlockPath = "/sys/block"
+		sysClassBlockPath = "/sys/class/block"
+		sysDevBlockPath = "/sys/dev/block"
+	}()
+
+	// sda1 is a partition, dm-0 a logical volume over sdb1 and sdc
+	devices := filepath.Join(dir, "sys", "devices")
+	blocks := map[string]string{
+		"sda":  filepath.Join("pci0000:00", "block", "sda"),
+		"sdb":  filepath.Join("pci0000:00", "block", "sdb"),
+		"sdc":  filepath.Join("pci0000:00", "block", "sdc"),
+		"dm-0": filepath.Join("virtual", "block", "dm-0"),
+	}
+	for name, path := range blocks {
+		os.MkdirAll(filepath.Join(devices, path), 0755)
+		os.MkdirAll(sysBlockPath, 0755)
+		os.Symlink(filepath.Join("..", "devices", path), filepath.Join(sysBlockPath, name))
+	}
+	for _, part := range []string{"sda1", "sdb1"} {
+		path := filepath.Join(devices, blocks[part[:3]], part)
+		os.MkdirAll(path, 0755)
+		ioutil.WriteFile(filepath.Join(path, "partition"), []byte("1\n"), 0644)
+		os.MkdirAll(sysClassBlockPath, 0755)
+		os.Symlink(path, filepath.Join(sysClassBlockPath, part))
+	}
+	os.MkdirAll(filepath.Join(devices, blocks["dm-0"], "slaves"), 0755)
+	os.Symlink(filepath.Join(devices, blocks["sdc"]), filepath.Join(devices, blocks["dm-0"], "slaves", "sdc"))
+	os.Symlink(filepath.Join(devices, blocks["sdb"], "sdb1"), filepath.Join(devices, blocks["dm-0"], "slaves", "sdb1"))
+
+	os.MkdirAll(sysDevBlockPath, 0755)
+	os.Symlink(filepath.Join(devices, blocks["sda"], "sda1"), filepath.Join(sysDevBlockPath, "8:1"))
+	os.Symlink(filepath.Join(devices, blocks["sda"]), filepath.Join(sysDevBlockPath, "8:0"))
+	os.Symlink(filepath.Join(devices, blocks["dm-0"]), filepath.Join(sysDevBlockPath, "253:0"))
+
+	testutil.CompareVar(t, GetDisksOfDevNumber("8:1"), []string{"sda"})
+	testutil.CompareVar(t, GetDisksOfDevNumber("8:0"), []string{"sda"})
+	testutil.CompareVar(t, GetDisksOfDevNumber("253:0"), []string{"sdb", "sdc"})
+	if disks := GetDisksOfDevNumber("7:0"); disks != nil {
+		t.Errorf("GetDisksOfDevNumber should return nil for an unknown device, got %v", disks)
+	}
+}
+
+func TestGetDevNumberOfPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devnum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	procMountInfoPath = filepath.Join(dir, "mountinfo")
+	sysClassBlockPath = filepath.Join(dir, "sys", "class", "block")
+	defer func() {
+		procMountInfoPath = "/proc/self/mountinfo"
+		sysClassBlockPath = "/sys/class/block"
+	}()
+
+	mountInfo := `21 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+25 21 0:44 / /var/lib/docker rw,relatime shared:12 - btrfs /dev/sdb rw,space_cache
+26 21 253:0 / /srv/my\040data rw,relatime shared:13 - xfs /dev/mapper/vg-data rw
+27 25 0:45 / /var/lib/docker/overlay2/abc/merged rw,relatime - overlay overlay rw,lowerdir=/l
+`
+	ioutil.WriteFile(procMountInfoPath, []byte(mountInfo), 0644)
+	os.MkdirAll(filepath.Join(sysClassBlockPath, "sdb"), 0755)
+	ioutil.WriteFile(filepath.Join(sysClassBlockPath, "sdb", "dev"), []byte("8:16\n"), 0644)
+
+	for path, devnum := range map[string]string{
+		"/etc/hosts":                            "8:1",
+		"/var/lib/dockerd":                      "8:1",
+		"/var/lib/docker/volumes/db/_data":      "8:16",
+		"/srv/my data/":                         "253:0",
+		"/var/lib/docker/overlay2/abc/merged/x": "0:45",
+	} {
+		dev, err := GetDevNumberOfPath(path)
+		if err != nil {
+			t.Errorf("GetDevNumberOfPath return error (%s)", err)
+		}
+		testutil.CompareVar(t, dev, devnum)
+	}
+}
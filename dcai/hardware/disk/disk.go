@@ -21,6 +21,7 @@ var (
 	sudoExeccmd        = util.ExecuteSudoCmdWithTimeout
 	checkCmdPermission = util.CheckCmdRootPermission
 	execcmd            = util.ExecuteCmdWithTimeout
+	directExeccmd      = util.ExecuteCmdDirectWithTimeout
 
 	//Name
 	megaraidDevice = regexp.MustCompile("^.*megaraid,([0-9]+)$")
@@ -616,6 +617,46 @@ func GetLocalDisks(smartctlPath string) ([]*DiskInfo, error) {
 
 }
 
+// GetLocalDisksDirect is the GetLocalDisks for hosts without bash and sudo,
+// e.g. Windows. It relies on smartctl --scan-open only.
+func GetLocalDisksDirect(smartctlPath string) ([]*DiskInfo, error) {
+	var (
+		disks   []*DiskInfo
+		diskMap map[string]*DiskInfo
+		out     []byte
+		disktxt []byte
+		d       *DiskInfo
+		err     error
+	)
+
+	if err = util.CheckCmdPath(smartctlPath); err != nil {
+		return nil, err
+	}
+
+	if out, err = directExeccmd(smartctlPath, "--scan-open"); err != nil {
+		return nil, err
+	}
+
+	diskMap = make(map[string]*DiskInfo)
+	for _, line := range strings.Split(strings.Replace(string(out), "\r\n", "\n", -1), "\n") {
+		dh := NewDiskHeaderFromSmartctlScan(line)
+		if dh == nil {
+			continue
+		}
+		disktxt, _ = directExeccmd(smartctlPath, "--xall", "--format=old", "-n", "never", dh.Devpath, "-d", dh.Devtype)
+		d, err = NewDiskInfoBySmartctlOutput(dh, string(disktxt))
+		if err != nil || !IsValidDisk(d) {
+			continue
+		}
+		if _, existed := diskMap[d.WWN]; !existed {
+			diskMap[d.WWN] = d
+			disks = append(disks, d)
+		}
+	}
+
+	return disks, nil
+}
+
 func IsValidDisk(d *DiskInfo) bool {
 	if d.WWN != "" && (isSASDisk(d.Type) || isSATADisk(d.Type)) {
 		return true
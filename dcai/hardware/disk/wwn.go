@@ -0,0 +1,104 @@
+package disk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	sysBlockPath      = "/sys/block"
+	sysClassBlockPath = "/sys/class/block"
+	devDiskByIdPath   = "/dev/disk/by-id"
+
+	// naa.5000c5005f50e6ab or eui.0025385b71b07e2f
+	sysfsWwidRegexp = regexp.MustCompile("^(?:naa|eui)\\.([0-9a-fA-F]+)$")
+	// wwn-0x5000c5005f50e6ab, partitions end with -partN
+	byIdWwnRegexp = regexp.MustCompile("^wwn-0x([0-9a-fA-F]+)$")
+)
+
+// GetWWNByKernelName maps a kernel block device name, e.g. sda, to the disk
+// WWN in the same format as DiskInfo.WWN. /sys/block/<name>/device/wwid is
+// tried first and then the wwn-* links in /dev/disk/by-id.
+func GetWWNByKernelName(name string) (string, error) {
+	name = filepath.Base(name)
+
+	if out, err := ioutil.ReadFile(filepath.Join(sysBlockPath, name, "device", "wwid")); err == nil {
+		if f := sysfsWwidRegexp.FindStringSubmatch(strings.TrimSpace(string(out))); len(f) > 1 {
+			return strings.ToLower(f[1]), nil
+		}
+	}
+
+	links, err := ioutil.ReadDir(devDiskByIdPath)
+	if err != nil {
+		return "", err
+	}
+	for _, link := range links {
+		if link.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		f := byIdWwnRegexp.FindStringSubmatch(link.Name())
+		if len(f) < 2 {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(devDiskByIdPath, link.Name()))
+		if err != nil {
+			continue
+		}
+		if filepath.Base(target) == name {
+			return strings.ToLower(f[1]), nil
+		}
+	}
+	return "", fmt.Errorf("Cannot find WWN of %s", name)
+}
+
+// GetDiskOfPartition maps the kernel name of a partition, e.g. sda1 or
+// nvme0n1p1, to the kernel name of its disk. Other names are returned as is.
+func GetDiskOfPartition(name string) string {
+	name = filepath.Base(name)
+
+	link := filepath.Join(sysClassBlockPath, name)
+	if _, err := os.Stat(filepath.Join(link, "partition")); err != nil {
+		return name
+	}
+	// /sys/class/block/sda1 -> ../../devices/.../block/sda/sda1
+	target, err := os.Readlink(link)
+	if err != nil {
+		return name
+	}
+	return filepath.Base(filepath.Dir(target))
+}
+
+// GetDiskOfDevice maps the kernel name of a device holding a disk, as found in
+// the kernel log, to the kernel name of the disk: an ATA port, e.g. ata3, a
+// SCSI address, e.g. 2:0:0:0, an NVMe controller, e.g. nvme0, or a
+// partition. Disk names are returned as is, and "" when no disk is found.
+func GetDiskOfDevice(name string) string {
+	if _, err := os.Stat(filepath.Join(sysBlockPath, name)); err == nil {
+		return name
+	}
+	if d := GetDiskOfPartition(name); d != name {
+		return d
+	}
+
+	disks, err := ioutil.ReadDir(sysBlockPath)
+	if err != nil {
+		return ""
+	}
+	for _, d := range disks {
+		// /sys/block/sda -> ../devices/pci0000:00/0000:00:1f.2/ata3/host2/target2:0:0/2:0:0:0/block/sda
+		target, err := os.Readlink(filepath.Join(sysBlockPath, d.Name()))
+		if err != nil {
+			continue
+		}
+		for _, component := range strings.Split(filepath.ToSlash(target), "/") {
+			if component == name {
+				return d.Name()
+			}
+		}
+	}
+	return ""
+}
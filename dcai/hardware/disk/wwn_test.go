@@ -0,0 +1,114 @@
+package disk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+func TestGetWWNByKernelName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wwn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysBlockPath = filepath.Join(dir, "sys", "block")
+	devDiskByIdPath = filepath.Join(dir, "dev", "disk", "by-id")
+	defer func() {
+		sysBlockPath = "/sys/block"
+		devDiskByIdPath = "/dev/disk/by-id"
+	}()
+
+	// sda has a naa wwid, sdb an ATA t10 wwid which needs the by-id link
+	os.MkdirAll(filepath.Join(sysBlockPath, "sda", "device"), 0755)
+	ioutil.WriteFile(filepath.Join(sysBlockPath, "sda", "device", "wwid"), []byte("naa.5000C5005F50E6AB\n"), 0644)
+	os.MkdirAll(filepath.Join(sysBlockPath, "sdb", "device"), 0755)
+	ioutil.WriteFile(filepath.Join(sysBlockPath, "sdb", "device", "wwid"), []byte("t10.ATA     WDC WD10EZEX-08WN4A0                    WD-WCC6Y3RFXC0L\n"), 0644)
+	os.MkdirAll(devDiskByIdPath, 0755)
+	os.Symlink("../../sdb", filepath.Join(devDiskByIdPath, "wwn-0x50014ee60711e39c"))
+	os.Symlink("../../sdb1", filepath.Join(devDiskByIdPath, "wwn-0x50014ee60711e39c-part1"))
+	os.Symlink("../../sdb", filepath.Join(devDiskByIdPath, "ata-WDC_WD10EZEX-08WN4A0_WD-WCC6Y3RFXC0L"))
+
+	wwn, err := GetWWNByKernelName("sda")
+	if err != nil {
+		t.Errorf("GetWWNByKernelName return error (%s)", err)
+	}
+	testutil.CompareVar(t, wwn, "5000c5005f50e6ab")
+
+	wwn, err = GetWWNByKernelName("/dev/sdb")
+	if err != nil {
+		t.Errorf("GetWWNByKernelName return error (%s)", err)
+	}
+	testutil.CompareVar(t, wwn, "50014ee60711e39c")
+
+	// partitions are not disks
+	if _, err = GetWWNByKernelName("sdb1"); err == nil {
+		t.Errorf("GetWWNByKernelName should return error for a partition")
+	}
+}
+
+func TestGetDiskOfPartition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wwn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysClassBlockPath = filepath.Join(dir, "sys", "class", "block")
+	defer func() {
+		sysClassBlockPath = "/sys/class/block"
+	}()
+
+	devices := filepath.Join(dir, "sys", "devices", "pci0000:00", "block")
+	os.MkdirAll(filepath.Join(devices, "sda", "sda1"), 0755)
+	ioutil.WriteFile(filepath.Join(devices, "sda", "sda1", "partition"), []byte("1\n"), 0644)
+	os.MkdirAll(filepath.Join(devices, "nvme0n1", "nvme0n1p2"), 0755)
+	ioutil.WriteFile(filepath.Join(devices, "nvme0n1", "nvme0n1p2", "partition"), []byte("2\n"), 0644)
+	os.MkdirAll(sysClassBlockPath, 0755)
+	os.Symlink("../../devices/pci0000:00/block/sda", filepath.Join(sysClassBlockPath, "sda"))
+	os.Symlink("../../devices/pci0000:00/block/sda/sda1", filepath.Join(sysClassBlockPath, "sda1"))
+	os.Symlink("../../devices/pci0000:00/block/nvme0n1/nvme0n1p2", filepath.Join(sysClassBlockPath, "nvme0n1p2"))
+
+	testutil.CompareVar(t, GetDiskOfPartition("sda1"), "sda")
+	testutil.CompareVar(t, GetDiskOfPartition("/dev/nvme0n1p2"), "nvme0n1")
+	testutil.CompareVar(t, GetDiskOfPartition("sda"), "sda")
+	testutil.CompareVar(t, GetDiskOfPartition("dm-0"), "dm-0")
+}
+
+func TestGetDiskOfDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wwn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysBlockPath = filepath.Join(dir, "sys", "block")
+	sysClassBlockPath = filepath.Join(dir, "sys", "class", "block")
+	defer func() {
+		sysBlockPath = "/sys/block"
+		sysClassBlockPath = "/sys/class/block"
+	}()
+
+	ata := filepath.Join("devices", "pci0000:00", "0000:00:1f.2", "ata3", "host2", "target2:0:0", "2:0:0:0", "block", "sda")
+	nvme := filepath.Join("devices", "pci0000:00", "0000:3d:00.0", "nvme", "nvme0", "nvme0n1")
+	os.MkdirAll(filepath.Join(dir, "sys", ata, "sda1"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "sys", ata, "sda1", "partition"), []byte("1\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "sys", nvme), 0755)
+	os.MkdirAll(sysBlockPath, 0755)
+	os.Symlink(filepath.Join("..", ata), filepath.Join(sysBlockPath, "sda"))
+	os.Symlink(filepath.Join("..", nvme), filepath.Join(sysBlockPath, "nvme0n1"))
+	os.MkdirAll(sysClassBlockPath, 0755)
+	os.Symlink(filepath.Join("..", "..", ata, "sda1"), filepath.Join(sysClassBlockPath, "sda1"))
+
+	testutil.CompareVar(t, GetDiskOfDevice("sda"), "sda")
+	testutil.CompareVar(t, GetDiskOfDevice("sda1"), "sda")
+	testutil.CompareVar(t, GetDiskOfDevice("ata3"), "sda")
+	testutil.CompareVar(t, GetDiskOfDevice("2:0:0:0"), "sda")
+	testutil.CompareVar(t, GetDiskOfDevice("nvme0"), "nvme0n1")
+	testutil.CompareVar(t, GetDiskOfDevice("ata4"), "")
+	testutil.CompareVar(t, GetDiskOfDevice("mpt3sas_cm0"), "")
+}
@@ -0,0 +1,70 @@
+package cpu
+
+import (
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/util"
+)
+
+var (
+	Query = &wmi.Query{
+		Class: "Win32_Processor",
+		Properties: []string{
+			"DeviceID", "SocketDesignation", "Manufacturer", "Name",
+			"CurrentClockSpeed", "MaxClockSpeed", "L2CacheSize", "L3CacheSize",
+			"NumberOfCores", "NumberOfLogicalProcessors", "ProcessorId",
+		},
+	}
+
+	cpuDeviceIDRegexp          = wmi.PropertyRegexp("DeviceID")
+	cpuSocketRegexp            = wmi.PropertyRegexp("SocketDesignation")
+	cpuManufacturerRegexp      = wmi.PropertyRegexp("Manufacturer")
+	cpuNameRegexp              = wmi.PropertyRegexp("Name")
+	cpuCurrentClockRegexp      = wmi.PropertyRegexp("CurrentClockSpeed")
+	cpuMaxClockRegexp          = wmi.PropertyRegexp("MaxClockSpeed")
+	cpuL2CacheSizeRegexp       = wmi.PropertyRegexp("L2CacheSize")
+	cpuL3CacheSizeRegexp       = wmi.PropertyRegexp("L3CacheSize")
+	cpuCoresRegexp             = wmi.PropertyRegexp("NumberOfCores")
+	cpuLogicalProcessorsRegexp = wmi.PropertyRegexp("NumberOfLogicalProcessors")
+	cpuProcessorIDRegexp       = wmi.PropertyRegexp("ProcessorId")
+)
+
+// CpuInfo is one physical processor reported by Win32_Processor.
+// Clock speeds are in MHz and cache sizes in KB.
+type CpuInfo struct {
+	DeviceID          string
+	Socket            string
+	Manufacturer      string
+	ModelName         string
+	CurrentMHz        string
+	MaxMHz            string
+	L2CacheSize       string
+	L3CacheSize       string
+	Cores             string
+	LogicalProcessors string
+	ProcessorID       string
+}
+
+// NewAllCpuInfo parses the Win32_Processor output of wmi.Run
+func NewAllCpuInfo(wmiOutput string) ([]*CpuInfo, error) {
+	var cpus []*CpuInfo
+
+	for _, cputxt := range wmi.SplitInstances(wmiOutput) {
+		cpu := new(CpuInfo)
+		m := []*util.FindRegexpMatchAndSetType{
+			{&cpu.DeviceID, cpuDeviceIDRegexp},
+			{&cpu.Socket, cpuSocketRegexp},
+			{&cpu.Manufacturer, cpuManufacturerRegexp},
+			{&cpu.ModelName, cpuNameRegexp},
+			{&cpu.CurrentMHz, cpuCurrentClockRegexp},
+			{&cpu.MaxMHz, cpuMaxClockRegexp},
+			{&cpu.L2CacheSize, cpuL2CacheSizeRegexp},
+			{&cpu.L3CacheSize, cpuL3CacheSizeRegexp},
+			{&cpu.Cores, cpuCoresRegexp},
+			{&cpu.LogicalProcessors, cpuLogicalProcessorsRegexp},
+			{&cpu.ProcessorID, cpuProcessorIDRegexp},
+		}
+		util.FindRegexpMatchAndSet(cputxt, m)
+		cpus = append(cpus, cpu)
+	}
+	return cpus, nil
+}
@@ -0,0 +1,73 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+var (
+	input = `
+DeviceID                  : CPU0
+SocketDesignation         : CPU 1
+Manufacturer              : GenuineIntel
+Name                      : Intel(R) Xeon(R) CPU E5-2620 v4 @ 2.10GHz
+CurrentClockSpeed         : 2095
+MaxClockSpeed             : 2095
+L2CacheSize               : 2048
+L3CacheSize               : 20480
+NumberOfCores             : 8
+NumberOfLogicalProcessors : 16
+ProcessorId               : BFEBFBFF000406F1
+
+DeviceID                  : CPU1
+SocketDesignation         : CPU 2
+Manufacturer              : GenuineIntel
+Name                      : Intel(R) Xeon(R) CPU E5-2620 v4 @ 2.10GHz
+CurrentClockSpeed         : 2095
+MaxClockSpeed             : 2095
+L2CacheSize               : 2048
+L3CacheSize               : 20480
+NumberOfCores             : 8
+NumberOfLogicalProcessors : 16
+ProcessorId               : BFEBFBFF000406F1
+
+
+`
+	expectedOutput = []*CpuInfo{
+		&CpuInfo{
+			DeviceID:          "CPU0",
+			Socket:            "CPU 1",
+			Manufacturer:      "GenuineIntel",
+			ModelName:         "Intel(R) Xeon(R) CPU E5-2620 v4 @ 2.10GHz",
+			CurrentMHz:        "2095",
+			MaxMHz:            "2095",
+			L2CacheSize:       "2048",
+			L3CacheSize:       "20480",
+			Cores:             "8",
+			LogicalProcessors: "16",
+			ProcessorID:       "BFEBFBFF000406F1",
+		},
+		&CpuInfo{
+			DeviceID:          "CPU1",
+			Socket:            "CPU 2",
+			Manufacturer:      "GenuineIntel",
+			ModelName:         "Intel(R) Xeon(R) CPU E5-2620 v4 @ 2.10GHz",
+			CurrentMHz:        "2095",
+			MaxMHz:            "2095",
+			L2CacheSize:       "2048",
+			L3CacheSize:       "20480",
+			Cores:             "8",
+			LogicalProcessors: "16",
+			ProcessorID:       "BFEBFBFF000406F1",
+		},
+	}
+)
+
+func TestNewAllCpuInfo(t *testing.T) {
+	cpus, err := NewAllCpuInfo(input)
+	if err != nil {
+		t.Errorf("NewAllCpuInfo return error (%s)", err)
+	}
+	testutil.CompareVar(t, cpus, expectedOutput)
+}
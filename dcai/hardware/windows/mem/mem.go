@@ -0,0 +1,106 @@
+package mem
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/util"
+)
+
+var (
+	Query = &wmi.Query{
+		Class: "Win32_PhysicalMemory",
+		Properties: []string{
+			"BankLabel", "DeviceLocator", "Capacity", "Speed", "Manufacturer",
+			"SerialNumber", "PartNumber", "Tag", "SMBIOSMemoryType", "TypeDetail",
+		},
+	}
+
+	memBankLabelRegexp    = wmi.PropertyRegexp("BankLabel")
+	memLocatorRegexp      = wmi.PropertyRegexp("DeviceLocator")
+	memCapacityRegexp     = wmi.PropertyRegexp("Capacity")
+	memSpeedRegexp        = wmi.PropertyRegexp("Speed")
+	memManufacturerRegexp = wmi.PropertyRegexp("Manufacturer")
+	memSerialNumberRegexp = wmi.PropertyRegexp("SerialNumber")
+	memPartNumberRegexp   = wmi.PropertyRegexp("PartNumber")
+	memTagRegexp          = wmi.PropertyRegexp("Tag")
+	memTypeRegexp         = wmi.PropertyRegexp("SMBIOSMemoryType")
+	memTypeDetailRegexp   = wmi.PropertyRegexp("TypeDetail")
+
+	// SMBIOS memory device types, the same names as dmidecode prints
+	smbiosMemoryTypes = map[string]string{
+		"1":  "Other",
+		"2":  "Unknown",
+		"3":  "DRAM",
+		"18": "DDR",
+		"19": "DDR2",
+		"20": "DDR2 FB-DIMM",
+		"24": "DDR3",
+		"26": "DDR4",
+		"27": "LPDDR",
+		"28": "LPDDR2",
+		"29": "LPDDR3",
+		"30": "LPDDR4",
+		"34": "DDR5",
+	}
+)
+
+// MemInfo is one DIMM reported by Win32_PhysicalMemory
+type MemInfo struct {
+	Manufacturer string
+	SerialNumber string
+	AssetTag     string
+	PartNumber   string
+	Type         string
+	TypeDetail   string
+	Speed        string
+	Bank         string
+	Locator      string
+	SizeMB       string
+}
+
+// NewAllMemInfo parses the Win32_PhysicalMemory output of wmi.Run
+func NewAllMemInfo(wmiOutput string) ([]*MemInfo, error) {
+	var (
+		mems     []*MemInfo
+		capacity string
+		memtype  string
+	)
+
+	instances := wmi.SplitInstances(wmiOutput)
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("Cannot find any memory device")
+	}
+	for _, memtxt := range instances {
+		mem := new(MemInfo)
+		m := []*util.FindRegexpMatchAndSetType{
+			{&mem.Manufacturer, memManufacturerRegexp},
+			{&mem.SerialNumber, memSerialNumberRegexp},
+			{&mem.AssetTag, memTagRegexp},
+			{&mem.PartNumber, memPartNumberRegexp},
+			{&memtype, memTypeRegexp},
+			{&mem.TypeDetail, memTypeDetailRegexp},
+			{&mem.Speed, memSpeedRegexp},
+			{&mem.Bank, memBankLabelRegexp},
+			{&mem.Locator, memLocatorRegexp},
+			{&capacity, memCapacityRegexp},
+		}
+		util.FindRegexpMatchAndSet(memtxt, m)
+
+		// Capacity is in bytes
+		if c, err := strconv.ParseInt(capacity, 10, 64); err == nil {
+			mem.SizeMB = strconv.FormatInt(c/(1024*1024), 10)
+		}
+		if t, ok := smbiosMemoryTypes[memtype]; ok {
+			mem.Type = t
+		} else {
+			mem.Type = memtype
+		}
+		if mem.Speed != "" {
+			mem.Speed = mem.Speed + " MHz"
+		}
+		mems = append(mems, mem)
+	}
+	return mems, nil
+}
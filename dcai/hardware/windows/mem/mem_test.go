@@ -0,0 +1,74 @@
+package mem
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+var (
+	input = `
+BankLabel        : P0_Node0_Channel0_Dimm0
+DeviceLocator    : DIMM_A1
+Capacity         : 17179869184
+Speed            : 2400
+Manufacturer     : Samsung
+SerialNumber     : 38A1B2C3
+PartNumber       : M393A2K40BB1-CRC
+Tag              : Physical Memory 0
+SMBIOSMemoryType : 26
+TypeDetail       : 128
+
+BankLabel        : P0_Node0_Channel1_Dimm0
+DeviceLocator    : DIMM_B1
+Capacity         : 8589934592
+Speed            : 2133
+Manufacturer     : Micron
+SerialNumber     : 12F0E0D1
+PartNumber       : 18ASF1G72PDZ-2G1B1
+Tag              : Physical Memory 1
+SMBIOSMemoryType : 24
+TypeDetail       : 128
+
+`
+	expectedOutput = []*MemInfo{
+		&MemInfo{
+			Manufacturer: "Samsung",
+			SerialNumber: "38A1B2C3",
+			AssetTag:     "Physical Memory 0",
+			PartNumber:   "M393A2K40BB1-CRC",
+			Type:         "DDR4",
+			TypeDetail:   "128",
+			Speed:        "2400 MHz",
+			Bank:         "P0_Node0_Channel0_Dimm0",
+			Locator:      "DIMM_A1",
+			SizeMB:       "16384",
+		},
+		&MemInfo{
+			Manufacturer: "Micron",
+			SerialNumber: "12F0E0D1",
+			AssetTag:     "Physical Memory 1",
+			PartNumber:   "18ASF1G72PDZ-2G1B1",
+			Type:         "DDR3",
+			TypeDetail:   "128",
+			Speed:        "2133 MHz",
+			Bank:         "P0_Node0_Channel1_Dimm0",
+			Locator:      "DIMM_B1",
+			SizeMB:       "8192",
+		},
+	}
+)
+
+func TestNewAllMemInfo(t *testing.T) {
+	mems, err := NewAllMemInfo(input)
+	if err != nil {
+		t.Errorf("NewAllMemInfo return error (%s)", err)
+	}
+	testutil.CompareVar(t, mems, expectedOutput)
+}
+
+func TestNewAllMemInfoEmpty(t *testing.T) {
+	if _, err := NewAllMemInfo("\n\n"); err == nil {
+		t.Errorf("NewAllMemInfo should return error for empty output")
+	}
+}
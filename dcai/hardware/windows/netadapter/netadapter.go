@@ -0,0 +1,59 @@
+package netadapter
+
+import (
+	"strings"
+
+	"github.com/influxdata/telegraf/dcai/hardware/nic"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/util"
+)
+
+var (
+	Query = &wmi.Query{
+		Class:      "Win32_NetworkAdapterConfiguration",
+		Filter:     "IPEnabled=True",
+		Properties: []string{"Description", "MACAddress", "IPAddress"},
+	}
+
+	nicNameRegexp = wmi.PropertyRegexp("Description")
+	nicMACRegexp  = wmi.PropertyRegexp("MACAddress")
+	nicIPRegexp   = wmi.PropertyRegexp("IPAddress")
+)
+
+// NewAllNetworkInfo parses the Win32_NetworkAdapterConfiguration output of
+// wmi.Run. Adapters without MAC address, e.g. loopback, are skipped.
+func NewAllNetworkInfo(wmiOutput string) ([]*nic.NetworkInfo, error) {
+	var (
+		allNics []*nic.NetworkInfo
+		name    string
+		mac     string
+		ips     string
+	)
+
+	for _, nictxt := range wmi.SplitInstances(wmiOutput) {
+		m := []*util.FindRegexpMatchAndSetType{
+			{&name, nicNameRegexp},
+			{&mac, nicMACRegexp},
+			{&ips, nicIPRegexp},
+		}
+		util.FindRegexpMatchAndSet(nictxt, m)
+		if mac == "" {
+			continue
+		}
+
+		var ipv4s, ipv6s []string
+		for _, ip := range wmi.ParseArray(ips) {
+			if strings.Contains(ip, ":") {
+				ipv6s = append(ipv6s, ip)
+			} else {
+				ipv4s = append(ipv4s, ip)
+			}
+		}
+		n, err := nic.NewNetworkInfo(name, []string{strings.ToLower(mac)}, ipv4s, ipv6s)
+		if err != nil {
+			return nil, err
+		}
+		allNics = append(allNics, n)
+	}
+	return allNics, nil
+}
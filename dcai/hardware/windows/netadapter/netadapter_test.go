@@ -0,0 +1,47 @@
+package netadapter
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/hardware/nic"
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+var (
+	input = `
+Description : Intel(R) 82574L Gigabit Network Connection
+MACAddress  : 00:0C:29:4B:3A:10
+IPAddress   : {172.31.86.120, fe80::b5c1:2e0d:41c8:9a3b}
+
+Description : Microsoft KM-TEST Loopback Adapter
+MACAddress  :
+IPAddress   : {169.254.10.5}
+
+Description : Intel(R) 82574L Gigabit Network Connection #2
+MACAddress  : 00:0C:29:4B:3A:1A
+IPAddress   : {10.0.0.20}
+
+`
+	expectedOutput = []*nic.NetworkInfo{
+		&nic.NetworkInfo{
+			Name:  "Intel(R) 82574L Gigabit Network Connection",
+			MACs:  []string{"00:0c:29:4b:3a:10"},
+			IPv4s: []string{"172.31.86.120"},
+			IPv6s: []string{"fe80::b5c1:2e0d:41c8:9a3b"},
+		},
+		&nic.NetworkInfo{
+			Name:  "Intel(R) 82574L Gigabit Network Connection #2",
+			MACs:  []string{"00:0c:29:4b:3a:1a"},
+			IPv4s: []string{"10.0.0.20"},
+			IPv6s: []string{},
+		},
+	}
+)
+
+func TestNewAllNetworkInfo(t *testing.T) {
+	nics, err := NewAllNetworkInfo(input)
+	if err != nil {
+		t.Errorf("NewAllNetworkInfo return error (%s)", err)
+	}
+	testutil.CompareVar(t, nics, expectedOutput)
+}
@@ -0,0 +1,119 @@
+package sysinfo
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/util"
+)
+
+var (
+	SystemQuery = &wmi.Query{
+		Class:      "Win32_ComputerSystemProduct",
+		Properties: []string{"Vendor", "Name", "IdentifyingNumber", "UUID"},
+	}
+	BaseboardQuery = &wmi.Query{
+		Class:      "Win32_BaseBoard",
+		Properties: []string{"Manufacturer", "Product", "SerialNumber"},
+	}
+	OSQuery = &wmi.Query{
+		Class:      "Win32_OperatingSystem",
+		Properties: []string{"CSName", "Caption", "Version"},
+	}
+
+	systemManufacturerRegexp = wmi.PropertyRegexp("Vendor")
+	systemProductNameRegexp  = wmi.PropertyRegexp("Name")
+	systemSerialNumberRegexp = wmi.PropertyRegexp("IdentifyingNumber")
+	systemUUIDRegexp         = wmi.PropertyRegexp("UUID")
+
+	baseboardManufacturerRegexp = wmi.PropertyRegexp("Manufacturer")
+	baseboardProductNameRegexp  = wmi.PropertyRegexp("Product")
+	baseboardSerialNumberRegexp = wmi.PropertyRegexp("SerialNumber")
+
+	osHostnameRegexp = wmi.PropertyRegexp("CSName")
+	osNameRegexp     = wmi.PropertyRegexp("Caption")
+	osVersionRegexp  = wmi.PropertyRegexp("Version")
+)
+
+// HostSysInfo is the Windows counterpart of dmidecode.HostDmiInfo
+type HostSysInfo struct {
+	Baseboard *BaseboardInfo
+	System    *SystemInfo
+}
+
+type BaseboardInfo struct {
+	Manufacturer string
+	ProductName  string
+	SerialNumber string
+}
+
+type SystemInfo struct {
+	Manufacturer string
+	ProductName  string
+	SerialNumber string
+	UUID         string
+}
+
+type OSInfo struct {
+	Hostname string
+	Name     string
+	Version  string
+}
+
+func (b *BaseboardInfo) String() string {
+	return fmt.Sprintf("%s%s%s", b.Manufacturer, b.ProductName, b.SerialNumber)
+}
+
+// String leaves UUID out to match the dmidecode based hardware id on linux
+func (s *SystemInfo) String() string {
+	return fmt.Sprintf("%s%s%s", s.Manufacturer, s.ProductName, s.SerialNumber)
+}
+
+func (h *HostSysInfo) String() string {
+	if h != nil {
+		return fmt.Sprintf("%s%s", h.System, h.Baseboard)
+	} else {
+		return ""
+	}
+}
+
+// NewHostSysInfo parses the Win32_ComputerSystemProduct and Win32_BaseBoard
+// output of wmi.Run
+func NewHostSysInfo(systemOutput string, baseboardOutput string) (*HostSysInfo, error) {
+	if len(wmi.SplitInstances(systemOutput)) == 0 {
+		return nil, fmt.Errorf("Cannot find system information")
+	}
+
+	system := new(SystemInfo)
+	util.FindRegexpMatchAndSet(systemOutput, []*util.FindRegexpMatchAndSetType{
+		{&system.Manufacturer, systemManufacturerRegexp},
+		{&system.ProductName, systemProductNameRegexp},
+		{&system.SerialNumber, systemSerialNumberRegexp},
+		{&system.UUID, systemUUIDRegexp},
+	})
+
+	// the baseboard is absent on some virtual machines
+	baseboard := new(BaseboardInfo)
+	util.FindRegexpMatchAndSet(baseboardOutput, []*util.FindRegexpMatchAndSetType{
+		{&baseboard.Manufacturer, baseboardManufacturerRegexp},
+		{&baseboard.ProductName, baseboardProductNameRegexp},
+		{&baseboard.SerialNumber, baseboardSerialNumberRegexp},
+	})
+
+	return &HostSysInfo{Baseboard: baseboard, System: system}, nil
+}
+
+// NewOSInfo parses the Win32_OperatingSystem output of wmi.Run
+func NewOSInfo(osOutput string) (*OSInfo, error) {
+	if len(wmi.SplitInstances(osOutput)) == 0 {
+		return nil, fmt.Errorf("Cannot find operating system information")
+	}
+
+	os := new(OSInfo)
+	util.FindRegexpMatchAndSet(osOutput, []*util.FindRegexpMatchAndSetType{
+		{&os.Hostname, osHostnameRegexp},
+		{&os.Name, osNameRegexp},
+		{&os.Version, osVersionRegexp},
+	})
+	return os, nil
+}
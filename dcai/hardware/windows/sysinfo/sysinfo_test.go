@@ -0,0 +1,71 @@
+package sysinfo
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+var (
+	systemInput = `
+Vendor            : Dell Inc.
+Name              : PowerEdge R730
+IdentifyingNumber : 7XK2JH2
+UUID              : 4C4C4544-0058-4B10-8032-B7C04F4A4832
+
+`
+	baseboardInput = `
+Manufacturer : Dell Inc.
+Product      : 072T6D
+SerialNumber : .7XK2JH2.CN747516A20123.
+
+`
+	osInput = `
+CSName  : WIN-SRV01
+Caption : Microsoft Windows Server 2016 Standard
+Version : 10.0.14393
+
+`
+	expectedSysInfo = &HostSysInfo{
+		System: &SystemInfo{
+			Manufacturer: "Dell Inc.",
+			ProductName:  "PowerEdge R730",
+			SerialNumber: "7XK2JH2",
+			UUID:         "4C4C4544-0058-4B10-8032-B7C04F4A4832",
+		},
+		Baseboard: &BaseboardInfo{
+			Manufacturer: "Dell Inc.",
+			ProductName:  "072T6D",
+			SerialNumber: ".7XK2JH2.CN747516A20123.",
+		},
+	}
+	expectedSysInfoString = "Dell Inc.PowerEdge R7307XK2JH2Dell Inc.072T6D.7XK2JH2.CN747516A20123."
+
+	expectedOSInfo = &OSInfo{
+		Hostname: "WIN-SRV01",
+		Name:     "Microsoft Windows Server 2016 Standard",
+		Version:  "10.0.14393",
+	}
+)
+
+func TestNewHostSysInfo(t *testing.T) {
+	s, err := NewHostSysInfo(systemInput, baseboardInput)
+	if err != nil {
+		t.Errorf("NewHostSysInfo return error (%s)", err)
+	}
+	testutil.CompareVar(t, s.System, expectedSysInfo.System)
+	testutil.CompareVar(t, s.Baseboard, expectedSysInfo.Baseboard)
+	testutil.CompareVar(t, s.String(), expectedSysInfoString)
+
+	if _, err := NewHostSysInfo("", baseboardInput); err == nil {
+		t.Errorf("NewHostSysInfo should return error without system information")
+	}
+}
+
+func TestNewOSInfo(t *testing.T) {
+	o, err := NewOSInfo(osInput)
+	if err != nil {
+		t.Errorf("NewOSInfo return error (%s)", err)
+	}
+	testutil.CompareVar(t, o, expectedOSInfo)
+}
@@ -0,0 +1,119 @@
+package wmi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf/dcai/util"
+)
+
+var (
+	execcmd       = util.ExecuteCmdDirectWithTimeout
+	sectionRegexp = regexp.MustCompile("^\\[(\\w+)\\]\\s*$")
+	arrayRegexp   = regexp.MustCompile("^\\{(.*)\\}$")
+)
+
+// Query describes the properties to be fetched from one WMI class
+type Query struct {
+	Class      string
+	Filter     string
+	Properties []string
+}
+
+func (q *Query) command() string {
+	cmd := "Get-CimInstance -ClassName " + q.Class
+	if q.Filter != "" {
+		cmd = cmd + " -Filter \"" + q.Filter + "\""
+	}
+	if len(q.Properties) > 0 {
+		cmd = cmd + " | Select-Object " + strings.Join(q.Properties, ",")
+	}
+	// widen the output so that long values are not wrapped
+	return cmd + " | Format-List | Out-String -Width 4096"
+}
+
+// Script builds one powershell script for all queries. The output of each
+// class is led by a [class] line.
+func Script(queries []*Query) string {
+	var cmds []string
+	for _, q := range queries {
+		cmds = append(cmds, fmt.Sprintf("Write-Output '[%s]'; %s", q.Class, q.command()))
+	}
+	return strings.Join(cmds, "; ")
+}
+
+// Run queries all classes by one powershell invocation and returns the
+// Format-List output of each class keyed by class name
+func Run(queries []*Query) (map[string]string, error) {
+	out, err := execcmd("powershell", "-NoProfile", "-NonInteractive", "-Command", Script(queries))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSections(string(out)), nil
+}
+
+// ParseSections splits the output of Run by [class] lines
+func ParseSections(out string) map[string]string {
+	sections := make(map[string]string)
+	class := ""
+	for _, line := range strings.Split(strings.Replace(out, "\r\n", "\n", -1), "\n") {
+		if f := sectionRegexp.FindStringSubmatch(line); len(f) > 1 {
+			class = f[1]
+			sections[class] = ""
+			continue
+		}
+		if class != "" {
+			sections[class] = sections[class] + line + "\n"
+		}
+	}
+	return sections
+}
+
+// SplitInstances splits the Format-List output of one class into instances,
+// which are separated by blank lines
+func SplitInstances(section string) []string {
+	var (
+		instances []string
+		lines     []string
+	)
+	for _, line := range strings.Split(strings.Replace(section, "\r\n", "\n", -1), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(lines) > 0 {
+				instances = append(instances, strings.Join(lines, "\n"))
+				lines = nil
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		instances = append(instances, strings.Join(lines, "\n"))
+	}
+	return instances
+}
+
+// ParseArray splits a Format-List array value like {a, b}
+func ParseArray(value string) []string {
+	var items []string
+	f := arrayRegexp.FindStringSubmatch(strings.TrimSpace(value))
+	if len(f) < 2 {
+		if v := strings.TrimSpace(value); v != "" {
+			items = append(items, v)
+		}
+		return items
+	}
+	for _, item := range strings.Split(f[1], ",") {
+		// a truncated array ends with ...
+		if v := strings.TrimSuffix(strings.TrimSpace(item), "..."); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+// PropertyRegexp returns the regexp matching the value of a Format-List
+// property line
+func PropertyRegexp(property string) *regexp.Regexp {
+	return regexp.MustCompile("^" + property + "\\s*:\\s*(.*?)\\s*$")
+}
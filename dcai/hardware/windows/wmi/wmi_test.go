@@ -0,0 +1,81 @@
+package wmi
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/testutil"
+)
+
+var (
+	input = "[Win32_OperatingSystem]\r\n" +
+		"\r\n" +
+		"CSName  : WIN-SRV01\r\n" +
+		"Caption : Microsoft Windows Server 2016 Standard\r\n" +
+		"Version : 10.0.14393\r\n" +
+		"\r\n" +
+		"\r\n" +
+		"[Win32_NetworkAdapterConfiguration]\r\n" +
+		"\r\n" +
+		"Description : Intel(R) 82574L Gigabit Network Connection\r\n" +
+		"MACAddress  : 00:0C:29:4B:3A:10\r\n" +
+		"IPAddress   : {172.31.86.120, fe80::b5c1:2e0d:41c8:9a3b}\r\n" +
+		"\r\n" +
+		"Description : Intel(R) 82574L Gigabit Network Connection #2\r\n" +
+		"MACAddress  : 00:0C:29:4B:3A:1A\r\n" +
+		"IPAddress   : {10.0.0.20}\r\n" +
+		"\r\n" +
+		"\r\n"
+
+	expectedOSSection = "\n" +
+		"CSName  : WIN-SRV01\n" +
+		"Caption : Microsoft Windows Server 2016 Standard\n" +
+		"Version : 10.0.14393\n" +
+		"\n" +
+		"\n"
+
+	expectedNICInstances = []string{
+		"Description : Intel(R) 82574L Gigabit Network Connection\n" +
+			"MACAddress  : 00:0C:29:4B:3A:10\n" +
+			"IPAddress   : {172.31.86.120, fe80::b5c1:2e0d:41c8:9a3b}",
+		"Description : Intel(R) 82574L Gigabit Network Connection #2\n" +
+			"MACAddress  : 00:0C:29:4B:3A:1A\n" +
+			"IPAddress   : {10.0.0.20}",
+	}
+
+	expectedScript = "Write-Output '[Win32_OperatingSystem]'; " +
+		"Get-CimInstance -ClassName Win32_OperatingSystem | Select-Object CSName,Caption,Version | Format-List | Out-String -Width 4096; " +
+		"Write-Output '[Win32_NetworkAdapterConfiguration]'; " +
+		"Get-CimInstance -ClassName Win32_NetworkAdapterConfiguration -Filter \"IPEnabled=True\" | Select-Object Description | Format-List | Out-String -Width 4096"
+
+	queries = []*Query{
+		&Query{Class: "Win32_OperatingSystem", Properties: []string{"CSName", "Caption", "Version"}},
+		&Query{Class: "Win32_NetworkAdapterConfiguration", Filter: "IPEnabled=True", Properties: []string{"Description"}},
+	}
+)
+
+func fakeExecCommand(cmd string, args ...string) ([]byte, error) {
+	return []byte(input), nil
+}
+
+func TestScript(t *testing.T) {
+	testutil.CompareVar(t, Script(queries), expectedScript)
+}
+
+func TestRun(t *testing.T) {
+	execcmd = fakeExecCommand
+
+	sections, err := Run(queries)
+	if err != nil {
+		t.Errorf("Run return error (%s)", err)
+	}
+	testutil.CompareVar(t, len(sections), 2)
+	testutil.CompareVar(t, sections["Win32_OperatingSystem"], expectedOSSection)
+	testutil.CompareVar(t, SplitInstances(sections["Win32_NetworkAdapterConfiguration"]), expectedNICInstances)
+}
+
+func TestParseArray(t *testing.T) {
+	testutil.CompareVar(t, ParseArray("{172.31.86.120, fe80::b5c1:2e0d:41c8:9a3b}"), []string{"172.31.86.120", "fe80::b5c1:2e0d:41c8:9a3b"})
+	testutil.CompareVar(t, ParseArray("{10.0.0.1, 10.0.0.2...}"), []string{"10.0.0.1", "10.0.0.2"})
+	testutil.CompareVar(t, ParseArray("10.0.0.20"), []string{"10.0.0.20"})
+	testutil.CompareVar(t, ParseArray(""), []string{})
+}
@@ -0,0 +1,407 @@
+package vcsim
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/simulator/vpx"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vim25/xml"
+)
+
+// Topology describes the inventory stood up by New. Datacenter, Cluster,
+// ClusterHost, Host and Machine have the same meaning as in simulator.Model.
+type Topology struct {
+	Datacenter  int
+	Cluster     int
+	ClusterHost int
+	Host        int
+	Machine     int
+	// local SCSI LUNs of each host. The first one is a SSD which is used as
+	// the vSAN cache disk.
+	LunsPerHost int
+	// depth of the snapshot tree of each virtual machine
+	Snapshots int
+	// enable vSAN on the hosts in clusters
+	Vsan bool
+}
+
+var DefaultTopology = Topology{
+	Datacenter:  1,
+	Cluster:     1,
+	ClusterHost: 2,
+	Host:        1,
+	Machine:     1,
+	LunsPerHost: 3,
+	Snapshots:   2,
+	Vsan:        true,
+}
+
+const (
+	// the SAN LUN backing the vmfs datastore, presented to every host
+	SharedLunName = "naa.6000c2900000000000000000000000ff"
+	VsanUuid      = "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5"
+	// congestion reported by the vSAN health service for the last local
+	// LUN of each vSAN host, the other LUNs are not congested
+	VsanCongestion = 80
+)
+
+// Simulator is an in-process vCenter stood up by govmomi's vcsim
+type Simulator struct {
+	Topology Topology
+	Model    *simulator.Model
+	Server   *simulator.Server
+}
+
+// New creates the vcsim inventory of topology t and starts the server
+func New(t Topology) (*Simulator, error) {
+	model := simulator.VPX()
+	model.Datacenter = t.Datacenter
+	model.Cluster = t.Cluster
+	model.ClusterHost = t.ClusterHost
+	model.Host = t.Host
+	model.Machine = t.Machine
+
+	if err := model.Create(); err != nil {
+		model.Remove()
+		return nil, err
+	}
+
+	s := &Simulator{Topology: t, Model: model}
+	s.setupHosts()
+	s.setupDatastores()
+	s.setupVirtualMachines()
+	// the connectors speak https only
+	model.Service.TLS = new(tls.Config)
+	// vcsim has no vSAN health service
+	model.Service.ServeMux = http.NewServeMux()
+	model.Service.ServeMux.HandleFunc("/vsanHealth", s.serveVsanHealth)
+	s.Server = model.Service.NewServer()
+
+	return s, nil
+}
+
+// Close stops the server and removes the inventory
+func (s *Simulator) Close() {
+	if s.Server != nil {
+		s.Server.Close()
+	}
+	s.Model.Remove()
+}
+
+// Address is the host:port of the server, as VcsaConnector.Url expects
+func (s *Simulator) Address() string {
+	return s.Server.URL.Host
+}
+
+func (s *Simulator) Username() string {
+	return s.Server.URL.User.Username()
+}
+
+func (s *Simulator) Password() string {
+	password, _ := s.Server.URL.User.Password()
+	return password
+}
+
+// Hosts returns the simulated hosts sorted by name
+func (s *Simulator) Hosts() []*simulator.HostSystem {
+	var hosts []*simulator.HostSystem
+	for _, e := range all("HostSystem") {
+		hosts = append(hosts, e.(*simulator.HostSystem))
+	}
+	return hosts
+}
+
+// VirtualMachines returns the simulated virtual machines sorted by name
+func (s *Simulator) VirtualMachines() []*simulator.VirtualMachine {
+	var vms []*simulator.VirtualMachine
+	for _, e := range all("VirtualMachine") {
+		vms = append(vms, e.(*simulator.VirtualMachine))
+	}
+	return vms
+}
+
+// Datastores returns the simulated datastores sorted by name
+func (s *Simulator) Datastores() []*simulator.Datastore {
+	var dss []*simulator.Datastore
+	for _, e := range all("Datastore") {
+		dss = append(dss, e.(*simulator.Datastore))
+	}
+	return dss
+}
+
+// LunName returns the canonical name of the j-th local LUN of the i-th host
+func LunName(i int, j int) string {
+	return fmt.Sprintf("naa.5000c500%04x%04x", i, j)
+}
+
+// LunWWN returns the WWN the connectors derive from LunName
+func LunWWN(i int, j int) string {
+	return LunName(i, j)[len("naa."):]
+}
+
+// HostUuid returns the hardware uuid of the i-th host
+func HostUuid(i int) string {
+	return fmt.Sprintf("4c4c4544-0000-0000-0000-0000000000%02x", i)
+}
+
+// DatastoreUuid returns the vmfs uuid of the i-th datastore
+func DatastoreUuid(i int) string {
+	return fmt.Sprintf("5a8e3f2c-%08x-1b2c-0050569a0b%02x", i, i)
+}
+
+// all returns the entities of a kind, walking the inventory from the root
+// folder
+func all(kind string) []mo.Entity {
+	var (
+		entities []mo.Entity
+		seen     = make(map[types.ManagedObjectReference]bool)
+		walk     func(ref types.ManagedObjectReference)
+	)
+	walk = func(ref types.ManagedObjectReference) {
+		// a datastore shared by the hosts is listed once by host
+		obj := simulator.Map.Get(ref)
+		if obj == nil || seen[ref] {
+			return
+		}
+		seen[ref] = true
+		if e, ok := obj.(mo.Entity); ok && ref.Type == kind {
+			entities = append(entities, e)
+		}
+
+		var children []types.ManagedObjectReference
+		switch o := obj.(type) {
+		case *simulator.Folder:
+			children = o.ChildEntity
+		case *simulator.Datacenter:
+			children = []types.ManagedObjectReference{o.HostFolder, o.VmFolder, o.DatastoreFolder}
+		case *simulator.ClusterComputeResource:
+			children = o.Host
+		case *mo.ComputeResource:
+			children = o.Host
+		}
+		for _, child := range children {
+			walk(child)
+		}
+	}
+	walk(vpx.ServiceContent.RootFolder)
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].Entity().Name < entities[j].Entity().Name
+	})
+	return entities
+}
+
+func newLun(name string, ssd bool) *types.HostScsiDisk {
+	return &types.HostScsiDisk{
+		ScsiLun: types.ScsiLun{
+			Uuid:             "0200000000" + name[len("naa."):],
+			CanonicalName:    name,
+			DisplayName:      "Local ATA Disk (" + name + ")",
+			LunType:          "disk",
+			Vendor:           "ATA",
+			Model:            "ST4000NM0035-1V4",
+			Revision:         "TN02",
+			SerialNumber:     "ZC1" + name[len(name)-5:],
+			OperationalState: []string{"ok"},
+		},
+		Capacity: types.HostDiskDimensionsLba{BlockSize: 512, Block: 7814037168},
+		Ssd:      types.NewBool(ssd),
+	}
+}
+
+func (s *Simulator) setupHosts() {
+	for i, h := range s.Hosts() {
+		// the simulated hosts may share the esx template's info, copy
+		// before customizing them
+		hw := *h.Hardware
+		hw.SystemInfo.Uuid = HostUuid(i)
+		h.Hardware = &hw
+		config := *h.Config
+		h.Config = &config
+
+		var (
+			luns  []types.BaseScsiLun
+			disks []types.HostScsiDisk
+		)
+		for j := 0; j < s.Topology.LunsPerHost; j++ {
+			lun := newLun(LunName(i, j), j == 0)
+			luns = append(luns, lun)
+			disks = append(disks, *lun)
+		}
+		luns = append(luns, newLun(SharedLunName, false))
+		h.Config.StorageDevice = &types.HostStorageDeviceInfo{ScsiLun: luns}
+
+		// the connectors expect a vSAN config on every host
+		vsan := &types.VsanHostConfigInfo{
+			Enabled:     types.NewBool(false),
+			ClusterInfo: &types.VsanHostConfigInfoClusterInfo{},
+			StorageInfo: &types.VsanHostConfigInfoStorageInfo{},
+		}
+		inCluster := h.Parent != nil && h.Parent.Type == "ClusterComputeResource"
+		if s.Topology.Vsan && inCluster && len(disks) > 1 {
+			vsan.Enabled = types.NewBool(true)
+			vsan.ClusterInfo.Uuid = VsanUuid
+			vsan.ClusterInfo.NodeUuid = fmt.Sprintf("5a8e3f2c-0000-0000-0000-0050569a0b%02x", i)
+			vsan.StorageInfo.DiskMapInfo = []types.VsanHostDiskMapInfo{
+				{
+					Mapping: types.VsanHostDiskMapping{Ssd: disks[0], NonSsd: disks[1:]},
+					Mounted: true,
+				},
+			}
+		}
+		h.Config.VsanHostConfig = vsan
+	}
+}
+
+func (s *Simulator) setupDatastores() {
+	for i, ds := range s.Datastores() {
+		info := ds.Info.GetDatastoreInfo()
+		ds.Info = &types.VmfsDatastoreInfo{
+			DatastoreInfo: types.DatastoreInfo{
+				Name:      info.Name,
+				Url:       "ds:///vmfs/volumes/" + DatastoreUuid(i) + "/",
+				FreeSpace: info.FreeSpace,
+			},
+			Vmfs: &types.HostVmfsVolume{
+				HostFileSystemVolume: types.HostFileSystemVolume{Name: info.Name},
+				Uuid:                 DatastoreUuid(i),
+				Extent:               []types.HostScsiDiskPartition{{DiskName: SharedLunName, Partition: 1}},
+			},
+		}
+		ds.Summary.Url = "ds:///vmfs/volumes/" + DatastoreUuid(i) + "/"
+	}
+}
+
+func (s *Simulator) setupVirtualMachines() {
+	for i, vm := range s.VirtualMachines() {
+		if s.Topology.Snapshots == 0 {
+			vm.Snapshot = nil
+			continue
+		}
+		var tree []types.VirtualMachineSnapshotTree
+		for j := s.Topology.Snapshots - 1; j >= 0; j-- {
+			node := types.VirtualMachineSnapshotTree{
+				Snapshot:          types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: fmt.Sprintf("snapshot-%d-%d", i, j)},
+				Vm:                vm.Self,
+				Name:              fmt.Sprintf("snapshot-%d", j),
+				Id:                int32(j + 1),
+				ChildSnapshotList: tree,
+			}
+			tree = []types.VirtualMachineSnapshotTree{node}
+		}
+		vm.Snapshot = &types.VirtualMachineSnapshotInfo{
+			CurrentSnapshot:  &tree[0].Snapshot,
+			RootSnapshotList: tree,
+		}
+	}
+}
+
+// the parts of the vSAN health summary answered by the simulator
+type (
+	vsanHealthSummary struct {
+		OverallHealth       string               `xml:"overallHealth"`
+		Groups              []vsanHealthGroup    `xml:"groups"`
+		PhysicalDisksHealth []vsanHostDiskHealth `xml:"physicalDisksHealth"`
+	}
+	vsanHealthGroup struct {
+		GroupId     string           `xml:"groupId"`
+		GroupName   string           `xml:"groupName"`
+		GroupHealth string           `xml:"groupHealth"`
+		GroupTests  []vsanHealthTest `xml:"groupTests"`
+	}
+	vsanHealthTest struct {
+		TestId     string `xml:"testId"`
+		TestName   string `xml:"testName"`
+		TestHealth string `xml:"testHealth"`
+	}
+	vsanHostDiskHealth struct {
+		Hostname string           `xml:"hostname"`
+		Disks    []vsanDiskHealth `xml:"disks"`
+	}
+	vsanDiskHealth struct {
+		Name             string              `xml:"name"`
+		CongestionValue  int32               `xml:"congestionValue"`
+		CongestionHealth string              `xml:"congestionHealth"`
+		ScsiDisk         *types.HostScsiDisk `xml:"scsiDisk"`
+	}
+)
+
+// serveVsanHealth answers VsanQueryVcClusterHealthSummary with a cluster
+// whose physical disk checks are yellow, as the last local LUN of each vSAN
+// host is congested
+func (s *Simulator) serveVsanHealth(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Body struct {
+			Req *struct {
+				Cluster *types.ManagedObjectReference `xml:"cluster"`
+			} `xml:"urn:vsan VsanQueryVcClusterHealthSummary"`
+		}
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || req.Body.Req == nil || req.Body.Req.Cluster == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	summary := vsanHealthSummary{
+		OverallHealth: "yellow",
+		Groups: []vsanHealthGroup{
+			{
+				GroupId:     "com.vmware.vsan.health.test.cluster",
+				GroupName:   "Cluster",
+				GroupHealth: "green",
+				GroupTests: []vsanHealthTest{
+					{TestId: "com.vmware.vsan.health.test.clusterpartition", TestName: "vSAN cluster partition", TestHealth: "green"},
+				},
+			},
+			{
+				GroupId:     "com.vmware.vsan.health.test.physicaldisks",
+				GroupName:   "Physical disk",
+				GroupHealth: "yellow",
+				GroupTests: []vsanHealthTest{
+					{TestId: "com.vmware.vsan.health.test.physdiskoverall", TestName: "Operation health", TestHealth: "green"},
+					{TestId: "com.vmware.vsan.health.test.physdiskcongestion", TestName: "Congestion", TestHealth: "yellow"},
+				},
+			},
+		},
+	}
+	for _, h := range s.Hosts() {
+		if h.Parent == nil || *h.Parent != *req.Body.Req.Cluster || h.Config.VsanHostConfig.StorageInfo == nil {
+			continue
+		}
+		hdh := vsanHostDiskHealth{Hostname: h.Name}
+		for _, dm := range h.Config.VsanHostConfig.StorageInfo.DiskMapInfo {
+			disks := append([]types.HostScsiDisk{dm.Mapping.Ssd}, dm.Mapping.NonSsd...)
+			for j := range disks {
+				dh := vsanDiskHealth{Name: disks[j].DisplayName, CongestionHealth: "green", ScsiDisk: &disks[j]}
+				if j == len(disks)-1 {
+					dh.CongestionValue, dh.CongestionHealth = VsanCongestion, "yellow"
+				}
+				hdh.Disks = append(hdh.Disks, dh)
+			}
+		}
+		summary.PhysicalDisksHealth = append(summary.PhysicalDisksHealth, hdh)
+	}
+
+	var res struct {
+		Res struct {
+			Returnval vsanHealthSummary `xml:"returnval"`
+		} `xml:"urn:vsan VsanQueryVcClusterHealthSummaryResponse"`
+	}
+	res.Res.Returnval = summary
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	if err := xml.NewEncoder(&out).Encode(&soap.Envelope{Body: &res}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write(out.Bytes())
+}
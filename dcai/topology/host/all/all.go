@@ -0,0 +1,6 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/dcai/topology/host/linux"
+	_ "github.com/influxdata/telegraf/dcai/topology/host/windows"
+)
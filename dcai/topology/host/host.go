@@ -3,16 +3,20 @@ package host
 import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/hardware/nic"
 	"github.com/influxdata/telegraf/dcai/type"
 )
 
 type HostConfig interface {
 	GetOsType() dcaitype.OSType
+	GetOsName() string
+	GetOsVersion() string
 	Hostname() string
 	DomainID() string
 	HWID() string
 	IPv4s() string
 	IPv6s() string
+	GetNICs() []*nic.NetworkInfo
 	GetDisks(string) ([]*disk.DiskInfo, error)
 }
 
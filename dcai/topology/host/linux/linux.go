@@ -12,6 +12,7 @@ import (
 	"github.com/influxdata/telegraf/dcai/hardware/linux/dmidecode"
 	"github.com/influxdata/telegraf/dcai/hardware/linux/mem"
 	"github.com/influxdata/telegraf/dcai/hardware/nic"
+	"github.com/influxdata/telegraf/dcai/topology/host"
 	"github.com/influxdata/telegraf/dcai/type"
 	"github.com/influxdata/telegraf/dcai/util"
 )
@@ -125,6 +126,14 @@ func (h *LinuxHostConfig) HWID() string {
 	return util.GenHash(hashkey)
 }
 
+func (h *LinuxHostConfig) GetOsName() string {
+	return h.OSName
+}
+
+func (h *LinuxHostConfig) GetOsVersion() string {
+	return h.OSVersion
+}
+
 func (h *LinuxHostConfig) Hostname() string {
 	return h.Name
 }
@@ -149,6 +158,10 @@ func (h *LinuxHostConfig) IPv6s() string {
 	return strings.Join(ipv6s, ",")
 }
 
+func (h *LinuxHostConfig) GetNICs() []*nic.NetworkInfo {
+	return h.NICs
+}
+
 func (h *LinuxHostConfig) GetDisks(smartctlPath string) ([]*disk.DiskInfo, error) {
 	var err error
 	if h.disks != nil {
@@ -164,3 +177,16 @@ func (h *LinuxHostConfig) GetDisks(smartctlPath string) ([]*disk.DiskInfo, error
 func (h *LinuxHostConfig) SetDisks(disks []*disk.DiskInfo) {
 	h.disks = disks
 }
+
+func init() {
+	creator := func(dmidecodePath string) (host.HostConfig, error) {
+		h, err := NewLinuxHostConfig(dmidecodePath)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+	host.Add(dcaitype.AgentLinux, creator)
+	// the vmware agent runs on a linux virtual machine
+	host.Add(dcaitype.AgentVMware, creator)
+}
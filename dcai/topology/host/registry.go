@@ -0,0 +1,27 @@
+package host
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf/dcai/type"
+)
+
+// Creator creates the host config of the agent host. Platforms without
+// dmidecode ignore dmidecodePath.
+type Creator func(dmidecodePath string) (HostConfig, error)
+
+var HostConfigs = map[dcaitype.AgentType]Creator{}
+
+// Add registers the host config creator of an agent type
+func Add(t dcaitype.AgentType, creator Creator) {
+	HostConfigs[t] = creator
+}
+
+// New creates the host config by the creator registered for agent type t
+func New(t dcaitype.AgentType, dmidecodePath string) (HostConfig, error) {
+	creator, ok := HostConfigs[t]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported agent host type %s", t.String())
+	}
+	return creator(dmidecodePath)
+}
@@ -0,0 +1,27 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/type"
+)
+
+func TestNew(t *testing.T) {
+	var dmidecodePath string
+	Add(dcaitype.AgentUnknown, func(p string) (HostConfig, error) {
+		dmidecodePath = p
+		return nil, nil
+	})
+	defer delete(HostConfigs, dcaitype.AgentUnknown)
+
+	if _, err := New(dcaitype.AgentUnknown, "/usr/sbin/dmidecode"); err != nil {
+		t.Errorf("New return error (%s)", err)
+	}
+	if dmidecodePath != "/usr/sbin/dmidecode" {
+		t.Errorf("%s != expected /usr/sbin/dmidecode", dmidecodePath)
+	}
+
+	if _, err := New(dcaitype.AgentType(99), ""); err == nil {
+		t.Errorf("New should return error for unregistered agent type")
+	}
+}
@@ -76,6 +76,14 @@ func (e *EsxiHostConfig) GetOsType() dcaitype.OSType {
 	return dcaitype.OSVMware
 }
 
+func (e *EsxiHostConfig) GetOsName() string {
+	return e.OSName
+}
+
+func (e *EsxiHostConfig) GetOsVersion() string {
+	return e.OSVersion
+}
+
 func (e *EsxiHostConfig) Hostname() string {
 	return e.Name
 }
@@ -104,6 +112,10 @@ func (e *EsxiHostConfig) IPv6s() string {
 	return strings.Join(ipv6s, ",")
 }
 
+func (e *EsxiHostConfig) GetNICs() []*nic.NetworkInfo {
+	return e.VNICs
+}
+
 func (e *EsxiHostConfig) GetDisks(smartctlPath string) ([]*disk.DiskInfo, error) {
 	return e.Disks, nil
 }
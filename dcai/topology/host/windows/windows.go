@@ -0,0 +1,152 @@
+package windows
+
+import (
+	"log"
+	"strings"
+
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/hardware/nic"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/cpu"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/mem"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/netadapter"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/sysinfo"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/type"
+	"github.com/influxdata/telegraf/dcai/util"
+)
+
+var (
+	getLocalDisks = disk.GetLocalDisksDirect
+
+	// all WMI classes are fetched by one powershell invocation
+	wmiQueries = []*wmi.Query{
+		sysinfo.OSQuery,
+		sysinfo.SystemQuery,
+		sysinfo.BaseboardQuery,
+		netadapter.Query,
+		cpu.Query,
+		mem.Query,
+	}
+)
+
+type WindowsHostConfig struct {
+	Name      string
+	OSType    dcaitype.OSType
+	OSName    string
+	OSVersion string
+	CPUs      []*cpu.CpuInfo
+	MEMs      []*mem.MemInfo
+	NICs      []*nic.NetworkInfo
+	SysInfo   *sysinfo.HostSysInfo
+	disks     []*disk.DiskInfo
+}
+
+// NewWindowsHostConfigByWMIOutput builds the host config from the sections
+// returned by wmi.Run
+func NewWindowsHostConfigByWMIOutput(hostname string, sections map[string]string) (*WindowsHostConfig, error) {
+	var err error
+
+	h := new(WindowsHostConfig)
+	h.disks = nil
+	h.Name = hostname
+	h.OSType = dcaitype.OSWindows
+
+	// getting OS info
+	if osinfo, err := sysinfo.NewOSInfo(sections[sysinfo.OSQuery.Class]); err != nil {
+		log.Printf("W! Cannot get OS information. (%s)", err.Error())
+	} else {
+		h.OSName = osinfo.Name
+		h.OSVersion = osinfo.Version
+		if h.Name == "" {
+			h.Name = osinfo.Hostname
+		}
+	}
+
+	// get system and baseboard info
+	if h.SysInfo, err = sysinfo.NewHostSysInfo(sections[sysinfo.SystemQuery.Class], sections[sysinfo.BaseboardQuery.Class]); err != nil {
+		log.Printf("W! Cannot get host system information. (%s)", err.Error())
+	}
+
+	// get network info
+	if h.NICs, err = netadapter.NewAllNetworkInfo(sections[netadapter.Query.Class]); err != nil {
+		log.Printf("W! Cannot get network information. (%s)", err.Error())
+	}
+
+	// get cpu info
+	if h.CPUs, err = cpu.NewAllCpuInfo(sections[cpu.Query.Class]); err != nil {
+		log.Printf("W! Cannot get CPU information. (%s)", err.Error())
+	}
+
+	// get memory info
+	if h.MEMs, err = mem.NewAllMemInfo(sections[mem.Query.Class]); err != nil {
+		log.Printf("W! Cannot get memory information. (%s)", err.Error())
+	}
+
+	return h, nil
+}
+
+func (h *WindowsHostConfig) GetOsType() dcaitype.OSType {
+	return dcaitype.OSWindows
+}
+
+// generate host HWID
+func (h *WindowsHostConfig) HWID() string {
+	hashkey := h.SysInfo.String()
+	// add MAC address to hash key
+	for _, v := range h.NICs {
+		hashkey = hashkey + strings.Join(v.MACs, "")
+	}
+	return util.GenHash(hashkey)
+}
+
+func (h *WindowsHostConfig) GetOsName() string {
+	return h.OSName
+}
+
+func (h *WindowsHostConfig) GetOsVersion() string {
+	return h.OSVersion
+}
+
+func (h *WindowsHostConfig) Hostname() string {
+	return h.Name
+}
+
+func (h *WindowsHostConfig) DomainID() string {
+	return h.HWID()
+}
+
+func (h *WindowsHostConfig) IPv4s() string {
+	ipv4s := []string{}
+	for _, nic := range h.NICs {
+		ipv4s = append(ipv4s, nic.IPv4s...)
+	}
+	return strings.Join(ipv4s, ",")
+}
+
+func (h *WindowsHostConfig) IPv6s() string {
+	ipv6s := []string{}
+	for _, nic := range h.NICs {
+		ipv6s = append(ipv6s, nic.IPv6s...)
+	}
+	return strings.Join(ipv6s, ",")
+}
+
+func (h *WindowsHostConfig) GetNICs() []*nic.NetworkInfo {
+	return h.NICs
+}
+
+func (h *WindowsHostConfig) GetDisks(smartctlPath string) ([]*disk.DiskInfo, error) {
+	var err error
+	if h.disks != nil {
+		return h.disks, nil
+	}
+
+	if h.disks, err = getLocalDisks(smartctlPath); err != nil {
+		return nil, err
+	}
+	return h.disks, nil
+}
+
+func (h *WindowsHostConfig) SetDisks(disks []*disk.DiskInfo) {
+	h.disks = disks
+}
@@ -0,0 +1,34 @@
+// +build windows
+
+package windows
+
+import (
+	"os"
+
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/topology/host"
+	"github.com/influxdata/telegraf/dcai/type"
+)
+
+func NewWindowsHostConfig() (*WindowsHostConfig, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := wmi.Run(wmiQueries)
+	if err != nil {
+		return nil, err
+	}
+	return NewWindowsHostConfigByWMIOutput(hostname, sections)
+}
+
+func init() {
+	host.Add(dcaitype.AgentWindows, func(dmidecodePath string) (host.HostConfig, error) {
+		h, err := NewWindowsHostConfig()
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	})
+}
@@ -0,0 +1,11 @@
+// +build !windows
+
+package windows
+
+import (
+	"fmt"
+)
+
+func NewWindowsHostConfig() (*WindowsHostConfig, error) {
+	return nil, fmt.Errorf("Windows host config is only available on Windows")
+}
@@ -0,0 +1,119 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/hardware/windows/wmi"
+	"github.com/influxdata/telegraf/dcai/testutil"
+	"github.com/influxdata/telegraf/dcai/type"
+)
+
+var (
+	wmiInput = `[Win32_OperatingSystem]
+
+CSName  : WIN-SRV01
+Caption : Microsoft Windows Server 2016 Standard
+Version : 10.0.14393
+
+
+[Win32_ComputerSystemProduct]
+
+Vendor            : VMware, Inc.
+Name              : VMware Virtual Platform
+IdentifyingNumber : VMware-56 4d 7a 1c 9e 2b 41 d8-3c 0a 6f 12 4b 3a 10 cf
+UUID              : 1C7A4D56-2B9E-D841-3C0A-6F124B3A10CF
+
+
+[Win32_BaseBoard]
+
+Manufacturer : Intel Corporation
+Product      : 440BX Desktop Reference Platform
+SerialNumber : None
+
+
+[Win32_NetworkAdapterConfiguration]
+
+Description : Intel(R) 82574L Gigabit Network Connection
+MACAddress  : 00:0C:29:4B:3A:10
+IPAddress   : {172.31.86.120, fe80::b5c1:2e0d:41c8:9a3b}
+
+
+[Win32_Processor]
+
+DeviceID                  : CPU0
+SocketDesignation         : CPU 0
+Manufacturer              : GenuineIntel
+Name                      : Intel(R) Xeon(R) CPU E5-2620 v4 @ 2.10GHz
+CurrentClockSpeed         : 2095
+MaxClockSpeed             : 2095
+L2CacheSize               :
+L3CacheSize               : 0
+NumberOfCores             : 2
+NumberOfLogicalProcessors : 2
+ProcessorId               : 0FABFBFF000406F1
+
+
+[Win32_PhysicalMemory]
+
+BankLabel        :
+DeviceLocator    : RAM slot #0
+Capacity         : 4294967296
+Speed            :
+Manufacturer     : VMware Virtual RAM
+SerialNumber     : 00000001
+PartNumber       : VMW-4096MB
+Tag              : Physical Memory 0
+SMBIOSMemoryType : 7
+TypeDetail       : 128
+
+
+`
+	expectedHostConfig = &WindowsHostConfig{
+		Name:      "WIN-SRV01",
+		OSType:    dcaitype.OSWindows,
+		OSName:    "Microsoft Windows Server 2016 Standard",
+		OSVersion: "10.0.14393",
+	}
+
+	expectedIPv4s = "172.31.86.120"
+	expectedIPv6s = "fe80::b5c1:2e0d:41c8:9a3b"
+	expectedHWID  = "7b161019f3d7bb8eb443c5d2105dc1fb"
+)
+
+func TestNewWindowsHostConfigByWMIOutput(t *testing.T) {
+	h, err := NewWindowsHostConfigByWMIOutput("", wmi.ParseSections(wmiInput))
+	if err != nil {
+		t.Errorf("NewWindowsHostConfigByWMIOutput return error (%s)", err)
+	}
+
+	testutil.CompareVar(t, h.Name, expectedHostConfig.Name)
+	testutil.CompareVar(t, h.GetOsType(), expectedHostConfig.OSType)
+	testutil.CompareVar(t, h.OSName, expectedHostConfig.OSName)
+	testutil.CompareVar(t, h.OSVersion, expectedHostConfig.OSVersion)
+	testutil.CompareVar(t, h.IPv4s(), expectedIPv4s)
+	testutil.CompareVar(t, h.IPv6s(), expectedIPv6s)
+	testutil.CompareVar(t, h.HWID(), expectedHWID)
+	testutil.CompareVar(t, len(h.CPUs), 1)
+	testutil.CompareVar(t, len(h.MEMs), 1)
+	testutil.CompareVar(t, h.MEMs[0].SizeMB, "4096")
+}
+
+func TestGetDisks(t *testing.T) {
+	called := 0
+	getLocalDisks = func(smartctlPath string) ([]*disk.DiskInfo, error) {
+		called++
+		return []*disk.DiskInfo{&disk.DiskInfo{Name: "sda", WWN: "5000c500a1b2c3d4"}}, nil
+	}
+
+	h := new(WindowsHostConfig)
+	for i := 0; i < 2; i++ {
+		disks, err := h.GetDisks("smartctl")
+		if err != nil {
+			t.Errorf("GetDisks return error (%s)", err)
+		}
+		testutil.CompareVar(t, len(disks), 1)
+	}
+	// disks are cached after the first scan
+	testutil.CompareVar(t, called, 1)
+}
@@ -0,0 +1,305 @@
+package vsandiskgroup
+
+import (
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// health of vSAN disks and disk groups, from the best to the worst
+const (
+	HealthUnknown   = "unknown"
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+var (
+	healthRank = map[string]int{
+		HealthUnknown:   0,
+		HealthHealthy:   1,
+		HealthDegraded:  2,
+		HealthUnhealthy: 3,
+	}
+
+	// health of the vSAN health service, whose checks are colored
+	colorHealth = map[string]string{
+		"green":  HealthHealthy,
+		"yellow": HealthDegraded,
+		"red":    HealthUnhealthy,
+	}
+
+	// ScsiLun operational states which make a disk unusable by vSAN
+	unhealthyOperationalStates = map[string]bool{
+		"error":               true,
+		"off":                 true,
+		"lostCommunication":   true,
+		"permanentDeviceLoss": true,
+		"quiesced":            true,
+	}
+)
+
+// VsanDiskHealth is the vSAN view of one disk of a host
+type VsanDiskHealth struct {
+	Name             string
+	WWN              string
+	VsanUuid         string
+	DiskgroupUuid    string
+	IsSsd            bool
+	IsCache          bool
+	State            string
+	OperationalState []string
+	Degraded         bool
+	Error            string
+	// -1 if vSAN does not report them
+	Components  int64
+	HealthFlags int64
+	// congestion of the disk from 0 to 255 and its color, from the vSAN
+	// health service. Congestion is -1 if the service does not report it.
+	Congestion       int64
+	CongestionHealth string
+}
+
+// VsanDiskgroupHealth is the vSAN view of one disk group, whose uuid is the
+// vSAN uuid of its cache disk
+type VsanDiskgroupHealth struct {
+	Uuid          string
+	CacheDisk     *VsanDiskHealth
+	CapacityDisks []*VsanDiskHealth
+	Mounted       bool
+}
+
+// VsanHostHealth is the vSAN disks and disk groups of one host
+type VsanHostHealth struct {
+	HostName    string
+	HostUuid    string
+	ClusterUuid string
+	Disks       []*VsanDiskHealth
+	Diskgroups  []*VsanDiskgroupHealth
+}
+
+// VsanHealthCheck is one test of the vSAN health service on a cluster, the
+// health of the test and of its group are colors
+type VsanHealthCheck struct {
+	GroupId     string
+	GroupName   string
+	GroupHealth string
+	TestId      string
+	TestName    string
+	TestHealth  string
+}
+
+// VsanClusterHealth is the outcome of the vSAN health service on a cluster,
+// the overall health is a color
+type VsanClusterHealth struct {
+	Name          string
+	Uuid          string
+	OverallHealth string
+	Checks        []*VsanHealthCheck
+}
+
+// VsanHealth is the vSAN health of the hosts and clusters of a vCenter
+type VsanHealth struct {
+	Hosts    []*VsanHostHealth
+	Clusters []*VsanClusterHealth
+}
+
+// ColorHealth maps a color of the vSAN health service to a health
+func ColorHealth(color string) string {
+	if h, ok := colorHealth[color]; ok {
+		return h
+	}
+	return HealthUnknown
+}
+
+// Health summarizes the state reported by vSAN
+func (d *VsanDiskHealth) Health() string {
+	if d.State == "" {
+		return HealthUnknown
+	}
+	if d.Error != "" {
+		return HealthUnhealthy
+	}
+	for _, s := range d.OperationalState {
+		if unhealthyOperationalStates[s] {
+			return HealthUnhealthy
+		}
+	}
+	if d.Degraded || d.HealthFlags != 0 {
+		return HealthDegraded
+	}
+	// a congested disk still serves its components, slower
+	if h := ColorHealth(d.CongestionHealth); h == HealthDegraded || h == HealthUnhealthy {
+		return HealthDegraded
+	}
+	for _, s := range d.OperationalState {
+		if s != "ok" {
+			return HealthDegraded
+		}
+	}
+	return HealthHealthy
+}
+
+// Disks returns the cache disk followed by the capacity disks
+func (dg *VsanDiskgroupHealth) Disks() []*VsanDiskHealth {
+	disks := []*VsanDiskHealth{}
+	if dg.CacheDisk != nil {
+		disks = append(disks, dg.CacheDisk)
+	}
+	return append(disks, dg.CapacityDisks...)
+}
+
+// Health is the worst health of the member disks. An unmounted disk group is
+// unhealthy.
+func (dg *VsanDiskgroupHealth) Health() string {
+	if !dg.Mounted {
+		return HealthUnhealthy
+	}
+	health := HealthUnknown
+	for _, d := range dg.Disks() {
+		if h := d.Health(); healthRank[h] > healthRank[health] {
+			health = h
+		}
+	}
+	return health
+}
+
+// CreateSaiVsanDiskDataPoint create a data point of sai_vsan_disk
+func CreateSaiVsanDiskDataPoint(
+	acc telegraf.Accumulator,
+	saiClusterDomainId string, hostDomainId string, vsanClusterUuid string,
+	d *VsanDiskHealth,
+) {
+	tags := map[string]string{}
+	fields := make(map[string]interface{})
+	tags["disk_name"] = d.Name
+	tags["disk_wwn"] = d.WWN
+	tags["disk_domain_id"] = d.WWN
+	tags["primary_key"] = saiClusterDomainId + "-" + hostDomainId + "-" + d.WWN
+	fields["cluster_domain_id"] = saiClusterDomainId
+	fields["host_domain_id"] = hostDomainId
+	fields["vsan_cluster_uuid"] = vsanClusterUuid
+	fields["vsan_uuid"] = d.VsanUuid
+	fields["diskgroup_uuid"] = d.DiskgroupUuid
+	fields["is_ssd"] = d.IsSsd
+	fields["is_cache"] = d.IsCache
+	fields["state"] = d.State
+	fields["operational_state"] = strings.Join(d.OperationalState, ",")
+	fields["degraded"] = d.Degraded
+	fields["error"] = d.Error
+	fields["health"] = d.Health()
+	fields["health_flags"] = d.HealthFlags
+	if d.Components >= 0 {
+		fields["components"] = d.Components
+	}
+	if d.Congestion >= 0 {
+		fields["congestion"] = d.Congestion
+		fields["congestion_health"] = ColorHealth(d.CongestionHealth)
+	}
+	acc.AddFields("sai_vsan_disk", fields, tags)
+}
+
+// CreateSaiVsanDiskgroupDataPoint create a data point of sai_vsan_diskgroup
+// keyed by the WWN of the cache disk
+func CreateSaiVsanDiskgroupDataPoint(
+	acc telegraf.Accumulator,
+	saiClusterDomainId string, hostDomainId string, vsanClusterUuid string,
+	dg *VsanDiskgroupHealth,
+) {
+	var (
+		capacityWWNs []string
+		components   int64 = -1
+		degraded     int64
+		unhealthy    int64
+	)
+
+	tags := map[string]string{}
+	fields := make(map[string]interface{})
+
+	cacheWWN := ""
+	if dg.CacheDisk != nil {
+		cacheWWN = dg.CacheDisk.WWN
+	}
+	for _, d := range dg.CapacityDisks {
+		capacityWWNs = append(capacityWWNs, d.WWN)
+	}
+	for _, d := range dg.Disks() {
+		switch d.Health() {
+		case HealthDegraded:
+			degraded++
+		case HealthUnhealthy:
+			unhealthy++
+		}
+		if d.Components >= 0 {
+			if components < 0 {
+				components = 0
+			}
+			components += d.Components
+		}
+	}
+
+	tags["disk_wwn"] = cacheWWN
+	tags["diskgroup_domain_id"] = dg.Uuid
+	tags["primary_key"] = saiClusterDomainId + "-" + hostDomainId + "-" + cacheWWN
+	fields["cluster_domain_id"] = saiClusterDomainId
+	fields["host_domain_id"] = hostDomainId
+	fields["vsan_cluster_uuid"] = vsanClusterUuid
+	fields["capacity_disk_wwns"] = strings.Join(capacityWWNs, ",")
+	fields["mounted"] = dg.Mounted
+	fields["health"] = dg.Health()
+	fields["disk_count"] = int64(len(dg.Disks()))
+	fields["degraded_disk_count"] = degraded
+	fields["unhealthy_disk_count"] = unhealthy
+	if components >= 0 {
+		fields["components"] = components
+	}
+	acc.AddFields("sai_vsan_diskgroup", fields, tags)
+}
+
+// CreateSaiVsanClusterDataPoint create a data point of sai_vsan_cluster keyed
+// by the vSAN cluster uuid, and one of sai_vsan_health_check by test
+func CreateSaiVsanClusterDataPoint(
+	acc telegraf.Accumulator,
+	saiClusterDomainId string,
+	c *VsanClusterHealth,
+) {
+	var (
+		degraded  int64
+		unhealthy int64
+	)
+
+	for _, hc := range c.Checks {
+		tags := map[string]string{}
+		fields := make(map[string]interface{})
+		tags["vsan_cluster_uuid"] = c.Uuid
+		tags["group_id"] = hc.GroupId
+		tags["test_id"] = hc.TestId
+		tags["primary_key"] = saiClusterDomainId + "-" + c.Uuid + "-" + hc.TestId
+		fields["cluster_domain_id"] = saiClusterDomainId
+		fields["cluster_name"] = c.Name
+		fields["group_name"] = hc.GroupName
+		fields["group_health"] = ColorHealth(hc.GroupHealth)
+		fields["test_name"] = hc.TestName
+		fields["health"] = ColorHealth(hc.TestHealth)
+		acc.AddFields("sai_vsan_health_check", fields, tags)
+
+		switch ColorHealth(hc.TestHealth) {
+		case HealthDegraded:
+			degraded++
+		case HealthUnhealthy:
+			unhealthy++
+		}
+	}
+
+	tags := map[string]string{}
+	fields := make(map[string]interface{})
+	tags["vsan_cluster_uuid"] = c.Uuid
+	tags["primary_key"] = saiClusterDomainId + "-" + c.Uuid
+	fields["cluster_domain_id"] = saiClusterDomainId
+	fields["cluster_name"] = c.Name
+	fields["health"] = ColorHealth(c.OverallHealth)
+	fields["check_count"] = int64(len(c.Checks))
+	fields["degraded_check_count"] = degraded
+	fields["unhealthy_check_count"] = unhealthy
+	acc.AddFields("sai_vsan_cluster", fields, tags)
+}
@@ -42,11 +42,13 @@ const (
 	EventTitleAgentStopped  = EventTitle(3)
 	EventTitleSmartDataSent = EventTitle(4)
 	EventTitleHostDataSent  = EventTitle(5)
+	EventTitleDiskError     = EventTitle(6)
 
 	EventTypeUnknown                = EventType(0)
 	EventTypeFirstAgentHeartbeat    = EventType(1)
 	EventTypeIntervalAgentHeartbeat = EventType(2)
 	EventTypeMetricsMonitoring      = EventType(3)
+	EventTypeDiskError              = EventType(4)
 
 	LogLevelDebug   = LogLevel(0) // General debugging information: basically useful information that is used for debugging purposes
 	LogLevelInfo    = LogLevel(1) // General information: Logs that track the general flow of the application
@@ -104,12 +106,14 @@ var (
 		3: "The Agent is stopped",
 		4: "Data of Raw SMART was written to DB",
 		5: "Data of Host was written to DB",
+		6: "A disk error was found in the kernel log",
 	}
 	EventTypes = map[int]string{
 		0: "Unknown",
 		1: "First Agent Heartbeat",
 		2: "Interval Agent Heartbeat",
 		3: "Metrics Monitoring",
+		4: "Disk Error",
 	}
 	LogLevels = map[int]string{
 		0: "Debug",
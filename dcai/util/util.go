@@ -5,9 +5,12 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"os"
 	"os/exec"
 	"os/user"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
@@ -17,6 +20,8 @@ import (
 const (
 	cmdTimeoutSecond    = 5 * time.Second
 	sshCmdTimeoutSecond = 10 * time.Second
+	// powershell takes a few seconds to start on a busy Windows host
+	directCmdTimeoutSecond = 30 * time.Second
 )
 
 type FindRegexpMatchAndSetType struct {
@@ -42,6 +47,11 @@ func GenHash(key string) string {
 }
 
 func isRoot() bool {
+	// there is no sudo on Windows. The agent service is expected to run
+	// with an elevated account there.
+	if runtime.GOOS == "windows" {
+		return true
+	}
 	u, err := user.Current()
 	if err != nil {
 		return false
@@ -105,6 +115,22 @@ func ExecuteCmdWithTimeout(cmd string, args ...string) ([]byte, error) {
 	return executecmdwithtimeoutInternal(false, cmdTimeoutSecond, cmd, args...)
 }
 
+// ExecuteCmdDirectWithTimeout executes cmd without bash and sudo, which are
+// not available on Windows hosts
+func ExecuteCmdDirectWithTimeout(cmd string, args ...string) ([]byte, error) {
+	if c, found := CommandExist(cmd); !found {
+		err := fmt.Errorf("Cannot find command %s", c)
+		return nil, err
+	}
+	e := exec.Command(cmd, args...)
+	out, err := internal.CombinedOutputTimeout(e, directCmdTimeoutSecond)
+	if err != nil {
+		return out, fmt.Errorf("%s %s got %s", cmd, strings.Join(args, " "), err.Error())
+	}
+
+	return out, nil
+}
+
 func FindRegexpMatchAndSet(txt string, pattern []*FindRegexpMatchAndSetType) {
 	// init target to empty
 	for _, p := range pattern {
@@ -141,7 +167,7 @@ func GetCmdPathInOsPath(cmd string) (string, error) {
 func CheckCmdPath(cmdPath string) error {
 	if len(cmdPath) > 0 {
 		// test if smartctl is valid
-		_, err := ExecuteCmdWithTimeout("ls", cmdPath)
+		_, err := os.Stat(cmdPath)
 		if err != nil {
 			return err
 		}
@@ -151,6 +177,27 @@ func CheckCmdPath(cmdPath string) error {
 	return fmt.Errorf("Path is not specified")
 }
 
+// ValidateCmd checks that cmd, a path or a command name looked up in the
+// system path, exists and, when withsudo is set, can be run as root
+func ValidateCmd(cmd string, withsudo bool) error {
+	var err error
+	path := cmd
+	if strings.ContainsAny(cmd, `/\`) {
+		err = CheckCmdPath(cmd)
+	} else {
+		path, err = GetCmdPathInOsPath(cmd)
+	}
+	if err != nil {
+		return err
+	}
+	if withsudo {
+		if _, err := CheckCmdRootPermission(filepath.Base(path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func FindIndexOfText(txt []byte, seps [][]byte) int {
 	var min = len(txt)
 	var hit = false
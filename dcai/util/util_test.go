@@ -39,3 +39,18 @@ func TestCommandExist(t *testing.T) {
 		t.Errorf("Command no_this_command should not existed")
 	}
 }
+
+func TestValidateCmd(t *testing.T) {
+	if err := ValidateCmd("sh", false); err != nil {
+		t.Errorf("sh should be found in PATH: %s", err)
+	}
+	if err := ValidateCmd("/bin/sh", false); err != nil {
+		t.Errorf("/bin/sh should exist: %s", err)
+	}
+	if err := ValidateCmd("nonexistent-cmd", false); err == nil {
+		t.Errorf("nonexistent-cmd should not be found")
+	}
+	if err := ValidateCmd("/nonexistent/cmd", false); err == nil {
+		t.Errorf("/nonexistent/cmd should not exist")
+	}
+}
@@ -29,3 +29,10 @@ type ServiceInput interface {
 	// Stop stops the services and closes any necessary channels and connections
 	Stop()
 }
+
+// StoppableInput is an optional interface of the inputs holding resources
+// between the gathers, e.g. sessions. Stop is called once the input is
+// removed by a reload, the agent shuts down or the input was tested.
+type StoppableInput interface {
+	Stop()
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
@@ -19,6 +22,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secretstore"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -56,6 +60,13 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// SecretStore is the keystore resolving the @secret:name/key references
+	// of the config files, SecretStoreKeyFile holds its key unless
+	// $TELEGRAF_SECRETSTORE_KEY is set
+	SecretStore        string
+	SecretStoreKeyFile string
+	secrets            *secretstore.Store
 }
 
 func NewConfig() *Config {
@@ -144,6 +155,10 @@ type AgentConfig struct {
 	SaiClusterName     string
 	AgentType          string
 	DmidecodePath      string
+
+	// AdminListen is the address of the HTTP admin and health API, it is
+	// disabled when empty
+	AdminListen string
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -268,6 +283,10 @@ var header = `# Telegraf Configuration
   ## Override dmidecode path.
   dmidecode_path = ""
 
+  ## Address of the HTTP admin and health API, ie, "localhost:8099".
+  ## Disabled when empty.
+  admin_listen = ""
+
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
 ###############################################################################
@@ -576,7 +595,7 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	tbl, err := parseFile(path)
+	tbl, err := c.parseFile(path)
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
@@ -691,8 +710,9 @@ func (c *Config) LoadConfig(path string) error {
 		}
 	}
 
+	// the processors of the same order keep the order they are loaded in
 	if len(c.Processors) > 1 {
-		sort.Sort(c.Processors)
+		sort.Stable(c.Processors)
 	}
 	return nil
 }
@@ -706,8 +726,9 @@ func trimBOM(f []byte) []byte {
 
 // parseFile loads a TOML configuration from a provided path and
 // returns the AST produced from the TOML parser. When loading the file, it
-// will find environment variables and replace them.
-func parseFile(fpath string) (*ast.Table, error) {
+// will find environment variables and replace them, the secret references
+// are replaced in the parsed values.
+func (c *Config) parseFile(fpath string) (*ast.Table, error) {
 	contents, err := ioutil.ReadFile(fpath)
 	if err != nil {
 		return nil, err
@@ -723,7 +744,64 @@ func parseFile(fpath string) (*ast.Table, error) {
 		}
 	}
 
-	return toml.Parse(contents)
+	tbl, err := toml.Parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	// the references in the comments are not resolved
+	if err := c.resolveSecrets(tbl); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// resolveSecrets replaces the secret references of the string values of a
+// TOML node, the secret store being opened on the first one
+func (c *Config) resolveSecrets(node interface{}) error {
+	switch node := node.(type) {
+	case *ast.Table:
+		for _, field := range node.Fields {
+			if err := c.resolveSecrets(field); err != nil {
+				return err
+			}
+		}
+	case []*ast.Table:
+		for _, tbl := range node {
+			if err := c.resolveSecrets(tbl); err != nil {
+				return err
+			}
+		}
+	case *ast.KeyValue:
+		return c.resolveSecrets(node.Value)
+	case *ast.Array:
+		for _, value := range node.Value {
+			if err := c.resolveSecrets(value); err != nil {
+				return err
+			}
+		}
+	case *ast.String:
+		if !secretstore.HasRefs(node.Value) {
+			return nil
+		}
+		if c.secrets == nil {
+			if c.SecretStore == "" {
+				return fmt.Errorf("The config references secrets but no secret store is set")
+			}
+			key, err := secretstore.LoadKey(c.SecretStoreKeyFile)
+			if err != nil {
+				return err
+			}
+			if c.secrets, err = secretstore.Open(c.SecretStore, key); err != nil {
+				return err
+			}
+		}
+		value, err := secretstore.Resolve(node.Value, c.secrets)
+		if err != nil {
+			return err
+		}
+		node.Value = value
+	}
+	return nil
 }
 
 func (c *Config) addAggregator(name string, table *ast.Table) error {
@@ -733,6 +811,7 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	}
 	aggregator := creator()
 
+	fingerprint := tableFingerprint(table)
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
@@ -742,7 +821,9 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	ra.Fingerprint = fingerprint
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -753,6 +834,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	}
 	processor := creator()
 
+	fingerprint := tableFingerprint(table)
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
@@ -762,11 +844,8 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return err
 	}
 
-	rf := &models.RunningProcessor{
-		Name:      name,
-		Processor: processor,
-		Config:    processorConfig,
-	}
+	rf := models.NewRunningProcessor(name, processor, processorConfig)
+	rf.Fingerprint = fingerprint
 
 	c.Processors = append(c.Processors, rf)
 	return nil
@@ -781,6 +860,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	fingerprint := tableFingerprint(table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -802,8 +882,18 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return err
 	}
 
+	// the output settings override the agent ones
+	batchSize, bufferLimit := c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit
+	if outputConfig.MetricBatchSize > 0 {
+		batchSize = outputConfig.MetricBatchSize
+	}
+	if outputConfig.MetricBufferLimit > 0 {
+		bufferLimit = outputConfig.MetricBufferLimit
+	}
+
 	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+		batchSize, bufferLimit)
+	ro.Fingerprint = fingerprint
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -822,6 +912,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	fingerprint := tableFingerprint(table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -844,10 +935,67 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	rp.Fingerprint = fingerprint
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
 
+// tableFingerprint returns the SHA-256 of the settings of a plugin table, keys
+// sorted, so it only changes with the settings, not with the comments or the
+// layout of the file
+func tableFingerprint(tbl *ast.Table) string {
+	h := sha256.New()
+	writeTable(h, tbl)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeTable(w io.Writer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			fmt.Fprintf(w, "%q=", key)
+			writeValue(w, v.Value)
+			fmt.Fprint(w, "\n")
+		case *ast.Table:
+			fmt.Fprintf(w, "%q={\n", key)
+			writeTable(w, v)
+			fmt.Fprint(w, "}\n")
+		case []*ast.Table:
+			fmt.Fprintf(w, "%q=[\n", key)
+			for _, t := range v {
+				fmt.Fprint(w, "{\n")
+				writeTable(w, t)
+				fmt.Fprint(w, "}\n")
+			}
+			fmt.Fprint(w, "]\n")
+		}
+	}
+}
+
+// writeValue writes the parsed strings rather than their source, so that the
+// fingerprint changes with the secrets they reference
+func writeValue(w io.Writer, v ast.Value) {
+	switch v := v.(type) {
+	case *ast.String:
+		fmt.Fprintf(w, "%q", v.Value)
+	case *ast.Array:
+		fmt.Fprint(w, "[")
+		for _, e := range v.Value {
+			writeValue(w, e)
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprint(w, "]")
+	default:
+		fmt.Fprint(w, v.Source())
+	}
+}
+
 // buildAggregator parses Aggregator specific items from the ast.Table,
 // builds the filter and returns a
 // models.AggregatorConfig to be inserted into models.RunningAggregator
@@ -977,7 +1125,20 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["workers"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				workers, err := strconv.Atoi(b.Value)
+				if err != nil {
+					return nil, fmt.Errorf("Error parsing int value for %s: %s", name, err)
+				}
+				conf.Workers = workers
+			}
+		}
+	}
+
 	delete(tbl.Fields, "order")
+	delete(tbl.Fields, "workers")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1294,7 +1455,10 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 // a serializers.Serializer object, and creates it, which can then be added onto
 // an Output object.
 func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error) {
-	c := &serializers.Config{TimestampUnits: time.Duration(1 * time.Second)}
+	c := &serializers.Config{
+		TimestampUnits:              time.Duration(1 * time.Second),
+		AiserviceGroupByMeasurement: true,
+	}
 
 	if node, ok := tbl.Fields["data_format"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1340,10 +1504,36 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["aiservice_group_by_measurement"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.AiserviceGroupByMeasurement, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, fmt.Errorf("Error parsing boolean value for %s: %s", name, err)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["aiservice_batch_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if i, ok := kv.Value.(*ast.Integer); ok {
+				size, err := strconv.Atoi(i.Value)
+				if err != nil {
+					return nil, fmt.Errorf("Error parsing int value for %s: %s", name, err)
+				}
+				c.AiserviceBatchSize = size
+			}
+		}
+	}
+
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "prefix")
 	delete(tbl.Fields, "template")
 	delete(tbl.Fields, "json_timestamp_units")
+	delete(tbl.Fields, "aiservice_group_by_measurement")
+	delete(tbl.Fields, "aiservice_batch_size")
 	return serializers.NewSerializer(c)
 }
 
@@ -1360,6 +1550,48 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		Name:   name,
 		Filter: filter,
 	}
+
+	for _, field := range []string{"flush_interval", "flush_jitter"} {
+		if node, ok := tbl.Fields[field]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					dur, err := time.ParseDuration(str.Value)
+					if err != nil {
+						return nil, err
+					}
+					if field == "flush_interval" {
+						oc.FlushInterval = dur
+					} else {
+						oc.FlushJitter = dur
+					}
+				}
+			}
+		}
+	}
+
+	for _, field := range []string{"metric_batch_size", "metric_buffer_limit"} {
+		if node, ok := tbl.Fields[field]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if b, ok := kv.Value.(*ast.Integer); ok {
+					n, err := strconv.Atoi(b.Value)
+					if err != nil {
+						return nil, fmt.Errorf("Error parsing int value for %s: %s", field, err)
+					}
+					if field == "metric_batch_size" {
+						oc.MetricBatchSize = n
+					} else {
+						oc.MetricBufferLimit = n
+					}
+				}
+			}
+		}
+	}
+
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "flush_jitter")
+	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "metric_buffer_limit")
+
 	// Outputs don't support FieldDrop/FieldPass, so set to NameDrop/NamePass
 	if len(oc.Filter.FieldDrop) > 0 {
 		oc.Filter.NameDrop = oc.Filter.FieldDrop
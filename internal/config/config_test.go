@@ -1,18 +1,23 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secretstore"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/toml"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -58,6 +63,40 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 		"Testdata did not produce correct memcached metadata.")
 }
 
+func TestConfig_LoadSingleInputWithSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "secrets.key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("passphrase\n"), 0600))
+	key, err := secretstore.LoadKey(keyFile)
+	require.NoError(t, err)
+
+	store, err := secretstore.Open(filepath.Join(dir, "secrets.db"), key)
+	require.NoError(t, err)
+	require.NoError(t, store.Set("memcached1", "server", `10.0.0.1:11211`))
+	require.NoError(t, store.Save())
+
+	c := NewConfig()
+	c.SecretStore = store.Path
+	c.SecretStoreKeyFile = keyFile
+	require.NoError(t, c.LoadConfig("./testdata/single_plugin_secrets.toml"))
+
+	memcached := c.Inputs[0].Input.(*memcached.Memcached)
+	assert.Equal(t, []string{"10.0.0.1:11211"}, memcached.Servers)
+
+	// without the secret
+	require.NoError(t, store.Delete("memcached1", ""))
+	require.NoError(t, store.Save())
+	c = NewConfig()
+	c.SecretStore = store.Path
+	c.SecretStoreKeyFile = keyFile
+	err = c.LoadConfig("./testdata/single_plugin_secrets.toml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Secret memcached1/server not found")
+}
+
 func TestConfig_LoadSingleInput(t *testing.T) {
 	c := NewConfig()
 	c.LoadConfig("./testdata/single_plugin.toml")
@@ -174,3 +213,45 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_BuildOutputFlushSettings(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+flush_interval = "30s"
+flush_jitter = "5s"
+metric_batch_size = 500
+metric_buffer_limit = 5000
+url = "http://localhost"
+`))
+	require.NoError(t, err)
+
+	oc, err := buildOutput("http", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, oc.FlushInterval)
+	assert.Equal(t, 5*time.Second, oc.FlushJitter)
+	assert.Equal(t, 500, oc.MetricBatchSize)
+	assert.Equal(t, 5000, oc.MetricBufferLimit)
+	// the plugin only gets its own settings
+	assert.Len(t, tbl.Fields, 1)
+	assert.Contains(t, tbl.Fields, "url")
+
+	tbl, err = toml.Parse([]byte(`flush_interval = "soon"`))
+	require.NoError(t, err)
+	_, err = buildOutput("http", tbl)
+	assert.Error(t, err)
+}
+
+func TestConfig_BuildProcessor(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+order = 2
+workers = 4
+namepass = ["cpu"]
+`))
+	require.NoError(t, err)
+
+	pc, err := buildProcessor("printer", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pc.Order)
+	assert.Equal(t, 4, pc.Workers)
+	assert.Equal(t, []string{"cpu"}, pc.Filter.NamePass)
+	assert.Empty(t, tbl.Fields)
+}
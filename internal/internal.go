@@ -67,6 +67,22 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// Number is a float setting also accepting the TOML integers, ie, 100 as
+// well as 100.0
+type Number struct {
+	Value float64
+}
+
+// UnmarshalTOML parses the number from the TOML config file
+func (n *Number) UnmarshalTOML(b []byte) error {
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return fmt.Errorf("Invalid number %s", b)
+	}
+	n.Value = f
+	return nil
+}
+
 // ReadLines reads contents from a file and splits them by new lines.
 // A convenience wrapper to ReadLinesOffsetN(filename, 0, -1).
 func ReadLines(filename string) ([]string, error) {
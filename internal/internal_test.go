@@ -155,3 +155,14 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestNumber(t *testing.T) {
+	var n Number
+	assert.NoError(t, n.UnmarshalTOML([]byte(`100`)))
+	assert.Equal(t, 100.0, n.Value)
+
+	assert.NoError(t, n.UnmarshalTOML([]byte(`2.5`)))
+	assert.Equal(t, 2.5, n.Value)
+
+	assert.Error(t, n.UnmarshalTOML([]byte(`"100"`)))
+}
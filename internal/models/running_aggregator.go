@@ -11,6 +11,10 @@ type RunningAggregator struct {
 	a      telegraf.Aggregator
 	Config *AggregatorConfig
 
+	// Fingerprint identifies the settings of the plugin in the config, the
+	// plugins left unchanged keep running when the config is reloaded
+	Fingerprint string
+
 	metrics chan telegraf.Metric
 
 	periodStart time.Time
@@ -48,6 +52,11 @@ func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// Aggregator returns the aggregator plugin run by r
+func (r *RunningAggregator) Aggregator() telegraf.Aggregator {
+	return r.a
+}
+
 func (r *RunningAggregator) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
@@ -2,9 +2,11 @@ package models
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/secretstore"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -14,10 +16,26 @@ type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
 
+	// Fingerprint identifies the settings of the plugin in the config, the
+	// plugins left unchanged keep running when the config is reloaded
+	Fingerprint string
+
 	trace       bool
 	defaultTags map[string]string
 
 	MetricsGathered selfstat.Stat
+	GatherErrors    selfstat.Stat
+
+	statusMu sync.Mutex
+	status   InputStatus
+}
+
+// InputStatus is the outcome of the last gathers of a RunningInput
+type InputStatus struct {
+	LastGather         time.Time
+	LastGatherDuration time.Duration
+	LastError          string
+	LastErrorTime      time.Time
 }
 
 func NewRunningInput(
@@ -32,6 +50,11 @@ func NewRunningInput(
 			"metrics_gathered",
 			map[string]string{"input": config.Name},
 		),
+		GatherErrors: selfstat.Register(
+			"gather",
+			"errors",
+			map[string]string{"input": config.Name},
+		),
 	}
 }
 
@@ -50,6 +73,30 @@ func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// Gathered records a gather started at start which took elapsed
+func (r *RunningInput) Gathered(start time.Time, elapsed time.Duration) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	r.status.LastGather = start
+	r.status.LastGatherDuration = elapsed
+}
+
+// RecordError records an error the input reported to its accumulator
+func (r *RunningInput) RecordError(err error) {
+	r.GatherErrors.Incr(1)
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	r.status.LastError = err.Error()
+	r.status.LastErrorTime = time.Now()
+}
+
+// Status returns the outcome of the last gathers
+func (r *RunningInput) Status() InputStatus {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	return r.status
+}
+
 // MakeMetric either returns a metric, or returns nil if the metric doesn't
 // need to be created (because of filtering, an error, etc.)
 func (r *RunningInput) MakeMetric(
@@ -75,7 +122,7 @@ func (r *RunningInput) MakeMetric(
 	)
 
 	if r.trace && m != nil {
-		fmt.Print("> " + m.String())
+		fmt.Print("> " + secretstore.Redact(m.String()))
 	}
 
 	r.MetricsGathered.Incr(1)
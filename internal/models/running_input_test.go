@@ -456,6 +456,24 @@ func TestMakeMetric_TrailingSlash(t *testing.T) {
 	}
 }
 
+func TestRunningInputStatus(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name: "TestRunningInputStatus",
+	})
+	assert.Equal(t, InputStatus{}, ri.Status())
+
+	start := time.Unix(1500000000, 0)
+	ri.Gathered(start, 2*time.Second)
+	ri.RecordError(fmt.Errorf("connection refused"))
+
+	status := ri.Status()
+	assert.Equal(t, start, status.LastGather)
+	assert.Equal(t, 2*time.Second, status.LastGatherDuration)
+	assert.Equal(t, "connection refused", status.LastError)
+	assert.False(t, status.LastErrorTime.IsZero())
+	assert.Equal(t, int64(1), ri.GatherErrors.Get())
+}
+
 type testInput struct{}
 
 func (t *testInput) Description() string                   { return "" }
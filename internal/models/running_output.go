@@ -3,6 +3,7 @@ package models
 import (
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -27,17 +28,40 @@ type RunningOutput struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// Fingerprint identifies the settings of the plugin in the config, the
+	// plugins left unchanged keep running when the config is reloaded
+	Fingerprint string
+
 	MetricsFiltered selfstat.Stat
 	MetricsWritten  selfstat.Stat
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
+	WriteErrors     selfstat.Stat
 
 	metrics     *buffer.Buffer
 	failMetrics *buffer.Buffer
 
 	// Guards against concurrent calls to the Output as described in #3009
 	sync.Mutex
+
+	statusMu sync.Mutex
+	status   OutputStatus
+
+	// set by BatchReady, AddMetric signals batchReady instead of writing
+	// the full batches
+	deferBatches int32
+	batchReady   chan struct{}
+}
+
+// OutputStatus is the outcome of the last writes of a RunningOutput
+type OutputStatus struct {
+	BufferSize        int
+	BufferLimit       int
+	LastWrite         time.Time
+	LastWriteDuration time.Duration
+	LastError         string
+	LastErrorTime     time.Time
 }
 
 func NewRunningOutput(
@@ -61,6 +85,7 @@ func NewRunningOutput(
 		Config:            conf,
 		MetricBufferLimit: bufferLimit,
 		MetricBatchSize:   batchSize,
+		batchReady:        make(chan struct{}, 1),
 		MetricsWritten: selfstat.Register(
 			"write",
 			"metrics_written",
@@ -86,6 +111,11 @@ func NewRunningOutput(
 			"write_time_ns",
 			map[string]string{"output": name},
 		),
+		WriteErrors: selfstat.Register(
+			"write",
+			"errors",
+			map[string]string{"output": name},
+		),
 	}
 	ro.BufferLimit.Incr(int64(ro.MetricBufferLimit))
 	return ro
@@ -116,13 +146,31 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	ro.metrics.Add(m)
 	if ro.metrics.Len() == ro.MetricBatchSize {
 		batch := ro.metrics.Batch(ro.MetricBatchSize)
+		if atomic.LoadInt32(&ro.deferBatches) == 1 {
+			// queued ahead of the metrics added since, for the next Write
+			ro.failMetrics.Add(batch...)
+			select {
+			case ro.batchReady <- struct{}{}:
+			default:
+			}
+			return
+		}
 		err := ro.write(batch)
 		if err != nil {
-			ro.failMetrics.Add(batch...)
+			ro.failMetrics.Add(unsent(batch, err)...)
 		}
 	}
 }
 
+// BatchReady makes AddMetric queue the full batches instead of writing them,
+// the returned channel is signaled when one is queued. The owner of the
+// output then does all the writes with Write, so a slow output does not block
+// the callers of AddMetric.
+func (ro *RunningOutput) BatchReady() <-chan struct{} {
+	atomic.StoreInt32(&ro.deferBatches, 1)
+	return ro.batchReady
+}
+
 // Write writes all cached points to this output.
 func (ro *RunningOutput) Write() error {
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
@@ -146,7 +194,9 @@ func (ro *RunningOutput) Write() error {
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
-				err = ro.write(batch)
+				if err = ro.write(batch); err != nil {
+					batch = unsent(batch, err)
+				}
 			}
 			if err != nil {
 				ro.failMetrics.Add(batch...)
@@ -158,7 +208,9 @@ func (ro *RunningOutput) Write() error {
 	// see comment above about not trying to write to an already failed output.
 	// if ro.failMetrics is empty then err will always be nil at this point.
 	if err == nil {
-		err = ro.write(batch)
+		if err = ro.write(batch); err != nil {
+			batch = unsent(batch, err)
+		}
 	}
 
 	if err != nil {
@@ -178,17 +230,54 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	start := time.Now()
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
+
+	ro.statusMu.Lock()
 	if err == nil {
 		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
 			ro.Name, nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		ro.status.LastWrite = start
+		ro.status.LastWriteDuration = elapsed
+	} else {
+		if pe, ok := err.(*telegraf.PartialWriteError); ok {
+			ro.MetricsWritten.Incr(int64(nMetrics - len(pe.Unsent)))
+		}
+		ro.WriteErrors.Incr(1)
+		ro.status.LastError = err.Error()
+		ro.status.LastErrorTime = time.Now()
 	}
+	ro.statusMu.Unlock()
 	return err
 }
 
+// unsent returns the metrics of batch to write again after a failed write
+func unsent(batch []telegraf.Metric, err error) []telegraf.Metric {
+	if pe, ok := err.(*telegraf.PartialWriteError); ok {
+		return pe.Unsent
+	}
+	return batch
+}
+
+// Status returns the buffer fill and the outcome of the last writes
+func (ro *RunningOutput) Status() OutputStatus {
+	ro.statusMu.Lock()
+	defer ro.statusMu.Unlock()
+	status := ro.status
+	status.BufferSize = ro.failMetrics.Len() + ro.metrics.Len()
+	status.BufferLimit = ro.MetricBufferLimit
+	return status
+}
+
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// Flush and buffer settings of the output, the agent ones are used when
+	// they are not set
+	FlushInterval     time.Duration
+	FlushJitter       time.Duration
+	MetricBatchSize   int
+	MetricBufferLimit int
 }
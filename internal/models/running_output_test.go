@@ -330,6 +330,28 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+// Verify that the metrics written by a partial write are not written again.
+func TestRunningOutputPartialWrite(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.partialWrite = 2
+	ro := NewRunningOutput("test", m, conf, 10, 20)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Len(t, m.Metrics(), 2)
+	assert.Equal(t, 3, ro.Status().BufferSize)
+
+	m.partialWrite = 0
+	require.NoError(t, ro.Write())
+	assert.Equal(t, first5, m.Metrics())
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -479,6 +501,66 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+// Test that the full batches are queued, not written, once BatchReady is
+// called
+func TestRunningOutputBatchReady(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	batchReady := ro.BatchReady()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, m.Metrics(), 0)
+	assert.Len(t, batchReady, 1)
+	assert.Equal(t, 10, ro.Status().BufferSize)
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	require.Len(t, m.Metrics(), 10)
+	for i, metric := range append(first5, next5...) {
+		assert.Equal(t, metric.Name(), m.Metrics()[i].Name())
+	}
+}
+
+func TestRunningOutputStatus(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("status", m, conf, 1000, 10000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	status := ro.Status()
+	assert.Equal(t, 5, status.BufferSize)
+	assert.Equal(t, 10000, status.BufferLimit)
+	assert.True(t, status.LastWrite.IsZero())
+
+	require.Error(t, ro.Write())
+	status = ro.Status()
+	assert.Equal(t, 5, status.BufferSize)
+	assert.Equal(t, "Failed Write!", status.LastError)
+	assert.False(t, status.LastErrorTime.IsZero())
+	assert.Equal(t, int64(1), ro.WriteErrors.Get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	status = ro.Status()
+	assert.Equal(t, 0, status.BufferSize)
+	assert.False(t, status.LastWrite.IsZero())
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -486,6 +568,8 @@ type mockOutput struct {
 
 	// if true, mock a write failure
 	failWrite bool
+	// if set, mock a failure after writing that many metrics
+	partialWrite int
 }
 
 func (m *mockOutput) Connect() error {
@@ -514,6 +598,13 @@ func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	if m.metrics == nil {
 		m.metrics = []telegraf.Metric{}
 	}
+	if m.partialWrite > 0 && m.partialWrite < len(metrics) {
+		m.metrics = append(m.metrics, metrics[:m.partialWrite]...)
+		return &telegraf.PartialWriteError{
+			Err:    fmt.Errorf("Failed Write!"),
+			Unsent: metrics[m.partialWrite:],
+		}
+	}
 
 	for _, metric := range metrics {
 		m.metrics = append(m.metrics, metric)
@@ -2,8 +2,10 @@ package models
 
 import (
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 type RunningProcessor struct {
@@ -12,6 +14,13 @@ type RunningProcessor struct {
 	sync.Mutex
 	Processor telegraf.Processor
 	Config    *ProcessorConfig
+
+	// Fingerprint identifies the settings of the plugin in the config, the
+	// plugins left unchanged keep running when the config is reloaded
+	Fingerprint string
+
+	MetricsProcessed selfstat.Stat
+	ProcessTime      selfstat.Stat
 }
 
 type RunningProcessors []*RunningProcessor
@@ -25,27 +34,111 @@ type ProcessorConfig struct {
 	Name   string
 	Order  int64
 	Filter Filter
+
+	// Workers is the number of goroutines applying the processor to a batch,
+	// the metrics of a series are always applied in order by the same one.
+	// The processor must be safe for concurrent use when it is more than 1.
+	Workers int
+}
+
+func NewRunningProcessor(
+	name string,
+	processor telegraf.Processor,
+	config *ProcessorConfig,
+) *RunningProcessor {
+	return &RunningProcessor{
+		Name:      name,
+		Processor: processor,
+		Config:    config,
+		MetricsProcessed: selfstat.Register(
+			"process",
+			"metrics_processed",
+			map[string]string{"processor": name},
+		),
+		ProcessTime: selfstat.RegisterTiming(
+			"process",
+			"process_time_ns",
+			map[string]string{"processor": name},
+		),
+	}
 }
 
+// Apply passes the batch in through the processor, the metrics excluded by
+// the filter are passed on unchanged. The order of the metrics of a series is
+// preserved.
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
-	rp.Lock()
-	defer rp.Unlock()
+	start := time.Now()
+	var ret []telegraf.Metric
+	if rp.Config.Workers > 1 && len(in) > 1 {
+		ret = rp.applyParallel(in)
+	} else {
+		rp.Lock()
+		ret = rp.apply(in)
+		rp.Unlock()
+	}
+
+	// processors built without NewRunningProcessor have no stats
+	if rp.MetricsProcessed != nil {
+		rp.MetricsProcessed.Incr(int64(len(in)))
+		rp.ProcessTime.Incr(time.Since(start).Nanoseconds())
+	}
+	return ret
+}
 
-	ret := []telegraf.Metric{}
+// apply passes the consecutive metrics accepted by the filter to the
+// processor at once
+func (rp *RunningProcessor) apply(in []telegraf.Metric) []telegraf.Metric {
+	if !rp.Config.Filter.IsActive() {
+		return rp.Processor.Apply(in...)
+	}
 
+	ret := make([]telegraf.Metric, 0, len(in))
+	batch := 0
+	for i, metric := range in {
+		// check if the filter should be applied to this metric
+		if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags()); ok {
+			continue
+		}
+		// this means filter should not be applied, the metrics before it
+		// are processed first to keep the order
+		if batch < i {
+			ret = append(ret, rp.Processor.Apply(in[batch:i]...)...)
+		}
+		ret = append(ret, metric)
+		batch = i + 1
+	}
+	if batch < len(in) {
+		ret = append(ret, rp.Processor.Apply(in[batch:]...)...)
+	}
+	return ret
+}
+
+// applyParallel shards in by series over the workers, each of them applying
+// the processor to its shard in order
+func (rp *RunningProcessor) applyParallel(in []telegraf.Metric) []telegraf.Metric {
+	workers := rp.Config.Workers
+	shards := make([][]telegraf.Metric, workers)
 	for _, metric := range in {
-		if rp.Config.Filter.IsActive() {
-			// check if the filter should be applied to this metric
-			if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags()); !ok {
-				// this means filter should not be applied
-				ret = append(ret, metric)
-				continue
-			}
+		i := metric.HashID() % uint64(workers)
+		shards[i] = append(shards[i], metric)
+	}
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		if len(shards[i]) == 0 {
+			continue
 		}
-		// This metric should pass through the filter, so call the filter Apply
-		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			shards[i] = rp.apply(shards[i])
+		}(i)
 	}
+	wg.Wait()
 
+	ret := make([]telegraf.Metric, 0, len(in))
+	for _, shard := range shards {
+		ret = append(ret, shard...)
+	}
 	return ret
 }
@@ -1,12 +1,17 @@
 package models
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type TestProcessor struct {
@@ -115,3 +120,102 @@ func TestRunningProcessor_DroppedMetric(t *testing.T) {
 	}
 	assert.Equal(t, expectedNames, actualNames)
 }
+
+// batchProcessor records the batches it is applied to
+type batchProcessor struct {
+	sync.Mutex
+	batches [][]string
+}
+
+func (p *batchProcessor) SampleConfig() string { return "" }
+func (p *batchProcessor) Description() string  { return "" }
+func (p *batchProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+	var names []string
+	for _, m := range in {
+		names = append(names, m.Name())
+	}
+	p.batches = append(p.batches, names)
+	return in
+}
+
+func TestRunningProcessor_Batch(t *testing.T) {
+	inmetrics := []telegraf.Metric{
+		testutil.TestMetric(1, "foo"),
+		testutil.TestMetric(1, "bar"),
+		testutil.TestMetric(1, "skip"),
+		testutil.TestMetric(1, "baz"),
+	}
+
+	p := &batchProcessor{}
+	rp := NewRunningProcessor("batch", p, &ProcessorConfig{Name: "batch"})
+	rp.Config.Filter.NameDrop = []string{"skip"}
+	require.NoError(t, rp.Config.Filter.Compile())
+
+	processed := rp.MetricsProcessed.Get()
+	out := rp.Apply(inmetrics...)
+	require.Len(t, out, 4)
+	for i, m := range out {
+		assert.Equal(t, inmetrics[i].Name(), m.Name())
+	}
+	// the excluded metric splits the batch
+	assert.Equal(t, [][]string{{"foo", "bar"}, {"baz"}}, p.batches)
+	assert.Equal(t, processed+4, rp.MetricsProcessed.Get())
+}
+
+// sequenceProcessor checks that the metrics of a series are applied in order
+type sequenceProcessor struct {
+	sync.Mutex
+	last  map[uint64]int64
+	order bool
+}
+
+func (p *sequenceProcessor) SampleConfig() string { return "" }
+func (p *sequenceProcessor) Description() string  { return "" }
+func (p *sequenceProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		seq, _ := m.Fields()["seq"].(int64)
+		p.Lock()
+		if last, ok := p.last[m.HashID()]; ok && seq <= last {
+			p.order = false
+		}
+		p.last[m.HashID()] = seq
+		p.Unlock()
+	}
+	return in
+}
+
+func TestRunningProcessor_Workers(t *testing.T) {
+	var inmetrics []telegraf.Metric
+	for seq := 0; seq < 100; seq++ {
+		for host := 0; host < 10; host++ {
+			m, err := metric.New("cpu",
+				map[string]string{"host": fmt.Sprintf("host%d", host)},
+				map[string]interface{}{"seq": int64(seq)},
+				time.Unix(0, 0))
+			require.NoError(t, err)
+			inmetrics = append(inmetrics, m)
+		}
+	}
+
+	p := &sequenceProcessor{last: make(map[uint64]int64), order: true}
+	rp := NewRunningProcessor("sequence", p, &ProcessorConfig{
+		Name:    "sequence",
+		Workers: 4,
+	})
+	out := rp.Apply(inmetrics...)
+	assert.Len(t, out, len(inmetrics))
+	assert.True(t, p.order, "the metrics of a series are applied in order")
+
+	// and so are they in the processed batch
+	last := make(map[uint64]int64)
+	for _, m := range out {
+		seq := m.Fields()["seq"].(int64)
+		if prev, ok := last[m.HashID()]; ok {
+			assert.True(t, seq > prev)
+		}
+		last[m.HashID()] = seq
+	}
+	assert.Len(t, last, 10)
+}
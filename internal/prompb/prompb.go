@@ -0,0 +1,208 @@
+// Package prompb encodes and decodes the WriteRequest of the Prometheus
+// remote write protocol, see
+// https://github.com/prometheus/prometheus/blob/master/prompb/remote.proto
+package prompb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// NameLabel is the label holding the name of a series
+const NameLabel = "__name__"
+
+var errTruncated = errors.New("Truncated protobuf message")
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type Sample struct {
+	Value float64
+	// Timestamp in milliseconds since the epoch
+	Timestamp int64
+}
+
+// TimeSeries holds the samples of a series, its labels are sorted by name
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Marshal encodes the request in the protobuf wire format
+func (r *WriteRequest) Marshal() []byte {
+	var b, ts, msg []byte
+	for _, series := range r.Timeseries {
+		ts = ts[:0]
+		for _, l := range series.Labels {
+			msg = msg[:0]
+			msg = appendString(msg, 1, l.Name)
+			msg = appendString(msg, 2, l.Value)
+			ts = appendBytes(ts, 1, msg)
+		}
+		for _, s := range series.Samples {
+			msg = msg[:0]
+			msg = appendTag(msg, 1, wireFixed64)
+			msg = appendFixed64(msg, math.Float64bits(s.Value))
+			msg = appendTag(msg, 2, wireVarint)
+			msg = appendUvarint(msg, uint64(s.Timestamp))
+			ts = appendBytes(ts, 2, msg)
+		}
+		b = appendBytes(b, 1, ts)
+	}
+	return b
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return appendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// Unmarshal decodes a request in the protobuf wire format, the unknown fields
+// are skipped
+func (r *WriteRequest) Unmarshal(b []byte) error {
+	r.Timeseries = r.Timeseries[:0]
+	return walk(b, func(field int, wireType int, v []byte, _ uint64) error {
+		if field != 1 || wireType != wireBytes {
+			return nil
+		}
+		var series TimeSeries
+		if err := series.unmarshal(v); err != nil {
+			return err
+		}
+		r.Timeseries = append(r.Timeseries, series)
+		return nil
+	})
+}
+
+func (t *TimeSeries) unmarshal(b []byte) error {
+	return walk(b, func(field int, wireType int, v []byte, _ uint64) error {
+		if wireType != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			var l Label
+			err := walk(v, func(field int, wireType int, v []byte, _ uint64) error {
+				if wireType != wireBytes {
+					return nil
+				}
+				switch field {
+				case 1:
+					l.Name = string(v)
+				case 2:
+					l.Value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			t.Labels = append(t.Labels, l)
+		case 2:
+			var s Sample
+			err := walk(v, func(field int, wireType int, _ []byte, n uint64) error {
+				switch {
+				case field == 1 && wireType == wireFixed64:
+					s.Value = math.Float64frombits(n)
+				case field == 2 && wireType == wireVarint:
+					s.Timestamp = int64(n)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			t.Samples = append(t.Samples, s)
+		}
+		return nil
+	})
+}
+
+// walk calls fn with each field of a message, v holds the content of the
+// length delimited fields and n the value of the others
+func walk(b []byte, fn func(field int, wireType int, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		key, k := binary.Uvarint(b)
+		if k <= 0 {
+			return errTruncated
+		}
+		b = b[k:]
+		field, wireType := int(key>>3), int(key&7)
+
+		var v []byte
+		var n uint64
+		switch wireType {
+		case wireVarint:
+			n, k = binary.Uvarint(b)
+			if k <= 0 {
+				return errTruncated
+			}
+			b = b[k:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			n = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			n = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			size, k := binary.Uvarint(b)
+			if k <= 0 || uint64(len(b)-k) < size {
+				return errTruncated
+			}
+			v = b[k : k+int(size)]
+			b = b[k+int(size):]
+		default:
+			return fmt.Errorf("Unsupported protobuf wire type %d", wireType)
+		}
+
+		if err := fn(field, wireType, v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
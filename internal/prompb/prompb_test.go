@@ -0,0 +1,88 @@
+package prompb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	r := WriteRequest{
+		Timeseries: []TimeSeries{{
+			Labels:  []Label{{Name: NameLabel, Value: "up"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1000}},
+		}},
+	}
+	expected := []byte{
+		0x0a, 0x1e, // timeseries, 30 bytes
+		0x0a, 0x0e, // label, 14 bytes
+		0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+		0x12, 0x02, 'u', 'p',
+		0x12, 0x0c, // sample, 12 bytes
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f,
+		0x10, 0xe8, 0x07,
+	}
+	assert.Equal(t, expected, r.Marshal())
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels: []Label{
+					{Name: NameLabel, Value: "smart_temp_c"},
+					{Name: "disk_wwn", Value: "5000c5005f50e6ab"},
+					{Name: "host", Value: ""},
+				},
+				Samples: []Sample{
+					{Value: 35.5, Timestamp: 1508400000000},
+					{Value: -1, Timestamp: -1},
+				},
+			},
+			{
+				Labels:  []Label{{Name: NameLabel, Value: "empty"}},
+				Samples: []Sample{{Value: 0, Timestamp: 0}},
+			},
+		},
+	}
+
+	var decoded WriteRequest
+	require.NoError(t, decoded.Unmarshal(r.Marshal()))
+	assert.Equal(t, r, decoded)
+}
+
+func TestUnmarshalSkipsUnknownFields(t *testing.T) {
+	r := WriteRequest{
+		Timeseries: []TimeSeries{{
+			Labels:  []Label{{Name: NameLabel, Value: "up"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1000}},
+		}},
+	}
+	b := r.Marshal()
+	// the metadata of a newer protocol, field 3
+	b = append(b, 0x1a, 0x02, 0x08, 0x01)
+	// a varint and a fixed32 field
+	b = append(b, 0x20, 0x01, 0x2d, 0x01, 0x02, 0x03, 0x04)
+
+	var decoded WriteRequest
+	require.NoError(t, decoded.Unmarshal(b))
+	assert.Equal(t, r, decoded)
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	r := WriteRequest{
+		Timeseries: []TimeSeries{{
+			Labels:  []Label{{Name: NameLabel, Value: "up"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1000}},
+		}},
+	}
+	b := r.Marshal()
+	for i := 1; i < len(b); i++ {
+		var decoded WriteRequest
+		assert.Error(t, decoded.Unmarshal(b[:i]), "length %d", i)
+	}
+
+	var decoded WriteRequest
+	assert.Error(t, decoded.Unmarshal([]byte{0x0b}))
+}
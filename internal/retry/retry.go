@@ -0,0 +1,33 @@
+// Package retry retries the requests of the outputs failing with a transient
+// error.
+package retry
+
+import (
+	"log"
+	"time"
+)
+
+// MaxBackoff is the longest wait between two attempts
+const MaxBackoff = 30 * time.Second
+
+var sleep = time.Sleep
+
+// Do calls attempt until it succeeds, it fails with an error which may not be
+// retried or max retries are exhausted, and returns the last error. The wait
+// between the attempts doubles from backoff up to MaxBackoff, the retries are
+// logged prefixed by name.
+func Do(name string, max int, backoff time.Duration, attempt func() (bool, error)) error {
+	for i := 0; ; i++ {
+		retry, err := attempt()
+		if err == nil || !retry || i >= max {
+			return err
+		}
+
+		log.Printf("W! %s: Retrying in %s: %s\n", name, backoff, err)
+		sleep(backoff)
+		backoff *= 2
+		if backoff > MaxBackoff {
+			backoff = MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package retry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// attempts returns an attempt failing with the errors in turn, and the
+// number of calls
+func attempts(errs ...error) (func() (bool, error), *int) {
+	n := 0
+	return func() (bool, error) {
+		n++
+		if n > len(errs) {
+			return false, nil
+		}
+		return errs[n-1] != nil, errs[n-1]
+	}, &n
+}
+
+func TestDo(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	attempt, n := attempts(fmt.Errorf("a"), fmt.Errorf("b"))
+	assert.NoError(t, Do("test", 3, 20*time.Second, attempt))
+	assert.Equal(t, 3, *n)
+	assert.Equal(t, []time.Duration{20 * time.Second, MaxBackoff}, waits)
+}
+
+func TestDoExhausted(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	attempt, n := attempts(fmt.Errorf("a"), fmt.Errorf("b"), fmt.Errorf("c"))
+	assert.EqualError(t, Do("test", 1, time.Second, attempt), "b")
+	assert.Equal(t, 2, *n)
+}
+
+func TestDoNotRetried(t *testing.T) {
+	n := 0
+	err := Do("test", 3, time.Second, func() (bool, error) {
+		n++
+		return false, fmt.Errorf("rejected")
+	})
+	assert.EqualError(t, err, "rejected")
+	assert.Equal(t, 1, n)
+}
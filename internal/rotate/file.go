@@ -0,0 +1,309 @@
+// Package rotate provides a file writer rotating its file by size and by age
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeLayout is the timestamp of the archives, it sorts as the time and has
+// no character reserved on windows
+const timeLayout = "2006-01-02T15-04-05Z"
+
+var now = time.Now
+
+// File is a writer appending to a file, the file is renamed to an archive
+// when it reaches its maximum size or age and a new one is created.
+// The archives are named after the file and the time of their rotation, e.g.
+// metrics.2017-11-20T10-15-30Z.out for metrics.out.
+type File struct {
+	path        string
+	interval    time.Duration
+	maxSize     int64
+	maxArchives int
+	compress    bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// the archives are gzipped in the background, one at a time
+	archiveMu sync.Mutex
+	archiving sync.WaitGroup
+}
+
+// NewFile opens the file at path, creating it when missing. The file is
+// rotated once interval passed since it was opened or before a write would
+// make it larger than maxSize, a zero interval or maxSize disables the
+// rotation on this criteria. The oldest archives are removed above
+// maxArchives, unless it is negative, and the archives are gzipped when
+// compress is set, in the background not to block the writes.
+func NewFile(path string, interval time.Duration, maxSize int64, maxArchives int, compress bool) (*File, error) {
+	f := &File{
+		path:        path,
+		interval:    interval,
+		maxSize:     maxSize,
+		maxArchives: maxArchives,
+		compress:    compress,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = now()
+	return nil
+}
+
+// Write writes p to the file, rotating it first when needed. A failed
+// rotation is logged and p written to the current file.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.needsRotation(len(p)) {
+		if err := f.rotate(); err != nil {
+			log.Printf("E! Unable to rotate %s: %s\n", f.path, err)
+			if f.file == nil {
+				if err := f.open(); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *File) needsRotation(n int) bool {
+	if f.interval > 0 && now().Sub(f.openedAt) >= f.interval {
+		return true
+	}
+	return f.maxSize > 0 && f.size > 0 && f.size+int64(n) > f.maxSize
+}
+
+// Reopen closes the file and opens it again, creating it when it was moved
+// away, e.g. by logrotate
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// Close closes the file, a later write opens it again. It waits for the
+// archives being gzipped.
+func (f *File) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.archiving.Wait()
+	return err
+}
+
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	t := now()
+	var archive string
+	for seq := 0; ; seq++ {
+		archive = archiveName(f.path, t, seq)
+		if !exists(archive) && !exists(archive+".gz") {
+			break
+		}
+	}
+	if err := os.Rename(f.path, archive); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if !f.compress {
+		return f.removeArchives()
+	}
+
+	// the oldest archives are removed once the new one is gzipped
+	f.archiving.Add(1)
+	go func() {
+		defer f.archiving.Done()
+		f.archiveMu.Lock()
+		defer f.archiveMu.Unlock()
+
+		if err := gzipFile(archive); err != nil {
+			log.Printf("W! Unable to gzip %s, it is kept uncompressed: %s\n", archive, err)
+		}
+		if err := f.removeArchives(); err != nil {
+			log.Printf("E! Unable to remove the archives of %s: %s\n", f.path, err)
+		}
+	}()
+	return nil
+}
+
+// removeArchives removes the oldest archives above maxArchives
+func (f *File) removeArchives() error {
+	if f.maxArchives < 0 {
+		return nil
+	}
+	archives, err := listArchives(f.path)
+	if err != nil {
+		return err
+	}
+	if len(archives) <= f.maxArchives {
+		return nil
+	}
+	for _, a := range archives[:len(archives)-f.maxArchives] {
+		if err := os.Remove(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveName returns the name of an archive of path rotated at t, seq
+// tells apart the archives rotated in the same second
+func archiveName(path string, t time.Time, seq int) string {
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(path, ext) + "." + t.UTC().Format(timeLayout)
+	if seq > 0 {
+		name += "-" + strconv.Itoa(seq)
+	}
+	return name + ext
+}
+
+type archive struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+// listArchives returns the archives of path, the oldest first
+func listArchives(path string) ([]string, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "."
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var archives []archive
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(stamp, ".gz")
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		stamp = strings.TrimSuffix(stamp, ext)
+
+		seq := 0
+		if len(stamp) > len(timeLayout) && stamp[len(timeLayout)] == '-' {
+			seq, err = strconv.Atoi(stamp[len(timeLayout)+1:])
+			if err != nil {
+				continue
+			}
+			stamp = stamp[:len(timeLayout)]
+		}
+		t, err := time.Parse(timeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		archives = append(archives, archive{filepath.Join(dir, name), t, seq})
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		if !archives[i].time.Equal(archives[j].time) {
+			return archives[i].time.Before(archives[j].time)
+		}
+		return archives[i].seq < archives[j].seq
+	})
+	paths := make([]string, len(archives))
+	for i, a := range archives {
+		paths[i] = a.path
+	}
+	return paths, nil
+}
+
+// gzipFile replaces path by path.gz
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return fmt.Errorf("Error compressing %s: %s", path, err)
+	}
+
+	in.Close()
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,192 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setNow sets the time seen by the writers, it returns a function advancing
+// it
+func setNow(t *testing.T, start time.Time) func(time.Duration) {
+	current := start
+	now = func() time.Time { return current }
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	return dir
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestArchiveName(t *testing.T) {
+	tm := time.Date(2017, 11, 20, 10, 15, 30, 0, time.UTC)
+	assert.Equal(t, "/var/log/metrics.2017-11-20T10-15-30Z.out", archiveName("/var/log/metrics.out", tm, 0))
+	assert.Equal(t, "/var/log/metrics.2017-11-20T10-15-30Z-2.out", archiveName("/var/log/metrics.out", tm, 2))
+	assert.Equal(t, "metrics.2017-11-20T10-15-30Z", archiveName("metrics", tm, 0))
+
+	local := time.FixedZone("CET", 3600)
+	assert.Equal(t, "metrics.2017-11-20T10-15-30Z.out", archiveName("metrics.out", tm.In(local), 0))
+}
+
+func TestRotateBySize(t *testing.T) {
+	defer func() { now = time.Now }()
+	setNow(t, time.Date(2017, 11, 20, 10, 15, 30, 0, time.UTC))
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+
+	f, err := NewFile(path, 0, 10, -1, false)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddddddddddd\n", "e\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// the lines larger than the maximum are written alone
+	assert.Equal(t, []string{
+		"metrics.2017-11-20T10-15-30Z-1.out",
+		"metrics.2017-11-20T10-15-30Z-2.out",
+		"metrics.2017-11-20T10-15-30Z.out",
+		"metrics.out",
+	}, dirNames(t, dir))
+	assert.Equal(t, "aaaa\nbbbb\n", readFile(t, filepath.Join(dir, "metrics.2017-11-20T10-15-30Z.out")))
+	assert.Equal(t, "cccc\n", readFile(t, filepath.Join(dir, "metrics.2017-11-20T10-15-30Z-1.out")))
+	assert.Equal(t, "dddddddddddd\n", readFile(t, filepath.Join(dir, "metrics.2017-11-20T10-15-30Z-2.out")))
+	assert.Equal(t, "e\n", readFile(t, path))
+}
+
+func TestRotateByInterval(t *testing.T) {
+	defer func() { now = time.Now }()
+	advance := setNow(t, time.Date(2017, 11, 20, 10, 15, 30, 0, time.UTC))
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+
+	// an existing file is appended to
+	require.NoError(t, ioutil.WriteFile(path, []byte("old\n"), 0644))
+	f, err := NewFile(path, time.Hour, 0, -1, false)
+	require.NoError(t, err)
+	defer f.Close()
+
+	f.Write([]byte("a\n"))
+	advance(59 * time.Minute)
+	f.Write([]byte("b\n"))
+	advance(time.Minute)
+	f.Write([]byte("c\n"))
+
+	assert.Equal(t, []string{"metrics.2017-11-20T11-15-30Z.out", "metrics.out"}, dirNames(t, dir))
+	assert.Equal(t, "old\na\nb\n", readFile(t, filepath.Join(dir, "metrics.2017-11-20T11-15-30Z.out")))
+	assert.Equal(t, "c\n", readFile(t, path))
+}
+
+func TestRotateRemovesArchives(t *testing.T) {
+	defer func() { now = time.Now }()
+	advance := setNow(t, time.Date(2017, 11, 20, 10, 15, 30, 0, time.UTC))
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+
+	// the files of other writers are left alone
+	other := []string{"metrics.log", "metrics.2017-11-20T00-00-00Z.log", "metrics.old.out"}
+	for _, name := range other {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	f, err := NewFile(path, time.Minute, 0, 2, false)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("a\n"))
+		advance(time.Minute)
+	}
+
+	expected := append([]string{
+		"metrics.2017-11-20T10-18-30Z.out",
+		"metrics.2017-11-20T10-19-30Z.out",
+		"metrics.out",
+	}, other...)
+	sort.Strings(expected)
+	assert.Equal(t, expected, dirNames(t, dir))
+}
+
+func TestRotateGzip(t *testing.T) {
+	defer func() { now = time.Now }()
+	advance := setNow(t, time.Date(2017, 11, 20, 10, 15, 30, 0, time.UTC))
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+
+	f, err := NewFile(path, time.Minute, 0, 1, true)
+	require.NoError(t, err)
+
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		f.Write([]byte(line))
+		advance(time.Minute)
+	}
+	// waits for the archives
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"metrics.2017-11-20T10-17-30Z.out.gz", "metrics.out"}, dirNames(t, dir))
+
+	gz, err := os.Open(filepath.Join(dir, "metrics.2017-11-20T10-17-30Z.out.gz"))
+	require.NoError(t, err)
+	defer gz.Close()
+	r, err := gzip.NewReader(gz)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(b))
+}
+
+func TestReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+
+	f, err := NewFile(path, 0, 0, -1, false)
+	require.NoError(t, err)
+	defer f.Close()
+
+	f.Write([]byte("a\n"))
+	// as by logrotate
+	require.NoError(t, os.Rename(path, path+".1"))
+	f.Write([]byte("b\n"))
+	require.NoError(t, f.Reopen())
+	f.Write([]byte("c\n"))
+
+	assert.Equal(t, "a\nb\n", readFile(t, path+".1"))
+	assert.Equal(t, "c\n", readFile(t, path))
+}
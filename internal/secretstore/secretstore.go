@@ -0,0 +1,344 @@
+package secretstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeyEnv is the environment variable holding the key of the keystore,
+	// it takes precedence over the key file
+	KeyEnv = "TELEGRAF_SECRETSTORE_KEY"
+
+	formatVersion = 2
+	saltSize      = 16
+	redacted      = "******"
+)
+
+var (
+	// refRe matches the secret references in the config files, ie,
+	// @secret:vcenter1/password
+	refRe = regexp.MustCompile(`@secret:([\w.-]+)/([\w.-]+)`)
+	// nameRe validates the name and the key of a secret
+	nameRe = regexp.MustCompile(`^[\w.-]+$`)
+
+	redactMu sync.RWMutex
+	// longest first, so a secret containing another one is fully masked
+	secrets []string
+
+	// the scrypt cost of the new stores, the one of a store is saved with it
+	scryptN, scryptR, scryptP = 1 << 15, 8, 1
+)
+
+// Store is a local keystore file holding the secrets of the plugins. The
+// secrets are grouped by name, ie, vcenter1/username and vcenter1/password,
+// and encrypted with AES-256-GCM, the key being derived from the passphrase
+// with scrypt and the salt of the store.
+type Store struct {
+	Path       string
+	passphrase []byte
+	// the key derivation of the store, the salt is nil until it is saved
+	salt    []byte
+	n, r, p int
+	key     []byte
+	secrets map[string]map[string]string
+}
+
+type envelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf,omitempty"`
+	Salt       string `json:"salt,omitempty"`
+	N          int    `json:"n,omitempty"`
+	R          int    `json:"r,omitempty"`
+	P          int    `json:"p,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// LoadKey returns the passphrase of the keystore from
+// $TELEGRAF_SECRETSTORE_KEY, or from keyFile when the variable is not set.
+// Any passphrase is accepted, the AES key is derived from it by Open.
+func LoadKey(keyFile string) ([]byte, error) {
+	material := os.Getenv(KeyEnv)
+	if material == "" {
+		if keyFile == "" {
+			return nil, fmt.Errorf("No secret store key, set $%s or provide a key file", KeyEnv)
+		}
+		b, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the secret store key: %s", err)
+		}
+		material = string(b)
+	}
+	material = strings.TrimSpace(material)
+	if material == "" {
+		return nil, fmt.Errorf("The secret store key is empty")
+	}
+	return []byte(material), nil
+}
+
+// Open loads the keystore at path with the passphrase returned by LoadKey. A
+// missing file is an empty keystore, it is created by Save. The version 1
+// stores, keyed with SHA-256, are saved again with scrypt.
+func Open(path string, passphrase []byte) (*Store, error) {
+	s := &Store{
+		Path:       path,
+		passphrase: passphrase,
+		secrets:    make(map[string]map[string]string),
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var e envelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, fmt.Errorf("Invalid secret store %s: %s", path, err)
+	}
+	var key []byte
+	switch {
+	case e.Version == 1:
+		sum := sha256.Sum256(passphrase)
+		key = sum[:]
+	case e.Version == formatVersion && e.KDF == "scrypt":
+		salt, err := base64.StdEncoding.DecodeString(e.Salt)
+		if err != nil || len(salt) == 0 {
+			return nil, fmt.Errorf("Invalid secret store %s: bad salt", path)
+		}
+		if key, err = scrypt.Key(passphrase, salt, e.N, e.R, e.P, 32); err != nil {
+			return nil, fmt.Errorf("Invalid secret store %s: %s", path, err)
+		}
+		s.salt, s.n, s.r, s.p, s.key = salt, e.N, e.R, e.P, key
+	default:
+		return nil, fmt.Errorf("Unsupported secret store version %d", e.Version)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid secret store %s: %s", path, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid secret store %s: %s", path, err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("Invalid secret store %s: bad nonce", path)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt secret store %s, wrong key?", path)
+	}
+	if err := json.Unmarshal(plaintext, &s.secrets); err != nil {
+		return nil, fmt.Errorf("Invalid secret store %s: %s", path, err)
+	}
+
+	return s, nil
+}
+
+// Save encrypts the keystore and writes it atomically, readable by the
+// owner only.
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	if s.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+		key, err := scrypt.Key(s.passphrase, salt, scryptN, scryptR, scryptP, 32)
+		if err != nil {
+			return err
+		}
+		s.salt, s.n, s.r, s.p, s.key = salt, scryptN, scryptR, scryptP, key
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(envelope{
+		Version:    formatVersion,
+		KDF:        "scrypt",
+		Salt:       base64.StdEncoding.EncodeToString(s.salt),
+		N:          s.n,
+		R:          s.r,
+		P:          s.p,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// Get returns the secret name/key
+func (s *Store) Get(name string, key string) (string, error) {
+	value, ok := s.secrets[name][key]
+	if !ok {
+		return "", fmt.Errorf("Secret %s/%s not found in %s", name, key, s.Path)
+	}
+	return value, nil
+}
+
+// Set adds or replaces the secret name/key
+func (s *Store) Set(name string, key string, value string) error {
+	if !nameRe.MatchString(name) || !nameRe.MatchString(key) {
+		return fmt.Errorf("Invalid secret %s/%s, only letters, digits, '_', '-' and '.' are allowed", name, key)
+	}
+	if s.secrets[name] == nil {
+		s.secrets[name] = make(map[string]string)
+	}
+	s.secrets[name][key] = value
+	return nil
+}
+
+// Delete removes the secret name/key, or all the secrets of name when key
+// is empty
+func (s *Store) Delete(name string, key string) error {
+	if _, ok := s.secrets[name]; !ok {
+		return fmt.Errorf("Secret %s not found in %s", name, s.Path)
+	}
+	if key == "" {
+		delete(s.secrets, name)
+		return nil
+	}
+	if _, ok := s.secrets[name][key]; !ok {
+		return fmt.Errorf("Secret %s/%s not found in %s", name, key, s.Path)
+	}
+	delete(s.secrets[name], key)
+	if len(s.secrets[name]) == 0 {
+		delete(s.secrets, name)
+	}
+	return nil
+}
+
+// List returns the sorted name/key of the secrets, never their values
+func (s *Store) List() []string {
+	var refs []string
+	for name, keys := range s.secrets {
+		for key := range keys {
+			refs = append(refs, name+"/"+key)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// ParseRef splits a name/key reference as given on the command line
+func ParseRef(ref string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(ref, "@secret:"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid secret reference %q, expected name/key", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// HasRefs reports whether value references any secret
+func HasRefs(value string) bool {
+	return refRe.MatchString(value)
+}
+
+// Resolve replaces the @secret:name/key references of a config value with
+// the secrets of s. The resolved secrets are redacted from the logs.
+func Resolve(value string, s *Store) (string, error) {
+	var err error
+	resolved := refRe.ReplaceAllStringFunc(value, func(ref string) string {
+		m := refRe.FindStringSubmatch(ref)
+		secret, e := s.Get(m[1], m[2])
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			return ref
+		}
+		AddRedacted(secret)
+		return secret
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// AddRedacted registers a secret value to be masked by Redact
+func AddRedacted(value string) {
+	if value == "" {
+		return
+	}
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, v := range secrets {
+		if v == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// Redact masks the registered secret values in s
+func Redact(s string) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	for _, value := range secrets {
+		s = strings.Replace(s, value, redacted, -1)
+	}
+	return s
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,204 @@
+package secretstore
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T, passphrase string) (*Store, string) {
+	dir, err := ioutil.TempDir("", "secretstore")
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(dir, "secrets.key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(passphrase), 0600))
+	key, err := LoadKey(keyFile)
+	require.NoError(t, err)
+
+	s, err := Open(filepath.Join(dir, "secrets.db"), key)
+	require.NoError(t, err)
+	return s, dir
+}
+
+func TestSaveAndOpen(t *testing.T) {
+	s, dir := newStore(t, "passphrase")
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, s.Set("vcenter1", "username", "administrator@vsphere.local"))
+	require.NoError(t, s.Set("vcenter1", "password", "p@ss\"word"))
+	require.NoError(t, s.Set("aiservice", "password", "secret"))
+	require.NoError(t, s.Save())
+
+	info, err := os.Stat(s.Path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the values are not stored in clear
+	b, err := ioutil.ReadFile(s.Path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "vsphere.local")
+
+	key, err := LoadKey(filepath.Join(dir, "secrets.key"))
+	require.NoError(t, err)
+	s2, err := Open(s.Path, key)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aiservice/password", "vcenter1/password", "vcenter1/username"}, s2.List())
+
+	value, err := s2.Get("vcenter1", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "p@ss\"word", value)
+
+	require.NoError(t, s2.Delete("vcenter1", "password"))
+	require.NoError(t, s2.Delete("aiservice", ""))
+	assert.Equal(t, []string{"vcenter1/username"}, s2.List())
+	assert.Error(t, s2.Delete("aiservice", "password"))
+}
+
+func TestSaveSalted(t *testing.T) {
+	s, dir := newStore(t, "passphrase")
+	defer os.RemoveAll(dir)
+	require.NoError(t, s.Set("vcenter1", "password", "secret"))
+	require.NoError(t, s.Save())
+
+	e := envelopeOf(t, s.Path)
+	assert.Equal(t, "scrypt", e.KDF)
+	assert.Equal(t, scryptN, e.N)
+
+	// another store with the same passphrase gets another salt
+	s2, dir2 := newStore(t, "passphrase")
+	defer os.RemoveAll(dir2)
+	require.NoError(t, s2.Save())
+	assert.NotEqual(t, e.Salt, envelopeOf(t, s2.Path).Salt)
+}
+
+func TestOpenVersion1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a store keyed with SHA-256
+	key := sha256.Sum256([]byte("passphrase"))
+	gcm, err := newGCM(key[:])
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	b, err := json.Marshal(envelope{
+		Version:    1,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(`{"vcenter1":{"password":"secret"}}`), nil)),
+	})
+	require.NoError(t, err)
+	path := filepath.Join(dir, "secrets.db")
+	require.NoError(t, ioutil.WriteFile(path, b, 0600))
+
+	s, err := Open(path, []byte("passphrase"))
+	require.NoError(t, err)
+	value, err := s.Get("vcenter1", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	// it is saved again with scrypt
+	require.NoError(t, s.Save())
+	s, err = Open(path, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "scrypt", envelopeOf(t, path).KDF)
+	assert.Equal(t, []string{"vcenter1/password"}, s.List())
+}
+
+func envelopeOf(t *testing.T, path string) envelope {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var e envelope
+	require.NoError(t, json.Unmarshal(b, &e))
+	return e
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	s, dir := newStore(t, "passphrase")
+	defer os.RemoveAll(dir)
+	require.NoError(t, s.Set("vcenter1", "password", "secret"))
+	require.NoError(t, s.Save())
+
+	os.Setenv(KeyEnv, "another passphrase")
+	defer os.Unsetenv(KeyEnv)
+	key, err := LoadKey("")
+	require.NoError(t, err)
+
+	_, err = Open(s.Path, key)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong key")
+}
+
+func TestLoadKey(t *testing.T) {
+	_, err := LoadKey("")
+	assert.Error(t, err)
+
+	_, err = LoadKey("/nonexistent/secrets.key")
+	assert.Error(t, err)
+
+	os.Setenv(KeyEnv, "  ")
+	defer os.Unsetenv(KeyEnv)
+	_, err = LoadKey("")
+	assert.Error(t, err)
+}
+
+func TestSetInvalidName(t *testing.T) {
+	s, dir := newStore(t, "passphrase")
+	defer os.RemoveAll(dir)
+
+	assert.Error(t, s.Set("vcenter 1", "password", "secret"))
+	assert.Error(t, s.Set("vcenter1", "pass/word", "secret"))
+}
+
+func TestParseRef(t *testing.T) {
+	name, key, err := ParseRef("vcenter1/password")
+	require.NoError(t, err)
+	assert.Equal(t, "vcenter1", name)
+	assert.Equal(t, "password", key)
+
+	name, key, err = ParseRef("@secret:vcenter1/password")
+	require.NoError(t, err)
+	assert.Equal(t, "vcenter1", name)
+	assert.Equal(t, "password", key)
+
+	_, _, err = ParseRef("vcenter1")
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	s, dir := newStore(t, "passphrase")
+	defer os.RemoveAll(dir)
+	require.NoError(t, s.Set("vcenter1", "username", "root"))
+	require.NoError(t, s.Set("vcenter1", "password", `pa"ss\word`))
+
+	assert.True(t, HasRefs("@secret:vcenter1/password"))
+	resolved, err := Resolve("@secret:vcenter1/password", s)
+	require.NoError(t, err)
+	assert.Equal(t, `pa"ss\word`, resolved)
+
+	resolved, err = Resolve("@secret:vcenter1/username@10.0.0.1", s)
+	require.NoError(t, err)
+	assert.Equal(t, "root@10.0.0.1", resolved)
+
+	_, err = Resolve("@secret:vcenter2/password", s)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vcenter2/password")
+
+	assert.False(t, HasRefs("secret"))
+}
+
+func TestRedact(t *testing.T) {
+	AddRedacted("hunter2")
+	AddRedacted("hunter22")
+	AddRedacted("")
+
+	assert.Equal(t, "login admin:****** failed", Redact("login admin:hunter2 failed"))
+	assert.Equal(t, "login admin:****** failed", Redact("login admin:hunter22 failed"))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
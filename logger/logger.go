@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/influxdata/telegraf/internal/secretstore"
 	"github.com/influxdata/wlog"
 )
 
@@ -25,6 +26,8 @@ type telegrafLog struct {
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
 	var line []byte
+	// never log the secrets resolved from the secret store
+	b = []byte(secretstore.Redact(string(b)))
 	if !prefixRegex.Match(b) {
 		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
 	} else {
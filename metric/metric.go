@@ -447,7 +447,7 @@ func (m *metric) AddTag(key, value string) {
 }
 
 func (m *metric) HasTag(key string) bool {
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
+	i := indexKey(m.tags, escape(key, "tagkey"))
 	if i == -1 {
 		return false
 	}
@@ -457,7 +457,7 @@ func (m *metric) HasTag(key string) bool {
 func (m *metric) RemoveTag(key string) {
 	m.hashID = 0
 
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
+	i := indexKey(m.tags, escape(key, "tagkey"))
 	if i == -1 {
 		return
 	}
@@ -471,13 +471,19 @@ func (m *metric) RemoveTag(key string) {
 	return
 }
 
+// AddField adds the field key, replacing the field of the same key
 func (m *metric) AddField(key string, value interface{}) {
-	m.fields = append(m.fields, ',')
+	if i := indexKey(m.fields, escape(key, "tagkey")); i != -1 {
+		m.fields = removeField(m.fields, i)
+	}
+	if len(m.fields) > 0 {
+		m.fields = append(m.fields, ',')
+	}
 	m.fields = appendField(m.fields, key, value)
 }
 
 func (m *metric) HasField(key string) bool {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+	i := indexKey(m.fields, escape(key, "tagkey"))
 	if i == -1 {
 		return false
 	}
@@ -485,26 +491,54 @@ func (m *metric) HasField(key string) bool {
 }
 
 func (m *metric) RemoveField(key string) error {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+	i := indexKey(m.fields, escape(key, "tagkey"))
 	if i == -1 {
 		return nil
 	}
 
+	tmp := removeField(m.fields, i)
+	if len(tmp) == 0 {
+		return fmt.Errorf("Metric cannot remove final field: %s", m.fields)
+	}
+
+	m.fields = tmp
+	return nil
+}
+
+// removeField returns fields without the field starting at i
+func removeField(fields []byte, i int) []byte {
 	var tmp []byte
 	if i != 0 {
-		tmp = m.fields[0 : i-1]
+		tmp = append(tmp, fields[0:i-1]...)
 	}
-	j := indexUnescapedByte(m.fields[i:], ',')
+	j := indexUnescapedByte(fields[i:], ',')
 	if j != -1 {
-		tmp = append(tmp, m.fields[i+j:]...)
+		if i == 0 {
+			// the next field is the first one now
+			j++
+		}
+		tmp = append(tmp, fields[i+j:]...)
 	}
+	return tmp
+}
 
-	if len(tmp) == 0 {
-		return fmt.Errorf("Metric cannot remove final field: %s", m.fields)
+// indexKey returns the index of the escaped key in the tags or the fields of
+// a metric, or -1. The key only matches a whole key, eg, "host=" is not
+// found in ",agenthost=a".
+func indexKey(buf []byte, key string) int {
+	k := []byte(key + "=")
+	offset := 0
+	for {
+		i := bytes.Index(buf[offset:], k)
+		if i == -1 {
+			return -1
+		}
+		i += offset
+		if i == 0 || (buf[i-1] == ',' && (i < 2 || buf[i-2] != '\\')) {
+			return i
+		}
+		offset = i + 1
 	}
-
-	m.fields = tmp
-	return nil
 }
 
 func (m *metric) Copy() telegraf.Metric {
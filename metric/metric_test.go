@@ -102,6 +102,27 @@ func TestNewMetric_Tags(t *testing.T) {
 	assert.Equal(t, "cpu value=1 "+fmt.Sprint(now.UnixNano())+"\n", m.String())
 }
 
+func TestNewMetric_TagSuffix(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"agenthost_domain_id": "agent",
+		"ds_name":             "datastore1",
+	}
+	m, err := New("cpu", tags, map[string]interface{}{"value": float64(1)}, now)
+	assert.NoError(t, err)
+
+	// keys ending the same as other keys are distinct
+	assert.False(t, m.HasTag("host_domain_id"))
+	assert.False(t, m.HasTag("name"))
+	m.RemoveTag("name")
+	m.AddTag("host_domain_id", "host")
+	assert.Equal(t, map[string]string{
+		"agenthost_domain_id": "agent",
+		"ds_name":             "datastore1",
+		"host_domain_id":      "host",
+	}, m.Tags())
+}
+
 func TestSerialize(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
@@ -242,6 +263,18 @@ func TestNewMetric_FieldModifiers(t *testing.T) {
 	m.AddField("value2", int64(101))
 	assert.NoError(t, m.RemoveField("value"))
 	assert.False(t, m.HasField("value"))
+	assert.Equal(t, map[string]interface{}{"value2": int64(101)}, m.Fields())
+
+	// the field of the same key is replaced
+	m.AddField("value2", "replaced")
+	m.AddField("total_value2", int64(1))
+	assert.Equal(t, map[string]interface{}{
+		"value2":       "replaced",
+		"total_value2": int64(1),
+	}, m.Fields())
+	assert.NoError(t, m.RemoveField("value2"))
+	assert.Equal(t, map[string]interface{}{"total_value2": int64(1)}, m.Fields())
+	assert.Equal(t, "cpu,host=localhost total_value2=1i "+fmt.Sprint(now.UnixNano())+"\n", m.String())
 }
 
 func TestNewMetric_Fields(t *testing.T) {
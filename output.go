@@ -29,3 +29,15 @@ type ServiceOutput interface {
 	// Stop the "service" that will provide an Output
 	Stop()
 }
+
+// PartialWriteError is returned by the outputs writing a batch in several
+// requests when a request failed after some succeeded. Only the Unsent
+// metrics are written again, so the sent ones are not duplicated.
+type PartialWriteError struct {
+	Err    error
+	Unsent []Metric
+}
+
+func (e *PartialWriteError) Error() string {
+	return e.Err.Error()
+}
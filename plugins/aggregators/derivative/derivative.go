@@ -0,0 +1,283 @@
+package derivative
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// Derivative computes the rate and delta of counter fields, keeping the last
+// sample of each series across periods
+type Derivative struct {
+	Fields      []string
+	CounterBits int
+	MaxAge      internal.Duration
+	OmitLast    bool
+
+	fieldFilter filter.Filter
+	compileErr  error
+	compiled    bool
+
+	series map[uint64]*series
+	// newest sample time seen, the series older than MaxAge are evicted
+	newest time.Time
+}
+
+type series struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*counter
+	last   time.Time
+}
+
+type counter struct {
+	// last sample of the counter, the integer counters are derived in
+	// integers so that no increase is lost to the float precision
+	value   float64
+	ivalue  uint64
+	integer bool
+	raw     interface{}
+	time    time.Time
+
+	// increase of the counter in the current period, since start
+	active bool
+	start  time.Time
+	delta  float64
+	idelta uint64
+}
+
+func NewDerivative() *Derivative {
+	d := &Derivative{
+		MaxAge: internal.Duration{Duration: time.Hour},
+		series: make(map[uint64]*series),
+	}
+	d.Reset()
+	return d
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Counter fields to derive, globs are supported. All the numeric fields
+  ## are derived when it is empty.
+  fields = ["*_bytes", "reads", "writes"]
+
+  ## Width of the counters in bits, 32 or 64. A counter going backwards is
+  ## taken as wrapped around when it is set, as reset to zero otherwise.
+  # counter_bits = 0
+
+  ## The last sample of a series older than max_age is not used for the
+  ## rates, and the series is forgotten.
+  # max_age = "1h"
+
+  ## If true, the last value of the raw counters is not added to the
+  ## aggregate, as <field>_last, along with the rates and deltas. The raw
+  ## counters of the original metrics are only dropped by drop_original.
+  # omit_last = false
+`
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Compute the per-second rate and delta of counter fields."
+}
+
+// Validate checks the field globs and the counter width
+func (d *Derivative) Validate() []error {
+	var errs []error
+	if err := d.compile(); err != nil {
+		errs = append(errs, err)
+	}
+	if d.CounterBits != 0 && d.CounterBits != 32 && d.CounterBits != 64 {
+		errs = append(errs, fmt.Errorf("Invalid counter_bits %d, must be 32 or 64", d.CounterBits))
+	}
+	return errs
+}
+
+func (d *Derivative) compile() error {
+	if !d.compiled {
+		d.compiled = true
+		d.fieldFilter, d.compileErr = filter.Compile(d.Fields)
+		if d.compileErr != nil {
+			d.compileErr = fmt.Errorf("Invalid fields: %s", d.compileErr)
+			log.Printf("E! %s, the derivative aggregator is disabled\n", d.compileErr)
+		}
+	}
+	return d.compileErr
+}
+
+func (d *Derivative) Add(in telegraf.Metric) {
+	if d.compile() != nil {
+		return
+	}
+
+	t := in.Time()
+	if t.After(d.newest) {
+		d.newest = t
+	}
+
+	id := in.HashID()
+	s, ok := d.series[id]
+	if !ok {
+		s = &series{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*counter),
+		}
+		d.series[id] = s
+	}
+	if t.After(s.last) {
+		s.last = t
+	}
+
+	for k, v := range in.Fields() {
+		if d.fieldFilter != nil && !d.fieldFilter.Match(k) {
+			continue
+		}
+		fv, iv, integer, ok := convert(v)
+		if !ok {
+			continue
+		}
+
+		c, ok := s.fields[k]
+		if !ok || d.expired(c.time, t) || c.integer != integer {
+			s.fields[k] = &counter{value: fv, ivalue: iv, integer: integer, raw: v, time: t}
+			continue
+		}
+		if !t.After(c.time) {
+			// duplicate or out of order sample
+			continue
+		}
+
+		if !c.active {
+			c.active = true
+			c.start = c.time
+			c.delta, c.idelta = 0, 0
+		}
+		if integer {
+			c.idelta += d.increaseInt(c.ivalue, iv)
+		} else {
+			c.delta += d.increase(c.value, fv)
+		}
+		c.value, c.ivalue, c.raw, c.time = fv, iv, v, t
+	}
+}
+
+// increase returns the increase of a counter from prev to cur, taking a
+// decrease as a wraparound or a reset
+func (d *Derivative) increase(prev, cur float64) float64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	if d.CounterBits > 0 {
+		wrap := math.Pow(2, float64(d.CounterBits))
+		// a wrap larger than half the range is more likely a reset
+		if prev < wrap && wrap-prev+cur < wrap/2 {
+			return wrap - prev + cur
+		}
+	}
+	// the counter restarted from zero
+	return cur
+}
+
+// increaseInt is increase for the integer counters
+func (d *Derivative) increaseInt(prev, cur uint64) uint64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	switch d.CounterBits {
+	case 32:
+		const wrap = uint64(1) << 32
+		if prev < wrap && wrap-prev+cur < wrap/2 {
+			return wrap - prev + cur
+		}
+	case 64:
+		// the difference wraps around 2^64
+		if cur-prev < 1<<63 {
+			return cur - prev
+		}
+	}
+	return cur
+}
+
+func (d *Derivative) expired(last, t time.Time) bool {
+	return d.MaxAge.Duration > 0 && t.Sub(last) > d.MaxAge.Duration
+}
+
+func (d *Derivative) Push(acc telegraf.Accumulator) {
+	for id, s := range d.series {
+		if d.expired(s.last, d.newest) {
+			delete(d.series, id)
+			continue
+		}
+
+		// the aggregate is timestamped with the push time, and the raw
+		// counters renamed, not to overwrite the points of the samples
+		fields := make(map[string]interface{})
+		for k, c := range s.fields {
+			if !c.active {
+				continue
+			}
+			elapsed := c.time.Sub(c.start).Seconds()
+			if c.integer {
+				fields[k+"_rate"] = float64(c.idelta) / elapsed
+				fields[k+"_delta"] = int64(c.idelta)
+			} else {
+				fields[k+"_rate"] = c.delta / elapsed
+				fields[k+"_delta"] = c.delta
+			}
+			if !d.OmitLast {
+				fields[k+"_last"] = c.raw
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(s.name, fields, s.tags)
+		}
+	}
+}
+
+// Reset starts a new period, the last sample of each counter is kept
+func (d *Derivative) Reset() {
+	for _, s := range d.series {
+		for _, c := range s.fields {
+			c.active = false
+		}
+	}
+}
+
+// convert returns the value of a counter field, as an integer too when it
+// is a non negative integer
+func convert(in interface{}) (float64, uint64, bool, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, 0, false, true
+	case int64:
+		if v < 0 {
+			return float64(v), 0, false, true
+		}
+		return float64(v), uint64(v), true, true
+	case uint64:
+		return float64(v), v, true, true
+	default:
+		return 0, 0, false, false
+	}
+}
+
+func init() {
+	aggregators.Add("derivative", func() telegraf.Aggregator {
+		return NewDerivative()
+	})
+}
@@ -0,0 +1,220 @@
+package derivative
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1508400000, 0)
+
+func newMetric(t *testing.T, offset time.Duration, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New("diskio",
+		map[string]string{"name": "sda"},
+		fields,
+		start.Add(offset),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func TestDerivative(t *testing.T) {
+	d := NewDerivative()
+	d.Fields = []string{"*_bytes"}
+
+	d.Add(newMetric(t, 0, map[string]interface{}{
+		"read_bytes":  int64(1000),
+		"write_bytes": int64(0),
+		"io_time":     int64(5),
+	}))
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{
+		"read_bytes":  int64(3000),
+		"write_bytes": int64(500),
+		"io_time":     int64(10),
+	}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	d.Reset()
+
+	// the raw counters are renamed and the aggregate timestamped with the
+	// push time, not to overwrite the last sample
+	acc.AssertContainsTaggedFields(t, "diskio", map[string]interface{}{
+		"read_bytes_last":   int64(3000),
+		"read_bytes_rate":   float64(200),
+		"read_bytes_delta":  int64(2000),
+		"write_bytes_last":  int64(500),
+		"write_bytes_rate":  float64(50),
+		"write_bytes_delta": int64(500),
+	}, map[string]string{"name": "sda"})
+	assert.True(t, acc.Metrics[0].Time.After(start.Add(10*time.Second)))
+}
+
+func TestDerivativeIntegers(t *testing.T) {
+	d := NewDerivative()
+	d.OmitLast = true
+
+	// the increase is exact beyond the float precision
+	d.Add(newMetric(t, 0, map[string]interface{}{
+		"read_bytes": int64(1<<62 + 1),
+		"io_time":    float64(1.5),
+	}))
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{
+		"read_bytes": int64(1<<62 + 11),
+		"io_time":    float64(3.5),
+	}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	d.Reset()
+
+	acc.AssertContainsTaggedFields(t, "diskio", map[string]interface{}{
+		"read_bytes_rate":  float64(1),
+		"read_bytes_delta": int64(10),
+		"io_time_rate":     float64(0.2),
+		"io_time_delta":    float64(2),
+	}, map[string]string{"name": "sda"})
+}
+
+func TestDerivativeAcrossPeriods(t *testing.T) {
+	d := NewDerivative()
+	d.OmitLast = true
+
+	d.Add(newMetric(t, 0, map[string]interface{}{"reads": int64(10)}))
+
+	// a single sample has no rate
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	d.Reset()
+	assert.Equal(t, 0, len(acc.Metrics))
+
+	// the next period continues from the last sample of the previous one
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{"reads": int64(30)}))
+	d.Add(newMetric(t, 20*time.Second, map[string]interface{}{"reads": int64(70)}))
+	d.Push(&acc)
+	d.Reset()
+
+	acc.AssertContainsTaggedFields(t, "diskio", map[string]interface{}{
+		"reads_rate":  float64(3),
+		"reads_delta": int64(60),
+	}, map[string]string{"name": "sda"})
+
+	// no sample in the period, nothing is emitted
+	acc.ClearMetrics()
+	d.Push(&acc)
+	d.Reset()
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestDerivativeReset(t *testing.T) {
+	d := NewDerivative()
+	d.OmitLast = true
+
+	d.Add(newMetric(t, 0, map[string]interface{}{"reads": int64(1000)}))
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{"reads": int64(1100)}))
+	// the host rebooted, the counter restarted from zero
+	d.Add(newMetric(t, 20*time.Second, map[string]interface{}{"reads": int64(50)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "diskio", map[string]interface{}{
+		"reads_rate":  float64(7.5),
+		"reads_delta": int64(150),
+	}, map[string]string{"name": "sda"})
+}
+
+func TestDerivativeWraparound(t *testing.T) {
+	d := NewDerivative()
+	d.OmitLast = true
+	d.CounterBits = 32
+
+	max := int64(math.MaxUint32)
+	d.Add(newMetric(t, 0, map[string]interface{}{"bytes_recv": max - 99}))
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{"bytes_recv": int64(900)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	d.Reset()
+
+	acc.AssertContainsTaggedFields(t, "diskio", map[string]interface{}{
+		"bytes_recv_rate":  float64(100),
+		"bytes_recv_delta": int64(1000),
+	}, map[string]string{"name": "sda"})
+
+	// a drop to a small value far from the top of the range is a reset
+	acc.ClearMetrics()
+	d.Add(newMetric(t, 20*time.Second, map[string]interface{}{"bytes_recv": int64(1000000)}))
+	d.Add(newMetric(t, 30*time.Second, map[string]interface{}{"bytes_recv": int64(10)}))
+	d.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "diskio", map[string]interface{}{
+		"bytes_recv_rate":  float64(49955.5),
+		"bytes_recv_delta": int64(999110),
+	}, map[string]string{"name": "sda"})
+}
+
+func TestDerivativeMaxAge(t *testing.T) {
+	d := NewDerivative()
+	d.OmitLast = true
+	d.MaxAge.Duration = time.Minute
+
+	d.Add(newMetric(t, 0, map[string]interface{}{"reads": int64(10)}))
+	// the previous sample is too old to compute a rate from
+	d.Add(newMetric(t, 2*time.Minute, map[string]interface{}{"reads": int64(30)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	d.Reset()
+	assert.Equal(t, 0, len(acc.Metrics))
+
+	d.Add(newMetric(t, 2*time.Minute+10*time.Second, map[string]interface{}{"reads": int64(50)}))
+	d.Push(&acc)
+	d.Reset()
+	acc.AssertContainsFields(t, "diskio", map[string]interface{}{
+		"reads_rate":  float64(2),
+		"reads_delta": int64(20),
+	})
+
+	// the series not updated for max_age are forgotten
+	other, err := metric.New("diskio",
+		map[string]string{"name": "sdb"},
+		map[string]interface{}{"reads": int64(1)},
+		start.Add(10*time.Minute),
+	)
+	require.NoError(t, err)
+	d.Add(other)
+	d.Push(&acc)
+	assert.Equal(t, 1, len(d.series))
+}
+
+func TestDerivativeIgnoresOutOfOrder(t *testing.T) {
+	d := NewDerivative()
+	d.OmitLast = true
+
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{"reads": int64(10), "state": "ok"}))
+	d.Add(newMetric(t, 0, map[string]interface{}{"reads": int64(5)}))
+	d.Add(newMetric(t, 10*time.Second, map[string]interface{}{"reads": int64(10)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestDerivativeValidate(t *testing.T) {
+	d := NewDerivative()
+	d.Fields = []string{"["}
+	d.CounterBits = 16
+	assert.Len(t, d.Validate(), 2)
+
+	d = NewDerivative()
+	d.Fields = []string{"reads"}
+	d.CounterBits = 64
+	assert.Empty(t, d.Validate())
+}
@@ -0,0 +1,266 @@
+package diskworkload
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	smartMeasurement  = "sai_disk_smart"
+	diskioMeasurement = "diskio"
+)
+
+var (
+	getWWN = disk.GetWWNByKernelName
+	now    = time.Now
+
+	// wwnTTL is how long the WWN of a device, or its absence, is cached. The
+	// WWN of a name changes when a disk is swapped.
+	wwnTTL = 10 * time.Minute
+
+	// SAS disks report the current temperature, SATA disks attribute 194
+	// or, on some vendors, 190
+	temperatureFields = []string{"CurrentDriveTemperature_raw", "194_raw", "190_raw"}
+)
+
+// DiskWorkload joins the temperature of sai_disk_smart, keyed by WWN, with
+// the diskio counters, keyed by kernel device name
+type DiskWorkload struct {
+	// latest smart data of each disk WWN, kept across periods since smartctl
+	// is usually gathered less often than diskio
+	smart map[string]*smartSample
+	// last diskio sample of each device, kept across periods to compute rates
+	last map[string]*ioSample
+	// first and last diskio sample of each device in the current period
+	cache map[string]*ioWindow
+	// WWN of each device, the lookups read sysfs
+	wwns map[string]*wwnEntry
+}
+
+type wwnEntry struct {
+	wwn     string
+	err     error
+	expires time.Time
+}
+
+type smartSample struct {
+	temperature int64
+	tags        map[string]string
+	fields      map[string]interface{}
+}
+
+type ioSample struct {
+	time       time.Time
+	reads      float64
+	writes     float64
+	readBytes  float64
+	writeBytes float64
+	ioTime     float64
+}
+
+type ioWindow struct {
+	first *ioSample
+	last  *ioSample
+}
+
+func NewDiskWorkload() telegraf.Aggregator {
+	dw := &DiskWorkload{
+		smart: make(map[string]*smartSample),
+		last:  make(map[string]*ioSample),
+		wwns:  make(map[string]*wwnEntry),
+	}
+	dw.Reset()
+	return dw
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "60s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Only smart and diskio metrics are joined
+  namepass = ["sai_disk_smart", "diskio"]
+`
+
+func (d *DiskWorkload) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DiskWorkload) Description() string {
+	return "Join disk temperature from sai_disk_smart with diskio workload by disk WWN."
+}
+
+func (d *DiskWorkload) Add(in telegraf.Metric) {
+	switch in.Name() {
+	case smartMeasurement:
+		d.addSmart(in)
+	case diskioMeasurement:
+		d.addDiskio(in)
+	}
+}
+
+func (d *DiskWorkload) addSmart(in telegraf.Metric) {
+	tags := in.Tags()
+	fields := in.Fields()
+	wwn := tags["disk_wwn"]
+	if wwn == "" {
+		return
+	}
+	for _, f := range temperatureFields {
+		t, ok := convert(fields[f])
+		if !ok {
+			continue
+		}
+		s := &smartSample{
+			temperature: int64(t),
+			tags:        make(map[string]string),
+			fields:      make(map[string]interface{}),
+		}
+		for _, k := range []string{"disk_domain_id", "primary_key"} {
+			if v, ok := tags[k]; ok {
+				s.tags[k] = v
+			}
+		}
+		for _, k := range []string{"cluster_domain_id", "host_domain_id"} {
+			if v, ok := fields[k]; ok {
+				s.fields[k] = v
+			}
+		}
+		d.smart[wwn] = s
+		return
+	}
+}
+
+func (d *DiskWorkload) addDiskio(in telegraf.Metric) {
+	name := in.Tags()["name"]
+	if name == "" {
+		return
+	}
+
+	fields := in.Fields()
+	s := &ioSample{time: in.Time()}
+	for k, target := range map[string]*float64{
+		"reads":       &s.reads,
+		"writes":      &s.writes,
+		"read_bytes":  &s.readBytes,
+		"write_bytes": &s.writeBytes,
+		"io_time":     &s.ioTime,
+	} {
+		v, ok := convert(fields[k])
+		if !ok {
+			return
+		}
+		*target = v
+	}
+
+	w, ok := d.cache[name]
+	if !ok {
+		// continue from the last sample of the previous period
+		w = &ioWindow{first: s}
+		if prev, ok := d.last[name]; ok {
+			w.first = prev
+		}
+		d.cache[name] = w
+	}
+	w.last = s
+	d.last[name] = s
+}
+
+func (d *DiskWorkload) Push(acc telegraf.Accumulator) {
+	for name, w := range d.cache {
+		elapsed := w.last.time.Sub(w.first.time).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+		reads := w.last.reads - w.first.reads
+		writes := w.last.writes - w.first.writes
+		readBytes := w.last.readBytes - w.first.readBytes
+		writeBytes := w.last.writeBytes - w.first.writeBytes
+		ioTime := w.last.ioTime - w.first.ioTime
+		if reads < 0 || writes < 0 || readBytes < 0 || writeBytes < 0 || ioTime < 0 {
+			// counters were reset, e.g. the host rebooted
+			continue
+		}
+
+		wwn, err := d.wwn(name)
+		if err != nil {
+			// partitions and virtual devices have no WWN
+			continue
+		}
+
+		// io_time is in milliseconds
+		utilization := ioTime / (elapsed * 1000) * 100
+		if utilization > 100 {
+			utilization = 100
+		}
+
+		tags := map[string]string{
+			"disk_name": name,
+			"disk_wwn":  wwn,
+		}
+		fields := map[string]interface{}{
+			"read_iops":       reads / elapsed,
+			"write_iops":      writes / elapsed,
+			"iops":            (reads + writes) / elapsed,
+			"read_bytes_sec":  readBytes / elapsed,
+			"write_bytes_sec": writeBytes / elapsed,
+			"throughput":      (readBytes + writeBytes) / elapsed,
+			"utilization":     utilization,
+		}
+		if s, ok := d.smart[wwn]; ok {
+			fields["temperature"] = s.temperature
+			for k, v := range s.tags {
+				tags[k] = v
+			}
+			for k, v := range s.fields {
+				fields[k] = v
+			}
+		} else {
+			tags["disk_domain_id"] = wwn
+			log.Printf("D! [aggregators.diskworkload] No temperature of %s (%s) yet", name, wwn)
+		}
+		acc.AddFields("sai_disk_workload", fields, tags, w.last.time)
+	}
+}
+
+// wwn returns the WWN of a device, cached for wwnTTL
+func (d *DiskWorkload) wwn(name string) (string, error) {
+	t := now()
+	e, ok := d.wwns[name]
+	if !ok || !t.Before(e.expires) {
+		e = &wwnEntry{expires: t.Add(wwnTTL)}
+		e.wwn, e.err = getWWN(name)
+		d.wwns[name] = e
+	}
+	return e.wwn, e.err
+}
+
+func (d *DiskWorkload) Reset() {
+	d.cache = make(map[string]*ioWindow)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("diskworkload", func() telegraf.Aggregator {
+		return NewDiskWorkload()
+	})
+}
@@ -0,0 +1,218 @@
+package diskworkload
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func fakeGetWWN(name string) (string, error) {
+	switch name {
+	case "sda":
+		return "5000c5005f50e6ab", nil
+	case "sdb":
+		return "50014ee60711e39c", nil
+	}
+	return "", fmt.Errorf("Cannot find WWN of %s", name)
+}
+
+func smartMetric(wwn string, field string, temperature int64) telegraf.Metric {
+	m, _ := metric.New("sai_disk_smart",
+		map[string]string{
+			"disk_name":      "sdx",
+			"disk_wwn":       wwn,
+			"disk_domain_id": wwn,
+			"primary_key":    "cluster-host-" + wwn,
+		},
+		map[string]interface{}{
+			"cluster_domain_id": "cluster",
+			"host_domain_id":    "host",
+			field:               temperature,
+		},
+		start,
+	)
+	return m
+}
+
+func diskioMetric(name string, offset time.Duration, reads, writes, readBytes, writeBytes, ioTime uint64) telegraf.Metric {
+	m, _ := metric.New("diskio",
+		map[string]string{"name": name},
+		map[string]interface{}{
+			"reads":       reads,
+			"writes":      writes,
+			"read_bytes":  readBytes,
+			"write_bytes": writeBytes,
+			"io_time":     ioTime,
+		},
+		start.Add(offset),
+	)
+	return m
+}
+
+func TestDiskWorkload(t *testing.T) {
+	getWWN = fakeGetWWN
+	acc := testutil.Accumulator{}
+	dw := NewDiskWorkload()
+
+	dw.Add(smartMetric("5000c5005f50e6ab", "CurrentDriveTemperature_raw", 38))
+	dw.Add(smartMetric("50014ee60711e39c", "194_raw", 41))
+	dw.Add(diskioMetric("sda", 0, 100, 200, 4096, 8192, 1000))
+	dw.Add(diskioMetric("sda", 10*time.Second, 600, 700, 4096+1024000, 8192+2048000, 6000))
+	dw.Add(diskioMetric("sdb", 0, 0, 0, 0, 0, 0))
+	dw.Add(diskioMetric("sdb", 10*time.Second, 10, 0, 40960, 0, 20000))
+	// partitions have no WWN
+	dw.Add(diskioMetric("sdb1", 0, 0, 0, 0, 0, 0))
+	dw.Add(diskioMetric("sdb1", 10*time.Second, 10, 0, 40960, 0, 200))
+	dw.Push(&acc)
+
+	assert.Equal(t, 2, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "sai_disk_workload",
+		map[string]interface{}{
+			"read_iops":         float64(50),
+			"write_iops":        float64(50),
+			"iops":              float64(100),
+			"read_bytes_sec":    float64(102400),
+			"write_bytes_sec":   float64(204800),
+			"throughput":        float64(307200),
+			"utilization":       float64(50),
+			"temperature":       int64(38),
+			"cluster_domain_id": "cluster",
+			"host_domain_id":    "host",
+		},
+		map[string]string{
+			"disk_name":      "sda",
+			"disk_wwn":       "5000c5005f50e6ab",
+			"disk_domain_id": "5000c5005f50e6ab",
+			"primary_key":    "cluster-host-5000c5005f50e6ab",
+		})
+	acc.AssertContainsTaggedFields(t, "sai_disk_workload",
+		map[string]interface{}{
+			"read_iops":         float64(1),
+			"write_iops":        float64(0),
+			"iops":              float64(1),
+			"read_bytes_sec":    float64(4096),
+			"write_bytes_sec":   float64(0),
+			"throughput":        float64(4096),
+			"utilization":       float64(100),
+			"temperature":       int64(41),
+			"cluster_domain_id": "cluster",
+			"host_domain_id":    "host",
+		},
+		map[string]string{
+			"disk_name":      "sdb",
+			"disk_wwn":       "50014ee60711e39c",
+			"disk_domain_id": "50014ee60711e39c",
+			"primary_key":    "cluster-host-50014ee60711e39c",
+		})
+}
+
+// Test that rates continue from the last sample of the previous period and
+// the temperature is kept across periods.
+func TestDiskWorkloadAcrossPeriods(t *testing.T) {
+	getWWN = fakeGetWWN
+	acc := testutil.Accumulator{}
+	dw := NewDiskWorkload()
+
+	dw.Add(smartMetric("5000c5005f50e6ab", "CurrentDriveTemperature_raw", 38))
+	dw.Add(diskioMetric("sda", 0, 0, 0, 0, 0, 0))
+	dw.Push(&acc)
+	dw.Reset()
+	// a single sample in the first period gives no rate
+	assert.Equal(t, 0, len(acc.Metrics))
+
+	dw.Add(diskioMetric("sda", 20*time.Second, 200, 0, 0, 0, 2000))
+	dw.Push(&acc)
+	dw.Reset()
+
+	acc.AssertContainsFields(t, "sai_disk_workload",
+		map[string]interface{}{
+			"read_iops":         float64(10),
+			"write_iops":        float64(0),
+			"iops":              float64(10),
+			"read_bytes_sec":    float64(0),
+			"write_bytes_sec":   float64(0),
+			"throughput":        float64(0),
+			"utilization":       float64(10),
+			"temperature":       int64(38),
+			"cluster_domain_id": "cluster",
+			"host_domain_id":    "host",
+		})
+}
+
+// Test that a counter reset skips one period.
+func TestDiskWorkloadCounterReset(t *testing.T) {
+	getWWN = fakeGetWWN
+	acc := testutil.Accumulator{}
+	dw := NewDiskWorkload()
+
+	dw.Add(diskioMetric("sda", 0, 1000, 1000, 0, 0, 1000))
+	dw.Add(diskioMetric("sda", 10*time.Second, 10, 10, 0, 0, 10))
+	dw.Push(&acc)
+	dw.Reset()
+	assert.Equal(t, 0, len(acc.Metrics))
+
+	dw.Add(diskioMetric("sda", 20*time.Second, 110, 10, 0, 0, 1010))
+	dw.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "sai_disk_workload",
+		map[string]interface{}{
+			"read_iops":       float64(10),
+			"write_iops":      float64(0),
+			"iops":            float64(10),
+			"read_bytes_sec":  float64(0),
+			"write_bytes_sec": float64(0),
+			"throughput":      float64(0),
+			"utilization":     float64(10),
+		},
+		map[string]string{
+			"disk_name":      "sda",
+			"disk_wwn":       "5000c5005f50e6ab",
+			"disk_domain_id": "5000c5005f50e6ab",
+		})
+}
+
+// Test that the WWN of the devices are looked up once per wwnTTL.
+func TestDiskWorkloadWWNCache(t *testing.T) {
+	current := start
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	lookups := map[string]int{}
+	wwn := "5000c5005f50e6ab"
+	getWWN = func(name string) (string, error) {
+		lookups[name]++
+		if name == "sda" {
+			return wwn, nil
+		}
+		return "", fmt.Errorf("Cannot find WWN of %s", name)
+	}
+	defer func() { getWWN = fakeGetWWN }()
+
+	acc := testutil.Accumulator{}
+	dw := NewDiskWorkload()
+	for i := 0; i < 3; i++ {
+		offset := time.Duration(i) * 10 * time.Second
+		dw.Add(diskioMetric("sda", offset, 0, 0, 0, 0, 0))
+		dw.Add(diskioMetric("sda1", offset, 0, 0, 0, 0, 0))
+		dw.Push(&acc)
+		dw.Reset()
+	}
+	// the absence of a WWN is cached too
+	assert.Equal(t, map[string]int{"sda": 1, "sda1": 1}, lookups)
+
+	// the disk was swapped
+	wwn = "50014ee60711e39c"
+	current = current.Add(wwnTTL)
+	acc.ClearMetrics()
+	dw.Add(diskioMetric("sda", 30*time.Second, 0, 0, 0, 0, 0))
+	dw.Push(&acc)
+	assert.Equal(t, 2, lookups["sda"])
+	assert.Equal(t, "50014ee60711e39c", acc.TagValue("sai_disk_workload", "disk_wwn"))
+}
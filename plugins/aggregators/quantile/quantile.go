@@ -0,0 +1,158 @@
+package quantile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// Quantile estimates the quantiles of the fields of each series over the
+// period with a t-digest
+type Quantile struct {
+	Quantiles   []float64
+	Compression internal.Number
+
+	cache map[uint64]aggregate
+}
+
+func NewQuantile() *Quantile {
+	q := &Quantile{
+		Quantiles:   []float64{0.5, 0.95, 0.99},
+		Compression: internal.Number{Value: 100},
+	}
+	q.Reset()
+	return q
+}
+
+type aggregate struct {
+	fields map[string]*tdigest
+	name   string
+	tags   map[string]string
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to compute, between 0 and 1. Each one adds a field suffixed
+  ## by its percentile, e.g. response_time_p99 for 0.99.
+  quantiles = [0.5, 0.95, 0.99]
+
+  ## Compression of the t-digest, higher values are more accurate and use
+  ## more memory. The memory per field grows with it, not with the values.
+  compression = 100
+`
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+// Validate checks the quantiles and the compression
+func (q *Quantile) Validate() []error {
+	var errs []error
+	for _, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			errs = append(errs, fmt.Errorf("Invalid quantile %v, must be between 0 and 1", quantile))
+		}
+	}
+	if q.Compression.Value < 1 {
+		errs = append(errs, fmt.Errorf("Invalid compression %v, must be at least 1", q.Compression.Value))
+	}
+	return errs
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*tdigest),
+		}
+		q.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		digest, ok := a.fields[k]
+		if !ok {
+			digest = newTDigest(q.compression())
+			a.fields[k] = digest
+		}
+		digest.add(fv)
+	}
+}
+
+func (q *Quantile) compression() float64 {
+	if q.Compression.Value < 1 {
+		return 100
+	}
+	return q.Compression.Value
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	for _, aggregate := range q.cache {
+		fields := map[string]interface{}{}
+		for k, digest := range aggregate.fields {
+			// no quantile of an empty digest
+			if digest.count == 0 {
+				continue
+			}
+			for _, quantile := range q.Quantiles {
+				if quantile < 0 || quantile > 1 {
+					continue
+				}
+				fields[k+"_"+suffix(quantile)] = digest.quantile(quantile)
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(aggregate.name, fields, aggregate.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+// suffix returns the field suffix of a quantile, p99 for 0.99 and p99_9 for
+// 0.999
+func suffix(quantile float64) string {
+	// formatted as a float32 to drop the rounding error of the product
+	p := strconv.FormatFloat(quantile*100, 'f', -1, 32)
+	return "p" + strings.Replace(p, ".", "_", -1)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
@@ -0,0 +1,116 @@
+package quantile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantileWithPeriod(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{0, 0.5, 0.999, 1}
+
+	for i := 1; i <= 101; i++ {
+		m, err := metric.New("http_response",
+			map[string]string{"server": "http://localhost"},
+			map[string]interface{}{
+				"response_time": float64(i) / 1000,
+				"http_code":     int64(200),
+				"result":        "success",
+			},
+			time.Now(),
+		)
+		require.NoError(t, err)
+		q.Add(m)
+	}
+	q.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"response_time_p0":    0.001,
+		"response_time_p50":   0.051,
+		"response_time_p99_9": 0.101,
+		"response_time_p100":  0.101,
+		"http_code_p0":        float64(200),
+		"http_code_p50":       float64(200),
+		"http_code_p99_9":     float64(200),
+		"http_code_p100":      float64(200),
+	}
+	require.Equal(t, 1, len(acc.Metrics))
+	assert.Equal(t, map[string]string{"server": "http://localhost"}, acc.Metrics[0].Tags)
+	for k, v := range expectedFields {
+		assert.InDelta(t, v, acc.Metrics[0].Fields[k], 1e-9, k)
+	}
+	assert.Equal(t, len(expectedFields), len(acc.Metrics[0].Fields))
+}
+
+// Test that the series are reset each period
+func TestQuantileReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{0.5}
+
+	m1, _ := metric.New("m1", map[string]string{"foo": "bar"},
+		map[string]interface{}{"a": int64(1)}, time.Now())
+	m2, _ := metric.New("m1", map[string]string{"foo": "baz"},
+		map[string]interface{}{"a": int64(100)}, time.Now())
+
+	q.Add(m1)
+	q.Add(m2)
+	q.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_p50": float64(1)}, map[string]string{"foo": "bar"})
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_p50": float64(100)}, map[string]string{"foo": "baz"})
+
+	acc.ClearMetrics()
+	q.Reset()
+	q.Add(m2)
+	q.Push(&acc)
+	require.Equal(t, 1, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_p50": float64(100)}, map[string]string{"foo": "baz"})
+}
+
+func TestQuantileSuffix(t *testing.T) {
+	assert.Equal(t, "p50", suffix(0.5))
+	assert.Equal(t, "p99", suffix(0.99))
+	assert.Equal(t, "p99_9", suffix(0.999))
+	assert.Equal(t, "p99_99", suffix(0.9999))
+	assert.Equal(t, "p0", suffix(0))
+	assert.Equal(t, "p100", suffix(1))
+}
+
+func TestQuantileValidate(t *testing.T) {
+	q := NewQuantile()
+	assert.Empty(t, q.Validate())
+
+	q.Quantiles = []float64{0.5, 1.5, -0.1}
+	q.Compression.Value = 0
+	assert.Len(t, q.Validate(), 3)
+}
+
+func TestQuantileSkipsEmptyDigests(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.cache[1] = aggregate{
+		name:   "m1",
+		fields: map[string]*tdigest{"a": newTDigest(100)},
+	}
+	q.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestQuantileIntegerCompression(t *testing.T) {
+	q := NewQuantile()
+	require.NoError(t, toml.Unmarshal([]byte("compression = 200\n"), q))
+	assert.Equal(t, 200.0, q.Compression.Value)
+
+	require.NoError(t, toml.Unmarshal([]byte("compression = 50.5\n"), q))
+	assert.Equal(t, 50.5, q.Compression.Value)
+}
@@ -0,0 +1,145 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// tdigest is a merging t-digest, a sketch of a distribution estimating its
+// quantiles in bounded memory, the most accurately in the tails. See
+// "Computing Extremely Accurate Quantiles Using t-Digests", T. Dunning and
+// O. Ertl.
+type tdigest struct {
+	compression float64
+
+	// merged centroids, sorted by mean
+	centroids []centroid
+	// values added since the last merge
+	buffer []centroid
+
+	count float64
+	min   float64
+	max   float64
+}
+
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+type byMean []centroid
+
+func (c byMean) Len() int           { return len(c) }
+func (c byMean) Less(i, j int) bool { return c[i].mean < c[j].mean }
+func (c byMean) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// add adds a value to the digest
+func (t *tdigest) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	t.buffer = append(t.buffer, centroid{mean: v, count: 1})
+	t.count++
+	if v < t.min {
+		t.min = v
+	}
+	if v > t.max {
+		t.max = v
+	}
+	if len(t.buffer) >= t.bufferSize() {
+		t.merge()
+	}
+}
+
+func (t *tdigest) bufferSize() int {
+	return int(5 * t.compression)
+}
+
+// merge merges the buffered values into the centroids. Adjacent centroids are
+// combined while their span of the k1 scale, k(q) = compression/2pi *
+// asin(2q-1), is at most 1, which keeps the centroids small in the tails.
+func (t *tdigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.buffer, t.centroids...)
+	sort.Sort(byMean(all))
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	// weight of the centroids before cur
+	before := 0.0
+	kLow := t.k(0)
+	for _, c := range all[1:] {
+		if t.k((before+cur.count+c.count)/t.count)-kLow <= 1 {
+			cur.count += c.count
+			cur.mean += (c.mean - cur.mean) * c.count / cur.count
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.count
+		kLow = t.k(before / t.count)
+		cur = c
+	}
+	merged = append(merged, cur)
+
+	t.centroids = merged
+	t.buffer = t.buffer[:0]
+}
+
+func (t *tdigest) k(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// quantile returns the estimate of the q quantile, 0 <= q <= 1, NaN when the
+// digest is empty
+func (t *tdigest) quantile(q float64) float64 {
+	t.merge()
+	if t.count == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+	if len(t.centroids) == 1 {
+		return t.centroids[0].mean
+	}
+
+	// each centroid holds its weight around its mean, the quantiles between
+	// the centers of two centroids are interpolated
+	target := q * t.count
+	first := t.centroids[0]
+	if target < first.count/2 {
+		return interpolate(target, 0, t.min, first.count/2, first.mean)
+	}
+	cum := 0.0
+	for i := 0; i < len(t.centroids)-1; i++ {
+		c, next := t.centroids[i], t.centroids[i+1]
+		center := cum + c.count/2
+		nextCenter := cum + c.count + next.count/2
+		if target < nextCenter {
+			return interpolate(target, center, c.mean, nextCenter, next.mean)
+		}
+		cum += c.count
+	}
+	last := t.centroids[len(t.centroids)-1]
+	return interpolate(target, t.count-last.count/2, last.mean, t.count, t.max)
+}
+
+func interpolate(x, x0, y0, x1, y1 float64) float64 {
+	if x1 == x0 {
+		return y0
+	}
+	return y0 + (x-x0)/(x1-x0)*(y1-y0)
+}
@@ -0,0 +1,88 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testQuantiles = []float64{0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 0.999}
+
+// rankError returns the difference between q and the rank of v in the sorted
+// values, the usual measure of the accuracy of a quantile sketch
+func rankError(sorted []float64, q, v float64) float64 {
+	rank := sort.SearchFloat64s(sorted, v)
+	return math.Abs(float64(rank)/float64(len(sorted)) - q)
+}
+
+func testAccuracy(t *testing.T, name string, gen func(r *rand.Rand) float64) {
+	r := rand.New(rand.NewSource(42))
+	digest := newTDigest(100)
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = gen(r)
+		digest.add(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range testQuantiles {
+		// the error bound shrinks in the tails, as the centroids do
+		bound := 0.005
+		if q < 0.05 || q > 0.95 {
+			bound = 0.001
+		}
+		estimate := digest.quantile(q)
+		assert.True(t, rankError(values, q, estimate) <= bound,
+			"%s: quantile %v estimated %v, exact %v", name, q, estimate, values[int(q*float64(len(values)))])
+	}
+	assert.Equal(t, values[0], digest.quantile(0))
+	assert.Equal(t, values[len(values)-1], digest.quantile(1))
+
+	// the memory is bounded by the compression, not the number of values
+	assert.True(t, len(digest.centroids) <= 200, "%s: %d centroids", name, len(digest.centroids))
+}
+
+func TestTDigestAccuracy(t *testing.T) {
+	testAccuracy(t, "uniform", func(r *rand.Rand) float64 {
+		return r.Float64() * 1000
+	})
+	testAccuracy(t, "normal", func(r *rand.Rand) float64 {
+		return r.NormFloat64()*10 + 100
+	})
+	// latencies have a long tail
+	testAccuracy(t, "exponential", func(r *rand.Rand) float64 {
+		return r.ExpFloat64() * 20
+	})
+	testAccuracy(t, "lognormal", func(r *rand.Rand) float64 {
+		return math.Exp(r.NormFloat64())
+	})
+}
+
+func TestTDigestSmall(t *testing.T) {
+	digest := newTDigest(100)
+	assert.True(t, math.IsNaN(digest.quantile(0.5)))
+
+	digest.add(5)
+	assert.Equal(t, float64(5), digest.quantile(0.5))
+	assert.Equal(t, float64(5), digest.quantile(0.99))
+
+	// few values are kept exactly, the quantiles are interpolated
+	for _, v := range []float64{1, 2, 3, 4} {
+		digest.add(v)
+	}
+	assert.Equal(t, float64(1), digest.quantile(0))
+	assert.Equal(t, float64(3), digest.quantile(0.5))
+	assert.Equal(t, float64(5), digest.quantile(1))
+	assert.Equal(t, 5, len(digest.centroids))
+}
+
+func TestTDigestIgnoresNaN(t *testing.T) {
+	digest := newTDigest(100)
+	digest.add(math.NaN())
+	digest.add(1)
+	assert.Equal(t, float64(1), digest.count)
+	assert.Equal(t, float64(1), digest.quantile(0.5))
+}
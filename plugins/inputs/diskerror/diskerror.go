@@ -0,0 +1,290 @@
+package diskerror
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai/event"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+var (
+	getDiskOfDevice = disk.GetDiskOfDevice
+	getWWN          = disk.GetWWNByKernelName
+	sendDiskError   = event.SendDiskError
+	now             = time.Now
+)
+
+// DiskError follows the kernel log and counts the disk errors it reports
+type DiskError struct {
+	Source        string
+	FromBeginning bool
+	PollInterval  internal.Duration
+	EventInterval internal.Duration
+
+	acc  telegraf.Accumulator
+	file *os.File
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu     sync.Mutex
+	counts map[counterKey]*counter
+}
+
+// counterKey identifies the errors of a type of a device, the device is the
+// disk when it is known
+type counterKey struct {
+	device    string
+	wwn       string
+	errorType string
+}
+
+type counter struct {
+	count int64
+
+	// events are sent at most once per EventInterval, the errors in between
+	// are reported with the next event
+	lastEvent  time.Time
+	suppressed int64
+}
+
+func NewDiskError() *DiskError {
+	return &DiskError{
+		Source:        "/dev/kmsg",
+		PollInterval:  internal.Duration{Duration: 250 * time.Millisecond},
+		EventInterval: internal.Duration{Duration: time.Minute},
+	}
+}
+
+const sampleConfig = `
+  ## Kernel log to follow, /dev/kmsg or a file such as /var/log/kern.log
+  source = "/dev/kmsg"
+
+  ## Parse the messages logged before the start, the counters are then
+  ## replayed from the kernel ring buffer or the start of the file
+  from_beginning = false
+
+  ## How often a file is checked for new lines
+  # poll_interval = "250ms"
+
+  ## Minimum interval between the sai_event of a device and error type, the
+  ## errors in between are reported with the next event
+  # event_interval = "1m"
+`
+
+func (d *DiskError) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DiskError) Description() string {
+	return "Count the disk errors reported in the kernel log and send them as dcai events"
+}
+
+// Gather reports the error counters since the start
+func (d *DiskError) Gather(acc telegraf.Accumulator) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, c := range d.counts {
+		tags := map[string]string{
+			"device":     key.device,
+			"error_type": key.errorType,
+		}
+		if key.wwn != "" {
+			tags["disk_wwn"] = key.wwn
+		}
+		acc.AddCounter("sai_disk_error", map[string]interface{}{"count": c.count}, tags)
+	}
+	return nil
+}
+
+func (d *DiskError) Start(acc telegraf.Accumulator) error {
+	d.acc = acc
+	d.counts = make(map[counterKey]*counter)
+	d.done = make(chan struct{})
+
+	f, err := d.open(!d.FromBeginning)
+	if err != nil {
+		return err
+	}
+	d.file = f
+
+	d.wg.Add(1)
+	go d.follow()
+	return nil
+}
+
+func (d *DiskError) Stop() {
+	close(d.done)
+	// unblocks the read of /dev/kmsg
+	d.mu.Lock()
+	d.file.Close()
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *DiskError) open(seekEnd bool) (*os.File, error) {
+	f, err := os.Open(d.Source)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open %s: %s", d.Source, err)
+	}
+	if seekEnd {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Unable to seek to the end of %s: %s", d.Source, err)
+		}
+	}
+	return f, nil
+}
+
+// follow reads the kernel log until Stop. /dev/kmsg blocks until a message
+// is logged, the end of a file is polled every PollInterval.
+func (d *DiskError) follow() {
+	defer d.wg.Done()
+	defer func() {
+		d.mu.Lock()
+		d.file.Close()
+		d.mu.Unlock()
+	}()
+
+	reader := bufio.NewReader(d.file)
+	var pending string
+	for {
+		line, err := reader.ReadString('\n')
+		pending += line
+		if err == nil {
+			d.handle(pending)
+			pending = ""
+			continue
+		}
+
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+
+		switch {
+		case err == io.EOF:
+			if !d.wait() {
+				return
+			}
+			if d.reopen() {
+				reader.Reset(d.file)
+				pending = ""
+			}
+		case isOverrun(err):
+			// the ring buffer overwrote messages before they were read
+			log.Printf("W! Messages of %s were lost, the reader is too slow\n", d.Source)
+		default:
+			d.acc.AddError(fmt.Errorf("E! Error reading %s: %s\n", d.Source, err))
+			return
+		}
+	}
+}
+
+// wait waits for PollInterval, it returns false when stopped
+func (d *DiskError) wait() bool {
+	select {
+	case <-d.done:
+		return false
+	case <-time.After(d.PollInterval.Duration):
+		return true
+	}
+}
+
+// reopen reopens the file from its start when it was rotated or truncated
+func (d *DiskError) reopen() bool {
+	current, err := d.file.Stat()
+	if err != nil || !current.Mode().IsRegular() {
+		return false
+	}
+	offset, err := d.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false
+	}
+	latest, err := os.Stat(d.Source)
+	if err != nil || (os.SameFile(current, latest) && latest.Size() >= offset) {
+		return false
+	}
+
+	f, err := d.open(false)
+	if err != nil {
+		return false
+	}
+	// Stop closes d.file concurrently
+	d.mu.Lock()
+	d.file.Close()
+	d.file = f
+	d.mu.Unlock()
+	return true
+}
+
+func isOverrun(err error) bool {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err == syscall.EPIPE
+	}
+	return false
+}
+
+// handle counts the error reported by a line and sends its event
+func (d *DiskError) handle(line string) {
+	e := parseLine(line)
+	if e == nil {
+		return
+	}
+
+	// the device is resolved on each error, since disks may be replaced
+	key := counterKey{device: e.device, errorType: e.errorType}
+	if name := getDiskOfDevice(e.device); name != "" {
+		key.device = name
+		if wwn, err := getWWN(name); err == nil {
+			key.wwn = wwn
+		}
+	}
+
+	d.mu.Lock()
+	c, ok := d.counts[key]
+	if !ok {
+		c = &counter{}
+		d.counts[key] = c
+	}
+	c.count++
+
+	t := now()
+	if !c.lastEvent.IsZero() && t.Sub(c.lastEvent) < d.EventInterval.Duration {
+		c.suppressed++
+		d.mu.Unlock()
+		return
+	}
+	details := fmt.Sprintf("%s on %s", e.errorType, key.device)
+	if key.wwn != "" {
+		details += fmt.Sprintf(" (WWN %s)", key.wwn)
+	}
+	details += ": " + e.message
+	if c.suppressed > 0 {
+		details += fmt.Sprintf(", %d more since the last event", c.suppressed)
+	}
+	c.lastEvent = t
+	c.suppressed = 0
+	d.mu.Unlock()
+
+	if err := sendDiskError(d.acc, key.wwn, details, e.level); err != nil {
+		d.acc.AddError(fmt.Errorf("E! Unable to send the disk error event: %s\n", err))
+	}
+}
+
+func init() {
+	inputs.Add("diskerror", func() telegraf.Input {
+		return NewDiskError()
+	})
+}
@@ -0,0 +1,192 @@
+package diskerror
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai/event"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/type"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDisks replaces the disk lookups and the dcai events, the events are
+// added to the accumulator as sai_event
+func mockDisks() func() {
+	disks := map[string]string{"sda": "sda", "ata3": "sda", "2:0:0:0": "sda", "sda1": "sda"}
+	wwns := map[string]string{"sda": "5000c5005f50e6ab"}
+
+	getDiskOfDevice = func(name string) string {
+		return disks[name]
+	}
+	getWWN = func(name string) (string, error) {
+		if wwn, ok := wwns[name]; ok {
+			return wwn, nil
+		}
+		return "", fmt.Errorf("Cannot find WWN of %s", name)
+	}
+	sendDiskError = func(acc telegraf.Accumulator, wwn string, details string, level dcaitype.LogLevel) error {
+		acc.AddFields("sai_event", map[string]interface{}{
+			"details":        details,
+			"event_level":    level.String(),
+			"disk_domain_id": wwn,
+		}, nil)
+		return nil
+	}
+	return func() {
+		getDiskOfDevice = disk.GetDiskOfDevice
+		getWWN = disk.GetWWNByKernelName
+		sendDiskError = event.SendDiskError
+		now = time.Now
+	}
+}
+
+func counts(acc *testutil.Accumulator) map[string]int64 {
+	acc.Lock()
+	defer acc.Unlock()
+	result := make(map[string]int64)
+	for _, m := range acc.Metrics {
+		if m.Measurement == "sai_disk_error" {
+			key := m.Tags["device"] + "/" + m.Tags["error_type"] + "/" + m.Tags["disk_wwn"]
+			result[key] = m.Fields["count"].(int64)
+		}
+	}
+	return result
+}
+
+func events(acc *testutil.Accumulator) []map[string]interface{} {
+	acc.Lock()
+	defer acc.Unlock()
+	var result []map[string]interface{}
+	for _, m := range acc.Metrics {
+		if m.Measurement == "sai_event" {
+			result = append(result, m.Fields)
+		}
+	}
+	return result
+}
+
+// waitErrors waits until n errors were counted
+func waitErrors(d *DiskError, n int64) {
+	for {
+		d.mu.Lock()
+		var total int64
+		for _, c := range d.counts {
+			total += c.count
+		}
+		d.mu.Unlock()
+		if total >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDiskErrorFromFile(t *testing.T) {
+	defer mockDisks()()
+
+	d := NewDiskError()
+	d.Source = filepath.Join("testdata", "kern.log")
+	d.FromBeginning = true
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, d.Start(&acc))
+	defer d.Stop()
+
+	// ata_failed_command, ata_error, scsi_failed_command, scsi_medium_error
+	// and io_error
+	acc.Wait(5)
+
+	require.NoError(t, acc.GatherError(d.Gather))
+	assert.Equal(t, map[string]int64{
+		"sda/ata_failed_command/5000c5005f50e6ab":  1,
+		"sda/ata_error/5000c5005f50e6ab":           1,
+		"sda/scsi_failed_command/5000c5005f50e6ab": 1,
+		"sda/scsi_medium_error/5000c5005f50e6ab":   1,
+		"sda/io_error/5000c5005f50e6ab":            1,
+	}, counts(&acc))
+
+	evs := events(&acc)
+	require.Len(t, evs, 5)
+	assert.Equal(t, "Warning", evs[0]["event_level"])
+	assert.Equal(t, "5000c5005f50e6ab", evs[0]["disk_domain_id"])
+	assert.Equal(t, "ata_failed_command on sda (WWN 5000c5005f50e6ab): ata3.00: failed command: READ FPDMA QUEUED", evs[0]["details"])
+	assert.Equal(t, "Error", evs[4]["event_level"])
+
+}
+
+func TestDiskErrorFollow(t *testing.T) {
+	defer mockDisks()()
+
+	dir, err := ioutil.TempDir("", "diskerror")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kern.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("ata3.00: error: { UNC }\n"), 0644))
+
+	var mu sync.Mutex
+	clock := time.Unix(1508400000, 0)
+	now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+
+	d := NewDiskError()
+	d.Source = path
+	d.PollInterval.Duration = 10 * time.Millisecond
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, d.Start(&acc))
+	defer d.Stop()
+
+	// the lines logged before the start are skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	fmt.Fprintln(f, "mpt3sas_cm0: sending diag reset !!")
+	// a partial line is completed by the next write
+	fmt.Fprint(f, "mpt3sas_cm0: sending diag")
+	time.Sleep(50 * time.Millisecond)
+	fmt.Fprintln(f, " reset !!")
+	waitErrors(d, 2)
+
+	// the events are sent once per event_interval
+	mu.Lock()
+	clock = clock.Add(2 * time.Minute)
+	mu.Unlock()
+	fmt.Fprintln(f, "mpt3sas_cm0: sending diag reset !!")
+	f.Close()
+	acc.Wait(2)
+
+	require.NoError(t, acc.GatherError(d.Gather))
+	// the controller is not a disk, it has no WWN
+	assert.Equal(t, map[string]int64{"mpt3sas_cm0/controller_reset/": 3}, counts(&acc))
+	evs := events(&acc)
+	require.Len(t, evs, 2)
+	assert.Equal(t, "", evs[0]["disk_domain_id"])
+	assert.Equal(t, "controller_reset on mpt3sas_cm0: mpt3sas_cm0: sending diag reset !!, 1 more since the last event", evs[1]["details"])
+
+	// the rotated file is read from its start
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, ioutil.WriteFile(path, []byte("Buffer I/O error on dev sda1, logical block 0, async page read\n"), 0644))
+	acc.Wait(4)
+	evs = events(&acc)
+	require.Len(t, evs, 3)
+	assert.Equal(t, "5000c5005f50e6ab", evs[2]["disk_domain_id"])
+}
+
+func TestDiskErrorStartError(t *testing.T) {
+	d := NewDiskError()
+	d.Source = filepath.Join("testdata", "missing.log")
+
+	acc := testutil.Accumulator{}
+	assert.Error(t, d.Start(&acc))
+}
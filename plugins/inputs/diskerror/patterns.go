@@ -0,0 +1,118 @@
+package diskerror
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf/dcai/type"
+)
+
+// pattern recognizes a kernel message reporting a disk error. The device
+// group is the kernel name of the device, the disk group, when set, the
+// kernel name of the disk and the detail group a short detail of the error.
+type pattern struct {
+	errorType string
+	level     dcaitype.LogLevel
+	regexp    *regexp.Regexp
+}
+
+// diskError is a disk error parsed from a kernel message
+type diskError struct {
+	errorType string
+	level     dcaitype.LogLevel
+	// kernel name of the device reporting the error, e.g. sda, ata3, 2:0:0:0,
+	// nvme0 or mpt3sas_cm0
+	device  string
+	detail  string
+	message string
+}
+
+var patterns = []pattern{
+	// ata3.00: failed command: READ FPDMA QUEUED
+	{"ata_failed_command", dcaitype.LogLevelWarning,
+		regexp.MustCompile(`^(?P<device>ata\d+)(?:\.\d+)?: failed command: (?P<detail>.+)$`)},
+	// ata3.00: error: { UNC }
+	{"ata_error", dcaitype.LogLevelError,
+		regexp.MustCompile(`^(?P<device>ata\d+)(?:\.\d+)?: error: \{ (?P<detail>.+) \}`)},
+	// ata3: hard resetting link
+	{"ata_link_reset", dcaitype.LogLevelWarning,
+		regexp.MustCompile(`^(?P<device>ata\d+)(?:\.\d+)?: (?P<detail>hard resetting link|soft resetting link|limiting SATA link speed.*)$`)},
+	// blk_update_request: I/O error, dev sda, sector 1234
+	// blk_update_request: critical medium error, dev sdb, sector 3721
+	// print_req_error: I/O error, dev nvme0n1, sector 5678
+	{"io_error", dcaitype.LogLevelError,
+		regexp.MustCompile(`(?P<detail>I/O|critical medium|critical target) error, dev (?P<device>[\w-]+), sector \d+`)},
+	// Buffer I/O error on dev sda1, logical block 0, async page read
+	{"buffer_io_error", dcaitype.LogLevelError,
+		regexp.MustCompile(`^Buffer I/O error on dev(?:ice)? (?P<device>[\w-]+), (?P<detail>logical block \d+)`)},
+	// sd 2:0:0:0: [sda] tag#0 Sense Key : Medium Error [current]
+	{"scsi_medium_error", dcaitype.LogLevelError,
+		regexp.MustCompile(`^sd (?P<device>[\d:]+): (?:\[(?P<disk>\w+)\] )?(?:tag#\d+ )?Sense Key : (?P<detail>Medium Error)`)},
+	// sd 2:0:0:0: [sda] tag#0 Sense Key : Hardware Error [current]
+	{"scsi_hardware_error", dcaitype.LogLevelError,
+		regexp.MustCompile(`^sd (?P<device>[\d:]+): (?:\[(?P<disk>\w+)\] )?(?:tag#\d+ )?Sense Key : (?P<detail>Hardware Error)`)},
+	// sd 2:0:0:0: [sda] tag#3 FAILED Result: hostbyte=DID_OK driverbyte=DRIVER_SENSE
+	{"scsi_failed_command", dcaitype.LogLevelWarning,
+		regexp.MustCompile(`^sd (?P<device>[\d:]+): (?:\[(?P<disk>\w+)\] )?(?:tag#\d+ )?FAILED Result: (?P<detail>.+)$`)},
+	// sd 0:0:1:0: attempting task abort! scmd(ffff8803f26a8c00)
+	{"scsi_task_abort", dcaitype.LogLevelWarning,
+		regexp.MustCompile(`^sd (?P<device>[\d:]+): (?P<detail>attempting task abort)`)},
+	// mpt3sas_cm0: sending diag reset !!
+	{"controller_reset", dcaitype.LogLevelWarning,
+		regexp.MustCompile(`^(?P<device>mpt[23]sas_cm\d+): (?P<detail>sending (?:diag|message unit) reset)`)},
+	// nvme nvme0: I/O 123 QID 4 timeout, aborting
+	{"nvme_timeout", dcaitype.LogLevelWarning,
+		regexp.MustCompile(`^nvme (?P<device>nvme\d+): (?P<detail>I/O \d+ QID \d+ timeout.*)$`)},
+	// nvme nvme0: controller is down; will reset: CSTS=0xffffffff
+	{"nvme_controller_error", dcaitype.LogLevelError,
+		regexp.MustCompile(`^nvme (?P<device>nvme\d+): (?P<detail>controller is down; will reset|Removing after probe failure|Device not ready; aborting reset)`)},
+}
+
+var (
+	// 3,1234,5678901,-;message, the format of /dev/kmsg
+	kmsgRegexp = regexp.MustCompile(`^\d+,\d+,\d+,[^;]*;`)
+	// Oct 19 10:00:00 node1 kernel: message, the format of syslog
+	syslogRegexp = regexp.MustCompile(`^.*? kernel: `)
+	// [ 1234.567890] message, the format of dmesg
+	dmesgRegexp = regexp.MustCompile(`^\[\s*\d+\.\d+\]\s*`)
+)
+
+// parseLine parses a line of /dev/kmsg, syslog or dmesg, it returns nil when
+// the line does not report a disk error
+func parseLine(line string) *diskError {
+	// the continuation lines of /dev/kmsg hold the device properties
+	if strings.HasPrefix(line, " ") {
+		return nil
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if loc := kmsgRegexp.FindStringIndex(line); loc != nil {
+		line = line[loc[1]:]
+	} else if loc := syslogRegexp.FindStringIndex(line); loc != nil {
+		line = line[loc[1]:]
+	}
+	line = dmesgRegexp.ReplaceAllString(line, "")
+
+	for _, p := range patterns {
+		match := p.regexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		e := &diskError{
+			errorType: p.errorType,
+			level:     p.level,
+			message:   line,
+		}
+		for i, name := range p.regexp.SubexpNames() {
+			switch {
+			case match[i] == "":
+			// the disk group follows the device group, it is preferred
+			case name == "device", name == "disk":
+				e.device = match[i]
+			case name == "detail":
+				e.detail = match[i]
+			}
+		}
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package diskerror
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/type"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		errorType string
+		level     dcaitype.LogLevel
+		device    string
+		detail    string
+	}{
+		{
+			line:      "3,1620,1234000003,-;ata3.00: failed command: READ FPDMA QUEUED",
+			errorType: "ata_failed_command",
+			level:     dcaitype.LogLevelWarning,
+			device:    "ata3",
+			detail:    "READ FPDMA QUEUED",
+		},
+		{
+			line:      "[ 1234.000006] ata3.00: error: { UNC }",
+			errorType: "ata_error",
+			level:     dcaitype.LogLevelError,
+			device:    "ata3",
+			detail:    "UNC",
+		},
+		{
+			line:      "Oct 19 10:00:00 node1 kernel: [ 1234.5] ata3: hard resetting link",
+			errorType: "ata_link_reset",
+			level:     dcaitype.LogLevelWarning,
+			device:    "ata3",
+			detail:    "hard resetting link",
+		},
+		{
+			line:      "ata5: limiting SATA link speed to 3.0 Gbps",
+			errorType: "ata_link_reset",
+			level:     dcaitype.LogLevelWarning,
+			device:    "ata5",
+			detail:    "limiting SATA link speed to 3.0 Gbps",
+		},
+		{
+			line:      "3,1630,1234100003,-;blk_update_request: I/O error, dev sda, sector 440528344",
+			errorType: "io_error",
+			level:     dcaitype.LogLevelError,
+			device:    "sda",
+			detail:    "I/O",
+		},
+		{
+			line:      "blk_update_request: critical medium error, dev sdb, sector 3721",
+			errorType: "io_error",
+			level:     dcaitype.LogLevelError,
+			device:    "sdb",
+			detail:    "critical medium",
+		},
+		{
+			line:      "print_req_error: I/O error, dev nvme0n1, sector 5678",
+			errorType: "io_error",
+			level:     dcaitype.LogLevelError,
+			device:    "nvme0n1",
+			detail:    "I/O",
+		},
+		{
+			line:      "Buffer I/O error on dev sda1, logical block 0, async page read",
+			errorType: "buffer_io_error",
+			level:     dcaitype.LogLevelError,
+			device:    "sda1",
+			detail:    "logical block 0",
+		},
+		{
+			line:      "3,1629,1234100001,-;sd 2:0:0:0: [sda] tag#3 Sense Key : Medium Error [current] [descriptor]",
+			errorType: "scsi_medium_error",
+			level:     dcaitype.LogLevelError,
+			device:    "sda",
+			detail:    "Medium Error",
+		},
+		{
+			line:      "sd 4:0:7:0: Sense Key : Hardware Error [current]",
+			errorType: "scsi_hardware_error",
+			level:     dcaitype.LogLevelError,
+			device:    "4:0:7:0",
+			detail:    "Hardware Error",
+		},
+		{
+			line:      "sd 2:0:0:0: [sda] tag#3 FAILED Result: hostbyte=DID_OK driverbyte=DRIVER_SENSE",
+			errorType: "scsi_failed_command",
+			level:     dcaitype.LogLevelWarning,
+			device:    "sda",
+			detail:    "hostbyte=DID_OK driverbyte=DRIVER_SENSE",
+		},
+		{
+			line:      "sd 0:0:1:0: attempting task abort! scmd(ffff8803f26a8c00)",
+			errorType: "scsi_task_abort",
+			level:     dcaitype.LogLevelWarning,
+			device:    "0:0:1:0",
+			detail:    "attempting task abort",
+		},
+		{
+			line:      "mpt3sas_cm0: sending diag reset !!",
+			errorType: "controller_reset",
+			level:     dcaitype.LogLevelWarning,
+			device:    "mpt3sas_cm0",
+			detail:    "sending diag reset",
+		},
+		{
+			line:      "4,2001,9876543210,-;nvme nvme0: I/O 123 QID 4 timeout, aborting",
+			errorType: "nvme_timeout",
+			level:     dcaitype.LogLevelWarning,
+			device:    "nvme0",
+			detail:    "I/O 123 QID 4 timeout, aborting",
+		},
+		{
+			line:      "nvme nvme1: controller is down; will reset: CSTS=0xffffffff, PCI_STATUS=0xffff",
+			errorType: "nvme_controller_error",
+			level:     dcaitype.LogLevelError,
+			device:    "nvme1",
+			detail:    "controller is down; will reset",
+		},
+	}
+
+	for _, tt := range tests {
+		e := parseLine(tt.line)
+		require.NotNil(t, e, tt.line)
+		assert.Equal(t, tt.errorType, e.errorType, tt.line)
+		assert.Equal(t, tt.level, e.level, tt.line)
+		assert.Equal(t, tt.device, e.device, tt.line)
+		assert.Equal(t, tt.detail, e.detail, tt.line)
+	}
+}
+
+func TestParseLineNoError(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"6,1700,1240000000,-;EXT4-fs (sda1): mounted filesystem with ordered data mode",
+		"ata3.00: exception Emask 0x0 SAct 0x8 SErr 0x0 action 0x0",
+		"ata3.00: status: { DRDY ERR }",
+		"sd 2:0:0:0: [sda] tag#3 Add. Sense: Unrecovered read error - auto reallocate failed",
+		"mpt3sas_cm0: diag reset: SUCCESS",
+		" SUBSYSTEM=scsi",
+		" DEVICE=+scsi:2:0:0:0",
+	} {
+		assert.Nil(t, parseLine(line), line)
+	}
+}
+
+func TestParseLineMessage(t *testing.T) {
+	e := parseLine("Oct 19 10:00:01 node1 kernel: [ 1234.100003] blk_update_request: I/O error, dev sda, sector 440528344\n")
+	require.NotNil(t, e)
+	assert.Equal(t, "blk_update_request: I/O error, dev sda, sector 440528344", e.message)
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+)
+
+// The disk lookups, switched for the tests
+var (
+	getDisksOfDevNumber = disk.GetDisksOfDevNumber
+	getDevNumberOfPath  = disk.GetDevNumberOfPath
+	getWWN              = disk.GetWWNByKernelName
+)
+
+type mappedDisk struct {
+	name string
+	wwn  string
+}
+
+// diskMapper maps the block devices, by major:minor number, to the disks
+// holding them. It caches the lookups of a gather, shared by the containers.
+type diskMapper struct {
+	sync.Mutex
+	disks map[string][]mappedDisk
+}
+
+func newDiskMapper() *diskMapper {
+	return &diskMapper{disks: make(map[string][]mappedDisk)}
+}
+
+// disksOf returns the disks with a WWN under the device number
+func (m *diskMapper) disksOf(devnum string) []mappedDisk {
+	m.Lock()
+	defer m.Unlock()
+
+	if disks, ok := m.disks[devnum]; ok {
+		return disks
+	}
+	var disks []mappedDisk
+	for _, name := range getDisksOfDevNumber(devnum) {
+		// loop or ram devices have no WWN
+		if wwn, err := getWWN(name); err == nil {
+			disks = append(disks, mappedDisk{name: name, wwn: wwn})
+		}
+	}
+	m.disks[devnum] = disks
+	return disks
+}
+
+// wwns returns the comma separated WWNs of the disks under the device number
+func (m *diskMapper) wwns(devnum string) string {
+	var wwns []string
+	for _, d := range m.disksOf(devnum) {
+		wwns = append(wwns, d.wwn)
+	}
+	return strings.Join(wwns, ",")
+}
+
+// gatherContainerVolumes reports the volumes, bind mounts and root filesystem
+// of a container with the disks holding them, a metric by volume and disk
+func (d *Docker) gatherContainerVolumes(
+	info types.ContainerJSON,
+	id string,
+	acc telegraf.Accumulator,
+	tags map[string]string,
+	disks *diskMapper,
+) {
+	now := time.Now()
+
+	type volume struct {
+		typ, name, source, destination string
+		rw                             bool
+	}
+	// the writable layer of the container is in the docker root directory
+	volumes := []volume{{"rootfs", "rootfs", d.dockerRootDir, "/", true}}
+	for _, m := range info.Mounts {
+		// tmpfs mounts have no source
+		if m.Source == "" {
+			continue
+		}
+		if m.Name != "" {
+			volumes = append(volumes, volume{"volume", m.Name, m.Source, m.Destination, m.RW})
+		} else {
+			volumes = append(volumes, volume{"bind", m.Source, m.Source, m.Destination, m.RW})
+		}
+	}
+
+	for _, v := range volumes {
+		if v.source == "" {
+			continue
+		}
+		vtags := copyTags(tags)
+		vtags["volume_type"] = v.typ
+		vtags["volume_name"] = v.name
+		vtags["volume_destination"] = v.destination
+		fields := map[string]interface{}{
+			"container_id": id,
+			"source":       v.source,
+			"rw":           v.rw,
+		}
+
+		devnum, err := getDevNumberOfPath(v.source)
+		if err != nil {
+			// the volume is reported without its disks
+			acc.AddFields("docker_container_volume", fields, vtags, now)
+			continue
+		}
+		vtags["device"] = devnum
+
+		mapped := disks.disksOf(devnum)
+		if len(mapped) == 0 {
+			acc.AddFields("docker_container_volume", fields, vtags, now)
+			continue
+		}
+		for _, md := range mapped {
+			dtags := copyTags(vtags)
+			dtags["disk_name"] = md.name
+			dtags["disk_wwn"] = md.wwn
+			acc.AddFields("docker_container_volume", fields, dtags, now)
+		}
+	}
+}
@@ -39,6 +39,8 @@ type Docker struct {
 	ContainerInclude []string `toml:"container_name_include"`
 	ContainerExclude []string `toml:"container_name_exclude"`
 
+	GatherDiskMapping bool `toml:"gather_disk_mapping"`
+
 	SSLCA              string `toml:"ssl_ca"`
 	SSLCert            string `toml:"ssl_cert"`
 	SSLKey             string `toml:"ssl_key"`
@@ -50,6 +52,7 @@ type Docker struct {
 	client          Client
 	httpClient      *http.Client
 	engine_host     string
+	dockerRootDir   string
 	filtersCreated  bool
 	labelFilter     filter.Filter
 	containerFilter filter.Filter
@@ -98,6 +101,12 @@ var sampleConfig = `
   ## Which environment variables should we use as a tag
   ##tag_env = ["JAVA_HOME", "HEAP_SIZE"]
 
+  ## Whether to map the blkio devices and the volumes of the containers to the
+  ## disks holding them: the blkio metrics are tagged with disk_wwn and the
+  ## volumes reported in docker_container_volume. The /sys and the mounts of
+  ## the host are needed, set HOST_PROC when running in a container.
+  # gather_disk_mapping = false
+
   ## docker labels to include and exclude as tags.  Globs accepted.
   ## Note that an empty array for both will include all labels as tags
   docker_label_include = []
@@ -173,13 +182,19 @@ func (d *Docker) Gather(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	// Map the devices of the containers for this gather only
+	var disks *diskMapper
+	if d.GatherDiskMapping {
+		disks = newDiskMapper()
+	}
+
 	// Get container data
 	var wg sync.WaitGroup
 	wg.Add(len(containers))
 	for _, container := range containers {
 		go func(c types.Container) {
 			defer wg.Done()
-			err := d.gatherContainer(c, acc)
+			err := d.gatherContainer(c, acc, disks)
 			if err != nil {
 				acc.AddError(fmt.Errorf("E! Error gathering container %s stats: %s\n",
 					c.Names, err.Error()))
@@ -273,6 +288,7 @@ func (d *Docker) gatherInfo(acc telegraf.Accumulator) error {
 		return err
 	}
 	d.engine_host = info.Name
+	d.dockerRootDir = info.DockerRootDir
 
 	fields := map[string]interface{}{
 		"n_cpus":                  info.NCPU,
@@ -336,6 +352,7 @@ func (d *Docker) gatherInfo(acc telegraf.Accumulator) error {
 func (d *Docker) gatherContainer(
 	container types.Container,
 	acc telegraf.Accumulator,
+	disks *diskMapper,
 ) error {
 	var v *types.StatsJSON
 	// Parse container name
@@ -391,12 +408,16 @@ func (d *Docker) gatherContainer(
 		}
 	}
 
-	// Add whitelisted environment variables to tags
-	if len(d.TagEnvironment) > 0 {
-		info, err := d.client.ContainerInspect(ctx, container.ID)
+	var info types.ContainerJSON
+	if len(d.TagEnvironment) > 0 || disks != nil {
+		info, err = d.client.ContainerInspect(ctx, container.ID)
 		if err != nil {
 			return fmt.Errorf("Error inspecting docker container: %s", err.Error())
 		}
+	}
+
+	// Add whitelisted environment variables to tags
+	if len(d.TagEnvironment) > 0 {
 		for _, envvar := range info.Config.Env {
 			for _, configvar := range d.TagEnvironment {
 				dock_env := strings.SplitN(envvar, "=", 2)
@@ -408,7 +429,11 @@ func (d *Docker) gatherContainer(
 		}
 	}
 
-	gatherContainerStats(v, acc, tags, container.ID, d.PerDevice, d.Total, daemonOSType)
+	gatherContainerStats(v, acc, tags, container.ID, d.PerDevice, d.Total, daemonOSType, disks)
+
+	if disks != nil {
+		d.gatherContainerVolumes(info, container.ID, acc, tags, disks)
+	}
 
 	return nil
 }
@@ -421,6 +446,7 @@ func gatherContainerStats(
 	perDevice bool,
 	total bool,
 	daemonOSType string,
+	disks *diskMapper,
 ) {
 	tm := stat.Read
 
@@ -585,7 +611,7 @@ func gatherContainerStats(
 		acc.AddFields("docker_container_net", totalNetworkStatMap, nettags, tm)
 	}
 
-	gatherBlockIOMetrics(stat, acc, tags, tm, id, perDevice, total)
+	gatherBlockIOMetrics(stat, acc, tags, tm, id, perDevice, total, disks)
 }
 
 func gatherBlockIOMetrics(
@@ -596,6 +622,7 @@ func gatherBlockIOMetrics(
 	id string,
 	perDevice bool,
 	total bool,
+	disks *diskMapper,
 ) {
 	blkioStats := stat.BlkioStats
 	// Make a map of devices to their block io stats
@@ -663,6 +690,11 @@ func gatherBlockIOMetrics(
 		if perDevice {
 			iotags := copyTags(tags)
 			iotags["device"] = device
+			if disks != nil {
+				if wwns := disks.wwns(device); wwns != "" {
+					iotags["disk_wwn"] = wwns
+				}
+			}
 			acc.AddFields("docker_container_blkio", fields, iotags, tm)
 		}
 		if total {
@@ -3,12 +3,15 @@ package docker
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"testing"
 
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -106,7 +109,7 @@ func TestDockerGatherContainerStats(t *testing.T) {
 		"container_image": "redis/image",
 	}
 
-	gatherContainerStats(stats, &acc, tags, "123456789", true, true, "linux")
+	gatherContainerStats(stats, &acc, tags, "123456789", true, true, "linux", nil)
 
 	// test docker_container_net measurement
 	netfields := map[string]interface{}{
@@ -711,3 +714,118 @@ func TestDockerGatherSwarmInfo(t *testing.T) {
 		},
 	)
 }
+
+func TestDockerGatherDiskMapping(t *testing.T) {
+	devnums := map[string]string{
+		"/var/lib/docker":                         "253:0",
+		"/var/lib/docker/volumes/etcd-data/_data": "253:0",
+		"/srv/etcd/conf":                          "7:0",
+	}
+	disks := map[string][]string{
+		"253:0": {"sda", "sdb"},
+		"252:1": {"vda"},
+		"7:0":   {"loop0"},
+	}
+	wwns := map[string]string{
+		"sda": "5000c5005f50e6ab",
+		"sdb": "50014ee60711e39c",
+		"vda": "5000c500a1b2c3d4",
+	}
+	getDevNumberOfPath = func(path string) (string, error) {
+		if devnum, ok := devnums[path]; ok {
+			return devnum, nil
+		}
+		return "", fmt.Errorf("Cannot find the mount of %s", path)
+	}
+	getDisksOfDevNumber = func(devnum string) []string { return disks[devnum] }
+	getWWN = func(name string) (string, error) {
+		if wwn, ok := wwns[name]; ok {
+			return wwn, nil
+		}
+		return "", fmt.Errorf("Cannot find WWN of %s", name)
+	}
+	defer func() {
+		getDevNumberOfPath = disk.GetDevNumberOfPath
+		getDisksOfDevNumber = disk.GetDisksOfDevNumber
+		getWWN = disk.GetWWNByKernelName
+	}()
+
+	var acc testutil.Accumulator
+	d := Docker{
+		newClient:         newClient,
+		PerDevice:         true,
+		GatherDiskMapping: true,
+	}
+	require.NoError(t, acc.GatherError(d.Gather))
+
+	blkio := 0
+	for _, m := range acc.Metrics {
+		if m.Measurement == "docker_container_blkio" && m.Tags["device"] == "252:1" {
+			assert.Equal(t, "5000c500a1b2c3d4", m.Tags["disk_wwn"])
+			blkio++
+		}
+	}
+	assert.Equal(t, 2, blkio)
+
+	tags := map[string]string{
+		"engine_host":        "absol",
+		"container_name":     "etcd2",
+		"container_image":    "quay.io:4443/coreos/etcd",
+		"container_version":  "v2.2.2",
+		"label1":             "test_value_1",
+		"label2":             "test_value_2",
+		"volume_type":        "volume",
+		"volume_name":        "etcd-data",
+		"volume_destination": "/var/lib/etcd",
+		"device":             "253:0",
+		"disk_name":          "sdb",
+		"disk_wwn":           "50014ee60711e39c",
+	}
+	acc.AssertContainsTaggedFields(t, "docker_container_volume",
+		map[string]interface{}{
+			"container_id": "b7dfbb9478a6ae55e237d4d74f8bbb753f0817192b5081334dc78476296e2173",
+			"source":       "/var/lib/docker/volumes/etcd-data/_data",
+			"rw":           true,
+		},
+		tags,
+	)
+
+	tags["volume_type"] = "rootfs"
+	tags["volume_name"] = "rootfs"
+	tags["volume_destination"] = "/"
+	tags["disk_name"] = "sda"
+	tags["disk_wwn"] = "5000c5005f50e6ab"
+	acc.AssertContainsTaggedFields(t, "docker_container_volume",
+		map[string]interface{}{
+			"container_id": "b7dfbb9478a6ae55e237d4d74f8bbb753f0817192b5081334dc78476296e2173",
+			"source":       "/var/lib/docker",
+			"rw":           true,
+		},
+		tags,
+	)
+
+	// the bind mount is on a loop device without WWN
+	tags["volume_type"] = "bind"
+	tags["volume_name"] = "/srv/etcd/conf"
+	tags["volume_destination"] = "/etc/etcd"
+	tags["device"] = "7:0"
+	delete(tags, "disk_name")
+	delete(tags, "disk_wwn")
+	acc.AssertContainsTaggedFields(t, "docker_container_volume",
+		map[string]interface{}{
+			"container_id": "b7dfbb9478a6ae55e237d4d74f8bbb753f0817192b5081334dc78476296e2173",
+			"source":       "/srv/etcd/conf",
+			"rw":           false,
+		},
+		tags,
+	)
+
+	// rootfs on 2 disks, the volume on 2 disks and the bind mount by container
+	volumes := 0
+	for _, m := range acc.Metrics {
+		if m.Measurement == "docker_container_volume" {
+			volumes++
+		}
+	}
+	assert.Equal(t, 10, volumes)
+}
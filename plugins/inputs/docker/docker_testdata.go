@@ -477,4 +477,21 @@ var containerInspect = types.ContainerJSON{
 			"PATH=/bin:/sbin",
 		},
 	},
+	Mounts: []types.MountPoint{
+		{
+			Name:        "etcd-data",
+			Source:      "/var/lib/docker/volumes/etcd-data/_data",
+			Destination: "/var/lib/etcd",
+			Driver:      "local",
+			RW:          true,
+		},
+		{
+			Source:      "/srv/etcd/conf",
+			Destination: "/etc/etcd",
+		},
+		{
+			Destination: "/run",
+			RW:          true,
+		},
+	},
 }
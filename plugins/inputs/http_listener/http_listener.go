@@ -8,13 +8,16 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/prompb"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/selfstat"
@@ -195,6 +198,10 @@ func (h *HTTPListener) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		h.WritesRecv.Incr(1)
 		defer h.WritesServed.Incr(1)
 		h.serveWrite(res, req)
+	case "/api/v1/prom/write":
+		h.WritesRecv.Incr(1)
+		defer h.WritesServed.Incr(1)
+		h.servePromWrite(res, req)
 	case "/query":
 		h.QueriesRecv.Incr(1)
 		defer h.QueriesServed.Incr(1)
@@ -330,6 +337,82 @@ func (h *HTTPListener) parse(b []byte, t time.Time, precision string) error {
 	return err
 }
 
+// servePromWrite accepts the snappy compressed WriteRequests of the
+// Prometheus remote write protocol, as sent by the prometheus_remote_write
+// output. The name of a series is the measurement, its other labels are the
+// tags and its samples the "value" field.
+func (h *HTTPListener) servePromWrite(res http.ResponseWriter, req *http.Request) {
+	if req.ContentLength > h.MaxBodySize {
+		tooLarge(res)
+		return
+	}
+
+	compressed, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, h.MaxBodySize))
+	if err != nil {
+		log.Println("E! " + err.Error())
+		badRequest(res)
+		return
+	}
+	h.BytesRecv.Incr(int64(len(compressed)))
+
+	if n, err := snappy.DecodedLen(compressed); err != nil || int64(n) > h.MaxBodySize {
+		if err != nil {
+			log.Println("E! " + err.Error())
+			badRequest(res)
+		} else {
+			tooLarge(res)
+		}
+		return
+	}
+	b, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		log.Println("E! " + err.Error())
+		badRequest(res)
+		return
+	}
+
+	var wr prompb.WriteRequest
+	if err := wr.Unmarshal(b); err != nil {
+		log.Println("E! " + err.Error())
+		badRequest(res)
+		return
+	}
+
+	// the request is validated as a whole, a rejected request adds no series
+	names := make([]string, len(wr.Timeseries))
+	for i, ts := range wr.Timeseries {
+		for _, l := range ts.Labels {
+			if l.Name == prompb.NameLabel {
+				names[i] = l.Value
+			}
+		}
+		if names[i] == "" {
+			log.Println("E! http_listener received a series without a " + prompb.NameLabel + " label")
+			badRequest(res)
+			return
+		}
+	}
+
+	for i, ts := range wr.Timeseries {
+		name := names[i]
+		tags := make(map[string]string, len(ts.Labels))
+		for _, l := range ts.Labels {
+			if l.Name != prompb.NameLabel {
+				tags[l.Name] = l.Value
+			}
+		}
+		for _, s := range ts.Samples {
+			// the stale markers and other NaNs have no line protocol value
+			if math.IsNaN(s.Value) {
+				continue
+			}
+			h.acc.AddFields(name, map[string]interface{}{"value": s.Value}, tags,
+				time.Unix(0, s.Timestamp*int64(time.Millisecond)))
+		}
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
 func tooLarge(res http.ResponseWriter) {
 	res.Header().Set("Content-Type", "application/json")
 	res.Header().Set("X-Influxdb-Version", "1.0")
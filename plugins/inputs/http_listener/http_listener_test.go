@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -13,6 +14,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf/internal/prompb"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/require"
@@ -495,6 +498,63 @@ func TestWriteHTTPEmpty(t *testing.T) {
 	require.EqualValues(t, 204, resp.StatusCode)
 }
 
+func TestPromWriteHTTP(t *testing.T) {
+	listener := newTestHTTPListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	wr := prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: prompb.NameLabel, Value: "cpu_load_short"},
+					{Name: "host", Value: "server01"},
+				},
+				Samples: []prompb.Sample{
+					{Value: 12, Timestamp: 1422568543702},
+					{Value: math.NaN(), Timestamp: 1422568544702},
+				},
+			},
+		},
+	}
+	body := snappy.Encode(nil, wr.Marshal())
+	resp, err := http.Post(createURL(listener, "http", "/api/v1/prom/write", ""),
+		"application/x-protobuf", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(1)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(12)},
+		map[string]string{"host": "server01"},
+	)
+	require.Equal(t, time.Unix(0, 1422568543702*int64(time.Millisecond)), acc.Metrics[0].Time)
+
+	// a series without a name rejects the whole request
+	wr.Timeseries = append(wr.Timeseries, prompb.TimeSeries{
+		Labels:  wr.Timeseries[0].Labels[1:],
+		Samples: wr.Timeseries[0].Samples,
+	})
+	body = snappy.Encode(nil, wr.Marshal())
+	resp, err = http.Post(createURL(listener, "http", "/api/v1/prom/write", ""),
+		"application/x-protobuf", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+	require.EqualValues(t, 1, acc.NMetrics())
+
+	// not snappy compressed
+	resp, err = http.Post(createURL(listener, "http", "/api/v1/prom/write", ""),
+		"application/x-protobuf", bytes.NewBuffer([]byte(testMsg)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+}
+
 func TestQueryAndPingHTTP(t *testing.T) {
 	listener := newTestHTTPListener()
 
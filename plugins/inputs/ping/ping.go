@@ -40,6 +40,12 @@ type Ping struct {
 	// URLs to ping
 	Urls []string
 
+	// Method used to ping, "exec" or "native"
+	Method string
+
+	// Percentiles of the response times, native method only
+	Percentiles []int
+
 	// host ping function
 	pingHost HostPinger
 }
@@ -49,11 +55,15 @@ func (_ *Ping) Description() string {
 }
 
 const sampleConfig = `
-  ## NOTE: this plugin forks the ping command. You may need to set capabilities
-  ## via setcap cap_net_raw+p /bin/ping
+  ## NOTE: the exec method forks the ping command. You may need to set
+  ## capabilities via setcap cap_net_raw+p /bin/ping
   #
   ## List of urls to ping
   urls = ["www.google.com"] # required
+  ## method used to ping, "exec" runs the ping command and "native" sends the
+  ## ICMP echo requests itself, from an unprivileged socket when the
+  ## net.ipv4.ping_group_range sysctl allows it or from a raw socket otherwise
+  # method = "exec"
   ## number of pings to send per collection (ping -c <COUNT>)
   # count = 1
   ## interval, in s, at which to ping. 0 == default (ping -i <PING_INTERVAL>)
@@ -62,6 +72,8 @@ const sampleConfig = `
   # timeout = 1.0
   ## interface to send ping from (ping -I <INTERFACE>)
   # interface = ""
+  ## percentiles of the response times, native method only
+  # percentiles = [50, 95, 99]
 `
 
 func (_ *Ping) SampleConfig() string {
@@ -69,6 +81,14 @@ func (_ *Ping) SampleConfig() string {
 }
 
 func (p *Ping) Gather(acc telegraf.Accumulator) error {
+	switch p.Method {
+	case "", "exec":
+	case "native":
+		p.gatherNative(acc)
+		return nil
+	default:
+		return fmt.Errorf("Invalid method %q, must be exec or native", p.Method)
+	}
 
 	var wg sync.WaitGroup
 
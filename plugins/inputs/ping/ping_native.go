@@ -0,0 +1,399 @@
+// +build !windows
+
+package ping
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// icmpConn is an ICMP socket, a datagram or a raw one
+type icmpConn interface {
+	ReadFrom(b []byte) (int, net.Addr, error)
+	WriteTo(b []byte, dst net.Addr) (int, error)
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// listenICMP opens an ICMP socket, it can be switched for the tests
+var listenICMP = func(network, address string) (icmpConn, error) {
+	return icmp.ListenPacket(network, address)
+}
+
+// the identifiers of the echo requests of the raw sockets, the kernel sets
+// the ones of the datagram sockets
+var lastEchoID = uint32(os.Getpid())
+
+var echoPayload = []byte("telegraf-ping-16")
+
+// pingTarget is an url being pinged
+type pingTarget struct {
+	url string
+	ip  net.IP
+	// the response time of each echo request, received tells the replied ones
+	rtts     []time.Duration
+	received []bool
+}
+
+type probe struct {
+	target *pingTarget
+	index  int
+	sent   time.Time
+}
+
+// icmpSocket sends the echo requests of all the targets of an IP version
+// and dispatches their replies
+type icmpSocket struct {
+	conn     icmpConn
+	proto    int
+	datagram bool
+	id       int
+
+	mu     sync.Mutex
+	seq    int
+	probes map[int]*probe
+}
+
+// openICMPSocket opens an unprivileged datagram socket, or a raw socket when
+// the datagram ones are not allowed
+func openICMPSocket(v6 bool, source string) (*icmpSocket, error) {
+	networks := []string{"udp4", "ip4:icmp"}
+	proto := protocolICMP
+	if v6 {
+		networks = []string{"udp6", "ip6:ipv6-icmp"}
+		proto = protocolIPv6ICMP
+	}
+
+	var errs []error
+	for i, network := range networks {
+		conn, err := listenICMP(network, source)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return &icmpSocket{
+			conn:     conn,
+			proto:    proto,
+			datagram: i == 0,
+			id:       int(atomic.AddUint32(&lastEchoID, 1) & 0xffff),
+			probes:   make(map[int]*probe),
+		}, nil
+	}
+	return nil, fmt.Errorf("Unable to open an ICMP socket, allow the unprivileged ones "+
+		"with the net.ipv4.ping_group_range sysctl or give telegraf the CAP_NET_RAW "+
+		"capability: %s, %s", errs[0], errs[1])
+}
+
+func (s *icmpSocket) send(t *pingTarget, index int) error {
+	s.mu.Lock()
+	seq := s.seq
+	s.seq = (s.seq + 1) & 0xffff
+	s.mu.Unlock()
+
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if s.proto == protocolIPv6ICMP {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	msg := icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{ID: s.id, Seq: seq, Data: echoPayload},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: t.ip}
+	if s.datagram {
+		dst = &net.UDPAddr{IP: t.ip}
+	}
+
+	s.mu.Lock()
+	s.probes[seq] = &probe{target: t, index: index, sent: time.Now()}
+	s.mu.Unlock()
+	_, err = s.conn.WriteTo(b, dst)
+	return err
+}
+
+// receive dispatches the replies until the read deadline of the socket
+func (s *icmpSocket) receive() {
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(s.proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		if msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		// the raw sockets receive the replies of the other processes too
+		if !ok || (!s.datagram && echo.ID != s.id) {
+			continue
+		}
+
+		s.mu.Lock()
+		p, ok := s.probes[echo.Seq]
+		if ok && p.target.ip.Equal(addrIP(peer)) {
+			// the duplicated replies are ignored
+			delete(s.probes, echo.Seq)
+			p.target.rtts[p.index] = received.Sub(p.sent)
+			p.target.received[p.index] = true
+		}
+		s.mu.Unlock()
+	}
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.IPAddr:
+		return addr.IP
+	}
+	return nil
+}
+
+// sourceAddress returns the address the sockets of an IP version listen on,
+// from the interface setting, an address or an interface name
+func (p *Ping) sourceAddress(v6 bool) (string, error) {
+	if p.Interface == "" {
+		if v6 {
+			return "::", nil
+		}
+		return "0.0.0.0", nil
+	}
+	if ip := net.ParseIP(p.Interface); ip != nil {
+		return ip.String(), nil
+	}
+
+	iface, err := net.InterfaceByName(p.Interface)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if ok && (ipnet.IP.To4() == nil) == v6 {
+			return ipnet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("Interface %s has no IPv%s address", p.Interface, map[bool]string{false: "4", true: "6"}[v6])
+}
+
+// gatherNative pings the urls with a socket shared by the targets of each IP
+// version, the echo requests being sent to all the targets at each interval
+func (p *Ping) gatherNative(acc telegraf.Accumulator) {
+	count := p.Count
+	if count < 1 {
+		count = 1
+	}
+	interval := time.Duration(p.PingInterval * float64(time.Second))
+	timeout := time.Duration(p.Timeout * float64(time.Second))
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	var targets []*pingTarget
+	for _, u := range p.Urls {
+		addr, err := net.ResolveIPAddr("ip", u)
+		if err != nil {
+			acc.AddError(err)
+			acc.AddFields("ping", map[string]interface{}{"result_code": 1}, map[string]string{"url": u})
+			continue
+		}
+		targets = append(targets, &pingTarget{
+			url:      u,
+			ip:       addr.IP,
+			rtts:     make([]time.Duration, count),
+			received: make([]bool, count),
+		})
+	}
+
+	sockets := make(map[bool]*icmpSocket)
+	var sending []*pingTarget
+	for _, t := range targets {
+		v6 := t.ip.To4() == nil
+		s, ok := sockets[v6]
+		if !ok {
+			source, err := p.sourceAddress(v6)
+			if err == nil {
+				s, err = openICMPSocket(v6, source)
+			}
+			if err != nil {
+				acc.AddError(err)
+				sockets[v6] = nil
+			} else {
+				sockets[v6] = s
+			}
+		}
+		if s == nil {
+			acc.AddFields("ping", map[string]interface{}{"result_code": 2}, map[string]string{"url": t.url})
+			continue
+		}
+		sending = append(sending, t)
+	}
+
+	var wg sync.WaitGroup
+	deadline := time.Now().Add(time.Duration(count-1)*interval + timeout)
+	for _, s := range sockets {
+		if s == nil {
+			continue
+		}
+		s.conn.SetReadDeadline(deadline.Add(time.Second))
+		wg.Add(1)
+		go func(s *icmpSocket) {
+			defer wg.Done()
+			s.receive()
+		}(s)
+	}
+
+	sent := make(map[*pingTarget]int)
+	var lastSend time.Time
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		for _, t := range sending {
+			s := sockets[t.ip.To4() == nil]
+			if err := s.send(t, i); err != nil {
+				acc.AddError(fmt.Errorf("%s: %s", err, t.url))
+				continue
+			}
+			sent[t]++
+		}
+		lastSend = time.Now()
+	}
+	for _, s := range sockets {
+		if s != nil {
+			s.conn.SetReadDeadline(lastSend.Add(timeout))
+		}
+	}
+	wg.Wait()
+	for _, s := range sockets {
+		if s != nil {
+			s.conn.Close()
+		}
+	}
+
+	for _, t := range sending {
+		if sent[t] == 0 {
+			acc.AddFields("ping", map[string]interface{}{"result_code": 2}, map[string]string{"url": t.url})
+			continue
+		}
+		acc.AddFields("ping", p.nativeFields(t, sent[t]), map[string]string{"url": t.url})
+	}
+}
+
+// nativeFields returns the fields of a target, the ones of the exec method
+// and the jitter and the percentiles of the response times
+func (p *Ping) nativeFields(t *pingTarget, sent int) map[string]interface{} {
+	var rtts []float64
+	for i, ok := range t.received {
+		if ok {
+			rtts = append(rtts, float64(t.rtts[i])/float64(time.Millisecond))
+		}
+	}
+
+	fields := map[string]interface{}{
+		"result_code":         0,
+		"packets_transmitted": sent,
+		"packets_received":    len(rtts),
+		"percent_packet_loss": float64(sent-len(rtts)) / float64(sent) * 100.0,
+	}
+	if len(rtts) == 0 {
+		return fields
+	}
+
+	s := computeStats(rtts, p.Percentiles)
+	fields["minimum_response_ms"] = s.min
+	fields["average_response_ms"] = s.avg
+	fields["maximum_response_ms"] = s.max
+	fields["standard_deviation_ms"] = s.stddev
+	if len(rtts) > 1 {
+		fields["jitter_ms"] = s.jitter
+	}
+	for i, perc := range p.Percentiles {
+		fields[fmt.Sprintf("percentile%d_response_ms", perc)] = s.percentiles[i]
+	}
+	return fields
+}
+
+type pingStats struct {
+	min, avg, max float64
+	// population standard deviation, as the mdev of ping
+	stddev float64
+	// mean of the differences between the consecutive response times
+	jitter      float64
+	percentiles []float64
+}
+
+// computeStats returns the statistics of the response times, in the order
+// of the echo requests
+func computeStats(rtts []float64, percentiles []int) pingStats {
+	s := pingStats{min: rtts[0], max: rtts[0]}
+	var sum float64
+	for i, rtt := range rtts {
+		sum += rtt
+		s.min = math.Min(s.min, rtt)
+		s.max = math.Max(s.max, rtt)
+		if i > 0 {
+			s.jitter += math.Abs(rtt - rtts[i-1])
+		}
+	}
+	n := float64(len(rtts))
+	s.avg = sum / n
+	if len(rtts) > 1 {
+		s.jitter /= n - 1
+	}
+
+	var squares float64
+	for _, rtt := range rtts {
+		squares += (rtt - s.avg) * (rtt - s.avg)
+	}
+	s.stddev = math.Sqrt(squares / n)
+
+	sorted := append([]float64(nil), rtts...)
+	sort.Float64s(sorted)
+	for _, perc := range percentiles {
+		s.percentiles = append(s.percentiles, percentile(sorted, perc))
+	}
+	return s
+}
+
+// percentile returns the nearest rank percentile of the sorted values
+func percentile(sorted []float64, perc int) float64 {
+	rank := int(math.Ceil(float64(perc) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,211 @@
+// +build !windows
+
+package ping
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+type reply struct {
+	b    []byte
+	peer net.Addr
+}
+
+// fakeConn answers the echo requests, except the ones to the dropped
+// addresses, replying with the echo identifier shifted by idShift
+type fakeConn struct {
+	network string
+	dropped map[string]bool
+	idShift int
+
+	mu       sync.Mutex
+	deadline time.Time
+	replies  []reply
+}
+
+func (c *fakeConn) WriteTo(b []byte, dst net.Addr) (int, error) {
+	proto, replyType := protocolICMP, icmp.Type(ipv4.ICMPTypeEchoReply)
+	if strings.HasSuffix(c.network, "6") || strings.HasPrefix(c.network, "ip6") {
+		proto, replyType = protocolIPv6ICMP, ipv6.ICMPTypeEchoReply
+	}
+	msg, err := icmp.ParseMessage(proto, b)
+	if err != nil {
+		return 0, err
+	}
+	ip := addrIP(dst)
+	if c.dropped[ip.String()] {
+		return len(b), nil
+	}
+
+	echo := msg.Body.(*icmp.Echo)
+	msg = &icmp.Message{
+		Type: replyType,
+		Body: &icmp.Echo{ID: echo.ID + c.idShift, Seq: echo.Seq, Data: echo.Data},
+	}
+	r, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.replies = append(c.replies, reply{r, dst})
+	c.mu.Unlock()
+	return len(b), nil
+}
+
+func (c *fakeConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mu.Lock()
+		if len(c.replies) > 0 {
+			r := c.replies[0]
+			c.replies = c.replies[1:]
+			c.mu.Unlock()
+			return copy(b, r.b), r.peer, nil
+		}
+		expired := time.Now().After(c.deadline)
+		c.mu.Unlock()
+		if expired {
+			return 0, nil, errors.New("i/o timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *fakeConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+// fakeListen switches listenICMP, the networks not allowed fail
+func fakeListen(t *testing.T, allowed map[string]bool, dropped map[string]bool, idShift int) *[]string {
+	var networks []string
+	listenICMP = func(network, address string) (icmpConn, error) {
+		networks = append(networks, network)
+		if !allowed[network] {
+			return nil, errors.New("socket: permission denied")
+		}
+		return &fakeConn{network: network, dropped: dropped, idShift: idShift}, nil
+	}
+	return &networks
+}
+
+func restoreListen() {
+	listenICMP = func(network, address string) (icmpConn, error) {
+		return icmp.ListenPacket(network, address)
+	}
+}
+
+func newNativePing(urls ...string) *Ping {
+	return &Ping{
+		Urls:         urls,
+		Method:       "native",
+		Count:        3,
+		PingInterval: 0.01,
+		Timeout:      0.05,
+		Percentiles:  []int{50, 99},
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	s := computeStats([]float64{10, 20, 15, 35}, []int{50, 95, 0})
+	assert.Equal(t, 10.0, s.min)
+	assert.Equal(t, 20.0, s.avg)
+	assert.Equal(t, 35.0, s.max)
+	assert.InDelta(t, 9.354, s.stddev, 0.001)
+	assert.InDelta(t, 11.667, s.jitter, 0.001)
+	assert.Equal(t, []float64{15, 35, 10}, s.percentiles)
+
+	s = computeStats([]float64{12}, nil)
+	assert.Equal(t, 12.0, s.avg)
+	assert.Equal(t, 0.0, s.stddev)
+	assert.Equal(t, 0.0, s.jitter)
+}
+
+func TestNativePingGather(t *testing.T) {
+	networks := fakeListen(t, map[string]bool{"udp4": true, "udp6": true},
+		map[string]bool{"192.0.2.1": true}, 0)
+	defer restoreListen()
+
+	var acc testutil.Accumulator
+	p := newNativePing("127.0.0.1", "::1", "192.0.2.1")
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	// a socket by IP version
+	assert.Equal(t, []string{"udp4", "udp6"}, *networks)
+
+	for _, url := range []string{"127.0.0.1", "::1"} {
+		tags := map[string]string{"url": url}
+		assert.True(t, acc.HasPoint("ping", tags, "packets_transmitted", 3))
+		assert.True(t, acc.HasPoint("ping", tags, "packets_received", 3))
+		assert.True(t, acc.HasPoint("ping", tags, "percent_packet_loss", 0.0))
+		assert.True(t, acc.HasPoint("ping", tags, "result_code", 0))
+		for _, field := range []string{"minimum_response_ms", "average_response_ms",
+			"maximum_response_ms", "standard_deviation_ms", "jitter_ms",
+			"percentile50_response_ms", "percentile99_response_ms"} {
+			assert.True(t, acc.HasFloatField("ping", field), field)
+		}
+	}
+
+	tags := map[string]string{"url": "192.0.2.1"}
+	assert.True(t, acc.HasPoint("ping", tags, "packets_transmitted", 3))
+	assert.True(t, acc.HasPoint("ping", tags, "packets_received", 0))
+	assert.True(t, acc.HasPoint("ping", tags, "percent_packet_loss", 100.0))
+	for _, m := range acc.Metrics {
+		if m.Tags["url"] == "192.0.2.1" {
+			assert.NotContains(t, m.Fields, "average_response_ms")
+		}
+	}
+}
+
+func TestNativePingRawFallback(t *testing.T) {
+	networks := fakeListen(t, map[string]bool{"ip4:icmp": true}, nil, 0)
+	defer restoreListen()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(newNativePing("127.0.0.1").Gather))
+	assert.Equal(t, []string{"udp4", "ip4:icmp"}, *networks)
+	assert.True(t, acc.HasPoint("ping", map[string]string{"url": "127.0.0.1"}, "packets_received", 3))
+}
+
+func TestNativePingRawOtherIdentifier(t *testing.T) {
+	// the replies to the echo requests of another process
+	fakeListen(t, map[string]bool{"ip4:icmp": true}, nil, 1)
+	defer restoreListen()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(newNativePing("127.0.0.1").Gather))
+	assert.True(t, acc.HasPoint("ping", map[string]string{"url": "127.0.0.1"}, "packets_received", 0))
+}
+
+func TestNativePingSocketError(t *testing.T) {
+	fakeListen(t, nil, nil, 0)
+	defer restoreListen()
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(newNativePing("127.0.0.1").Gather))
+	assert.True(t, acc.HasPoint("ping", map[string]string{"url": "127.0.0.1"}, "result_code", 2))
+}
+
+func TestInvalidMethod(t *testing.T) {
+	var acc testutil.Accumulator
+	p := newNativePing("127.0.0.1")
+	p.Method = "icmp"
+	assert.Error(t, acc.GatherError(p.Gather))
+}
@@ -0,0 +1,758 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	scrapeAnnotation = "prometheus.io/scrape"
+	schemeAnnotation = "prometheus.io/scheme"
+	pathAnnotation   = "prometheus.io/path"
+	portAnnotation   = "prometheus.io/port"
+
+	defaultScrapePort = "9102"
+	defaultScrapePath = "/metrics"
+
+	// the API server ends the watches after this many seconds, they are then
+	// resumed from the last resource version
+	watchTimeoutSeconds = 300
+)
+
+var (
+	// serviceAccountDir holds the token and the CA of the pod service account
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// kubernetesRetryInterval is the wait before retrying a failed list or
+	// watch of the API
+	kubernetesRetryInterval = 5 * time.Second
+
+	// errExpired is returned when the resource version of a watch is too old,
+	// the resource is then listed again
+	errExpired = errors.New("resource version expired")
+)
+
+// The subset of the Kubernetes API objects read by the discovery
+
+type objectMeta struct {
+	Name            string
+	Namespace       string
+	ResourceVersion string
+	Labels          map[string]string
+	Annotations     map[string]string
+}
+
+type pod struct {
+	Metadata objectMeta
+	Spec     struct {
+		NodeName string
+	}
+	Status struct {
+		Phase string
+		PodIP string
+	}
+}
+
+type endpoints struct {
+	Metadata objectMeta
+	Subsets  []struct {
+		Addresses []struct {
+			IP        string
+			NodeName  string
+			TargetRef *struct {
+				Kind string
+				Name string
+			}
+		}
+		Ports []struct {
+			Name string
+			Port int
+		}
+	}
+}
+
+type service struct {
+	Metadata objectMeta
+}
+
+type objectList struct {
+	Metadata struct {
+		ResourceVersion string
+	}
+	Items []json.RawMessage
+}
+
+type watchEvent struct {
+	Type   string
+	Object json.RawMessage
+}
+
+type apiStatus struct {
+	Code    int
+	Reason  string
+	Message string
+}
+
+// kubernetesClient reads the Kubernetes API
+type kubernetesClient struct {
+	server string
+	client *http.Client
+
+	token string
+	// tokenFile is read on each request, since the tokens are rotated
+	tokenFile string
+	username  string
+	password  string
+}
+
+// newKubernetesClient connects with a kubeconfig file, or with the service
+// account of the pod when kubeconfig is empty
+func newKubernetesClient(kubeconfig string) (*kubernetesClient, error) {
+	if kubeconfig == "" {
+		return inClusterClient()
+	}
+	return kubeconfigClient(kubeconfig)
+}
+
+func inClusterClient() (*kubernetesClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("Not running in a Kubernetes pod, kubeconfig must be set")
+	}
+	tlsCfg, err := internal.GetTLSConfig("", "", filepath.Join(serviceAccountDir, "ca.crt"), false)
+	if err != nil {
+		return nil, err
+	}
+	return &kubernetesClient{
+		server:    "https://" + net.JoinHostPort(host, port),
+		client:    newAPIHTTPClient(tlsCfg),
+		tokenFile: filepath.Join(serviceAccountDir, "token"),
+	}, nil
+}
+
+// kubeconfig is the subset of a kubeconfig file read by the discovery
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string
+		Cluster struct {
+			Server                   string
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		}
+	}
+	Contexts []struct {
+		Name    string
+		Context struct {
+			Cluster string
+			User    string
+		}
+	}
+	Users []struct {
+		Name string
+		User struct {
+			Token                 string
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Username              string
+			Password              string
+		}
+	}
+}
+
+// kubeconfigClient connects to the cluster of the current context of a
+// kubeconfig file
+func kubeconfigClient(path string) (*kubernetesClient, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the kubeconfig: %s", err)
+	}
+	var config kubeconfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("Unable to parse the kubeconfig %s: %s", path, err)
+	}
+
+	var clusterName, userName string
+	for _, c := range config.Contexts {
+		if c.Name == config.CurrentContext {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+		}
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("The current context %q is not in the kubeconfig %s", config.CurrentContext, path)
+	}
+
+	dir := filepath.Dir(path)
+	c := &kubernetesClient{}
+	tlsCfg := &tls.Config{}
+	found := false
+	for _, cluster := range config.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		found = true
+		c.server = strings.TrimRight(cluster.Cluster.Server, "/")
+		tlsCfg.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+		ca, err := readConfigData(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority, dir)
+		if err != nil {
+			return nil, err
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("Invalid certificate authority of the cluster %s", clusterName)
+			}
+			tlsCfg.RootCAs = pool
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("The cluster %q is not in the kubeconfig %s", clusterName, path)
+	}
+
+	for _, user := range config.Users {
+		if user.Name != userName {
+			continue
+		}
+		c.token = user.User.Token
+		if user.User.TokenFile != "" {
+			c.tokenFile = configPath(user.User.TokenFile, dir)
+		}
+		c.username, c.password = user.User.Username, user.User.Password
+
+		cert, err := readConfigData(user.User.ClientCertificateData, user.User.ClientCertificate, dir)
+		if err != nil {
+			return nil, err
+		}
+		key, err := readConfigData(user.User.ClientKeyData, user.User.ClientKey, dir)
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid client certificate of the user %s: %s", userName, err)
+			}
+			tlsCfg.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	c.client = newAPIHTTPClient(tlsCfg)
+	return c, nil
+}
+
+// readConfigData returns the base64 data of a kubeconfig entry, or else the
+// content of its file
+func readConfigData(data string, file string, dir string) ([]byte, error) {
+	if data != "" {
+		content, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid kubeconfig data: %s", err)
+		}
+		return content, nil
+	}
+	if file != "" {
+		return ioutil.ReadFile(configPath(file, dir))
+	}
+	return nil, nil
+}
+
+// configPath resolves the paths of a kubeconfig relative to its directory
+func configPath(path string, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func newAPIHTTPClient(tlsCfg *tls.Config) *http.Client {
+	// no client timeout, the watches are long lived
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:       tlsCfg,
+			ResponseHeaderTimeout: time.Minute,
+		},
+	}
+}
+
+func (c *kubernetesClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.server + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	token := c.token
+	if c.tokenFile != "" {
+		content, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return resp, fmt.Errorf("%s returned HTTP status %s: %s", path, resp.Status, bytes.TrimSpace(body))
+	}
+	return resp, nil
+}
+
+func (c *kubernetesClient) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func resourcePath(namespace string, resource string) string {
+	if namespace == "" {
+		return "/api/v1/" + resource
+	}
+	return "/api/v1/namespaces/" + namespace + "/" + resource
+}
+
+// startKubernetesDiscovery watches the configured resources of each
+// namespace, and the services along with the endpoints
+func (p *Prometheus) startKubernetesDiscovery() error {
+	var resources []string
+	for _, resource := range p.KubernetesDiscovery {
+		switch resource {
+		case "pods":
+		case "endpoints":
+			resources = append(resources, "services")
+		default:
+			return fmt.Errorf("Invalid kubernetes_discovery %q, must be pods or endpoints", resource)
+		}
+		resources = append(resources, resource)
+	}
+
+	client, err := newKubernetesClient(p.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	p.kubernetes = client
+	p.targets = make(map[string][]UrlAndAddress)
+	p.endpoints = make(map[string]endpoints)
+	p.services = make(map[string]service)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	namespaces := p.KubernetesNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	for _, resource := range resources {
+		for _, namespace := range namespaces {
+			p.wg.Add(1)
+			go p.watch(ctx, namespace, resource)
+		}
+	}
+	return nil
+}
+
+// watch keeps the targets of a resource of a namespace up to date until ctx
+// is done. The resource is listed, then watched from the resource version of
+// the list.
+func (p *Prometheus) watch(ctx context.Context, namespace string, resource string) {
+	defer p.wg.Done()
+
+	var version string
+	for {
+		var err error
+		if version == "" {
+			version, err = p.list(ctx, namespace, resource)
+		}
+		if err == nil {
+			version, err = p.watchFrom(ctx, namespace, resource, version)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err == errExpired {
+			version = ""
+			continue
+		}
+		if err != nil {
+			log.Printf("E! prometheus: Unable to watch the Kubernetes %s: %s\n", resource, err)
+			version = ""
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(kubernetesRetryInterval):
+			}
+		}
+	}
+}
+
+func (p *Prometheus) selectors(resource string) url.Values {
+	query := url.Values{}
+	if p.KubernetesLabelSelector != "" {
+		query.Set("labelSelector", p.KubernetesLabelSelector)
+	}
+	// the fields of the endpoints differ from those of the pods
+	if p.KubernetesFieldSelector != "" && resource == "pods" {
+		query.Set("fieldSelector", p.KubernetesFieldSelector)
+	}
+	return query
+}
+
+// list replaces the objects of a resource of a namespace, and their targets,
+// it returns the resource version of the list
+func (p *Prometheus) list(ctx context.Context, namespace string, resource string) (string, error) {
+	var list objectList
+	if err := p.kubernetes.getJSON(ctx, resourcePath(namespace, resource), p.selectors(resource), &list); err != nil {
+		return "", err
+	}
+
+	scope := ""
+	if namespace != "" {
+		scope = namespace + "/"
+	}
+
+	p.targetsMu.Lock()
+	defer p.targetsMu.Unlock()
+
+	targets := make(map[string][]UrlAndAddress)
+	switch resource {
+	case "pods":
+		for _, item := range list.Items {
+			var po pod
+			if err := json.Unmarshal(item, &po); err != nil {
+				log.Printf("E! prometheus: Unable to decode the pods: %s\n", err)
+				continue
+			}
+			if t := podTargets(po); len(t) > 0 {
+				targets[objectKey(resource, po.Metadata)] = t
+			}
+		}
+		p.replaceTargets("pods/"+scope, targets)
+		return list.Metadata.ResourceVersion, nil
+	case "endpoints":
+		for key := range p.endpoints {
+			if strings.HasPrefix(key, "endpoints/"+scope) {
+				delete(p.endpoints, key)
+			}
+		}
+		for _, item := range list.Items {
+			var ep endpoints
+			if err := json.Unmarshal(item, &ep); err != nil {
+				log.Printf("E! prometheus: Unable to decode the endpoints: %s\n", err)
+				continue
+			}
+			p.endpoints[objectKey(resource, ep.Metadata)] = ep
+		}
+	case "services":
+		for key := range p.services {
+			if strings.HasPrefix(key, "services/"+scope) {
+				delete(p.services, key)
+			}
+		}
+		for _, item := range list.Items {
+			var svc service
+			if err := json.Unmarshal(item, &svc); err != nil {
+				log.Printf("E! prometheus: Unable to decode the services: %s\n", err)
+				continue
+			}
+			p.services[objectKey(resource, svc.Metadata)] = svc
+		}
+	}
+
+	// the targets of the endpoints depend on both lists
+	for key, ep := range p.endpoints {
+		if !strings.HasPrefix(key, "endpoints/"+scope) {
+			continue
+		}
+		if t := endpointsTargets(ep, p.services[objectKey("services", ep.Metadata)]); len(t) > 0 {
+			targets[key] = t
+		}
+	}
+	p.replaceTargets("endpoints/"+scope, targets)
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watchFrom updates the targets with the changes of a resource since a
+// version until the watch ends, it returns the version of the last change
+func (p *Prometheus) watchFrom(ctx context.Context, namespace string, resource string, version string) (string, error) {
+	query := p.selectors(resource)
+	query.Set("watch", "true")
+	query.Set("resourceVersion", version)
+	query.Set("timeoutSeconds", strconv.Itoa(watchTimeoutSeconds))
+
+	resp, err := p.kubernetes.get(ctx, resourcePath(namespace, resource), query)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusGone {
+			return "", errExpired
+		}
+		return version, err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return version, nil
+			}
+			return version, err
+		}
+
+		switch event.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			var object struct {
+				Metadata objectMeta
+			}
+			if err := json.Unmarshal(event.Object, &object); err != nil {
+				return version, err
+			}
+			version = object.Metadata.ResourceVersion
+
+			if err := p.update(resource, event.Type == "DELETED", event.Object); err != nil {
+				log.Printf("E! prometheus: Unable to discover the targets of %s: %s\n",
+					objectKey(resource, object.Metadata), err)
+			}
+		case "ERROR":
+			var status apiStatus
+			if err := json.Unmarshal(event.Object, &status); err != nil {
+				return version, err
+			}
+			if status.Code == http.StatusGone {
+				return "", errExpired
+			}
+			return version, fmt.Errorf("%s", status.Message)
+		}
+	}
+}
+
+func objectKey(resource string, meta objectMeta) string {
+	return resource + "/" + meta.Namespace + "/" + meta.Name
+}
+
+// update updates the targets with an added, modified or deleted object
+func (p *Prometheus) update(resource string, deleted bool, raw json.RawMessage) error {
+	p.targetsMu.Lock()
+	defer p.targetsMu.Unlock()
+
+	switch resource {
+	case "pods":
+		var po pod
+		if err := json.Unmarshal(raw, &po); err != nil {
+			return err
+		}
+		key := objectKey(resource, po.Metadata)
+		if deleted {
+			p.setTargets(key, nil)
+			return nil
+		}
+		p.setTargets(key, podTargets(po))
+	case "endpoints":
+		var ep endpoints
+		if err := json.Unmarshal(raw, &ep); err != nil {
+			return err
+		}
+		key := objectKey(resource, ep.Metadata)
+		if deleted {
+			delete(p.endpoints, key)
+			p.setTargets(key, nil)
+			return nil
+		}
+		p.endpoints[key] = ep
+		// the annotations are those of the service of the endpoints
+		p.setTargets(key, endpointsTargets(ep, p.services[objectKey("services", ep.Metadata)]))
+	case "services":
+		var svc service
+		if err := json.Unmarshal(raw, &svc); err != nil {
+			return err
+		}
+		key := objectKey(resource, svc.Metadata)
+		if deleted {
+			delete(p.services, key)
+		} else {
+			p.services[key] = svc
+		}
+		// the targets of the endpoints follow the annotations of the service
+		epKey := objectKey("endpoints", svc.Metadata)
+		if ep, ok := p.endpoints[epKey]; ok {
+			p.setTargets(epKey, endpointsTargets(ep, p.services[key]))
+		}
+	}
+	return nil
+}
+
+// podTargets returns the target of an annotated pod
+func podTargets(po pod) []UrlAndAddress {
+	annotations := po.Metadata.Annotations
+	if annotations[scrapeAnnotation] != "true" || po.Status.PodIP == "" {
+		return nil
+	}
+	switch po.Status.Phase {
+	case "Succeeded", "Failed":
+		return nil
+	}
+
+	port := annotations[portAnnotation]
+	if port == "" {
+		port = defaultScrapePort
+	}
+	u := scrapeURL(annotations, po.Status.PodIP, port)
+
+	tags := map[string]string{
+		"namespace": po.Metadata.Namespace,
+		"pod_name":  po.Metadata.Name,
+	}
+	if po.Spec.NodeName != "" {
+		tags["node_name"] = po.Spec.NodeName
+	}
+	for k, v := range po.Metadata.Labels {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return []UrlAndAddress{{OriginalUrl: u, Url: u, Address: po.Status.PodIP, Tags: tags}}
+}
+
+// endpointsTargets returns the targets of the endpoints of an annotated
+// service, an address is scraped on the annotated port, or else on each port
+// of the endpoints
+func endpointsTargets(ep endpoints, svc service) []UrlAndAddress {
+	annotations := svc.Metadata.Annotations
+	if annotations[scrapeAnnotation] != "true" {
+		return nil
+	}
+
+	var targets []UrlAndAddress
+	for _, subset := range ep.Subsets {
+		var ports []string
+		if port := annotations[portAnnotation]; port != "" {
+			ports = []string{port}
+		} else {
+			for _, port := range subset.Ports {
+				ports = append(ports, strconv.Itoa(port.Port))
+			}
+		}
+
+		for _, address := range subset.Addresses {
+			for _, port := range ports {
+				u := scrapeURL(annotations, address.IP, port)
+				tags := map[string]string{
+					"namespace":    ep.Metadata.Namespace,
+					"service_name": ep.Metadata.Name,
+				}
+				if address.NodeName != "" {
+					tags["node_name"] = address.NodeName
+				}
+				if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+					tags["pod_name"] = address.TargetRef.Name
+				}
+				targets = append(targets, UrlAndAddress{OriginalUrl: u, Url: u, Address: address.IP, Tags: tags})
+			}
+		}
+	}
+	return targets
+}
+
+func scrapeURL(annotations map[string]string, ip string, port string) string {
+	scheme := annotations[schemeAnnotation]
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := annotations[pathAnnotation]
+	if path == "" {
+		path = defaultScrapePath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + net.JoinHostPort(ip, port) + path
+}
+
+// setTargets sets the targets of an object, no targets remove it. It is
+// called with targetsMu locked.
+func (p *Prometheus) setTargets(key string, targets []UrlAndAddress) {
+	_, known := p.targets[key]
+	if len(targets) == 0 {
+		if known {
+			log.Printf("D! prometheus: Removed the targets of %s\n", key)
+			delete(p.targets, key)
+		}
+		return
+	}
+	if !known {
+		log.Printf("D! prometheus: Discovered %d targets of %s\n", len(targets), key)
+	}
+	p.targets[key] = targets
+}
+
+// replaceTargets replaces the targets of the objects whose key starts with
+// scope. It is called with targetsMu locked.
+func (p *Prometheus) replaceTargets(scope string, targets map[string][]UrlAndAddress) {
+	for key := range p.targets {
+		if strings.HasPrefix(key, scope) {
+			delete(p.targets, key)
+		}
+	}
+	for key, t := range targets {
+		p.targets[key] = t
+	}
+}
+
+// discoveredURLs returns the targets discovered with the Kubernetes API
+func (p *Prometheus) discoveredURLs() []UrlAndAddress {
+	p.targetsMu.Lock()
+	defer p.targetsMu.Unlock()
+
+	keys := make([]string, 0, len(p.targets))
+	for key := range p.targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var urls []UrlAndAddress
+	for _, key := range keys {
+		urls = append(urls, p.targets[key]...)
+	}
+	return urls
+}
@@ -0,0 +1,368 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI is a Kubernetes API server serving lists and the watch events sent
+// by the tests
+type fakeAPI struct {
+	sync.Mutex
+	token   string
+	lists   map[string]string
+	events  map[string]chan string
+	queries map[string]url.Values
+	// the number of requests by path
+	requests map[string]int
+}
+
+func newFakeAPI(token string) *fakeAPI {
+	return &fakeAPI{
+		token:    token,
+		lists:    make(map[string]string),
+		events:   make(map[string]chan string),
+		queries:  make(map[string]url.Values),
+		requests: make(map[string]int),
+	}
+}
+
+func (f *fakeAPI) watchEvents(path string) chan string {
+	f.Lock()
+	defer f.Unlock()
+	ch, ok := f.events[path]
+	if !ok {
+		ch = make(chan string, 10)
+		f.events[path] = ch
+	}
+	return ch
+}
+
+func (f *fakeAPI) setList(path string, list string) {
+	f.Lock()
+	defer f.Unlock()
+	f.lists[path] = list
+}
+
+func (f *fakeAPI) query(path string) url.Values {
+	f.Lock()
+	defer f.Unlock()
+	return f.queries[path]
+}
+
+func (f *fakeAPI) requestCount(path string) int {
+	f.Lock()
+	defer f.Unlock()
+	return f.requests[path]
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	f.requests[r.URL.Path]++
+	f.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Query().Get("watch") == "true" {
+		events := f.watchEvents(r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				fmt.Fprintln(w, event)
+				w.(http.Flusher).Flush()
+			}
+		}
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	f.queries[r.URL.Path] = r.URL.Query()
+	if list, ok := f.lists[r.URL.Path]; ok {
+		fmt.Fprint(w, list)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func writeKubeconfig(t *testing.T, dir string, server string, token string) string {
+	path := filepath.Join(dir, "kubeconfig")
+	config := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test-cluster
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test-cluster
+    user: test-user
+users:
+- name: test-user
+  user:
+    token: %s
+`, server, token)
+	require.NoError(t, ioutil.WriteFile(path, []byte(config), 0600))
+	return path
+}
+
+func podJSON(name string, version string, ip string, annotations string) string {
+	return fmt.Sprintf(`{"metadata":{"name":"%s","namespace":"default","resourceVersion":"%s",`+
+		`"labels":{"app":"web"},"annotations":{%s}},`+
+		`"spec":{"nodeName":"node1"},"status":{"phase":"Running","podIP":"%s"}}`,
+		name, version, annotations, ip)
+}
+
+func waitTargets(t *testing.T, p *Prometheus, n int) []UrlAndAddress {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		urls := p.discoveredURLs()
+		if len(urls) == n {
+			return urls
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d targets, got %v", n, urls)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestKubernetesPodDiscovery(t *testing.T) {
+	metrics := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/custom", r.URL.Path)
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer metrics.Close()
+	_, port, err := net.SplitHostPort(metrics.Listener.Addr().String())
+	require.NoError(t, err)
+	annotations := fmt.Sprintf(`"prometheus.io/scrape":"true","prometheus.io/port":"%s","prometheus.io/path":"custom"`, port)
+
+	api := newFakeAPI("secret")
+	api.setList("/api/v1/namespaces/default/pods", `{"metadata":{"resourceVersion":"10"},"items":[`+
+		podJSON("web-0", "9", "127.0.0.1", annotations)+","+
+		podJSON("web-1", "8", "127.0.0.2", `"prometheus.io/scrape":"false"`)+","+
+		podJSON("pending", "7", "", annotations)+`]}`)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := &Prometheus{
+		KubernetesDiscovery:     []string{"pods"},
+		Kubeconfig:              writeKubeconfig(t, dir, server.URL, "secret"),
+		KubernetesNamespaces:    []string{"default"},
+		KubernetesLabelSelector: "app=web",
+		KubernetesFieldSelector: "spec.nodeName=node1",
+	}
+	require.NoError(t, p.Start(&testutil.Accumulator{}))
+	defer p.Stop()
+
+	urls := waitTargets(t, p, 1)
+	assert.Equal(t, "http://127.0.0.1:"+port+"/custom", urls[0].Url)
+	assert.Equal(t, "127.0.0.1", urls[0].Address)
+	assert.Equal(t, map[string]string{
+		"namespace": "default",
+		"pod_name":  "web-0",
+		"node_name": "node1",
+		"app":       "web",
+	}, urls[0].Tags)
+
+	query := api.query("/api/v1/namespaces/default/pods")
+	assert.Equal(t, "app=web", query.Get("labelSelector"))
+	assert.Equal(t, "spec.nodeName=node1", query.Get("fieldSelector"))
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+	assert.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	assert.Equal(t, "web-0", acc.TagValue("go_goroutines", "pod_name"))
+	assert.Equal(t, "default", acc.TagValue("go_goroutines", "namespace"))
+	// the labels of the metric are kept over those of the pod
+	assert.Equal(t, "value", acc.TagValue("test_metric", "label"))
+
+	// the pods are added, modified and removed live
+	events := api.watchEvents("/api/v1/namespaces/default/pods")
+	events <- `{"type":"ADDED","object":` + podJSON("web-2", "11", "127.0.0.3", annotations) + `}`
+	waitTargets(t, p, 2)
+	events <- `{"type":"MODIFIED","object":` + podJSON("web-1", "12", "127.0.0.2", annotations) + `}`
+	waitTargets(t, p, 3)
+	events <- `{"type":"DELETED","object":` + podJSON("web-0", "13", "127.0.0.1", annotations) + `}`
+	events <- `{"type":"MODIFIED","object":` + podJSON("web-2", "14", "127.0.0.3", `"prometheus.io/scrape":"false"`) + `}`
+	urls = waitTargets(t, p, 1)
+	assert.Equal(t, "web-1", urls[0].Tags["pod_name"])
+}
+
+func TestKubernetesWatchExpired(t *testing.T) {
+	annotations := `"prometheus.io/scrape":"true"`
+
+	api := newFakeAPI("secret")
+	api.setList("/api/v1/pods", `{"metadata":{"resourceVersion":"10"},"items":[`+
+		podJSON("web-0", "9", "127.0.0.1", annotations)+`]}`)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := &Prometheus{
+		KubernetesDiscovery: []string{"pods"},
+		Kubeconfig:          writeKubeconfig(t, dir, server.URL, "secret"),
+	}
+	require.NoError(t, p.Start(&testutil.Accumulator{}))
+	defer p.Stop()
+
+	urls := waitTargets(t, p, 1)
+	assert.Equal(t, "http://127.0.0.1:9102/metrics", urls[0].Url)
+
+	// the pods are listed again when the watch expires
+	api.setList("/api/v1/pods", `{"metadata":{"resourceVersion":"20"},"items":[`+
+		podJSON("web-1", "19", "127.0.0.2", annotations)+","+
+		podJSON("web-2", "18", "127.0.0.3", annotations)+`]}`)
+	api.watchEvents("/api/v1/pods") <- `{"type":"ERROR","object":{"kind":"Status","code":410,"reason":"Expired","message":"too old resource version"}}`
+
+	urls = waitTargets(t, p, 2)
+	assert.Equal(t, "web-1", urls[0].Tags["pod_name"])
+	assert.Equal(t, "web-2", urls[1].Tags["pod_name"])
+}
+
+func TestKubernetesEndpointsDiscovery(t *testing.T) {
+	api := newFakeAPI("secret")
+	api.setList("/api/v1/endpoints", `{"metadata":{"resourceVersion":"10"},"items":[
+		{"metadata":{"name":"web","namespace":"default","resourceVersion":"9"},"subsets":[{
+			"addresses":[
+				{"ip":"10.0.0.1","nodeName":"node1","targetRef":{"kind":"Pod","name":"web-0"}},
+				{"ip":"10.0.0.2","nodeName":"node2","targetRef":{"kind":"Pod","name":"web-1"}}],
+			"ports":[{"name":"http","port":8080},{"name":"metrics","port":9090}]}]},
+		{"metadata":{"name":"db","namespace":"default","resourceVersion":"8"},"subsets":[{
+			"addresses":[{"ip":"10.0.0.3"}],
+			"ports":[{"name":"sql","port":5432}]}]}]}`)
+	api.setList("/api/v1/services", `{"metadata":{"resourceVersion":"10"},"items":[
+		{"metadata":{"name":"web","namespace":"default","resourceVersion":"7",
+			"annotations":{"prometheus.io/scrape":"true","prometheus.io/port":"9090","prometheus.io/scheme":"https"}}},
+		{"metadata":{"name":"db","namespace":"default","resourceVersion":"6"}}]}`)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := &Prometheus{
+		KubernetesDiscovery:     []string{"endpoints"},
+		Kubeconfig:              writeKubeconfig(t, dir, server.URL, "secret"),
+		KubernetesFieldSelector: "spec.nodeName=node1",
+	}
+	require.NoError(t, p.Start(&testutil.Accumulator{}))
+	defer p.Stop()
+
+	urls := waitTargets(t, p, 2)
+	assert.Equal(t, UrlAndAddress{
+		OriginalUrl: "https://10.0.0.1:9090/metrics",
+		Url:         "https://10.0.0.1:9090/metrics",
+		Address:     "10.0.0.1",
+		Tags: map[string]string{
+			"namespace":    "default",
+			"service_name": "web",
+			"pod_name":     "web-0",
+			"node_name":    "node1",
+		},
+	}, urls[0])
+	assert.Equal(t, "https://10.0.0.2:9090/metrics", urls[1].Url)
+	assert.Equal(t, "", api.query("/api/v1/endpoints").Get("fieldSelector"))
+
+	// the annotations of the services are watched, not read per endpoints
+	api.watchEvents("/api/v1/services") <- `{"type":"MODIFIED","object":{"metadata":{"name":"db","namespace":"default",
+		"resourceVersion":"11","annotations":{"prometheus.io/scrape":"true"}}}}`
+	urls = waitTargets(t, p, 3)
+	assert.Equal(t, "http://10.0.0.3:5432/metrics", urls[0].Url)
+	api.watchEvents("/api/v1/services") <- `{"type":"MODIFIED","object":{"metadata":{"name":"web","namespace":"default",
+		"resourceVersion":"12"}}}`
+	waitTargets(t, p, 1)
+	assert.Equal(t, 0, api.requestCount("/api/v1/namespaces/default/services/web"))
+	assert.Equal(t, 0, api.requestCount("/api/v1/namespaces/default/services/db"))
+
+	api.watchEvents("/api/v1/services") <- `{"type":"DELETED","object":{"metadata":{"name":"db","namespace":"default","resourceVersion":"13"}}}`
+	waitTargets(t, p, 0)
+}
+
+func TestEndpointsTargetsAllPorts(t *testing.T) {
+	var ep endpoints
+	require.NoError(t, json.Unmarshal([]byte(`{"metadata":{"name":"web","namespace":"default"},"subsets":[{
+		"addresses":[{"ip":"10.0.0.1"}],
+		"ports":[{"port":8080},{"port":9090}]}]}`), &ep))
+	var svc service
+	svc.Metadata.Annotations = map[string]string{scrapeAnnotation: "true", pathAnnotation: "/stats"}
+
+	targets := endpointsTargets(ep, svc)
+	require.Len(t, targets, 2)
+	assert.Equal(t, "http://10.0.0.1:8080/stats", targets[0].Url)
+	assert.Equal(t, "http://10.0.0.1:9090/stats", targets[1].Url)
+}
+
+func TestInClusterClient(t *testing.T) {
+	api := newFakeAPI("pod-token")
+	api.setList("/api/v1/pods", `{"metadata":{"resourceVersion":"1"},"items":[]}`)
+	server := httptest.NewTLSServer(api)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "serviceaccount")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("pod-token\n"), 0600))
+
+	defer func(d string) { serviceAccountDir = d }(serviceAccountDir)
+	serviceAccountDir = dir
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+	os.Setenv("KUBERNETES_SERVICE_HOST", host)
+	os.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	client, err := newKubernetesClient("")
+	require.NoError(t, err)
+	var list objectList
+	require.NoError(t, client.getJSON(context.Background(), "/api/v1/pods", nil, &list))
+	assert.Equal(t, "1", list.Metadata.ResourceVersion)
+
+	os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	_, err = newKubernetesClient("")
+	assert.Error(t, err)
+}
+
+func TestInvalidKubernetesDiscovery(t *testing.T) {
+	p := &Prometheus{KubernetesDiscovery: []string{"services"}}
+	assert.Error(t, p.Start(&testutil.Accumulator{}))
+}
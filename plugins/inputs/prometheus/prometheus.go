@@ -1,6 +1,7 @@
 package prometheus
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -25,6 +26,16 @@ type Prometheus struct {
 	// An array of Kubernetes services to scrape metrics from.
 	KubernetesServices []string
 
+	// Kubernetes resources to discover the targets from, "pods" and/or
+	// "endpoints"
+	KubernetesDiscovery []string `toml:"kubernetes_discovery"`
+	// Path of the kubeconfig, the service account of the pod when empty
+	Kubeconfig string
+	// Namespaces of the discovered targets, all when empty
+	KubernetesNamespaces    []string `toml:"kubernetes_namespaces"`
+	KubernetesLabelSelector string   `toml:"kubernetes_label_selector"`
+	KubernetesFieldSelector string   `toml:"kubernetes_field_selector"`
+
 	// Bearer Token authorization file path
 	BearerToken string `toml:"bearer_token"`
 
@@ -40,6 +51,17 @@ type Prometheus struct {
 	InsecureSkipVerify bool
 
 	client *http.Client
+
+	kubernetes *kubernetesClient
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	// targets are the discovered targets by object, e.g. pods/default/web-0
+	targetsMu sync.Mutex
+	targets   map[string][]UrlAndAddress
+	// the watched endpoints and services by object, the targets of the
+	// endpoints follow the annotations of their service
+	endpoints map[string]endpoints
+	services  map[string]service
 }
 
 var sampleConfig = `
@@ -49,6 +71,21 @@ var sampleConfig = `
   ## An array of Kubernetes services to scrape metrics from.
   # kubernetes_services = ["http://my-service-dns.my-namespace:9100/metrics"]
 
+  ## Discover the targets with the Kubernetes API, "pods" scrapes the pods
+  ## annotated with prometheus.io/scrape = "true" and "endpoints" the
+  ## endpoints of the annotated services. The prometheus.io/port,
+  ## prometheus.io/path and prometheus.io/scheme annotations set the URL.
+  # kubernetes_discovery = ["pods"]
+  ## Path of the kubeconfig, the service account of the pod is used when
+  ## empty
+  # kubeconfig = ""
+  ## Namespaces to discover the targets in, all when empty
+  # kubernetes_namespaces = []
+  ## Selectors of the discovered objects, the field selector only applies
+  ## to the pods, e.g. "spec.nodeName=node1"
+  # kubernetes_label_selector = "app=web,env!=dev"
+  # kubernetes_field_selector = ""
+
   ## Use bearer token for authorization
   # bearer_token = /path/to/bearer/token
 
@@ -96,6 +133,8 @@ type UrlAndAddress struct {
 	OriginalUrl string
 	Url         string
 	Address     string
+	// Tags of the discovered targets
+	Tags map[string]string
 }
 
 func (p *Prometheus) GetAllURLs() ([]UrlAndAddress, error) {
@@ -118,6 +157,7 @@ func (p *Prometheus) GetAllURLs() ([]UrlAndAddress, error) {
 			allUrls = append(allUrls, UrlAndAddress{Url: serviceUrl, Address: resolved, OriginalUrl: service})
 		}
 	}
+	allUrls = append(allUrls, p.discoveredURLs()...)
 	return allUrls, nil
 }
 
@@ -151,6 +191,21 @@ func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// Start watches the Kubernetes API when the targets are discovered
+func (p *Prometheus) Start(_ telegraf.Accumulator) error {
+	if len(p.KubernetesDiscovery) == 0 {
+		return nil
+	}
+	return p.startKubernetesDiscovery()
+}
+
+func (p *Prometheus) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+}
+
 var tr = &http.Transport{
 	ResponseHeaderTimeout: time.Duration(3 * time.Second),
 }
@@ -218,6 +273,11 @@ func (p *Prometheus) gatherURL(url UrlAndAddress, acc telegraf.Accumulator) erro
 		if url.Address != "" {
 			tags["address"] = url.Address
 		}
+		for k, v := range url.Tags {
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
 
 		switch metric.Type() {
 		case telegraf.Counter:
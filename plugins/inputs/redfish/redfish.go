@@ -0,0 +1,733 @@
+package redfish
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/type"
+	"github.com/influxdata/telegraf/dcai/util"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	authSession = "session"
+	authBasic   = "basic"
+
+	sessionsPath = "/redfish/v1/SessionService/Sessions"
+	systemsPath  = "/redfish/v1/Systems"
+	chassisPath  = "/redfish/v1/Chassis"
+)
+
+var getLocalDisks = localDisks
+
+// localDisks returns the WWN of the local disks by serial number
+func localDisks() (map[string]string, error) {
+	path, err := util.GetCmdPathInOsPath("smartctl")
+	if err != nil {
+		return nil, err
+	}
+	disks, err := disk.GetLocalDisks(path)
+	if err != nil {
+		return nil, err
+	}
+	return serialWWNs(disks), nil
+}
+
+// serialWWNs maps the serial numbers of disks to their WWN, the serial
+// numbers reported by smartctl may be padded with spaces as the ones of
+// the BMCs
+func serialWWNs(disks []*disk.DiskInfo) map[string]string {
+	wwns := make(map[string]string)
+	for _, d := range disks {
+		if serial := strings.TrimSpace(d.SerialNumber); serial != "" {
+			wwns[serial] = d.WWN
+		}
+	}
+	return wwns
+}
+
+// Redfish gathers the hardware health of servers from their BMC
+type Redfish struct {
+	Servers         []string
+	Username        string
+	Password        string
+	Auth            string
+	ResponseTimeout internal.Duration
+	MatchLocalDisks bool
+	LogServices     []string
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+
+	mu sync.Mutex
+	// sessions by server
+	sessions map[string]session
+	// the last log entries added by log service
+	lastEntries map[string]lastEntries
+}
+
+// session is a Redfish session, it is deleted through its location
+type session struct {
+	token    string
+	location string
+}
+
+// lastEntries are the newest log entries added, the entries created at the
+// same second
+type lastEntries struct {
+	created time.Time
+	ids     map[string]bool
+}
+
+func NewRedfish() *Redfish {
+	return &Redfish{
+		Auth:            authSession,
+		ResponseTimeout: internal.Duration{Duration: 10 * time.Second},
+		LogServices:     []string{"SEL"},
+		sessions:        make(map[string]session),
+		lastEntries:     make(map[string]lastEntries),
+	}
+}
+
+var sampleConfig = `
+  ## The BMCs to gather from, they are gathered concurrently. The path of a
+  ## server, e.g. "https://proxy/bmc1", prefixes the Redfish paths.
+  servers = ["https://192.168.0.100"]
+
+  ## Credentials of the BMCs
+  username = "root"
+  password = "calvin"
+
+  ## Authentication, "session" creates a Redfish session per BMC and reuses
+  ## its token, "basic" sends the credentials with each request
+  # auth = "session"
+
+  ## Maximum time to receive a response
+  # response_timeout = "10s"
+
+  ## Tag the drives without a WWN identifier with the WWN of the local disk
+  ## having their serial number, when the agent runs on the server of the BMC.
+  ## Requires smartctl.
+  # match_local_disks = false
+
+  ## The log services of the systems whose entries are gathered, by Id, e.g.
+  ## SEL for the System Event Log. An empty list disables the log entries.
+  # log_services = ["SEL"]
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification, BMCs usually have self
+  ## signed certificates
+  # insecure_skip_verify = false
+`
+
+func (r *Redfish) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Redfish) Description() string {
+	return "Read hardware health, drives, thermal and power status from Redfish BMCs"
+}
+
+// Validate checks the servers and the authentication
+func (r *Redfish) Validate() []error {
+	var errs []error
+	for _, server := range r.Servers {
+		if u, err := url.Parse(server); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("Invalid server %q", server))
+		}
+	}
+	if r.Auth != authSession && r.Auth != authBasic {
+		errs = append(errs, fmt.Errorf("Invalid auth %q, must be %q or %q", r.Auth, authSession, authBasic))
+	}
+	return errs
+}
+
+func (r *Redfish) Gather(acc telegraf.Accumulator) error {
+	if r.client == nil {
+		tlsCfg, err := internal.GetTLSConfig(
+			r.SSLCert, r.SSLKey, r.SSLCA, r.InsecureSkipVerify)
+		if err != nil {
+			return err
+		}
+		r.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: r.ResponseTimeout.Duration,
+		}
+	}
+
+	var serials map[string]string
+	if r.MatchLocalDisks {
+		var err error
+		if serials, err = getLocalDisks(); err != nil {
+			acc.AddError(fmt.Errorf("Unable to get the local disks: %s", err))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range r.Servers {
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" {
+			acc.AddError(fmt.Errorf("Invalid server %q", server))
+			continue
+		}
+		wg.Add(1)
+		go func(u *url.URL) {
+			defer wg.Done()
+			g := &gatherer{
+				r:       r,
+				acc:     acc,
+				base:    u,
+				name:    u.Host + strings.TrimSuffix(u.Path, "/"),
+				serials: serials,
+			}
+			if err := g.gather(); err != nil {
+				acc.AddError(fmt.Errorf("Unable to gather %s: %s", g.name, err))
+			}
+		}(u)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Stop deletes the sessions, the BMCs limit the number of open sessions
+func (r *Redfish) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, s := range r.sessions {
+		if err := r.deleteSession(s); err != nil {
+			log.Printf("W! Unable to delete the Redfish session of %s: %s\n", name, err)
+		}
+		delete(r.sessions, name)
+	}
+}
+
+// deleteSession deletes a session through its location, if any
+func (r *Redfish) deleteSession(s session) error {
+	if s.location == "" {
+		return nil
+	}
+	req, err := http.NewRequest("DELETE", s.location, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", s.token)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+// logService returns whether the entries of the log service id are gathered
+func (r *Redfish) logService(id string) bool {
+	for _, name := range r.LogServices {
+		if strings.EqualFold(name, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherer walks the resources of a BMC
+type gatherer struct {
+	r    *Redfish
+	acc  telegraf.Accumulator
+	base *url.URL
+	// the BMC, its address and the path of the server
+	name    string
+	serials map[string]string
+}
+
+func (g *gatherer) gather() error {
+	// the systems and the chassis are independent, an error in one does not
+	// prevent gathering the other
+	systemsErr := g.gatherSystems()
+	chassisErr := g.gatherChassis()
+	if systemsErr != nil {
+		return systemsErr
+	}
+	return chassisErr
+}
+
+func (g *gatherer) gatherSystems() error {
+	var systems collection
+	if err := g.get(systemsPath, &systems); err != nil {
+		return err
+	}
+	for _, member := range systems.Members {
+		var s system
+		if err := g.get(member.ODataID, &s); err != nil {
+			g.acc.AddError(err)
+			continue
+		}
+		tags := g.tags(map[string]string{
+			"system_id":     s.ID,
+			"name":          s.Name,
+			"manufacturer":  s.Manufacturer,
+			"model":         s.Model,
+			"serial_number": s.SerialNumber,
+		})
+		fields := statusFields(s.Status)
+		addString(fields, "power_state", s.PowerState)
+		g.add("redfish_system", fields, tags)
+
+		if s.Storage.ODataID != "" {
+			g.gatherStorage(s, s.Storage.ODataID)
+		}
+		if s.LogServices.ODataID != "" && len(g.r.LogServices) > 0 {
+			g.gatherLogServices(s, s.LogServices.ODataID)
+		}
+	}
+	return nil
+}
+
+func (g *gatherer) gatherLogServices(s system, path string) {
+	var services collection
+	if err := g.get(path, &services); err != nil {
+		g.acc.AddError(err)
+		return
+	}
+	for _, member := range services.Members {
+		var ls logService
+		if err := g.get(member.ODataID, &ls); err != nil {
+			g.acc.AddError(err)
+			continue
+		}
+		if g.r.logService(ls.ID) && ls.Entries.ODataID != "" {
+			g.gatherLogEntries(s, ls)
+		}
+	}
+}
+
+// gatherLogEntries adds the log entries created since the previous gather,
+// all of them on the first one. The entries are timestamped with their
+// creation time.
+func (g *gatherer) gatherLogEntries(s system, ls logService) {
+	key := g.name + ls.Entries.ODataID
+	g.r.mu.Lock()
+	last := g.r.lastEntries[key]
+	g.r.mu.Unlock()
+
+	var entries []logEntry
+	for path := ls.Entries.ODataID; path != ""; {
+		var page logEntryCollection
+		if err := g.get(path, &page); err != nil {
+			// the entries are added again by the next gather
+			g.acc.AddError(err)
+			return
+		}
+		entries = append(entries, page.Members...)
+		path = page.NextLink
+	}
+
+	newest := lastEntries{created: last.created, ids: make(map[string]bool)}
+	for id := range last.ids {
+		newest.ids[id] = true
+	}
+	for _, e := range entries {
+		if e.Created == "" && e.ODataID != "" {
+			// a collection of links
+			if err := g.get(e.ODataID, &e); err != nil {
+				g.acc.AddError(err)
+				continue
+			}
+		}
+		created, err := time.Parse(time.RFC3339, e.Created)
+		if err != nil || created.Before(last.created) ||
+			(created.Equal(last.created) && last.ids[e.ID]) {
+			continue
+		}
+		if created.After(newest.created) {
+			newest = lastEntries{created: created, ids: make(map[string]bool)}
+		}
+		if created.Equal(newest.created) {
+			newest.ids[e.ID] = true
+		}
+
+		fields := make(map[string]interface{})
+		addString(fields, "entry_id", e.ID)
+		addString(fields, "message", e.Message)
+		addString(fields, "message_id", e.MessageID)
+		g.acc.AddFields("redfish_log_entry", fields, g.tags(map[string]string{
+			"system_id":      s.ID,
+			"log_service_id": ls.ID,
+			"severity":       e.Severity,
+			"entry_type":     e.EntryType,
+			"sensor_type":    e.SensorType,
+		}), created)
+	}
+
+	g.r.mu.Lock()
+	g.r.lastEntries[key] = newest
+	g.r.mu.Unlock()
+}
+
+func (g *gatherer) gatherStorage(s system, path string) {
+	var storages collection
+	if err := g.get(path, &storages); err != nil {
+		g.acc.AddError(err)
+		return
+	}
+	for _, member := range storages.Members {
+		var st storage
+		if err := g.get(member.ODataID, &st); err != nil {
+			g.acc.AddError(err)
+			continue
+		}
+		g.add("redfish_storage", statusFields(st.Status), g.tags(map[string]string{
+			"system_id":  s.ID,
+			"storage_id": st.ID,
+			"name":       st.Name,
+		}))
+
+		for _, l := range st.Drives {
+			var d drive
+			if err := g.get(l.ODataID, &d); err != nil {
+				g.acc.AddError(err)
+				continue
+			}
+			g.addDrive(s, st, d)
+		}
+	}
+}
+
+func (g *gatherer) addDrive(s system, st storage, d drive) {
+	tags := g.tags(map[string]string{
+		"system_id":     s.ID,
+		"storage_id":    st.ID,
+		"drive_id":      d.ID,
+		"name":          d.Name,
+		"manufacturer":  d.Manufacturer,
+		"model":         d.Model,
+		"serial_number": d.SerialNumber,
+		"media_type":    d.MediaType,
+		"protocol":      d.Protocol,
+		"disk_wwn":      g.driveWWN(d),
+	})
+	fields := statusFields(d.Status)
+	fields["disk_status"] = int(diskStatus(d))
+	if d.CapacityBytes != nil {
+		fields["capacity_bytes"] = *d.CapacityBytes
+	}
+	if d.FailurePredicted != nil {
+		fields["failure_predicted"] = *d.FailurePredicted
+	}
+	addFloat(fields, "predicted_media_life_left_percent", d.PredictedMediaLifeLeftPercent)
+	g.add("redfish_drive", fields, tags)
+}
+
+// driveWWN returns the WWN of a drive in the format of the dcai disks, from
+// its NAA or EUI identifier, or from the local disk with its serial number
+func (g *gatherer) driveWWN(d drive) string {
+	for _, id := range d.Identifiers {
+		switch strings.ToUpper(id.DurableNameFormat) {
+		case "NAA", "EUI":
+			name := strings.ToLower(id.DurableName)
+			name = strings.TrimPrefix(name, "0x")
+			name = strings.TrimPrefix(name, "naa.")
+			name = strings.TrimPrefix(name, "eui.")
+			return strings.Replace(name, ":", "", -1)
+		}
+	}
+	if d.SerialNumber != "" {
+		return g.serials[strings.TrimSpace(d.SerialNumber)]
+	}
+	return ""
+}
+
+// diskStatus maps the health of a drive to the dcai disk status
+func diskStatus(d drive) dcaitype.DiskStatusType {
+	switch d.Status.Health {
+	case "OK":
+		if d.FailurePredicted != nil && *d.FailurePredicted {
+			return dcaitype.DiskStatusWarning
+		}
+		return dcaitype.DiskStatusGood
+	case "Warning":
+		return dcaitype.DiskStatusWarning
+	case "Critical":
+		return dcaitype.DiskStatusCritical
+	}
+	return dcaitype.DiskStatusUnknown
+}
+
+func (g *gatherer) gatherChassis() error {
+	var chassisList collection
+	if err := g.get(chassisPath, &chassisList); err != nil {
+		return err
+	}
+	for _, member := range chassisList.Members {
+		var c chassis
+		if err := g.get(member.ODataID, &c); err != nil {
+			g.acc.AddError(err)
+			continue
+		}
+		g.add("redfish_chassis", statusFields(c.Status), g.tags(map[string]string{
+			"chassis_id": c.ID,
+			"name":       c.Name,
+		}))
+
+		if c.Thermal.ODataID != "" {
+			var t thermal
+			if err := g.get(c.Thermal.ODataID, &t); err != nil {
+				g.acc.AddError(err)
+			} else {
+				g.addThermal(c, t)
+			}
+		}
+		if c.Power.ODataID != "" {
+			var p power
+			if err := g.get(c.Power.ODataID, &p); err != nil {
+				g.acc.AddError(err)
+			} else {
+				g.addPower(c, p)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *gatherer) addThermal(c chassis, t thermal) {
+	for _, temp := range t.Temperatures {
+		fields := statusFields(temp.Status)
+		addFloat(fields, "reading_celsius", temp.ReadingCelsius)
+		addFloat(fields, "upper_threshold_critical", temp.UpperThresholdCritical)
+		addFloat(fields, "upper_threshold_fatal", temp.UpperThresholdFatal)
+		g.add("redfish_temperature", fields, g.tags(map[string]string{
+			"chassis_id": c.ID,
+			"name":       temp.Name,
+		}))
+	}
+	for _, fan := range t.Fans {
+		name := fan.Name
+		if name == "" {
+			// FanName is the deprecated name of Name
+			name = fan.FanName
+		}
+		fields := statusFields(fan.Status)
+		addFloat(fields, "reading", fan.Reading)
+		addString(fields, "reading_units", fan.ReadingUnits)
+		g.add("redfish_fan", fields, g.tags(map[string]string{
+			"chassis_id": c.ID,
+			"name":       name,
+		}))
+	}
+}
+
+func (g *gatherer) addPower(c chassis, p power) {
+	for _, control := range p.PowerControl {
+		fields := make(map[string]interface{})
+		addFloat(fields, "power_consumed_watts", control.PowerConsumedWatts)
+		g.add("redfish_power", fields, g.tags(map[string]string{
+			"chassis_id": c.ID,
+			"name":       control.Name,
+		}))
+	}
+	for _, psu := range p.PowerSupplies {
+		fields := statusFields(psu.Status)
+		addFloat(fields, "power_input_watts", psu.PowerInputWatts)
+		addFloat(fields, "last_power_output_watts", psu.LastPowerOutputWatts)
+		addFloat(fields, "line_input_voltage", psu.LineInputVoltage)
+		addFloat(fields, "power_capacity_watts", psu.PowerCapacityWatts)
+		g.add("redfish_power_supply", fields, g.tags(map[string]string{
+			"chassis_id": c.ID,
+			"name":       psu.Name,
+		}))
+	}
+}
+
+// add adds a metric unless it has no field, e.g. an absent sensor
+func (g *gatherer) add(measurement string, fields map[string]interface{}, tags map[string]string) {
+	if len(fields) > 0 {
+		g.acc.AddFields(measurement, fields, tags)
+	}
+}
+
+// tags returns the non empty tags with the bmc tag
+func (g *gatherer) tags(tags map[string]string) map[string]string {
+	result := map[string]string{"bmc": g.name}
+	for k, v := range tags {
+		if v = strings.TrimSpace(v); v != "" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func statusFields(s status) map[string]interface{} {
+	fields := make(map[string]interface{})
+	addString(fields, "state", s.State)
+	addString(fields, "health", s.Health)
+	addString(fields, "health_rollup", s.HealthRollup)
+	return fields
+}
+
+func addString(fields map[string]interface{}, key string, value string) {
+	if value != "" {
+		fields[key] = value
+	}
+}
+
+func addFloat(fields map[string]interface{}, key string, value *float64) {
+	if value != nil {
+		fields[key] = *value
+	}
+}
+
+// get gets a resource of the BMC. With session auth, a session is created
+// when there is none or when its token was rejected.
+func (g *gatherer) get(path string, v interface{}) error {
+	token, err := g.token(false)
+	if err != nil {
+		return err
+	}
+	resp, err := g.do(path, token)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && g.r.Auth == authSession {
+		resp.Body.Close()
+		if token, err = g.token(true); err != nil {
+			return err
+		}
+		if resp, err = g.do(path, token); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s%s returned HTTP status %s", g.name, path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("Unable to decode %s%s: %s", g.name, path, err)
+	}
+	return nil
+}
+
+func (g *gatherer) do(path string, token string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", g.url(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if g.r.Auth == authSession {
+		req.Header.Set("X-Auth-Token", token)
+	} else {
+		req.SetBasicAuth(g.r.Username, g.r.Password)
+	}
+	return g.r.client.Do(req)
+}
+
+// url returns the URL of a Redfish path, below the path of the server, e.g.
+// when the BMC is behind a proxy
+func (g *gatherer) url(path string) string {
+	u := *g.base
+	u.RawQuery = ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		path, u.RawQuery = path[:i], path[i+1:]
+	}
+	u.Path = strings.TrimSuffix(g.base.Path, "/") + path
+	u.RawPath = ""
+	return u.String()
+}
+
+// location returns the URL of a session from its Location header, a path or
+// an absolute URL
+func (g *gatherer) location(location string) string {
+	if location == "" {
+		return ""
+	}
+	if u, err := url.Parse(location); err == nil && u.IsAbs() {
+		return location
+	}
+	return g.url(location)
+}
+
+// token returns the session token of the BMC, it creates a session when
+// there is none or renew is set. It returns "" with basic auth.
+func (g *gatherer) token(renew bool) (string, error) {
+	if g.r.Auth != authSession {
+		return "", nil
+	}
+
+	g.r.mu.Lock()
+	s, ok := g.r.sessions[g.name]
+	g.r.mu.Unlock()
+	if ok && !renew {
+		return s.token, nil
+	}
+	if ok {
+		// the rejected session may still be open, e.g. after a restart of
+		// the BMC web server, the error of an expired one is expected
+		g.r.deleteSession(s)
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"UserName": g.r.Username,
+		"Password": g.r.Password,
+	})
+	req, err := http.NewRequest("POST", g.url(sessionsPath), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unable to create a session on %s: HTTP status %s", g.name, resp.Status)
+	}
+	s = session{
+		token:    resp.Header.Get("X-Auth-Token"),
+		location: g.location(resp.Header.Get("Location")),
+	}
+	if s.token == "" {
+		return "", fmt.Errorf("Unable to create a session on %s: no X-Auth-Token", g.name)
+	}
+
+	g.r.mu.Lock()
+	g.r.sessions[g.name] = s
+	g.r.mu.Unlock()
+	return s.token, nil
+}
+
+func init() {
+	inputs.Add("redfish", func() telegraf.Input {
+		return NewRedfish()
+	})
+}
@@ -0,0 +1,630 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var resources = map[string]string{
+	"/redfish/v1/Systems": `{
+		"Members": [{"@odata.id": "/redfish/v1/Systems/System.Embedded.1"}]
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1": `{
+		"Id": "System.Embedded.1",
+		"Name": "System",
+		"Manufacturer": "Dell Inc.",
+		"Model": "PowerEdge R740",
+		"SerialNumber": "CN7475189C0075",
+		"PowerState": "On",
+		"Status": {"State": "Enabled", "Health": "Warning", "HealthRollup": "Warning"},
+		"Storage": {"@odata.id": "/redfish/v1/Systems/System.Embedded.1/Storage"},
+		"LogServices": {"@odata.id": "/redfish/v1/Systems/System.Embedded.1/LogServices"}
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/LogServices": `{
+		"Members": [
+			{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/LogServices/Lclog"},
+			{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/LogServices/Sel"}
+		]
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/LogServices/Lclog": `{
+		"Id": "Lclog",
+		"Name": "Lifecycle Controller Log Service",
+		"Entries": {"@odata.id": "/redfish/v1/Systems/System.Embedded.1/LogServices/Lclog/Entries"}
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/LogServices/Sel": `{
+		"Id": "Sel",
+		"Name": "SEL Log Service",
+		"Entries": {"@odata.id": "/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries"}
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries": `{
+		"Members": [
+			{
+				"Id": "2",
+				"Created": "2019-04-15T10:12:33-05:00",
+				"Severity": "Critical",
+				"Message": "The power supply 2 input is lost.",
+				"MessageId": "PSU0003",
+				"EntryType": "SEL",
+				"SensorType": "Power Supply"
+			},
+			{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries/1"}
+		],
+		"Members@odata.nextLink": "/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries?$skip=2"
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries/1": `{
+		"Id": "1",
+		"Created": "2019-04-15T10:12:33-05:00",
+		"Severity": "OK",
+		"Message": "The chassis is closed while the power is off.",
+		"MessageId": "SEC0033",
+		"EntryType": "SEL",
+		"SensorType": "Physical Chassis Security"
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries?$skip=2": `{
+		"Members": [{"Id": "0", "Created": "2019-04-14T08:00:00Z", "Message": "Log cleared."}]
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/Storage": `{
+		"Members": [{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/Storage/RAID.Integrated.1-1"}]
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/Storage/RAID.Integrated.1-1": `{
+		"Id": "RAID.Integrated.1-1",
+		"Name": "PERC H730P Mini",
+		"Status": {"State": "Enabled", "Health": "OK", "HealthRollup": "Warning"},
+		"Drives": [
+			{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/Storage/Drives/Disk.Bay.0"},
+			{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/Storage/Drives/Disk.Bay.1"},
+			{"@odata.id": "/redfish/v1/Systems/System.Embedded.1/Storage/Drives/Disk.Bay.2"}
+		]
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/Storage/Drives/Disk.Bay.0": `{
+		"Id": "Disk.Bay.0",
+		"Name": "Physical Disk 0:1:0",
+		"Manufacturer": "SEAGATE",
+		"Model": "ST600MM0088",
+		"SerialNumber": "W420S3KV",
+		"MediaType": "HDD",
+		"Protocol": "SAS",
+		"CapacityBytes": 599550590976,
+		"FailurePredicted": false,
+		"Identifiers": [{"DurableName": "5000C5005F50E6AB", "DurableNameFormat": "NAA"}],
+		"Status": {"State": "Enabled", "Health": "OK"}
+	}`,
+	"/redfish/v1/Systems/System.Embedded.1/Storage/Drives/Disk.Bay.1": `{
+		"Id": "Disk.Bay.1",
+		"Name": "Solid State Disk 0:1:1",
+		"Manufacturer": "INTEL",
+		"Model": "SSDSC2KB480G7R",
+		"SerialNumber": "BTYS815301ZR480BGN  ",
+		"MediaType": "SSD",
+		"Protocol": "SATA",
+		"CapacityBytes": 479559942144,
+		"FailurePredicted": true,
+		"PredictedMediaLifeLeftPercent": 97,
+		"Status": {"State": "Enabled", "Health": "Warning"}
+	}`,
+	"/redfish/v1/Chassis": `{
+		"Members": [{"@odata.id": "/redfish/v1/Chassis/System.Embedded.1"}]
+	}`,
+	"/redfish/v1/Chassis/System.Embedded.1": `{
+		"Id": "System.Embedded.1",
+		"Name": "Computer System Chassis",
+		"Status": {"State": "Enabled", "Health": "OK", "HealthRollup": "OK"},
+		"Thermal": {"@odata.id": "/redfish/v1/Chassis/System.Embedded.1/Thermal"},
+		"Power": {"@odata.id": "/redfish/v1/Chassis/System.Embedded.1/Power"}
+	}`,
+	"/redfish/v1/Chassis/System.Embedded.1/Thermal": `{
+		"Temperatures": [
+			{"Name": "CPU1 Temp", "ReadingCelsius": 43, "UpperThresholdCritical": 95, "UpperThresholdFatal": 100, "Status": {"State": "Enabled", "Health": "OK"}},
+			{"Name": "CPU2 Temp", "ReadingCelsius": null, "Status": {"State": "Absent"}}
+		],
+		"Fans": [
+			{"FanName": "System Board Fan1A", "Reading": 5880, "ReadingUnits": "RPM", "Status": {"State": "Enabled", "Health": "OK"}}
+		]
+	}`,
+	"/redfish/v1/Chassis/System.Embedded.1/Power": `{
+		"PowerControl": [{"Name": "System Power Control", "PowerConsumedWatts": 238}],
+		"PowerSupplies": [
+			{"Name": "PS1 Status", "PowerInputWatts": 262, "LastPowerOutputWatts": 238, "LineInputVoltage": 232, "PowerCapacityWatts": 750, "Status": {"State": "Enabled", "Health": "OK"}},
+			{"Name": "PS2 Status", "PowerInputWatts": 0, "LineInputVoltage": 0, "PowerCapacityWatts": 750, "Status": {"State": "Enabled", "Health": "Critical"}}
+		]
+	}`,
+}
+
+// mockBMC is a Redfish service supporting session and basic authentication,
+// behind a proxy when its prefix is set
+type mockBMC struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	prefix   string
+	token    string
+	sessions int
+	requests int
+	// the deleted sessions
+	deleted []string
+}
+
+func newMockBMC(t *testing.T) *mockBMC {
+	m := &mockBMC{}
+	m.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.requests++
+
+		if !strings.HasPrefix(r.URL.Path, m.prefix) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, m.prefix)
+		if r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+
+		if r.Method == "DELETE" && strings.HasPrefix(path, sessionsPath+"/") {
+			m.deleted = append(m.deleted, path)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if path == sessionsPath {
+			var creds map[string]string
+			if r.Method != "POST" || json.NewDecoder(r.Body).Decode(&creds) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if creds["UserName"] != "root" || creds["Password"] != "calvin" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			m.sessions++
+			m.token = strings.Repeat("t", m.sessions)
+			w.Header().Set("X-Auth-Token", m.token)
+			w.Header().Set("Location", sessionsPath+"/"+strconv.Itoa(m.sessions))
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		user, password, basic := r.BasicAuth()
+		authorized := (basic && user == "root" && password == "calvin") ||
+			(m.token != "" && r.Header.Get("X-Auth-Token") == m.token)
+		if !authorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, ok := resources[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	return m
+}
+
+// expire invalidates the session token
+func (m *mockBMC) expire() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = ""
+}
+
+func newRedfish(servers ...string) *Redfish {
+	r := NewRedfish()
+	r.Servers = servers
+	r.Username = "root"
+	r.Password = "calvin"
+	r.InsecureSkipVerify = true
+	return r
+}
+
+func host(m *mockBMC) string {
+	return strings.TrimPrefix(m.URL, "https://")
+}
+
+func TestRedfishGather(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	r := newRedfish(bmc.URL)
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+
+	// Disk.Bay.2 is missing
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "Disk.Bay.2 returned HTTP status 404")
+
+	bmcTag := host(bmc)
+	acc.AssertContainsTaggedFields(t, "redfish_system",
+		map[string]interface{}{
+			"state":         "Enabled",
+			"health":        "Warning",
+			"health_rollup": "Warning",
+			"power_state":   "On",
+		},
+		map[string]string{
+			"bmc":           bmcTag,
+			"system_id":     "System.Embedded.1",
+			"name":          "System",
+			"manufacturer":  "Dell Inc.",
+			"model":         "PowerEdge R740",
+			"serial_number": "CN7475189C0075",
+		})
+	acc.AssertContainsTaggedFields(t, "redfish_storage",
+		map[string]interface{}{
+			"state":         "Enabled",
+			"health":        "OK",
+			"health_rollup": "Warning",
+		},
+		map[string]string{
+			"bmc":        bmcTag,
+			"system_id":  "System.Embedded.1",
+			"storage_id": "RAID.Integrated.1-1",
+			"name":       "PERC H730P Mini",
+		})
+	acc.AssertContainsTaggedFields(t, "redfish_drive",
+		map[string]interface{}{
+			"state":             "Enabled",
+			"health":            "OK",
+			"disk_status":       1,
+			"capacity_bytes":    int64(599550590976),
+			"failure_predicted": false,
+		},
+		map[string]string{
+			"bmc":           bmcTag,
+			"system_id":     "System.Embedded.1",
+			"storage_id":    "RAID.Integrated.1-1",
+			"drive_id":      "Disk.Bay.0",
+			"name":          "Physical Disk 0:1:0",
+			"manufacturer":  "SEAGATE",
+			"model":         "ST600MM0088",
+			"serial_number": "W420S3KV",
+			"media_type":    "HDD",
+			"protocol":      "SAS",
+			"disk_wwn":      "5000c5005f50e6ab",
+		})
+	acc.AssertContainsTaggedFields(t, "redfish_drive",
+		map[string]interface{}{
+			"state":                             "Enabled",
+			"health":                            "Warning",
+			"disk_status":                       3,
+			"capacity_bytes":                    int64(479559942144),
+			"failure_predicted":                 true,
+			"predicted_media_life_left_percent": float64(97),
+		},
+		map[string]string{
+			"bmc":           bmcTag,
+			"system_id":     "System.Embedded.1",
+			"storage_id":    "RAID.Integrated.1-1",
+			"drive_id":      "Disk.Bay.1",
+			"name":          "Solid State Disk 0:1:1",
+			"manufacturer":  "INTEL",
+			"model":         "SSDSC2KB480G7R",
+			"serial_number": "BTYS815301ZR480BGN",
+			"media_type":    "SSD",
+			"protocol":      "SATA",
+		})
+	acc.AssertContainsTaggedFields(t, "redfish_chassis",
+		map[string]interface{}{
+			"state":         "Enabled",
+			"health":        "OK",
+			"health_rollup": "OK",
+		},
+		map[string]string{
+			"bmc":        bmcTag,
+			"chassis_id": "System.Embedded.1",
+			"name":       "Computer System Chassis",
+		})
+	acc.AssertContainsTaggedFields(t, "redfish_temperature",
+		map[string]interface{}{
+			"state":                    "Enabled",
+			"health":                   "OK",
+			"reading_celsius":          float64(43),
+			"upper_threshold_critical": float64(95),
+			"upper_threshold_fatal":    float64(100),
+		},
+		map[string]string{"bmc": bmcTag, "chassis_id": "System.Embedded.1", "name": "CPU1 Temp"})
+	acc.AssertContainsTaggedFields(t, "redfish_temperature",
+		map[string]interface{}{"state": "Absent"},
+		map[string]string{"bmc": bmcTag, "chassis_id": "System.Embedded.1", "name": "CPU2 Temp"})
+	acc.AssertContainsTaggedFields(t, "redfish_fan",
+		map[string]interface{}{
+			"state":         "Enabled",
+			"health":        "OK",
+			"reading":       float64(5880),
+			"reading_units": "RPM",
+		},
+		map[string]string{"bmc": bmcTag, "chassis_id": "System.Embedded.1", "name": "System Board Fan1A"})
+	acc.AssertContainsTaggedFields(t, "redfish_power",
+		map[string]interface{}{"power_consumed_watts": float64(238)},
+		map[string]string{"bmc": bmcTag, "chassis_id": "System.Embedded.1", "name": "System Power Control"})
+	acc.AssertContainsTaggedFields(t, "redfish_power_supply",
+		map[string]interface{}{
+			"state":                   "Enabled",
+			"health":                  "OK",
+			"power_input_watts":       float64(262),
+			"last_power_output_watts": float64(238),
+			"line_input_voltage":      float64(232),
+			"power_capacity_watts":    float64(750),
+		},
+		map[string]string{"bmc": bmcTag, "chassis_id": "System.Embedded.1", "name": "PS1 Status"})
+	acc.AssertContainsTaggedFields(t, "redfish_power_supply",
+		map[string]interface{}{
+			"state":                "Enabled",
+			"health":               "Critical",
+			"power_input_watts":    float64(0),
+			"line_input_voltage":   float64(0),
+			"power_capacity_watts": float64(750),
+		},
+		map[string]string{"bmc": bmcTag, "chassis_id": "System.Embedded.1", "name": "PS2 Status"})
+}
+
+func TestRedfishSession(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	r := newRedfish(bmc.URL)
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.NoError(t, r.Gather(&acc))
+	// the session is reused across gathers
+	assert.Equal(t, 1, bmc.sessions)
+	assert.Empty(t, bmc.deleted)
+
+	// a rejected token is renewed, the rejected session is deleted first
+	bmc.expire()
+	acc.ClearMetrics()
+	acc.Errors = nil
+	require.NoError(t, r.Gather(&acc))
+	assert.Equal(t, 2, bmc.sessions)
+	assert.Equal(t, []string{sessionsPath + "/1"}, bmc.deleted)
+	assert.Len(t, acc.Errors, 1)
+	assert.True(t, acc.HasMeasurement("redfish_system"))
+
+	// the session is deleted on stop
+	r.Stop()
+	assert.Equal(t, []string{sessionsPath + "/1", sessionsPath + "/2"}, bmc.deleted)
+	assert.Empty(t, r.sessions)
+}
+
+func TestRedfishStopBeforeGather(t *testing.T) {
+	r := newRedfish("https://192.168.0.100")
+	r.Stop()
+}
+
+func TestRedfishPathPrefix(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+	bmc.prefix = "/bmc1"
+
+	r := newRedfish(bmc.URL + "/bmc1/")
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "Disk.Bay.2 returned HTTP status 404")
+
+	// the BMCs behind a proxy are told apart by the path
+	assert.True(t, acc.HasPoint("redfish_system",
+		map[string]string{
+			"bmc":           host(bmc) + "/bmc1",
+			"system_id":     "System.Embedded.1",
+			"name":          "System",
+			"manufacturer":  "Dell Inc.",
+			"model":         "PowerEdge R740",
+			"serial_number": "CN7475189C0075",
+		}, "power_state", "On"))
+
+	r.Stop()
+	assert.Equal(t, []string{sessionsPath + "/1"}, bmc.deleted)
+}
+
+func TestRedfishLogEntries(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	r := newRedfish(bmc.URL)
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+
+	entries := func() map[string]testutil.Metric {
+		result := make(map[string]testutil.Metric)
+		for _, m := range acc.Metrics {
+			if m.Measurement == "redfish_log_entry" {
+				result[m.Fields["entry_id"].(string)] = *m
+			}
+		}
+		return result
+	}
+	// the entries of the Lclog service are not gathered
+	added := entries()
+	require.Len(t, added, 3)
+	assert.Equal(t, map[string]string{
+		"bmc":            host(bmc),
+		"system_id":      "System.Embedded.1",
+		"log_service_id": "Sel",
+		"severity":       "Critical",
+		"entry_type":     "SEL",
+		"sensor_type":    "Power Supply",
+	}, added["2"].Tags)
+	assert.Equal(t, map[string]interface{}{
+		"entry_id":   "2",
+		"message":    "The power supply 2 input is lost.",
+		"message_id": "PSU0003",
+	}, added["2"].Fields)
+	assert.True(t, added["2"].Time.Equal(time.Date(2019, 4, 15, 15, 12, 33, 0, time.UTC)))
+	assert.Equal(t, "OK", added["1"].Tags["severity"])
+	assert.Equal(t, map[string]string{
+		"bmc":            host(bmc),
+		"system_id":      "System.Embedded.1",
+		"log_service_id": "Sel",
+	}, added["0"].Tags)
+
+	// the next gathers add the new entries only, including those created at
+	// the second of the last ones
+	path := "/redfish/v1/Systems/System.Embedded.1/LogServices/Sel/Entries?$skip=2"
+	page := resources[path]
+	defer func() {
+		resources[path] = page
+	}()
+	resources[path] = `{
+		"Members": [
+			{"Id": "3", "Created": "2019-04-15T15:12:33Z", "Message": "The chassis is open."},
+			{"Id": "0", "Created": "2019-04-14T08:00:00Z", "Message": "Log cleared."}
+		]
+	}`
+	acc.ClearMetrics()
+	require.NoError(t, r.Gather(&acc))
+	added = entries()
+	require.Len(t, added, 1)
+	assert.Contains(t, added, "3")
+
+	acc.ClearMetrics()
+	require.NoError(t, r.Gather(&acc))
+	assert.Empty(t, entries())
+}
+
+func TestRedfishNoLogServices(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	r := newRedfish(bmc.URL)
+	r.LogServices = nil
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	assert.True(t, acc.HasMeasurement("redfish_system"))
+	assert.False(t, acc.HasMeasurement("redfish_log_entry"))
+}
+
+func TestRedfishBadCredentials(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	for _, auth := range []string{authSession, authBasic} {
+		r := newRedfish(bmc.URL)
+		r.Auth = auth
+		r.Password = "wrong"
+		var acc testutil.Accumulator
+		require.NoError(t, r.Gather(&acc))
+		assert.False(t, acc.HasMeasurement("redfish_system"), auth)
+		assert.NotEmpty(t, acc.Errors, auth)
+	}
+}
+
+func TestRedfishBasicAuth(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	r := newRedfish(bmc.URL)
+	r.Auth = authBasic
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	assert.Equal(t, 0, bmc.sessions)
+	assert.True(t, acc.HasMeasurement("redfish_drive"))
+}
+
+func TestRedfishConcurrentServers(t *testing.T) {
+	bmc1 := newMockBMC(t)
+	defer bmc1.Close()
+	bmc2 := newMockBMC(t)
+	defer bmc2.Close()
+
+	r := newRedfish(bmc1.URL, bmc2.URL, "https://127.0.0.1:1")
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+
+	// the unreachable BMC does not prevent gathering the others
+	for _, bmc := range []*mockBMC{bmc1, bmc2} {
+		assert.True(t, acc.HasPoint("redfish_system",
+			map[string]string{
+				"bmc":           host(bmc),
+				"system_id":     "System.Embedded.1",
+				"name":          "System",
+				"manufacturer":  "Dell Inc.",
+				"model":         "PowerEdge R740",
+				"serial_number": "CN7475189C0075",
+			}, "power_state", "On"), host(bmc))
+	}
+	found := false
+	for _, err := range acc.Errors {
+		if strings.Contains(err.Error(), "Unable to gather 127.0.0.1:1") {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRedfishMatchLocalDisks(t *testing.T) {
+	bmc := newMockBMC(t)
+	defer bmc.Close()
+
+	getLocalDisks = func() (map[string]string, error) {
+		return map[string]string{"BTYS815301ZR480BGN": "55cd2e414e5d1c0a"}, nil
+	}
+	defer func() {
+		getLocalDisks = localDisks
+	}()
+
+	r := newRedfish(bmc.URL)
+	r.MatchLocalDisks = true
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+
+	wwns := map[string]string{}
+	for _, m := range acc.Metrics {
+		if m.Measurement == "redfish_drive" {
+			wwns[m.Tags["drive_id"]] = m.Tags["disk_wwn"]
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"Disk.Bay.0": "5000c5005f50e6ab",
+		"Disk.Bay.1": "55cd2e414e5d1c0a",
+	}, wwns)
+}
+
+func TestSerialWWNs(t *testing.T) {
+	wwns := serialWWNs([]*disk.DiskInfo{
+		{SerialNumber: "  BTYS815301ZR480BGN", WWN: "55cd2e414e5d1c0a"},
+		{SerialNumber: "W420S3KV    ", WWN: "5000c5005f50e6ab"},
+		{SerialNumber: "  ", WWN: "5000c5005f50e7cd"},
+	})
+	assert.Equal(t, map[string]string{
+		"BTYS815301ZR480BGN": "55cd2e414e5d1c0a",
+		"W420S3KV":           "5000c5005f50e6ab",
+	}, wwns)
+}
+
+func TestDriveWWN(t *testing.T) {
+	g := &gatherer{}
+	for _, tt := range []struct {
+		id  identifier
+		wwn string
+	}{
+		{identifier{"5000C5005F50E6AB", "NAA"}, "5000c5005f50e6ab"},
+		{identifier{"0x5000C5005F50E6AB", "NAA"}, "5000c5005f50e6ab"},
+		{identifier{"naa.5000c5005f50e6ab", "naa"}, "5000c5005f50e6ab"},
+		{identifier{"00:25:38:5B:71:B0:7E:2F", "EUI"}, "0025385b71b07e2f"},
+		{identifier{"BTYS815301ZR480BGN", "UUID"}, ""},
+	} {
+		assert.Equal(t, tt.wwn, g.driveWWN(drive{Identifiers: []identifier{tt.id}}), tt.id.DurableName)
+	}
+}
+
+func TestRedfishValidate(t *testing.T) {
+	r := newRedfish("https://192.168.0.100")
+	assert.Empty(t, r.Validate())
+
+	r = newRedfish("192.168.0.100", "https://192.168.0.101")
+	r.Auth = "digest"
+	assert.Len(t, r.Validate(), 2)
+}
@@ -0,0 +1,121 @@
+package redfish
+
+// The subset of the Redfish schemas read by the plugin, see
+// https://redfish.dmtf.org/redfish/schema_index
+
+type link struct {
+	ODataID string `json:"@odata.id"`
+}
+
+type collection struct {
+	Members []link
+}
+
+type status struct {
+	State        string
+	Health       string
+	HealthRollup string
+}
+
+type system struct {
+	ID           string `json:"Id"`
+	Name         string
+	Manufacturer string
+	Model        string
+	SerialNumber string
+	PowerState   string
+	Status       status
+	Storage      link
+	LogServices  link
+}
+
+type storage struct {
+	ID     string `json:"Id"`
+	Name   string
+	Status status
+	Drives []link
+}
+
+type identifier struct {
+	DurableName       string
+	DurableNameFormat string
+}
+
+type drive struct {
+	ID                            string `json:"Id"`
+	Name                          string
+	Manufacturer                  string
+	Model                         string
+	SerialNumber                  string
+	MediaType                     string
+	Protocol                      string
+	CapacityBytes                 *int64
+	FailurePredicted              *bool
+	PredictedMediaLifeLeftPercent *float64
+	Identifiers                   []identifier
+	Status                        status
+}
+
+type chassis struct {
+	ID      string `json:"Id"`
+	Name    string
+	Status  status
+	Thermal link
+	Power   link
+}
+
+type thermal struct {
+	Temperatures []struct {
+		Name                   string
+		ReadingCelsius         *float64
+		UpperThresholdCritical *float64
+		UpperThresholdFatal    *float64
+		Status                 status
+	}
+	Fans []struct {
+		Name         string
+		FanName      string
+		Reading      *float64
+		ReadingUnits string
+		Status       status
+	}
+}
+
+type power struct {
+	PowerControl []struct {
+		Name               string
+		PowerConsumedWatts *float64
+	}
+	PowerSupplies []struct {
+		Name                 string
+		PowerInputWatts      *float64
+		LastPowerOutputWatts *float64
+		LineInputVoltage     *float64
+		PowerCapacityWatts   *float64
+		Status               status
+	}
+}
+
+type logService struct {
+	ID      string `json:"Id"`
+	Name    string
+	Entries link
+}
+
+// logEntryCollection has the entries, or their links only, and the link of
+// its next page
+type logEntryCollection struct {
+	Members  []logEntry
+	NextLink string `json:"Members@odata.nextLink"`
+}
+
+type logEntry struct {
+	ODataID    string `json:"@odata.id"`
+	ID         string `json:"Id"`
+	Created    string
+	Severity   string
+	Message    string
+	MessageID  string `json:"MessageId"`
+	EntryType  string
+	SensorType string
+}
@@ -209,6 +209,18 @@ func parseInt(str string) int64 {
 	return 0
 }
 
+// Validate checks that smartctl exists and can be run as root
+func (m *Smart) Validate() []error {
+	path := m.Path
+	if len(path) == 0 {
+		path = "smartctl"
+	}
+	if err := util.ValidateCmd(path, true); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
 func init() {
 	m := Smart{}
 	m.Nocheck = "never"
@@ -0,0 +1,568 @@
+package snmp_trap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	// sysUpTime.0 and snmpTrapOID.0, the first varbinds of a v2c or v3 trap
+	sysUpTimeOid   = ".1.3.6.1.2.1.1.3.0"
+	snmpTrapOidOid = ".1.3.6.1.6.3.1.1.4.1.0"
+	// prefix of the OIDs of the generic traps of SNMPv1, see RFC 3584
+	genericTrapPrefix = ".1.3.6.1.6.3.1.1.5."
+
+	maxPacketSize = 64 * 1024
+)
+
+var (
+	// execCommand is so tests can mock out exec.Command usage.
+	execCommand = exec.Command
+	// unmarshalTrap decodes a trap, it returns nil when the packet is not a
+	// valid trap for the security parameters
+	unmarshalTrap = func(params *gosnmp.GoSNMP, packet []byte) *gosnmp.SnmpPacket {
+		return params.UnmarshalTrap(packet)
+	}
+	now = time.Now
+)
+
+// SnmpTrap receives the SNMP traps sent to it
+type SnmpTrap struct {
+	ServiceAddress string
+	// Values: 1, 2, 3
+	Version uint8
+
+	// Parameters for Version 1 & 2
+	Community string
+
+	// Parameters for Version 3
+	ContextName string
+	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+	SecLevel string
+	SecName  string
+	// Values: "MD5", "SHA", "". Default: ""
+	AuthProtocol string
+	AuthPassword string
+	// Values: "DES", "AES", "". Default: ""
+	PrivProtocol string
+	PrivPassword string
+
+	// OidMap names the OIDs without looking them up in the MIBs, the
+	// longest prefix of an OID wins
+	OidMap map[string]string `toml:"oid_map"`
+	// Translate looks up the OIDs in the MIBs with snmptranslate
+	Translate bool
+	MibDirs   []string
+	// TagOids are the varbinds reported as tags instead of fields
+	TagOids []string
+
+	acc      telegraf.Accumulator
+	params   *gosnmp.GoSNMP
+	conn     net.PacketConn
+	done     chan struct{}
+	wg       sync.WaitGroup
+	accepted map[gosnmp.SnmpVersion]bool
+
+	mu    sync.Mutex
+	cache map[string]oidInfo
+
+	malformed int
+}
+
+// oidInfo is an OID looked up in the OID map or the MIBs
+type oidInfo struct {
+	// oid is the numeric OID of the object, without the index
+	oid   string
+	mib   string
+	name  string
+	index string
+}
+
+// key is the field name of a varbind, the name of the object followed by its
+// index
+func (o oidInfo) key() string {
+	if o.index == "" {
+		return o.name
+	}
+	return o.name + "." + o.index
+}
+
+func NewSnmpTrap() *SnmpTrap {
+	return &SnmpTrap{
+		ServiceAddress: "udp://:162",
+		Version:        2,
+		Translate:      true,
+	}
+}
+
+const sampleConfig = `
+  ## Address to listen on, the protocol is one of udp, udp4 or udp6. Port
+  ## 162 needs root privileges or the CAP_NET_BIND_SERVICE capability.
+  service_address = "udp://:162"
+
+  ## SNMP version of the traps, values can be 1, 2, or 3. Version 1 and 2
+  ## accept the v1 and v2c traps, version 3 only the v3 traps.
+  version = 2
+
+  ## SNMP community string, when set the traps of other communities are
+  ## dropped.
+  # community = "public"
+
+  ## SNMPv3 auth parameters
+  #sec_name = "myuser"
+  #auth_protocol = "md5"      # Values: "MD5", "SHA", ""
+  #auth_password = "pass"
+  #sec_level = "authNoPriv"   # Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+  #context_name = ""
+  #priv_protocol = ""         # Values: "DES", "AES", ""
+  #priv_password = ""
+
+  ## Look up the OIDs in the MIBs with snmptranslate, the OIDs that are not
+  ## found keep their numeric form.
+  translate = true
+  ## Directories added to the MIB search path of snmptranslate
+  # mib_dirs = ["/usr/share/snmp/mibs"]
+
+  ## Varbinds reported as tags instead of fields, by OID or MIB name
+  # tag_oids = ["IF-MIB::ifDescr", ".1.3.6.1.2.1.2.2.1.7"]
+
+  ## Names of OIDs, used before the MIBs. An OID is named after its longest
+  ## prefix in the map, the rest of the OID is kept as its index.
+  # [inputs.snmp_trap.oid_map]
+  #   ".1.3.6.1.4.1.8072.2.3.0.1" = "NET-SNMP-EXAMPLES-MIB::netSnmpExampleHeartbeatNotification"
+  #   ".1.3.6.1.4.1.8072.2.3.2.1" = "netSnmpExampleHeartbeatRate"
+`
+
+func (s *SnmpTrap) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SnmpTrap) Description() string {
+	return "Receive SNMP traps"
+}
+
+// All the work is done in the Start() function, so this is just a dummy
+// function.
+func (s *SnmpTrap) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (s *SnmpTrap) Start(acc telegraf.Accumulator) error {
+	params, err := s.securityParams()
+	if err != nil {
+		return err
+	}
+	s.params = params
+	s.acc = acc
+	s.cache = make(map[string]oidInfo)
+	s.done = make(chan struct{})
+
+	if s.Version == 3 {
+		s.accepted = map[gosnmp.SnmpVersion]bool{gosnmp.Version3: true}
+	} else {
+		s.accepted = map[gosnmp.SnmpVersion]bool{gosnmp.Version1: true, gosnmp.Version2c: true}
+	}
+
+	network, address := "udp", s.ServiceAddress
+	if i := strings.Index(address, "://"); i != -1 {
+		network, address = address[:i], address[i+3:]
+	}
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("Invalid service address %s, the protocol must be udp, udp4 or udp6", s.ServiceAddress)
+	}
+	s.conn, err = net.ListenPacket(network, address)
+	if err != nil {
+		return fmt.Errorf("Unable to listen on %s: %s", s.ServiceAddress, err)
+	}
+
+	s.wg.Add(1)
+	go s.listen()
+
+	log.Printf("I! Started the SNMP trap receiver on %s\n", s.conn.LocalAddr())
+	return nil
+}
+
+func (s *SnmpTrap) Stop() {
+	close(s.done)
+	s.conn.Close()
+	s.wg.Wait()
+	log.Printf("I! Stopped the SNMP trap receiver on %s\n", s.ServiceAddress)
+}
+
+// securityParams builds the parameters decoding the traps
+func (s *SnmpTrap) securityParams() (*gosnmp.GoSNMP, error) {
+	params := &gosnmp.GoSNMP{}
+
+	switch s.Version {
+	case 3:
+		params.Version = gosnmp.Version3
+	case 2, 0:
+		params.Version = gosnmp.Version2c
+	case 1:
+		params.Version = gosnmp.Version1
+	default:
+		return nil, fmt.Errorf("Invalid version %d", s.Version)
+	}
+	params.Community = s.Community
+
+	if s.Version != 3 {
+		return params, nil
+	}
+
+	params.ContextName = s.ContextName
+
+	// gosnmp logs the parsed security parameters unconditionally
+	sp := &gosnmp.UsmSecurityParameters{Logger: log.New(ioutil.Discard, "", 0)}
+	params.SecurityParameters = sp
+	params.SecurityModel = gosnmp.UserSecurityModel
+
+	switch strings.ToLower(s.SecLevel) {
+	case "noauthnopriv", "":
+		params.MsgFlags = gosnmp.NoAuthNoPriv
+	case "authnopriv":
+		params.MsgFlags = gosnmp.AuthNoPriv
+	case "authpriv":
+		params.MsgFlags = gosnmp.AuthPriv
+	default:
+		return nil, fmt.Errorf("Invalid sec_level %s", s.SecLevel)
+	}
+
+	sp.UserName = s.SecName
+
+	switch strings.ToLower(s.AuthProtocol) {
+	case "md5":
+		sp.AuthenticationProtocol = gosnmp.MD5
+	case "sha":
+		sp.AuthenticationProtocol = gosnmp.SHA
+	case "":
+		sp.AuthenticationProtocol = gosnmp.NoAuth
+	default:
+		return nil, fmt.Errorf("Invalid auth_protocol %s", s.AuthProtocol)
+	}
+	sp.AuthenticationPassphrase = s.AuthPassword
+
+	switch strings.ToLower(s.PrivProtocol) {
+	case "des":
+		sp.PrivacyProtocol = gosnmp.DES
+	case "aes":
+		sp.PrivacyProtocol = gosnmp.AES
+	case "":
+		sp.PrivacyProtocol = gosnmp.NoPriv
+	default:
+		return nil, fmt.Errorf("Invalid priv_protocol %s", s.PrivProtocol)
+	}
+	sp.PrivacyPassphrase = s.PrivPassword
+
+	return params, nil
+}
+
+func (s *SnmpTrap) listen() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			if err, ok := err.(net.Error); ok && err.Temporary() {
+				continue
+			}
+			s.acc.AddError(fmt.Errorf("E! Error reading SNMP traps: %s\n", err))
+			return
+		}
+		s.handle(buf[:n], addr)
+	}
+}
+
+// handle decodes a packet and adds the metric of its trap
+func (s *SnmpTrap) handle(packet []byte, addr net.Addr) {
+	trap := unmarshalTrap(s.params, packet)
+	if trap == nil {
+		s.malformed++
+		if s.malformed == 1 || s.malformed%1000 == 0 {
+			log.Printf("E! snmp_trap has received %d malformed packets thus far\n", s.malformed)
+		}
+		return
+	}
+	if !s.accepted[trap.Version] {
+		log.Printf("D! Dropped an SNMP %s trap from %s, version %d is configured\n",
+			versionName(trap.Version), addr, s.Version)
+		return
+	}
+	if s.Community != "" && trap.Version != gosnmp.Version3 && trap.Community != s.Community {
+		log.Printf("D! Dropped an SNMP trap from %s, the community does not match\n", addr)
+		return
+	}
+
+	fields, tags := s.parse(trap)
+	tags["source"] = hostOf(addr)
+	s.acc.AddFields("snmp_trap", fields, tags, now())
+}
+
+// parse returns the fields and the tags of a trap
+func (s *SnmpTrap) parse(trap *gosnmp.SnmpPacket) (map[string]interface{}, map[string]string) {
+	fields := make(map[string]interface{})
+	tags := map[string]string{
+		"version": versionName(trap.Version),
+	}
+
+	var trapOid string
+	if trap.Version == gosnmp.Version1 {
+		trapOid = v1TrapOid(trap)
+		if trap.AgentAddress != "" {
+			tags["agent_address"] = trap.AgentAddress
+		}
+		fields[s.lookup(sysUpTimeOid).key()] = capUint(uint64(trap.Timestamp))
+	}
+
+	for _, pdu := range trap.Variables {
+		name := normalizeOid(pdu.Name)
+		if name == snmpTrapOidOid {
+			if oid, ok := pdu.Value.(string); ok {
+				trapOid = normalizeOid(oid)
+			}
+			continue
+		}
+
+		value := s.convert(pdu)
+		if value == nil {
+			continue
+		}
+		info := s.lookup(name)
+		if s.isTag(info) {
+			tags[info.name] = fmt.Sprint(value)
+		} else {
+			fields[info.key()] = value
+		}
+	}
+
+	if trapOid != "" {
+		info := s.lookup(trapOid)
+		tags["oid"] = trapOid
+		tags["name"] = info.key()
+		if info.mib != "" {
+			tags["mib"] = info.mib
+		}
+	}
+	return fields, tags
+}
+
+// v1TrapOid returns the OID of a v1 trap, mapped as in RFC 3584
+func v1TrapOid(trap *gosnmp.SnmpPacket) string {
+	if trap.GenericTrap >= 0 && trap.GenericTrap < 6 {
+		return genericTrapPrefix + strconv.Itoa(trap.GenericTrap+1)
+	}
+	return normalizeOid(trap.Enterprise) + ".0." + strconv.Itoa(trap.SpecificTrap)
+}
+
+// convert returns the field value of a varbind, nil when it has none
+func (s *SnmpTrap) convert(pdu gosnmp.SnmpPDU) interface{} {
+	switch pdu.Type {
+	case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		return nil
+	case gosnmp.ObjectIdentifier:
+		if oid, ok := pdu.Value.(string); ok {
+			info := s.lookup(normalizeOid(oid))
+			if info.mib != "" {
+				return info.mib + "::" + info.key()
+			}
+			return info.key()
+		}
+	}
+
+	switch v := pdu.Value.(type) {
+	case []byte:
+		if utf8.Valid(v) && isPrintable(v) {
+			return string(v)
+		}
+		return hex.EncodeToString(v)
+	case string:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return capUint(uint64(v))
+	case uint32:
+		return int64(v)
+	case uint64:
+		return capUint(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case nil:
+		return nil
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func capUint(v uint64) int64 {
+	if v > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(v)
+}
+
+func isPrintable(b []byte) bool {
+	for _, r := range string(bytes.TrimRight(b, "\x00")) {
+		if r < ' ' && r != '\t' && r != '\r' && r != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// isTag tells whether a varbind is configured as a tag
+func (s *SnmpTrap) isTag(info oidInfo) bool {
+	for _, t := range s.TagOids {
+		switch t {
+		case info.oid, info.name, info.mib + "::" + info.name:
+			return true
+		}
+	}
+	return false
+}
+
+// lookup names an OID with the OID map, then the MIBs. The OIDs that are not
+// found are named by their numeric form.
+func (s *SnmpTrap) lookup(oid string) oidInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info, ok := s.cache[oid]; ok {
+		return info
+	}
+	info, ok := s.lookupMap(oid)
+	if !ok && s.Translate {
+		var err error
+		info, err = s.translate(oid)
+		if err != nil {
+			log.Printf("D! Unable to translate the OID %s: %s\n", oid, err)
+		}
+	}
+	if info.name == "" {
+		info = oidInfo{oid: oid, name: oid}
+	}
+	s.cache[oid] = info
+	return info
+}
+
+func (s *SnmpTrap) lookupMap(oid string) (oidInfo, bool) {
+	var prefix, mapped string
+	for p, name := range s.OidMap {
+		p = normalizeOid(p)
+		if (oid == p || strings.HasPrefix(oid, p+".")) && len(p) > len(prefix) {
+			prefix, mapped = p, name
+		}
+	}
+	if prefix == "" {
+		return oidInfo{}, false
+	}
+
+	info := oidInfo{oid: prefix, name: mapped}
+	if i := strings.Index(mapped, "::"); i != -1 {
+		info.mib, info.name = mapped[:i], mapped[i+2:]
+	}
+	info.index = strings.TrimPrefix(strings.TrimPrefix(oid, prefix), ".")
+	return info, true
+}
+
+// translate looks up an OID in the MIBs with snmptranslate
+func (s *SnmpTrap) translate(oid string) (oidInfo, error) {
+	args := []string{"-m", "all", "-Ob"}
+	if len(s.MibDirs) > 0 {
+		args = append(args, "-M", "+"+strings.Join(s.MibDirs, ":"))
+	}
+	out, err := execCommand("snmptranslate", append(args, oid)...).Output()
+	if err != nil {
+		if err, ok := err.(*exec.ExitError); ok {
+			return oidInfo{}, fmt.Errorf("%s", bytes.TrimRight(err.Stderr, "\r\n"))
+		}
+		return oidInfo{}, err
+	}
+	return parseTranslation(oid, strings.TrimSpace(string(out))), nil
+}
+
+// parseTranslation parses the MIB::object.index output of snmptranslate
+func parseTranslation(oid string, text string) oidInfo {
+	i := strings.Index(text, "::")
+	if i == -1 {
+		// not found in the MIBs
+		return oidInfo{}
+	}
+	info := oidInfo{mib: text[:i], name: text[i+2:], oid: oid}
+	if j := strings.Index(info.name, "."); j != -1 {
+		info.name, info.index = info.name[:j], info.name[j+1:]
+		// the numeric OID of the object drops the components of the index
+		parts := strings.Split(oid, ".")
+		n := len(parts) - len(strings.Split(info.index, "."))
+		if n > 0 {
+			info.oid = strings.Join(parts[:n], ".")
+		}
+	}
+	return info
+}
+
+func normalizeOid(oid string) string {
+	if oid != "" && !strings.HasPrefix(oid, ".") {
+		return "." + oid
+	}
+	return oid
+}
+
+func versionName(v gosnmp.SnmpVersion) string {
+	switch v {
+	case gosnmp.Version1:
+		return "1"
+	case gosnmp.Version2c:
+		return "2c"
+	case gosnmp.Version3:
+		return "3"
+	default:
+		return fmt.Sprint(uint8(v))
+	}
+}
+
+func hostOf(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func init() {
+	inputs.Add("snmp_trap", func() telegraf.Input {
+		return NewSnmpTrap()
+	})
+}
@@ -0,0 +1,403 @@
+package snmp_trap
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mockedTranslations = map[string]string{
+	".1.3.6.1.2.1.1.3.0":        "DISMAN-EVENT-MIB::sysUpTimeInstance",
+	".1.3.6.1.6.3.1.1.5.3":      "IF-MIB::linkDown",
+	".1.3.6.1.2.1.2.2.1.1.2":    "IF-MIB::ifIndex.2",
+	".1.3.6.1.2.1.2.2.1.2.2":    "IF-MIB::ifDescr.2",
+	".1.3.6.1.2.1.2.2.1.7.2":    "IF-MIB::ifAdminStatus.2",
+	".1.3.6.1.2.1.2.2.1.8.2":    "IF-MIB::ifOperStatus.2",
+	".1.3.6.1.6.3.1.1.5.1":      "SNMPv2-MIB::coldStart",
+	".1.3.6.1.4.1.8072.2.3.0.1": "NET-SNMP-EXAMPLES-MIB::netSnmpExampleHeartbeatNotification",
+}
+
+func mockExecCommand(arg0 string, args ...string) *exec.Cmd {
+	args = append([]string{"-test.run=TestMockExecCommand", "--", arg0}, args...)
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// This is not a real test. This is just a way of mocking out snmptranslate.
+func TestMockExecCommand(t *testing.T) {
+	var cmd []string
+	for _, arg := range os.Args {
+		if arg == "--" {
+			cmd = []string{}
+			continue
+		}
+		if cmd != nil {
+			cmd = append(cmd, arg)
+		}
+	}
+	if cmd == nil {
+		return
+	}
+
+	oid := cmd[len(cmd)-1]
+	text, ok := mockedTranslations[oid]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: Unknown Object Identifier\n", oid)
+		os.Exit(2)
+	}
+	fmt.Println(text)
+	os.Exit(0)
+}
+
+func init() {
+	execCommand = mockExecCommand
+}
+
+func linkDown(version gosnmp.SnmpVersion) *gosnmp.SnmpPacket {
+	return &gosnmp.SnmpPacket{
+		Version:   version,
+		Community: "public",
+		PDUType:   gosnmp.SNMPv2Trap,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(123456)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+			{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.3.6.1.2.1.2.2.1.2.2", Type: gosnmp.OctetString, Value: []byte("eth1")},
+			{Name: ".1.3.6.1.2.1.2.2.1.7.2", Type: gosnmp.Integer, Value: 1},
+			{Name: ".1.3.6.1.2.1.2.2.1.8.2", Type: gosnmp.Integer, Value: 2},
+		},
+	}
+}
+
+func newTestTrap(t *testing.T, s *SnmpTrap, packets ...*gosnmp.SnmpPacket) *testutil.Accumulator {
+	params, err := s.securityParams()
+	require.NoError(t, err)
+	s.params = params
+	s.cache = make(map[string]oidInfo)
+	if s.Version == 3 {
+		s.accepted = map[gosnmp.SnmpVersion]bool{gosnmp.Version3: true}
+	} else {
+		s.accepted = map[gosnmp.SnmpVersion]bool{gosnmp.Version1: true, gosnmp.Version2c: true}
+	}
+
+	acc := &testutil.Accumulator{}
+	s.acc = acc
+	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.10"), Port: 40000}
+	for _, p := range packets {
+		unmarshalTrap = func(*gosnmp.GoSNMP, []byte) *gosnmp.SnmpPacket { return p }
+		s.handle([]byte{}, addr)
+	}
+	return acc
+}
+
+func TestV2cTrapTranslated(t *testing.T) {
+	s := NewSnmpTrap()
+	s.TagOids = []string{"IF-MIB::ifDescr"}
+	acc := newTestTrap(t, s, linkDown(gosnmp.Version2c))
+
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			"sysUpTimeInstance": int64(123456),
+			"ifIndex.2":         int64(2),
+			"ifAdminStatus.2":   int64(1),
+			"ifOperStatus.2":    int64(2),
+		},
+		map[string]string{
+			"source":  "192.168.1.10",
+			"version": "2c",
+			"oid":     ".1.3.6.1.6.3.1.1.5.3",
+			"name":    "linkDown",
+			"mib":     "IF-MIB",
+			"ifDescr": "eth1",
+		})
+}
+
+func TestV2cTrapNumeric(t *testing.T) {
+	s := NewSnmpTrap()
+	s.Translate = false
+	s.TagOids = []string{".1.3.6.1.2.1.2.2.1.2.2"}
+	acc := newTestTrap(t, s, linkDown(gosnmp.Version2c))
+
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			".1.3.6.1.2.1.1.3.0":     int64(123456),
+			".1.3.6.1.2.1.2.2.1.1.2": int64(2),
+			".1.3.6.1.2.1.2.2.1.7.2": int64(1),
+			".1.3.6.1.2.1.2.2.1.8.2": int64(2),
+		},
+		map[string]string{
+			"source":                 "192.168.1.10",
+			"version":                "2c",
+			"oid":                    ".1.3.6.1.6.3.1.1.5.3",
+			"name":                   ".1.3.6.1.6.3.1.1.5.3",
+			".1.3.6.1.2.1.2.2.1.2.2": "eth1",
+		})
+}
+
+func TestOidMap(t *testing.T) {
+	s := NewSnmpTrap()
+	s.OidMap = map[string]string{
+		".1.3.6.1.2.1.2.2.1":     "ifEntry",
+		".1.3.6.1.2.1.2.2.1.2":   "IF-MIB::ifDescr",
+		"1.3.6.1.6.3.1.1.5.3":    "IF-MIB::linkDown",
+		".1.3.6.1.2.1.2.2.1.8.2": "operStatus",
+	}
+	s.TagOids = []string{".1.3.6.1.2.1.2.2.1.2"}
+	acc := newTestTrap(t, s, linkDown(gosnmp.Version2c))
+
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			"sysUpTimeInstance": int64(123456),
+			"ifEntry.1.2":       int64(2),
+			"ifEntry.7.2":       int64(1),
+			"operStatus":        int64(2),
+		},
+		map[string]string{
+			"source":  "192.168.1.10",
+			"version": "2c",
+			"oid":     ".1.3.6.1.6.3.1.1.5.3",
+			"name":    "linkDown",
+			"mib":     "IF-MIB",
+			"ifDescr": "eth1",
+		})
+}
+
+func TestV1Trap(t *testing.T) {
+	generic := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version1,
+		Community: "public",
+		PDUType:   gosnmp.Trap,
+		SnmpTrap: gosnmp.SnmpTrap{
+			Enterprise:   ".1.3.6.1.4.1.8072.3.2.10",
+			AgentAddress: "192.168.1.1",
+			GenericTrap:  0,
+			Timestamp:    42,
+		},
+	}
+	specific := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version1,
+		Community: "public",
+		PDUType:   gosnmp.Trap,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.4.1.8072.2.3.2.1", Type: gosnmp.Integer, Value: 30},
+		},
+		SnmpTrap: gosnmp.SnmpTrap{
+			Enterprise:   "1.3.6.1.4.1.8072.2.3",
+			AgentAddress: "192.168.1.1",
+			GenericTrap:  6,
+			SpecificTrap: 1,
+			Timestamp:    43,
+		},
+	}
+	s := NewSnmpTrap()
+	acc := newTestTrap(t, s, generic, specific)
+
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{"sysUpTimeInstance": int64(42)},
+		map[string]string{
+			"source":        "192.168.1.10",
+			"version":       "1",
+			"agent_address": "192.168.1.1",
+			"oid":           ".1.3.6.1.6.3.1.1.5.1",
+			"name":          "coldStart",
+			"mib":           "SNMPv2-MIB",
+		})
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			"sysUpTimeInstance":         int64(43),
+			".1.3.6.1.4.1.8072.2.3.2.1": int64(30),
+		},
+		map[string]string{
+			"source":        "192.168.1.10",
+			"version":       "1",
+			"agent_address": "192.168.1.1",
+			"oid":           ".1.3.6.1.4.1.8072.2.3.0.1",
+			"name":          "netSnmpExampleHeartbeatNotification",
+			"mib":           "NET-SNMP-EXAMPLES-MIB",
+		})
+}
+
+func TestDroppedTraps(t *testing.T) {
+	other := linkDown(gosnmp.Version2c)
+	other.Community = "private"
+
+	s := NewSnmpTrap()
+	s.Community = "public"
+	acc := newTestTrap(t, s, other, linkDown(gosnmp.Version3), nil, linkDown(gosnmp.Version2c))
+	assert.Len(t, acc.Metrics, 1)
+	assert.Equal(t, 1, s.malformed)
+
+	s = NewSnmpTrap()
+	s.Version = 3
+	s.SecName = "user"
+	s.SecLevel = "authPriv"
+	s.AuthProtocol = "SHA"
+	s.AuthPassword = "password"
+	s.PrivProtocol = "AES"
+	s.PrivPassword = "password"
+	acc = newTestTrap(t, s, linkDown(gosnmp.Version2c), linkDown(gosnmp.Version3))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, "3", acc.Metrics[0].Tags["version"])
+}
+
+func TestSecurityParams(t *testing.T) {
+	s := NewSnmpTrap()
+	s.Version = 3
+	s.SecName = "user"
+	s.SecLevel = "authPriv"
+	s.AuthProtocol = "md5"
+	s.AuthPassword = "authpass"
+	s.PrivProtocol = "des"
+	s.PrivPassword = "privpass"
+	params, err := s.securityParams()
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.Version3, params.Version)
+	assert.Equal(t, gosnmp.AuthPriv, params.MsgFlags)
+	sp := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	assert.Equal(t, "user", sp.UserName)
+	assert.Equal(t, gosnmp.MD5, sp.AuthenticationProtocol)
+	assert.Equal(t, gosnmp.DES, sp.PrivacyProtocol)
+
+	for _, bad := range []func(*SnmpTrap){
+		func(s *SnmpTrap) { s.Version = 4 },
+		func(s *SnmpTrap) { s.SecLevel = "private" },
+		func(s *SnmpTrap) { s.AuthProtocol = "sha512" },
+		func(s *SnmpTrap) { s.PrivProtocol = "3des" },
+	} {
+		s := NewSnmpTrap()
+		s.Version = 3
+		bad(s)
+		_, err := s.securityParams()
+		assert.Error(t, err)
+	}
+}
+
+func TestParseTranslation(t *testing.T) {
+	info := parseTranslation(".1.3.6.1.2.1.2.2.1.2.2", "IF-MIB::ifDescr.2")
+	assert.Equal(t, oidInfo{oid: ".1.3.6.1.2.1.2.2.1.2", mib: "IF-MIB", name: "ifDescr", index: "2"}, info)
+
+	info = parseTranslation(".1.3.6.1.6.3.1.1.5.3", "IF-MIB::linkDown")
+	assert.Equal(t, oidInfo{oid: ".1.3.6.1.6.3.1.1.5.3", mib: "IF-MIB", name: "linkDown"}, info)
+
+	assert.Equal(t, oidInfo{}, parseTranslation(".1.2.3", ".1.2.3"))
+}
+
+func TestListener(t *testing.T) {
+	received := make(chan []byte, 1)
+	unmarshalTrap = func(_ *gosnmp.GoSNMP, packet []byte) *gosnmp.SnmpPacket {
+		received <- append([]byte{}, packet...)
+		return linkDown(gosnmp.Version2c)
+	}
+
+	s := NewSnmpTrap()
+	s.ServiceAddress = "udp://127.0.0.1:0"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("trap"))
+	require.NoError(t, err)
+
+	select {
+	case packet := <-received:
+		assert.Equal(t, "trap", string(packet))
+	case <-time.After(5 * time.Second):
+		t.Fatal("The trap was not received")
+	}
+	acc.Wait(1)
+	assert.Equal(t, "127.0.0.1", acc.Metrics[0].Tags["source"])
+}
+
+// TestDecodeTraps sends traps encoded by gosnmp to the receiver
+func TestDecodeTraps(t *testing.T) {
+	defer func(f func(*gosnmp.GoSNMP, []byte) *gosnmp.SnmpPacket) { unmarshalTrap = f }(unmarshalTrap)
+	unmarshalTrap = func(params *gosnmp.GoSNMP, packet []byte) *gosnmp.SnmpPacket {
+		return params.UnmarshalTrap(packet)
+	}
+
+	sendTrap := func(t *testing.T, s *SnmpTrap, sender *gosnmp.GoSNMP, trap gosnmp.SnmpTrap) *testutil.Accumulator {
+		s.ServiceAddress = "udp://127.0.0.1:0"
+		acc := &testutil.Accumulator{}
+		require.NoError(t, s.Start(acc))
+		defer s.Stop()
+
+		sender.Target = "127.0.0.1"
+		sender.Port = uint16(s.conn.LocalAddr().(*net.UDPAddr).Port)
+		sender.Timeout = time.Second
+		require.NoError(t, sender.Connect())
+		defer sender.Conn.Close()
+		_, err := sender.SendTrap(trap)
+		require.NoError(t, err)
+
+		acc.Wait(1)
+		return acc
+	}
+	linkDownTrap := gosnmp.SnmpTrap{Variables: linkDown(gosnmp.Version2c).Variables}
+
+	s := NewSnmpTrap()
+	acc := sendTrap(t, s, &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"}, linkDownTrap)
+	assert.Equal(t, "2c", acc.Metrics[0].Tags["version"])
+	assert.Equal(t, "linkDown", acc.Metrics[0].Tags["name"])
+	assert.Equal(t, "eth1", acc.Metrics[0].Fields["ifDescr.2"])
+
+	s = NewSnmpTrap()
+	acc = sendTrap(t, s, &gosnmp.GoSNMP{Version: gosnmp.Version1, Community: "public"}, gosnmp.SnmpTrap{
+		Enterprise:   ".1.3.6.1.4.1.8072.3.2.10",
+		AgentAddress: "192.168.1.1",
+		GenericTrap:  0,
+		Timestamp:    42,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2},
+		},
+	})
+	assert.Equal(t, "1", acc.Metrics[0].Tags["version"])
+	assert.Equal(t, "coldStart", acc.Metrics[0].Tags["name"])
+	assert.Equal(t, "192.168.1.1", acc.Metrics[0].Tags["agent_address"])
+	assert.Equal(t, int64(2), acc.Metrics[0].Fields["ifIndex.2"])
+
+	s = NewSnmpTrap()
+	s.Version = 3
+	s.SecName = "user"
+	s.SecLevel = "authPriv"
+	s.AuthProtocol = "SHA"
+	s.AuthPassword = "authpassword"
+	s.PrivProtocol = "AES"
+	s.PrivPassword = "privpassword"
+	acc = sendTrap(t, s, &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityModel: gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID:    "8000000001020304",
+			UserName:                 "user",
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "authpassword",
+			PrivacyProtocol:          gosnmp.AES,
+			PrivacyPassphrase:        "privpassword",
+		},
+	}, linkDownTrap)
+	assert.Equal(t, "3", acc.Metrics[0].Tags["version"])
+	assert.Equal(t, "linkDown", acc.Metrics[0].Tags["name"])
+}
+
+func TestInvalidServiceAddress(t *testing.T) {
+	s := NewSnmpTrap()
+	s.ServiceAddress = "tcp://:162"
+	err := s.Start(&testutil.Accumulator{})
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "protocol"))
+}
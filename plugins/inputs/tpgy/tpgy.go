@@ -6,7 +6,6 @@ import (
 	"github.com/influxdata/telegraf/dcai/event"
 	saicluster "github.com/influxdata/telegraf/dcai/sai/cluster"
 	"github.com/influxdata/telegraf/dcai/topology/host"
-	"github.com/influxdata/telegraf/dcai/topology/host/linux"
 	"github.com/influxdata/telegraf/dcai/type"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
@@ -50,14 +49,9 @@ func (t *Tpgy) Gather(acc telegraf.Accumulator) error {
 }
 
 func (t *Tpgy) gatherTpgy(acc telegraf.Accumulator, saiClusterDomainId string, saiClusterName string, h host.HostConfig) error {
-	ostype := h.GetOsType()
-	if ostype == dcaitype.OSLinux {
-		linuxhost := h.(*linux.LinuxHostConfig)
-
-		host.CreateSaiHostDataPoint(acc, linuxhost.DomainID(), linuxhost.Hostname(), linuxhost.HWID(), saiClusterDomainId, linuxhost.OSType, linuxhost.OSName, linuxhost.OSVersion, linuxhost.IPv4s(), linuxhost.IPv6s())
-		saicluster.CreateSaiClusterDataPoint(acc, saiClusterDomainId, saiClusterName)
-		event.SendMetricsMonitoring(acc, linuxhost, saiClusterDomainId, "1 point(s) of Host are written to DB", dcaitype.EventTitleHostDataSent, dcaitype.LogLevelInfo)
-	}
+	host.CreateSaiHostDataPoint(acc, h.DomainID(), h.Hostname(), h.HWID(), saiClusterDomainId, h.GetOsType(), h.GetOsName(), h.GetOsVersion(), h.IPv4s(), h.IPv6s())
+	saicluster.CreateSaiClusterDataPoint(acc, saiClusterDomainId, saiClusterName)
+	event.SendMetricsMonitoring(acc, h, saiClusterDomainId, "1 point(s) of Host are written to DB", dcaitype.EventTitleHostDataSent, dcaitype.LogLevelInfo)
 	return nil
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/telegraf/dcai/topology/datacenter"
 	"github.com/influxdata/telegraf/dcai/topology/host"
 	"github.com/influxdata/telegraf/dcai/topology/host/linux"
+	"github.com/influxdata/telegraf/dcai/topology/host/windows"
 	"github.com/influxdata/telegraf/dcai/type"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -32,6 +33,16 @@ var (
 		},
 	}
 
+	windowshost = windows.WindowsHostConfig{
+		Name:      "WIN-SRV01",
+		OSType:    dcaitype.OSWindows,
+		OSName:    "Microsoft Windows Server 2016 Standard",
+		OSVersion: "10.0.14393",
+		NICs: []*nic.NetworkInfo{
+			&nic.NetworkInfo{Name: "Intel(R) 82574L Gigabit Network Connection", MACs: []string{"00:0c:29:4b:3a:10"}, IPv4s: []string{"172.31.86.120"}, IPv6s: []string{"fe80::b5c1:2e0d:41c8:9a3b"}},
+		},
+	}
+
 	Disks = []*disk.DiskInfo{
 		&disk.DiskInfo{
 			Name:              "/dev/sda",
@@ -114,3 +125,27 @@ func TestGatherTpgy(t *testing.T) {
 		acc.AssertContainsTaggedFields(t, "sai_cluster", test.fields, test.tags)
 	}
 }
+
+func TestGatherTpgyWindows(t *testing.T) {
+
+	s := &Tpgy{}
+
+	var acc testutil.Accumulator
+
+	s.gatherTpgy(&acc, "dpCluster", "DiskProphet for Lab Test", &windowshost)
+
+	acc.AssertContainsTaggedFields(t, "sai_host",
+		map[string]interface{}{
+			"host_uuid":         windowshost.HWID(),
+			"name":              "WIN-SRV01",
+			"cluster_domain_id": "dpCluster",
+			"os_type":           "windows",
+			"os_name":           "Microsoft Windows Server 2016 Standard",
+			"os_version":        "10.0.14393",
+			"host_ip":           "172.31.86.120",
+			"host_ipv6":         "fe80::b5c1:2e0d:41c8:9a3b",
+		},
+		map[string]string{
+			"domain_id": windowshost.HWID(),
+		})
+}
@@ -0,0 +1,106 @@
+package vsanhealth
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/dcai/connector/vcsa"
+	"github.com/influxdata/telegraf/dcai/topology/vsandiskgroup"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// VsanHealth collects vSAN disk, disk group and cluster health from vCenter
+type VsanHealth struct {
+	Urls [][]string
+}
+
+var sampleConfig = `
+## The vCenters of the vSAN clusters.
+##
+## Multiple urls can be specified as different vCenter cluster,
+## please follow the inputs order as below
+## urls = [
+##		["First_VCenter_name","First_VCenter_URL","First_VCenter_User","First_VCenter_PW"],
+##		["Second_VCenter_name","Second_VCenter_URL","Second_VCenter_User","Second_VCenter_PW"],
+##		...
+##	]
+## e.g.
+## urls = [
+##		["vc1","192.168.0.1","john","qwerad"],
+##		["vc2","192.168.0.2","peter","akdfljd"]
+##	]
+##
+urls = [[]]
+`
+
+// SampleConfig returns sampleConfig
+func (v *VsanHealth) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns description of vsanhealth plugin
+func (v *VsanHealth) Description() string {
+	return "Read vSAN disk, disk group and cluster health from vCenter"
+}
+
+// Gather collects vSAN health of every vCenter
+func (v *VsanHealth) Gather(acc telegraf.Accumulator) error {
+	dcaiAgent, err := dcai.GetDcaiAgent()
+	if err != nil {
+		return err
+	}
+
+	for i, urls := range v.Urls {
+		if len(urls) == 0 {
+			log.Printf("W! Need to put vCenter information!")
+			continue
+		}
+
+		if len(urls) != 4 {
+			acc.AddError(fmt.Errorf("the %d_th vsphere configuration is incorrect! ", i+1))
+			continue
+		}
+
+		vc, err := vcsa.NewVcsaConnector(urls[0], urls[1], urls[2], urls[3], true)
+		if err != nil {
+			acc.AddError(fmt.Errorf("failed to connect '%v", err))
+			continue
+		}
+
+		health, err := dcai.FetchVsanHealth(vc)
+		if err != nil {
+			acc.AddError(fmt.Errorf("Cannot get vSAN health from %s. %s", vc.Url, err))
+			continue
+		}
+
+		gatherVsanHealth(acc, dcaiAgent.GetSaiClusterDomainId(), health)
+	}
+
+	return nil
+}
+
+func gatherVsanHealth(acc telegraf.Accumulator, saiClusterDomainId string, health *vsandiskgroup.VsanHealth) {
+	for _, hh := range health.Hosts {
+		// the domain id of an esxi host is its uuid
+		for _, d := range hh.Disks {
+			vsandiskgroup.CreateSaiVsanDiskDataPoint(acc, saiClusterDomainId, hh.HostUuid, hh.ClusterUuid, d)
+		}
+		for _, dg := range hh.Diskgroups {
+			vsandiskgroup.CreateSaiVsanDiskgroupDataPoint(acc, saiClusterDomainId, hh.HostUuid, hh.ClusterUuid, dg)
+		}
+	}
+	for _, c := range health.Clusters {
+		vsandiskgroup.CreateSaiVsanClusterDataPoint(acc, saiClusterDomainId, c)
+	}
+}
+
+// Validate checks the vCenter tuples of urls
+func (v *VsanHealth) Validate() []error {
+	return vcsa.ValidateUrls(v.Urls)
+}
+
+func init() {
+	inputs.Add("vsanhealth", func() telegraf.Input { return &VsanHealth{} })
+}
@@ -0,0 +1,160 @@
+package vsanhealth
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai/topology/vsandiskgroup"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var (
+	cacheDisk = &vsandiskgroup.VsanDiskHealth{
+		Name:             "naa.55cd2e404b7ee0e9",
+		WWN:              "55cd2e404b7ee0e9",
+		VsanUuid:         "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01",
+		DiskgroupUuid:    "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01",
+		IsSsd:            true,
+		IsCache:          true,
+		State:            "inUse",
+		OperationalState: []string{"ok"},
+		Components:       0,
+		Congestion:       -1,
+	}
+	capacityDisk = &vsandiskgroup.VsanDiskHealth{
+		Name:             "naa.5000c5005f50e6ab",
+		WWN:              "5000c5005f50e6ab",
+		VsanUuid:         "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a02",
+		DiskgroupUuid:    "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01",
+		State:            "inUse",
+		OperationalState: []string{"ok"},
+		Degraded:         true,
+		Components:       12,
+		Congestion:       120,
+		CongestionHealth: "yellow",
+	}
+
+	health = &vsandiskgroup.VsanHealth{
+		Hosts: []*vsandiskgroup.VsanHostHealth{
+			&vsandiskgroup.VsanHostHealth{
+				HostName:    "esxi-01",
+				HostUuid:    "4c4c4544-0058-4b10-8032-b7c04f4a4832",
+				ClusterUuid: "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+				Disks:       []*vsandiskgroup.VsanDiskHealth{cacheDisk, capacityDisk},
+				Diskgroups: []*vsandiskgroup.VsanDiskgroupHealth{
+					&vsandiskgroup.VsanDiskgroupHealth{
+						Uuid:          "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01",
+						CacheDisk:     cacheDisk,
+						CapacityDisks: []*vsandiskgroup.VsanDiskHealth{capacityDisk},
+						Mounted:       true,
+					},
+				},
+			},
+		},
+		Clusters: []*vsandiskgroup.VsanClusterHealth{
+			&vsandiskgroup.VsanClusterHealth{
+				Name:          "cluster-01",
+				Uuid:          "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+				OverallHealth: "yellow",
+				Checks: []*vsandiskgroup.VsanHealthCheck{
+					&vsandiskgroup.VsanHealthCheck{
+						GroupId:     "com.vmware.vsan.health.test.physicaldisks",
+						GroupName:   "Physical disk",
+						GroupHealth: "yellow",
+						TestId:      "com.vmware.vsan.health.test.physdiskcongestion",
+						TestName:    "Congestion",
+						TestHealth:  "yellow",
+					},
+					&vsandiskgroup.VsanHealthCheck{
+						GroupId:     "com.vmware.vsan.health.test.physicaldisks",
+						GroupName:   "Physical disk",
+						GroupHealth: "yellow",
+						TestId:      "com.vmware.vsan.health.test.physdiskoverall",
+						TestName:    "Operation health",
+						TestHealth:  "green",
+					},
+				},
+			},
+		},
+	}
+)
+
+func TestGatherVsanHealth(t *testing.T) {
+	var acc testutil.Accumulator
+
+	gatherVsanHealth(&acc, "dpCluster", health)
+
+	acc.AssertContainsTaggedFields(t, "sai_vsan_disk",
+		map[string]interface{}{
+			"cluster_domain_id": "dpCluster",
+			"host_domain_id":    "4c4c4544-0058-4b10-8032-b7c04f4a4832",
+			"vsan_cluster_uuid": "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+			"vsan_uuid":         "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a02",
+			"diskgroup_uuid":    "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01",
+			"is_ssd":            false,
+			"is_cache":          false,
+			"state":             "inUse",
+			"operational_state": "ok",
+			"degraded":          true,
+			"error":             "",
+			"health":            "degraded",
+			"health_flags":      int64(0),
+			"components":        int64(12),
+			"congestion":        int64(120),
+			"congestion_health": "degraded",
+		},
+		map[string]string{
+			"disk_name":      "naa.5000c5005f50e6ab",
+			"disk_wwn":       "5000c5005f50e6ab",
+			"disk_domain_id": "5000c5005f50e6ab",
+			"primary_key":    "dpCluster-4c4c4544-0058-4b10-8032-b7c04f4a4832-5000c5005f50e6ab",
+		})
+
+	acc.AssertContainsTaggedFields(t, "sai_vsan_diskgroup",
+		map[string]interface{}{
+			"cluster_domain_id":    "dpCluster",
+			"host_domain_id":       "4c4c4544-0058-4b10-8032-b7c04f4a4832",
+			"vsan_cluster_uuid":    "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+			"capacity_disk_wwns":   "5000c5005f50e6ab",
+			"mounted":              true,
+			"health":               "degraded",
+			"disk_count":           int64(2),
+			"degraded_disk_count":  int64(1),
+			"unhealthy_disk_count": int64(0),
+			"components":           int64(12),
+		},
+		map[string]string{
+			"disk_wwn":            "55cd2e404b7ee0e9",
+			"diskgroup_domain_id": "52c4a6a1-0a7c-1cd1-7b2e-1c8a4e8e3a01",
+			"primary_key":         "dpCluster-4c4c4544-0058-4b10-8032-b7c04f4a4832-55cd2e404b7ee0e9",
+		})
+
+	acc.AssertContainsTaggedFields(t, "sai_vsan_cluster",
+		map[string]interface{}{
+			"cluster_domain_id":     "dpCluster",
+			"cluster_name":          "cluster-01",
+			"health":                "degraded",
+			"check_count":           int64(2),
+			"degraded_check_count":  int64(1),
+			"unhealthy_check_count": int64(0),
+		},
+		map[string]string{
+			"vsan_cluster_uuid": "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+			"primary_key":       "dpCluster-52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+		})
+
+	acc.AssertContainsTaggedFields(t, "sai_vsan_health_check",
+		map[string]interface{}{
+			"cluster_domain_id": "dpCluster",
+			"cluster_name":      "cluster-01",
+			"group_name":        "Physical disk",
+			"group_health":      "degraded",
+			"test_name":         "Congestion",
+			"health":            "degraded",
+		},
+		map[string]string{
+			"vsan_cluster_uuid": "52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5",
+			"group_id":          "com.vmware.vsan.health.test.physicaldisks",
+			"test_id":           "com.vmware.vsan.health.test.physdiskcongestion",
+			"primary_key":       "dpCluster-52b1a2c3-d4e5-f6a7-b8c9-d0e1f2a3b4c5-com.vmware.vsan.health.test.physdiskcongestion",
+		})
+}
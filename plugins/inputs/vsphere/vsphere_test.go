@@ -1,11 +1,15 @@
 package vsphere
 
 import (
+	"context"
 	"os"
 	"testing"
 
+	"github.com/influxdata/telegraf/dcai/testutil/vcsim"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 func TestConnection(t *testing.T) {
@@ -26,3 +30,80 @@ func TestConnection(t *testing.T) {
 	}
 
 }
+
+// TestSummarySimulator walks the vcsim inventory and checks the summary
+// measurements. The performance counters are injected as vcsim does not
+// serve the performance manager.
+func TestSummarySimulator(t *testing.T) {
+	sim, err := vcsim.New(vcsim.DefaultTopology)
+	require.NoError(t, err)
+	defer sim.Close()
+
+	v := &VSphere{
+		Server:   sim.Address(),
+		Username: sim.Username(),
+		Password: sim.Password(),
+		Insecure: true,
+	}
+	require.NoError(t, v.Connect())
+	defer v.Disconnect()
+
+	v.objectMap = &ObjectMap{metricToName: map[int32]string{2: "cpu_usage_average"}}
+	v.Summary = &Summary{}
+
+	ctx := context.Background()
+	dcs, err := v.getDatacenters(ctx)
+	require.NoError(t, err)
+	require.Len(t, dcs, vcsim.DefaultTopology.Datacenter)
+
+	mors, err := v.getAllManagedObjectReference(ctx, dcs)
+	require.NoError(t, err)
+	require.NoError(t, v.setSummaryObjectMap(ctx, mors))
+
+	hosts := sim.Hosts()
+	assert.Len(t, v.Summary.hostSummary, len(hosts))
+	assert.Len(t, v.Summary.vmSummary, len(sim.VirtualMachines()))
+
+	host := hosts[0]
+	performances := &types.QueryPerfResponse{
+		Returnval: []types.BasePerfEntityMetricBase{
+			&types.PerfEntityMetric{
+				PerfEntityMetricBase: types.PerfEntityMetricBase{Entity: host.Self},
+				Value: []types.BasePerfMetricSeries{
+					&types.PerfMetricIntSeries{
+						PerfMetricSeries: types.PerfMetricSeries{Id: types.PerfMetricId{CounterId: 2}},
+						Value:            []int64{10, 20, 30},
+					},
+				},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	v.SetAcc(&acc, performances)
+
+	acc.AssertContainsTaggedFields(t, "hostsystem",
+		map[string]interface{}{
+			"cpu_usage_average":   int64(20),
+			"uptime":              int64(host.Summary.QuickStats.Uptime),
+			"cpu_corecount_total": int64(host.Summary.Hardware.NumCpuThreads),
+		},
+		map[string]string{
+			"name":      host.Summary.Config.Name,
+			"host_uuid": vcsim.HostUuid(0),
+			"cluster":   v.objectMap.hostToCluster[host.Self],
+		})
+
+	for i, ds := range sim.Datastores() {
+		acc.AssertContainsTaggedFields(t, "datastore",
+			map[string]interface{}{
+				"capacity":   ds.Summary.Capacity,
+				"free_space": ds.Summary.FreeSpace,
+			},
+			map[string]string{
+				"ds_name": ds.Summary.Name,
+				"uuid":    vcsim.DatastoreUuid(i),
+			})
+	}
+	assert.True(t, acc.HasMeasurement("resourcepool"))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/telegraf/dcai/hardware/disk"
 	"github.com/influxdata/telegraf/dcai/topology/host/vmware/esxi"
 	"github.com/influxdata/telegraf/dcai/type"
+	"github.com/influxdata/telegraf/dcai/util"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -107,6 +108,24 @@ func (m *VsphereSmart) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// Validate checks the esxi tuples of vspheres and sshpass, which is used to
+// run smartctl on the esxi hosts
+func (m *VsphereSmart) Validate() []error {
+	var errs []error
+	if len(m.Vspheres) == 0 {
+		errs = append(errs, fmt.Errorf("Invalid vsphere server list"))
+	}
+	for i, c := range m.Vspheres {
+		if len(c) < 4 {
+			errs = append(errs, fmt.Errorf("Insufficient vsphere server parameters at index %d", i))
+		}
+	}
+	if err := util.ValidateCmd("sshpass", false); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 func init() {
 	m := VsphereSmart{}
 	inputs.Add("vspheresmart", func() telegraf.Input {
@@ -125,6 +125,11 @@ func (n *Vspheretpgy) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// Validate checks the vCenter tuples of urls
+func (n *Vspheretpgy) Validate() []error {
+	return vcsa.ValidateUrls(n.Urls)
+}
+
 func init() {
 	inputs.Add("vspheretpgy", func() telegraf.Input { return &Vspheretpgy{} })
 }
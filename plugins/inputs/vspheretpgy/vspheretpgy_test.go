@@ -0,0 +1,106 @@
+package vspheretpgy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/dcai/testutil/vcsim"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mockConfig = &config.Config{
+	Agent: &config.AgentConfig{
+		AgentType: "vmware",
+	},
+}
+
+// countRelationship counts the db_relay cypher commands merging relationship
+func countRelationship(acc *testutil.Accumulator, relationship string) int {
+	n := 0
+	for _, m := range acc.Metrics {
+		if m.Measurement != "db_relay" {
+			continue
+		}
+		if cmd, ok := m.Fields["cmd"].(string); ok && strings.Contains(cmd, ":"+relationship+"]") {
+			n++
+		}
+	}
+	return n
+}
+
+func countMeasurement(acc *testutil.Accumulator, measurement string) int {
+	n := 0
+	for _, m := range acc.Metrics {
+		if m.Measurement == measurement {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGatherSimulator(t *testing.T) {
+	topology := vcsim.DefaultTopology
+	sim, err := vcsim.New(topology)
+	require.NoError(t, err)
+	defer sim.Close()
+
+	dcai.NewDcaiAgent(mockConfig, "1.5.0", "", "test", "")
+
+	var acc testutil.Accumulator
+	v := &Vspheretpgy{
+		Urls: [][]string{{"vcsim", sim.Address(), sim.Username(), sim.Password()}},
+	}
+	require.NoError(t, v.Gather(&acc))
+	assert.Empty(t, acc.Errors)
+
+	hosts := sim.Hosts()
+	datastores := 0
+	diskgroups := 0
+	for _, h := range hosts {
+		datastores += len(h.Datastore)
+		if h.Config.VsanHostConfig.StorageInfo != nil {
+			diskgroups += len(h.Config.VsanHostConfig.StorageInfo.DiskMapInfo)
+		}
+	}
+	vms := len(sim.VirtualMachines())
+
+	assert.Equal(t, len(hosts), countMeasurement(&acc, "sai_host"))
+	assert.Equal(t, topology.Datacenter, countRelationship(&acc, "VmDataCenterContainsVmCluster"))
+	assert.Equal(t, len(hosts), countRelationship(&acc, "VmClusterContainsVmHost"))
+	assert.Equal(t, len(hosts)*(topology.LunsPerHost+1), countRelationship(&acc, "VmHostContainsVmDisk"))
+	assert.Equal(t, datastores, countRelationship(&acc, "VmHostHasVmDatastore"))
+	assert.Equal(t, datastores, countRelationship(&acc, "VmDatastoreComposesOfVmDisk"))
+	assert.Equal(t, topology.Cluster*topology.ClusterHost, diskgroups)
+	assert.Equal(t, diskgroups, countRelationship(&acc, "VmHostHasVmDiskGroup"))
+	assert.Equal(t, vms, countRelationship(&acc, "VmHostHostsVmVirtualMachine"))
+	assert.Equal(t, vms, countRelationship(&acc, "VmVirtualMachineUsesVmDatastore"))
+	assert.Equal(t, vms*topology.Snapshots, countRelationship(&acc, "VmVirtualMachineTakesVmSnapshot"))
+
+	// every disk node carries the WWN reported by the simulated LUNs
+	for i := range hosts {
+		for j := 0; j < topology.LunsPerHost; j++ {
+			found := false
+			for _, m := range acc.Metrics {
+				if cmd, ok := m.Fields["cmd"].(string); ok && strings.Contains(cmd, "domainId:'"+vcsim.LunWWN(i, j)+"'") {
+					found = true
+					break
+				}
+			}
+			assert.True(t, found, "expected a VMDisk node for %s", vcsim.LunName(i, j))
+		}
+	}
+}
+
+func TestGatherIncorrectConfig(t *testing.T) {
+	var acc testutil.Accumulator
+	v := &Vspheretpgy{
+		Urls: [][]string{{"vcsim", "127.0.0.1:443"}},
+	}
+	require.NoError(t, v.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "the 1_th vsphere configuration is incorrect")
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/dcai/event"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	aiservice_serializer "github.com/influxdata/telegraf/plugins/serializers/aiservice"
 )
 
 // Aiservice struct is the primary data structure for the plugin
@@ -40,7 +41,7 @@ var (
 	loginInterval          = time.Minute * 50
 	loginURL               = "https://api.aiservice.io/devdcaccount/v1/login"
 	aiserviceURL           = "https://api.aiservice.io/devdp/v1/metrics/"
-	writeAmountOfBatchData = 100
+	writeAmountOfBatchData = aiservice_serializer.DefaultBatchSize
 )
 
 var sampleConfig = `
@@ -132,18 +133,20 @@ func (i *Aiservice) Write(metrics []telegraf.Metric) error {
 		return err
 	}
 
-	// block metrics
-	measurementsOfBlocksMetricsArray := createBlockMetricsArray(metrics)
-
-	for measurement, blocksMetricsArray := range measurementsOfBlocksMetricsArray {
-
-		for _, blockMetrics := range blocksMetricsArray {
-			payloadBytes := buildPayloadOfAiServiceCloudAPIMetrics(blockMetrics)
-			body := bytes.NewReader(payloadBytes)
-			_, err := i.makeAndDoRequest(metric, aiserviceURL+measurement, body)
-			if err != nil {
-				return err
-			}
+	// block metrics, as the aiservice data format
+	serializer := aiservice_serializer.AiserviceSerializer{
+		GroupByMeasurement: true,
+		BatchSize:          writeAmountOfBatchData,
+	}
+	for _, block := range serializer.Blocks(metrics) {
+		payloadBytes, err := aiservice_serializer.Points(block.Metrics)
+		if err != nil {
+			return err
+		}
+		body := bytes.NewReader(payloadBytes)
+		_, err = i.makeAndDoRequest(metric, aiserviceURL+block.Measurement, body)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -156,6 +159,25 @@ func newAiservice() *Aiservice {
 	}
 }
 
+// Validate checks the credentials and the URLs of the aiservice
+func (i *Aiservice) Validate() []error {
+	var errs []error
+	if i.Username == "" || i.Password == "" {
+		errs = append(errs, fmt.Errorf("username and password are required"))
+	}
+	if i.LoginURL != "" {
+		if err := checkURL(i.LoginURL); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid login_url: %s", err))
+		}
+	}
+	if i.URL != "" {
+		if err := checkURL(i.URL); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid url: %s", err))
+		}
+	}
+	return errs
+}
+
 func init() {
 	outputs.Add("aiservice", func() telegraf.Output { return newAiservice() })
 }
@@ -241,42 +263,6 @@ func (i *Aiservice) getAuthority(url string, body io.Reader) error {
 	return nil
 }
 
-// merge metric.Fields and metric.Tags
-// return payloadbytes
-func buildPayloadOfAiServiceCloudAPIMetrics(metrics []telegraf.Metric) []byte {
-	var payloadBytes []byte
-
-	type Tmp struct {
-		Points []map[string]interface{} `json:"points"`
-	}
-
-	var payload = Tmp{Points: []map[string]interface{}{}}
-
-	// claim a null map to restore maps
-	var data map[string]interface{}
-
-	for _, metric := range metrics {
-		data = map[string]interface{}{}
-
-		data["time"] = fmt.Sprintf("%d", metric.Time().UnixNano())
-
-		// merge both mertic.Fields() and metric.Tags()
-		for k, v := range metric.Tags() {
-			data[k] = v
-		}
-		for k, v := range metric.Fields() {
-			data[k] = v
-		}
-
-		payload.Points = append(payload.Points, data)
-	}
-
-	payloadBytes, _ = json.Marshal(payload)
-
-	return payloadBytes
-
-}
-
 func (i *Aiservice) checkAuthoriyExpiration() error {
 	if (time.Now().UnixNano() - i.refreshTime.UnixNano()) > loginInterval.Nanoseconds() {
 		err := i.Connect()
@@ -286,35 +272,3 @@ func (i *Aiservice) checkAuthoriyExpiration() error {
 	}
 	return nil
 }
-
-// make block before send request
-// avoid send request frequency
-func createBlockMetricsArray(metrics []telegraf.Metric) map[string][][]telegraf.Metric {
-	measurementsOfBlocksMetricsArray := map[string][][]telegraf.Metric{}
-	for _, metric := range metrics {
-
-		measurement := metric.Name()
-		blocksMetricArray, exist := measurementsOfBlocksMetricsArray[measurement]
-
-		if exist == true {
-
-			lastBlockMetricsIndex := len(blocksMetricArray) - 1
-			lastBlockMetrics := blocksMetricArray[lastBlockMetricsIndex]
-
-			if len(lastBlockMetrics) < writeAmountOfBatchData {
-
-				lastBlockMetrics = append(lastBlockMetrics, metric)
-				measurementsOfBlocksMetricsArray[measurement][lastBlockMetricsIndex] = lastBlockMetrics
-			} else {
-
-				blockMetrics := []telegraf.Metric{metric}
-				measurementsOfBlocksMetricsArray[measurement] = append(measurementsOfBlocksMetricsArray[measurement], blockMetrics)
-			}
-
-		} else {
-			blockMetrics := []telegraf.Metric{metric}
-			measurementsOfBlocksMetricsArray[measurement] = append(measurementsOfBlocksMetricsArray[measurement], blockMetrics)
-		}
-	}
-	return measurementsOfBlocksMetricsArray
-}
@@ -6,15 +6,22 @@ import (
 	"os"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/rotate"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
 type File struct {
-	Files []string
+	Files               []string
+	RotationInterval    internal.Duration `toml:"rotation_interval"`
+	RotationMaxSize     int64             `toml:"rotation_max_size"`
+	RotationMaxArchives int               `toml:"rotation_max_archives"`
+	RotationGzip        bool              `toml:"rotation_gzip"`
 
 	writer  io.Writer
 	closers []io.Closer
+	files   []*rotate.File
 
 	serializer serializers.Serializer
 }
@@ -23,6 +30,18 @@ var sampleConfig = `
   ## Files to write to, "stdout" is a specially handled file.
   files = ["stdout", "/tmp/metrics.out"]
 
+  ## The files are rotated once rotation_interval passed since they were
+  ## opened or before they grow larger than rotation_max_size bytes, the
+  ## rotated files are suffixed by the time of their rotation, e.g.
+  ## /tmp/metrics.2017-11-20T10-15-30Z.out. 0 disables the rotation.
+  # rotation_interval = "0h"
+  # rotation_max_size = 0
+  ## Number of rotated files kept per file, the oldest ones are removed
+  ## above it, -1 keeps them all
+  # rotation_max_archives = 5
+  ## Gzip the rotated files
+  # rotation_gzip = false
+
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -45,25 +64,34 @@ func (f *File) Connect() error {
 		if file == "stdout" {
 			writers = append(writers, os.Stdout)
 		} else {
-			var of *os.File
-			var err error
-			if _, err := os.Stat(file); os.IsNotExist(err) {
-				of, err = os.Create(file)
-			} else {
-				of, err = os.OpenFile(file, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
-			}
-
+			of, err := rotate.NewFile(file, f.RotationInterval.Duration,
+				f.RotationMaxSize, f.RotationMaxArchives, f.RotationGzip)
 			if err != nil {
 				return err
 			}
 			writers = append(writers, of)
 			f.closers = append(f.closers, of)
+			f.files = append(f.files, of)
 		}
 	}
 	f.writer = io.MultiWriter(writers...)
 	return nil
 }
 
+// Reopen reopens the files, the agent calls it on SIGUSR1
+func (f *File) Reopen() error {
+	var errS string
+	for _, of := range f.files {
+		if err := of.Reopen(); err != nil {
+			errS += err.Error() + "\n"
+		}
+	}
+	if errS != "" {
+		return fmt.Errorf(errS)
+	}
+	return nil
+}
+
 func (f *File) Close() error {
 	var errS string
 	for _, c := range f.closers {
@@ -71,6 +99,8 @@ func (f *File) Close() error {
 			errS += err.Error() + "\n"
 		}
 	}
+	f.closers = nil
+	f.files = nil
 	if errS != "" {
 		return fmt.Errorf(errS)
 	}
@@ -90,6 +120,17 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	if s, ok := f.serializer.(serializers.BatchSerializer); ok {
+		b, err := s.SerializeBatch(metrics)
+		if err != nil {
+			return fmt.Errorf("failed to serialize message: %s", err)
+		}
+		if _, err = f.writer.Write(b); err != nil {
+			return fmt.Errorf("failed to write message: %s", err)
+		}
+		return nil
+	}
+
 	for _, metric := range metrics {
 		b, err := f.serializer.Serialize(metric)
 		if err != nil {
@@ -105,6 +146,8 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 
 func init() {
 	outputs.Add("file", func() telegraf.Output {
-		return &File{}
+		return &File{
+			RotationMaxArchives: 5,
+		}
 	})
 }
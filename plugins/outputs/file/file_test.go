@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -170,6 +171,67 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fh := filepath.Join(dir, "metrics.out")
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:               []string{fh},
+		RotationMaxSize:     int64(len(expNewFile)),
+		RotationMaxArchives: 5,
+		serializer:          s,
+	}
+
+	err = f.Connect()
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = f.Write(testutil.MockMetrics())
+		assert.NoError(t, err)
+	}
+	err = f.Close()
+	assert.NoError(t, err)
+
+	validateFile(fh, expNewFile, t)
+	archives, err := filepath.Glob(filepath.Join(dir, "metrics.*Z.out"))
+	assert.NoError(t, err)
+	if assert.Len(t, archives, 1) {
+		validateFile(archives[0], expNewFile, t)
+	}
+}
+
+func TestFileReopen(t *testing.T) {
+	fh := tmpFile()
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:      []string{fh},
+		serializer: s,
+	}
+
+	err := f.Connect()
+	assert.NoError(t, err)
+
+	err = f.Write(testutil.MockMetrics())
+	assert.NoError(t, err)
+
+	// as by logrotate
+	err = os.Rename(fh, fh+".1")
+	assert.NoError(t, err)
+	err = f.Reopen()
+	assert.NoError(t, err)
+
+	err = f.Write(testutil.MockMetrics())
+	assert.NoError(t, err)
+	err = f.Close()
+	assert.NoError(t, err)
+
+	validateFile(fh+".1", expNewFile, t)
+	validateFile(fh, expNewFile, t)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
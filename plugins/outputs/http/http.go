@@ -0,0 +1,402 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/retry"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// HTTP sends the metrics serialized in any data format to an HTTP endpoint
+type HTTP struct {
+	URL               string
+	Method            string
+	Timeout           internal.Duration
+	ContentEncoding   string            `toml:"content_encoding"`
+	Headers           map[string]string `toml:"headers"`
+	MetricsPerRequest int               `toml:"metrics_per_request"`
+
+	MaxRetries       int
+	RetryBackoff     internal.Duration
+	RetryStatusCodes []int `toml:"retry_status_codes"`
+
+	Username string
+	Password string
+	// Bearer Token authorization file path
+	BearerToken string `toml:"bearer_token"`
+
+	// OAuth2 client credentials
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	TokenURL     string   `toml:"token_url"`
+	Scopes       []string `toml:"scopes"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client     *http.Client
+	serializer serializers.Serializer
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func NewHTTP() *HTTP {
+	return &HTTP{
+		Method:           "POST",
+		Timeout:          internal.Duration{Duration: 5 * time.Second},
+		ContentEncoding:  "identity",
+		MaxRetries:       3,
+		RetryBackoff:     internal.Duration{Duration: 500 * time.Millisecond},
+		RetryStatusCodes: []int{429, 500, 502, 503, 504},
+	}
+}
+
+var sampleConfig = `
+  ## URL the metrics are sent to
+  url = "http://127.0.0.1:8080/metric"
+
+  ## HTTP method, one of "POST", "PUT" or "PATCH"
+  # method = "POST"
+
+  ## Timeout of a request
+  # timeout = "5s"
+
+  ## Number of metrics of a request, the metrics of a write are split into
+  ## several requests above it. 0 sends each write in a single request.
+  # metrics_per_request = 0
+
+  ## Compress the requests, "identity" or "gzip"
+  # content_encoding = "identity"
+
+  ## Additional HTTP headers
+  # [outputs.http.headers]
+  #   Content-Type = "text/plain; charset=utf-8"
+
+  ## Retries of a request failing with a network error or with one of the
+  ## retry_status_codes, the wait between them doubles from retry_backoff.
+  ## The metrics of the failed requests stay in the buffer.
+  # max_retries = 3
+  # retry_backoff = "500ms"
+  # retry_status_codes = [429, 500, 502, 503, 504]
+
+  ## Basic authentication
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+  ## Use bearer token for authorization
+  # bearer_token = /path/to/bearer/token
+
+  ## OAuth2 client credentials, the access token is requested from the
+  ## token_url and renewed when it expires
+  # client_id = "clientid"
+  # client_secret = "secret"
+  # token_url = "https://identityprovider/oauth2/v1/token"
+  # scopes = ["urn:opc:idm:__myscopes__"]
+
+  ## Optional SSL Config
+  # ssl_ca = /path/to/cafile
+  # ssl_cert = /path/to/certfile
+  # ssl_key = /path/to/keyfile
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+func (h *HTTP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTP) Description() string {
+	return "A plugin that can transmit metrics over HTTP"
+}
+
+func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
+	h.serializer = serializer
+}
+
+// Validate checks the URLs, the method, the encoding and the OAuth2 settings
+func (h *HTTP) Validate() []error {
+	var errs []error
+	if err := checkURL(h.URL); err != nil {
+		errs = append(errs, err)
+	}
+	switch strings.ToUpper(h.Method) {
+	case "POST", "PUT", "PATCH":
+	default:
+		errs = append(errs, fmt.Errorf("Invalid method %s, must be POST, PUT or PATCH", h.Method))
+	}
+	switch h.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		errs = append(errs, fmt.Errorf("Invalid content_encoding %s, must be identity or gzip", h.ContentEncoding))
+	}
+	if h.MetricsPerRequest < 0 {
+		errs = append(errs, fmt.Errorf("Invalid metrics_per_request %d, must be at least 0", h.MetricsPerRequest))
+	}
+	if h.ClientID != "" || h.ClientSecret != "" || h.TokenURL != "" {
+		if h.ClientID == "" || h.ClientSecret == "" || h.TokenURL == "" {
+			errs = append(errs, fmt.Errorf("The OAuth2 client credentials need client_id, client_secret and token_url"))
+		} else if err := checkURL(h.TokenURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("Invalid url %s: %s", s, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Invalid url %s, the scheme must be http or https", s)
+	}
+	return nil
+}
+
+func (h *HTTP) Connect() error {
+	tlsCfg, err := internal.GetTLSConfig(h.SSLCert, h.SSLKey, h.SSLCA, h.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	h.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: h.Timeout.Duration,
+	}
+	return nil
+}
+
+func (h *HTTP) Close() error {
+	return nil
+}
+
+// Write sends the metrics in requests of MetricsPerRequest metrics. When a
+// request fails, the metrics of the previous requests are not sent again.
+func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	size := h.MetricsPerRequest
+	if size <= 0 {
+		size = len(metrics)
+	}
+	for start := 0; start < len(metrics); start += size {
+		end := start + size
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+
+		body, err := h.serialize(metrics[start:end])
+		if err == nil {
+			err = h.send(body)
+		} else {
+			err = fmt.Errorf("failed to serialize message: %s", err)
+		}
+		if err != nil {
+			if start > 0 {
+				return &telegraf.PartialWriteError{Err: err, Unsent: metrics[start:]}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// serialize serializes the metrics of a request, as a batch when the data
+// format groups the metrics
+func (h *HTTP) serialize(metrics []telegraf.Metric) ([]byte, error) {
+	if s, ok := h.serializer.(serializers.BatchSerializer); ok {
+		return s.SerializeBatch(metrics)
+	}
+	var body []byte
+	for _, m := range metrics {
+		b, err := h.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, b...)
+	}
+	return body, nil
+}
+
+// send sends a request, retrying it on the transient errors
+func (h *HTTP) send(body []byte) error {
+	if h.ContentEncoding == "gzip" {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	return retry.Do("http", h.MaxRetries, h.RetryBackoff.Duration, func() (bool, error) {
+		return h.do(body)
+	})
+}
+
+// do sends a request body, it returns whether a failed request may be
+// retried
+func (h *HTTP) do(body []byte) (bool, error) {
+	method := strings.ToUpper(h.Method)
+	if method == "" {
+		method = "POST"
+	}
+	req, err := http.NewRequest(method, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", "Telegraf")
+	if h.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range h.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	if err := h.authorize(req); err != nil {
+		// the token endpoint or file may be back at the next attempt
+		return true, err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("Error sending the metrics to %s: %s", h.URL, err)
+	}
+	defer resp.Body.Close()
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("%s returned HTTP status %s: %s", h.URL, resp.Status, bytes.TrimSpace(message))
+
+	// the access token may have been revoked before its expiry
+	if resp.StatusCode == http.StatusUnauthorized && h.TokenURL != "" {
+		h.mu.Lock()
+		h.token = ""
+		h.mu.Unlock()
+		return true, err
+	}
+	for _, code := range h.RetryStatusCodes {
+		if resp.StatusCode == code {
+			return true, err
+		}
+	}
+	return false, err
+}
+
+func (h *HTTP) authorize(req *http.Request) error {
+	if h.Username != "" || h.Password != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	if h.BearerToken != "" {
+		token, err := ioutil.ReadFile(h.BearerToken)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	if h.TokenURL != "" {
+		token, err := h.accessToken()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// accessToken returns the OAuth2 access token, requesting a new one with the
+// client credentials grant when it is missing or about to expire
+func (h *HTTP) accessToken() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.token != "" && (h.tokenExpiry.IsZero() || time.Now().Before(h.tokenExpiry)) {
+		return h.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(h.Scopes) > 0 {
+		form.Set("scope", strings.Join(h.Scopes, " "))
+	}
+	req, err := http.NewRequest("POST", h.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(h.ClientID), url.QueryEscape(h.ClientSecret))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Error requesting an access token from %s: %s", h.TokenURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%s returned HTTP status %s: %s", h.TokenURL, resp.Status, bytes.TrimSpace(message))
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("Error decoding the access token of %s: %s", h.TokenURL, err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("%s returned no access token", h.TokenURL)
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", fmt.Errorf("%s returned an unsupported %s token", h.TokenURL, token.TokenType)
+	}
+
+	h.token = token.AccessToken
+	h.tokenExpiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		// renewed a bit early, so that it does not expire in flight
+		h.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - 10*time.Second)
+	}
+	return h.token, nil
+}
+
+func init() {
+	outputs.Add("http", func() telegraf.Output {
+		return NewHTTP()
+	})
+}
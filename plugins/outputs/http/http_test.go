@@ -0,0 +1,320 @@
+package http
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	method string
+	header http.Header
+	body   string
+}
+
+type fakeServer struct {
+	sync.Mutex
+	*httptest.Server
+	statuses []int
+	requests []request
+}
+
+func newFakeServer(t *testing.T, statuses ...int) *fakeServer {
+	s := &fakeServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		b, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		s.requests = append(s.requests, request{r.Method, r.Header, string(b)})
+
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func (s *fakeServer) received() []request {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests
+}
+
+func newTestHTTP(t *testing.T, url string) *HTTP {
+	h := NewHTTP()
+	h.URL = url
+	h.RetryBackoff = internal.Duration{Duration: time.Millisecond}
+	serializer, _ := serializers.NewInfluxSerializer()
+	h.SetSerializer(serializer)
+	return h
+}
+
+func connect(t *testing.T, h *HTTP) {
+	require.Empty(t, h.Validate())
+	require.NoError(t, h.Connect())
+}
+
+func TestValidate(t *testing.T) {
+	h := NewHTTP()
+	h.URL = "localhost:8080"
+	h.Method = "GET"
+	h.ContentEncoding = "deflate"
+	h.MetricsPerRequest = -1
+	h.ClientID = "telegraf"
+	assert.Len(t, h.Validate(), 5)
+
+	h = NewHTTP()
+	h.URL = "https://localhost:8080/metric"
+	h.Method = "put"
+	h.ClientID = "telegraf"
+	h.ClientSecret = "secret"
+	h.TokenURL = "https://localhost/token"
+	assert.Empty(t, h.Validate())
+}
+
+func TestWrite(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.Method = "PUT"
+	h.Username = "telegraf"
+	h.Password = "secret"
+	h.Headers = map[string]string{"Content-Type": "application/x-influx", "X-Tenant": "dcai"}
+	connect(t, h)
+
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+
+	requests := s.received()
+	require.Len(t, requests, 1)
+	r := requests[0]
+	assert.Equal(t, "PUT", r.method)
+	assert.Equal(t, "test1,tag1=value1 value=1 1257894000000000000\n", r.body)
+	assert.Equal(t, "application/x-influx", r.header.Get("Content-Type"))
+	assert.Equal(t, "dcai", r.header.Get("X-Tenant"))
+	assert.Equal(t, "Basic dGVsZWdyYWY6c2VjcmV0", r.header.Get("Authorization"))
+}
+
+func TestWriteJSONGzip(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.ContentEncoding = "gzip"
+	serializer, _ := serializers.NewJsonSerializer(time.Second)
+	h.SetSerializer(serializer)
+	connect(t, h)
+
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+
+	requests := s.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "gzip", requests[0].header.Get("Content-Encoding"))
+	assert.JSONEq(t, `{"fields":{"value":1},"name":"test1","tags":{"tag1":"value1"},"timestamp":1257894000}`,
+		requests[0].body)
+}
+
+func TestWriteBatchSerializer(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	serializer, _ := serializers.NewAiserviceSerializer(true, 0)
+	h.SetSerializer(serializer)
+	connect(t, h)
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1), testutil.TestMetric(2)}
+	require.NoError(t, h.Write(metrics))
+
+	requests := s.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, `{"measurement":"test1","points":[{"tag1":"value1","time":"1257894000000000000","value":1},{"tag1":"value1","time":"1257894000000000000","value":2}]}`+"\n",
+		requests[0].body)
+}
+
+func TestWriteMetricsPerRequest(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.MetricsPerRequest = 2
+	connect(t, h)
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, testutil.TestMetric(i))
+	}
+	require.NoError(t, h.Write(metrics))
+
+	requests := s.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, "test1,tag1=value1 value=0i 1257894000000000000\n"+
+		"test1,tag1=value1 value=1i 1257894000000000000\n", requests[0].body)
+	assert.Equal(t, "test1,tag1=value1 value=4i 1257894000000000000\n", requests[2].body)
+
+	// a single request by write
+	h.MetricsPerRequest = 0
+	require.NoError(t, h.Write(metrics))
+	assert.Len(t, s.received(), 4)
+}
+
+func TestWriteMetricsPerRequestFailure(t *testing.T) {
+	s := newFakeServer(t, http.StatusNoContent, http.StatusBadRequest)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.MetricsPerRequest = 2
+	connect(t, h)
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, testutil.TestMetric(i))
+	}
+	err := h.Write(metrics)
+	require.Error(t, err)
+	assert.Len(t, s.received(), 2)
+
+	// the metrics of the first request are not written again
+	pe, ok := err.(*telegraf.PartialWriteError)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, metrics[2:], pe.Unsent)
+	assert.Contains(t, pe.Error(), "400")
+
+	// a failure of the first request fails the whole write
+	s.statuses = []int{http.StatusBadRequest}
+	err = h.Write(metrics)
+	require.Error(t, err)
+	_, ok = err.(*telegraf.PartialWriteError)
+	assert.False(t, ok)
+}
+
+func TestWriteRetries(t *testing.T) {
+	s := newFakeServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	connect(t, h)
+
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.Len(t, s.received(), 3)
+}
+
+func TestWriteRetriesExhausted(t *testing.T) {
+	s := newFakeServer(t, 500, 500, 500)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.MaxRetries = 2
+	connect(t, h)
+
+	assert.Error(t, h.Write(testutil.MockMetrics()))
+	assert.Len(t, s.received(), 3)
+}
+
+func TestWriteRetryStatusCodes(t *testing.T) {
+	s := newFakeServer(t, http.StatusConflict, http.StatusServiceUnavailable)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.RetryStatusCodes = []int{http.StatusConflict}
+	connect(t, h)
+
+	// 503 is not retried
+	assert.Error(t, h.Write(testutil.MockMetrics()))
+	assert.Len(t, s.received(), 2)
+}
+
+func TestWriteKeepsRejected(t *testing.T) {
+	s := newFakeServer(t, http.StatusBadRequest)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	connect(t, h)
+
+	// the metrics stay in the buffer
+	assert.Error(t, h.Write(testutil.MockMetrics()))
+	assert.Len(t, s.received(), 1)
+}
+
+func TestWriteOAuth2(t *testing.T) {
+	var tokens int
+	var mu sync.Mutex
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		user, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		assert.Equal(t, "telegraf", user)
+		assert.Equal(t, "secret", password)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "write read", r.PostForm.Get("scope"))
+
+		tokens++
+		w.Header().Set("Content-Type", "application/json")
+		if tokens == 1 {
+			w.Write([]byte(`{"access_token":"token1","token_type":"bearer","expires_in":3600}`))
+		} else {
+			w.Write([]byte(`{"access_token":"token2","token_type":"Bearer","expires_in":3600}`))
+		}
+	}))
+	defer tokenServer.Close()
+
+	// the first token is revoked after a request
+	s := newFakeServer(t, http.StatusNoContent, http.StatusUnauthorized)
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.ClientID = "telegraf"
+	h.ClientSecret = "secret"
+	h.TokenURL = tokenServer.URL
+	h.Scopes = []string{"write", "read"}
+	connect(t, h)
+
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+
+	requests := s.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, "Bearer token1", requests[0].header.Get("Authorization"))
+	assert.Equal(t, "Bearer token1", requests[1].header.Get("Authorization"))
+	assert.Equal(t, "Bearer token2", requests[2].header.Get("Authorization"))
+	assert.Equal(t, 2, tokens)
+}
+
+func TestWriteTLS(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	h := newTestHTTP(t, s.URL)
+	h.MaxRetries = 0
+	connect(t, h)
+	assert.Error(t, h.Write(testutil.MockMetrics()))
+
+	h.InsecureSkipVerify = true
+	connect(t, h)
+	assert.NoError(t, h.Write(testutil.MockMetrics()))
+}
@@ -0,0 +1,416 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/prompb"
+	"github.com/influxdata/telegraf/internal/retry"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// the same naming as the prometheus_client output
+var invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// PrometheusRemoteWrite sends the metrics to a Prometheus remote write
+// endpoint
+type PrometheusRemoteWrite struct {
+	URL               string
+	Timeout           internal.Duration
+	MaxSamplesPerSend int `toml:"max_samples_per_send"`
+	MaxRetries        int
+	RetryBackoff      internal.Duration
+
+	Username string
+	Password string
+	// Bearer Token authorization file path
+	BearerToken string            `toml:"bearer_token"`
+	Headers     map[string]string `toml:"headers"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+}
+
+func NewPrometheusRemoteWrite() *PrometheusRemoteWrite {
+	return &PrometheusRemoteWrite{
+		Timeout:           internal.Duration{Duration: 5 * time.Second},
+		MaxSamplesPerSend: 500,
+		MaxRetries:        3,
+		RetryBackoff:      internal.Duration{Duration: 500 * time.Millisecond},
+	}
+}
+
+var sampleConfig = `
+  ## URL of the remote write endpoint, e.g. of Prometheus, Cortex, Thanos or
+  ## the http_listener input of another telegraf
+  url = "http://localhost:9090/api/v1/write"
+
+  ## Timeout of a request
+  # timeout = "5s"
+
+  ## Maximum number of samples of a request, the metrics of a write are
+  ## split into several requests above it
+  # max_samples_per_send = 500
+
+  ## Retries of a request failing with a network error, a 5xx or a 429
+  ## status, the wait between them doubles from retry_backoff. The requests
+  ## rejected with a 400 status are dropped, the metrics of the other failed
+  ## requests stay in the buffer.
+  # max_retries = 3
+  # retry_backoff = "500ms"
+
+  ## Basic authentication
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+  ## Use bearer token for authorization
+  # bearer_token = /path/to/bearer/token
+
+  ## Additional HTTP headers
+  # [outputs.prometheus_remote_write.headers]
+  #   X-Scope-OrgID = "dcai"
+
+  ## Optional SSL Config
+  # ssl_ca = /path/to/cafile
+  # ssl_cert = /path/to/certfile
+  # ssl_key = /path/to/keyfile
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Send metrics to a Prometheus remote write endpoint"
+}
+
+// Validate checks the URL and the batch size
+func (p *PrometheusRemoteWrite) Validate() []error {
+	var errs []error
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid url %s: %s", p.URL, err))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		errs = append(errs, fmt.Errorf("Invalid url %s, the scheme must be http or https", p.URL))
+	}
+	if p.MaxSamplesPerSend < 1 {
+		errs = append(errs, fmt.Errorf("Invalid max_samples_per_send %d, must be at least 1", p.MaxSamplesPerSend))
+	}
+	return errs
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	tlsCfg, err := internal.GetTLSConfig(p.SSLCert, p.SSLKey, p.SSLCA, p.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	p.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: p.Timeout.Duration,
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	max := p.MaxSamplesPerSend
+	if max < 1 {
+		max = 500
+	}
+	for _, req := range batch(timeSeries(metrics), max) {
+		if err := p.send(&req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send posts a request, retrying it on the transient errors. The requests
+// rejected as invalid are dropped, since they would be rejected again.
+func (p *PrometheusRemoteWrite) send(req *prompb.WriteRequest) error {
+	body := snappy.Encode(nil, req.Marshal())
+
+	var status int
+	err := retry.Do("prometheus_remote_write", p.MaxRetries, p.RetryBackoff.Duration, func() (bool, error) {
+		var err error
+		status, err = p.post(body)
+		return retryable(status), err
+	})
+	if err != nil && status == http.StatusBadRequest {
+		log.Printf("E! prometheus_remote_write: Dropped %d samples: %s\n", samples(req), err)
+		return nil
+	}
+	return err
+}
+
+// retryable returns whether a request failed with the status may succeed
+// later, the status is 0 when no response was received
+func retryable(status int) bool {
+	return status == 0 || status/100 == 5 || status == http.StatusTooManyRequests
+}
+
+// post posts a request body, it returns the status of the response
+func (p *PrometheusRemoteWrite) post(body []byte) (int, error) {
+	req, err := http.NewRequest("POST", p.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "Telegraf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	if p.BearerToken != "" {
+		token, err := ioutil.ReadFile(p.BearerToken)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("Error sending the samples to %s: %s", p.URL, err)
+	}
+	defer resp.Body.Close()
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode/100 == 2 {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, fmt.Errorf("%s returned HTTP status %s: %s", p.URL, resp.Status, bytes.TrimSpace(message))
+}
+
+func samples(req *prompb.WriteRequest) int {
+	n := 0
+	for _, ts := range req.Timeseries {
+		n += len(ts.Samples)
+	}
+	return n
+}
+
+// batch splits the series into requests of at most max samples
+func batch(series []prompb.TimeSeries, max int) []prompb.WriteRequest {
+	var reqs []prompb.WriteRequest
+	var req prompb.WriteRequest
+	n := 0
+	for _, ts := range series {
+		remaining := ts.Samples
+		for len(remaining) > 0 {
+			take := max - n
+			if take > len(remaining) {
+				take = len(remaining)
+			}
+			req.Timeseries = append(req.Timeseries, prompb.TimeSeries{Labels: ts.Labels, Samples: remaining[:take]})
+			remaining = remaining[take:]
+			n += take
+			if n == max {
+				reqs = append(reqs, req)
+				req = prompb.WriteRequest{}
+				n = 0
+			}
+		}
+	}
+	if n > 0 {
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// timeSeries converts the metrics to series, the samples of a series are
+// sorted by time
+func timeSeries(metrics []telegraf.Metric) []prompb.TimeSeries {
+	var series []prompb.TimeSeries
+	index := make(map[string]int)
+
+	for _, m := range metrics {
+		timestamp := m.Time().UnixNano() / int64(time.Millisecond)
+		for _, s := range metricSamples(m) {
+			labels := make([]prompb.Label, 0, len(s.labels)+1)
+			labels = append(labels, prompb.Label{Name: prompb.NameLabel, Value: s.name})
+			for k, v := range s.labels {
+				labels = append(labels, prompb.Label{Name: k, Value: v})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+			key := seriesKey(labels)
+			i, ok := index[key]
+			if !ok {
+				i = len(series)
+				index[key] = i
+				series = append(series, prompb.TimeSeries{Labels: labels})
+			}
+			series[i].Samples = append(series[i].Samples, prompb.Sample{Value: s.value, Timestamp: timestamp})
+		}
+	}
+
+	for _, ts := range series {
+		samples := ts.Samples
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+	}
+	return series
+}
+
+func seriesKey(labels []prompb.Label) string {
+	var b bytes.Buffer
+	for _, l := range labels {
+		b.WriteString(l.Name)
+		b.WriteByte(0xff)
+		b.WriteString(l.Value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// metricSamples returns the samples of a metric, named as by the
+// prometheus_client output: the tags and the string fields are labels, the
+// value field and the counter and gauge fields of the prometheus input are
+// named after the measurement, the other fields after the measurement and
+// the field. The summaries and histograms are expanded to their quantiles or
+// buckets, sum and count.
+func metricSamples(m telegraf.Metric) []sample {
+	labels := make(map[string]string)
+	for k, v := range m.Tags() {
+		labels[sanitize(k)] = v
+	}
+	for k, v := range m.Fields() {
+		if s, ok := v.(string); ok {
+			labels[sanitize(k)] = s
+		}
+	}
+
+	name := sanitize(m.Name())
+	var samples []sample
+	switch m.Type() {
+	case telegraf.Summary, telegraf.Histogram:
+		bucket, bucketName := "quantile", name
+		if m.Type() == telegraf.Histogram {
+			bucket, bucketName = "le", name+"_bucket"
+		}
+		hasInf := false
+		var count float64
+		for k, v := range m.Fields() {
+			value, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			switch k {
+			case "sum":
+				samples = append(samples, sample{name + "_sum", labels, value})
+			case "count":
+				count = value
+				samples = append(samples, sample{name + "_count", labels, value})
+			default:
+				limit, err := strconv.ParseFloat(k, 64)
+				if err != nil {
+					continue
+				}
+				if math.IsInf(limit, 1) {
+					hasInf = true
+				}
+				samples = append(samples, sample{bucketName, with(labels, bucket, formatFloat(limit)), value})
+			}
+		}
+		// the +Inf bucket is implicit in the prometheus_client output
+		if m.Type() == telegraf.Histogram && !hasInf && m.HasField("count") {
+			samples = append(samples, sample{bucketName, with(labels, bucket, "+Inf"), count})
+		}
+	default:
+		for k, v := range m.Fields() {
+			value, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			var sname string
+			switch {
+			case m.Type() == telegraf.Counter && k == "counter",
+				m.Type() == telegraf.Gauge && k == "gauge",
+				k == "value":
+				sname = name
+			default:
+				sname = sanitize(m.Name() + "_" + k)
+			}
+			samples = append(samples, sample{sname, labels, value})
+		}
+	}
+	return samples
+}
+
+func with(labels map[string]string, k string, v string) map[string]string {
+	l := make(map[string]string, len(labels)+1)
+	for lk, lv := range labels {
+		l[lk] = lv
+	}
+	l[k] = v
+	return l
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// toFloat converts the numeric fields, the others are ignored as by the
+// prometheus_client output
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func sanitize(value string) string {
+	return invalidNameCharRE.ReplaceAllString(value, "_")
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return NewPrometheusRemoteWrite()
+	})
+}
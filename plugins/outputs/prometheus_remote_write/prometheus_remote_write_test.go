@@ -0,0 +1,247 @@
+package prometheus_remote_write
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/prompb"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEndpoint struct {
+	sync.Mutex
+	server   *httptest.Server
+	statuses []int
+	requests []prompb.WriteRequest
+	headers  []http.Header
+}
+
+func newFakeEndpoint(t *testing.T, statuses ...int) *fakeEndpoint {
+	e := &fakeEndpoint{statuses: statuses}
+	e.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+
+		compressed, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		b, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		var wr prompb.WriteRequest
+		require.NoError(t, wr.Unmarshal(b))
+		e.requests = append(e.requests, wr)
+		e.headers = append(e.headers, r.Header)
+
+		status := http.StatusNoContent
+		if len(e.statuses) > 0 {
+			status, e.statuses = e.statuses[0], e.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return e
+}
+
+func newTestOutput(t *testing.T, url string) *PrometheusRemoteWrite {
+	p := NewPrometheusRemoteWrite()
+	p.URL = url
+	p.RetryBackoff = internal.Duration{Duration: time.Millisecond}
+	require.Empty(t, p.Validate())
+	require.NoError(t, p.Connect())
+	return p
+}
+
+func newMetric(t *testing.T, name string, tags map[string]string, fields map[string]interface{}, tm time.Time, tp ...telegraf.ValueType) telegraf.Metric {
+	m, err := metric.New(name, tags, fields, tm, tp...)
+	require.NoError(t, err)
+	return m
+}
+
+func findSeries(series []prompb.TimeSeries, labels ...prompb.Label) *prompb.TimeSeries {
+	for i := range series {
+		if assert.ObjectsAreEqual(labels, series[i].Labels) {
+			return &series[i]
+		}
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	p := NewPrometheusRemoteWrite()
+	p.URL = "localhost:9090"
+	assert.Len(t, p.Validate(), 1)
+
+	p.URL = "https://localhost:9090/api/v1/write"
+	p.MaxSamplesPerSend = 0
+	assert.Len(t, p.Validate(), 1)
+
+	p.MaxSamplesPerSend = 10
+	assert.Empty(t, p.Validate())
+}
+
+func TestTimeSeriesNaming(t *testing.T) {
+	tm := time.Unix(1508400000, 500000000)
+	metrics := []telegraf.Metric{
+		newMetric(t, "cpu", map[string]string{"host": "a", "cpu-name": "cpu0"},
+			map[string]interface{}{"usage_idle": 99.5, "value": int64(3), "state": "ok", "up": true}, tm),
+		newMetric(t, "http_requests", nil,
+			map[string]interface{}{"counter": 10.0}, tm, telegraf.Counter),
+		newMetric(t, "rpc_duration", nil,
+			map[string]interface{}{"0.5": 2.0, "sum": 10.0, "count": 4.0}, tm, telegraf.Summary),
+		newMetric(t, "latency", nil,
+			map[string]interface{}{"0.1": 1.0, "1": 3.0, "sum": 2.5, "count": 4.0}, tm, telegraf.Histogram),
+	}
+
+	series := timeSeries(metrics)
+	ms := int64(1508400000500)
+	sample := []prompb.Sample{{Value: 0, Timestamp: ms}}
+
+	expected := []struct {
+		labels []prompb.Label
+		value  float64
+	}{
+		{[]prompb.Label{{Name: "__name__", Value: "cpu_usage_idle"}, {Name: "cpu_name", Value: "cpu0"}, {Name: "host", Value: "a"}, {Name: "state", Value: "ok"}}, 99.5},
+		{[]prompb.Label{{Name: "__name__", Value: "cpu"}, {Name: "cpu_name", Value: "cpu0"}, {Name: "host", Value: "a"}, {Name: "state", Value: "ok"}}, 3},
+		{[]prompb.Label{{Name: "__name__", Value: "http_requests"}}, 10},
+		{[]prompb.Label{{Name: "__name__", Value: "rpc_duration"}, {Name: "quantile", Value: "0.5"}}, 2},
+		{[]prompb.Label{{Name: "__name__", Value: "rpc_duration_sum"}}, 10},
+		{[]prompb.Label{{Name: "__name__", Value: "rpc_duration_count"}}, 4},
+		{[]prompb.Label{{Name: "__name__", Value: "latency_bucket"}, {Name: "le", Value: "0.1"}}, 1},
+		{[]prompb.Label{{Name: "__name__", Value: "latency_bucket"}, {Name: "le", Value: "1"}}, 3},
+		{[]prompb.Label{{Name: "__name__", Value: "latency_bucket"}, {Name: "le", Value: "+Inf"}}, 4},
+		{[]prompb.Label{{Name: "__name__", Value: "latency_sum"}}, 2.5},
+		{[]prompb.Label{{Name: "__name__", Value: "latency_count"}}, 4},
+	}
+	require.Len(t, series, len(expected))
+	for _, e := range expected {
+		s := findSeries(series, e.labels...)
+		require.NotNil(t, s, "%v", e.labels)
+		sample[0].Value = e.value
+		assert.Equal(t, sample, s.Samples, "%v", e.labels)
+	}
+}
+
+func TestTimeSeriesSortsSamples(t *testing.T) {
+	metrics := []telegraf.Metric{
+		newMetric(t, "temp", nil, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		newMetric(t, "temp", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+	}
+	series := timeSeries(metrics)
+	require.Len(t, series, 1)
+	assert.Equal(t, []prompb.Sample{{Value: 1, Timestamp: 1000}, {Value: 2, Timestamp: 2000}}, series[0].Samples)
+}
+
+func TestBatch(t *testing.T) {
+	labels := []prompb.Label{{Name: prompb.NameLabel, Value: "a"}}
+	series := []prompb.TimeSeries{
+		{Labels: labels, Samples: []prompb.Sample{{Timestamp: 1}, {Timestamp: 2}, {Timestamp: 3}}},
+		{Labels: labels, Samples: []prompb.Sample{{Timestamp: 4}}},
+	}
+
+	reqs := batch(series, 2)
+	require.Len(t, reqs, 2)
+	assert.Equal(t, 2, samples(&reqs[0]))
+	assert.Len(t, reqs[0].Timeseries, 1)
+	assert.Equal(t, 2, samples(&reqs[1]))
+	assert.Len(t, reqs[1].Timeseries, 2)
+
+	assert.Len(t, batch(series, 4), 1)
+	assert.Empty(t, batch(nil, 4))
+}
+
+func TestWrite(t *testing.T) {
+	e := newFakeEndpoint(t)
+	defer e.server.Close()
+
+	token, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(token.Name())
+	token.WriteString("secret\n")
+	token.Close()
+
+	p := newTestOutput(t, e.server.URL)
+	p.MaxSamplesPerSend = 2
+	p.BearerToken = token.Name()
+	p.Headers = map[string]string{"X-Scope-OrgID": "dcai"}
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 3; i++ {
+		metrics = append(metrics, newMetric(t, "temp", nil,
+			map[string]interface{}{"value": float64(i)}, time.Unix(int64(i), 0)))
+	}
+	require.NoError(t, p.Write(metrics))
+
+	e.Lock()
+	defer e.Unlock()
+	require.Len(t, e.requests, 2)
+	assert.Equal(t, 2, samples(&e.requests[0]))
+	assert.Equal(t, 1, samples(&e.requests[1]))
+
+	h := e.headers[0]
+	assert.Equal(t, "snappy", h.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", h.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", h.Get("X-Prometheus-Remote-Write-Version"))
+	assert.Equal(t, "Bearer secret", h.Get("Authorization"))
+	assert.Equal(t, "dcai", h.Get("X-Scope-OrgID"))
+}
+
+func TestWriteRetries(t *testing.T) {
+	e := newFakeEndpoint(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer e.server.Close()
+
+	p := newTestOutput(t, e.server.URL)
+	m := newMetric(t, "temp", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.NoError(t, p.Write([]telegraf.Metric{m}))
+
+	e.Lock()
+	assert.Len(t, e.requests, 3)
+	e.Unlock()
+}
+
+func TestWriteRetriesExhausted(t *testing.T) {
+	e := newFakeEndpoint(t, 500, 500, 500, 500)
+	defer e.server.Close()
+
+	p := newTestOutput(t, e.server.URL)
+	m := newMetric(t, "temp", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	assert.Error(t, p.Write([]telegraf.Metric{m}))
+
+	e.Lock()
+	assert.Len(t, e.requests, 4)
+	e.Unlock()
+}
+
+func TestWriteDropsRejected(t *testing.T) {
+	e := newFakeEndpoint(t, http.StatusBadRequest)
+	defer e.server.Close()
+
+	p := newTestOutput(t, e.server.URL)
+	m := newMetric(t, "temp", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	assert.NoError(t, p.Write([]telegraf.Metric{m}))
+
+	e.Lock()
+	assert.Len(t, e.requests, 1)
+	e.Unlock()
+}
+
+func TestWriteKeepsUnauthorized(t *testing.T) {
+	e := newFakeEndpoint(t, http.StatusUnauthorized)
+	defer e.server.Close()
+
+	// the metrics stay in the buffer until the credentials are fixed
+	p := newTestOutput(t, e.server.URL)
+	m := newMetric(t, "temp", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	assert.Error(t, p.Write([]telegraf.Metric{m}))
+
+	e.Lock()
+	assert.Len(t, e.requests, 1)
+	e.Unlock()
+}
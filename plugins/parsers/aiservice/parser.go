@@ -0,0 +1,153 @@
+package aiservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// AiserviceParser parses the documents of the aiservice serializer,
+// {"measurement":"cpu","points":[{"time":"1257894000000000000","host":"a","usage_idle":99}]},
+// or the bare {"points":[...]} posted by the aiservice output, named after
+// MetricName
+type AiserviceParser struct {
+	// MetricName is the measurement of the documents without one
+	MetricName string
+	// TagKeys are the keys of the tags, the other strings being string
+	// fields. All the strings are tags when empty.
+	TagKeys     []string
+	DefaultTags map[string]string
+}
+
+type document struct {
+	Measurement string                   `json:"measurement"`
+	Points      []map[string]interface{} `json:"points"`
+}
+
+func (p *AiserviceParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	// the integers keep their precision
+	dec.UseNumber()
+	for {
+		var doc document
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return metrics, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse out as aiservice JSON, %s", err)
+		}
+
+		name := doc.Measurement
+		if name == "" {
+			name = p.MetricName
+		}
+		for _, point := range doc.Points {
+			m, err := p.parsePoint(name, point)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+}
+
+func (p *AiserviceParser) parsePoint(name string, point map[string]interface{}) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	t := time.Now().UTC()
+
+	for k, v := range point {
+		if k == "time" {
+			var err error
+			if t, err = parseTime(v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		switch v := v.(type) {
+		case string:
+			if len(p.TagKeys) == 0 || p.isTag(k) {
+				tags[k] = v
+			} else {
+				fields[k] = v
+			}
+		case json.Number:
+			if p.isTag(k) {
+				tags[k] = v.String()
+			} else if i, err := v.Int64(); err == nil {
+				fields[k] = i
+			} else if f, err := v.Float64(); err == nil {
+				fields[k] = f
+			}
+		case bool:
+			if p.isTag(k) {
+				tags[k] = strconv.FormatBool(v)
+			} else {
+				fields[k] = v
+			}
+		}
+	}
+
+	return metric.New(name, tags, fields, t)
+}
+
+func (p *AiserviceParser) isTag(key string) bool {
+	for _, k := range p.TagKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime parses the time of a point, in nanoseconds as a string or as a
+// number
+func parseTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		ns, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid aiservice point time %q: %s", v, err)
+		}
+		return time.Unix(0, ns), nil
+	case json.Number:
+		ns, err := v.Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid aiservice point time %s: %s", v, err)
+		}
+		return time.Unix(0, ns), nil
+	default:
+		return time.Time{}, fmt.Errorf("Invalid aiservice point time %v", v)
+	}
+}
+
+func (p *AiserviceParser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line + "\n"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: aiservice ", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *AiserviceParser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
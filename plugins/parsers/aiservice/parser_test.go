@@ -0,0 +1,77 @@
+package aiservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	validDocuments = `{"measurement":"cpu","points":[{"host":"a","time":"1257894000000000000","value":1},{"host":"b","time":"1257894000000000001","value":3}]}
+{"measurement":"smart","points":[{"disk_wwn":"5000c5005f50e6ab","health_ok":true,"model":"ST4000","temp_c":35,"time":"1257894000000000000"}]}
+`
+	bareDocument = `{"points":[{"host":"a","time":"1257894000000000000","value":1.5}]}`
+)
+
+func TestParse(t *testing.T) {
+	parser := AiserviceParser{MetricName: "aiservice"}
+	metrics, err := parser.Parse([]byte(validDocuments))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, "cpu", metrics[0].Name())
+	assert.Equal(t, map[string]string{"host": "a"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": int64(1)}, metrics[0].Fields())
+	assert.Equal(t, time.Unix(0, 1257894000000000000), metrics[0].Time())
+	assert.Equal(t, time.Unix(0, 1257894000000000001), metrics[1].Time())
+
+	assert.Equal(t, "smart", metrics[2].Name())
+	assert.Equal(t, map[string]string{"disk_wwn": "5000c5005f50e6ab", "model": "ST4000"}, metrics[2].Tags())
+	assert.Equal(t, map[string]interface{}{"health_ok": true, "temp_c": int64(35)}, metrics[2].Fields())
+}
+
+func TestParseLargeIntegers(t *testing.T) {
+	parser := AiserviceParser{MetricName: "aiservice"}
+	metrics, err := parser.Parse([]byte(`{"points":[{"time":1257894000000000001,"reads":9007199254740993,"ratio":0.5}]}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	assert.Equal(t, map[string]interface{}{"reads": int64(9007199254740993), "ratio": 0.5}, metrics[0].Fields())
+	assert.Equal(t, time.Unix(0, 1257894000000000001), metrics[0].Time())
+}
+
+func TestParseTagKeys(t *testing.T) {
+	parser := AiserviceParser{TagKeys: []string{"disk_wwn", "temp_c"}}
+	metrics, err := parser.Parse([]byte(validDocuments))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, map[string]string{"disk_wwn": "5000c5005f50e6ab", "temp_c": "35"}, metrics[2].Tags())
+	assert.Equal(t, map[string]interface{}{"health_ok": true, "model": "ST4000"}, metrics[2].Fields())
+}
+
+func TestParseBareDocument(t *testing.T) {
+	parser := AiserviceParser{MetricName: "aiservice"}
+	parser.SetDefaultTags(map[string]string{"relay": "r1"})
+	m, err := parser.ParseLine(bareDocument)
+	require.NoError(t, err)
+
+	assert.Equal(t, "aiservice", m.Name())
+	assert.Equal(t, map[string]string{"host": "a", "relay": "r1"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"value": 1.5}, m.Fields())
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser := AiserviceParser{}
+	_, err := parser.Parse([]byte(`{"points":[`))
+	assert.Error(t, err)
+
+	_, err = parser.Parse([]byte(`{"points":[{"time":"yesterday","value":1}]}`))
+	assert.Error(t, err)
+
+	metrics, err := parser.Parse([]byte(""))
+	assert.NoError(t, err)
+	assert.Empty(t, metrics)
+}
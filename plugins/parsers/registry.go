@@ -5,6 +5,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 
+	"github.com/influxdata/telegraf/plugins/parsers/aiservice"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
@@ -41,7 +42,8 @@ type Parser interface {
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
-	// Dataformat can be one of: json, influx, graphite, value, nagios
+	// Dataformat can be one of: json, influx, graphite, value, nagios,
+	// collectd, aiservice
 	DataFormat string
 
 	// Separator only applied to Graphite data.
@@ -49,9 +51,9 @@ type Config struct {
 	// Templates only apply to Graphite data.
 	Templates []string
 
-	// TagKeys only apply to JSON data
+	// TagKeys only apply to JSON and aiservice data
 	TagKeys []string
-	// MetricName applies to JSON & value. This will be the name of the measurement.
+	// MetricName applies to JSON, value & aiservice. This will be the name of the measurement.
 	MetricName string
 
 	// Authentication file for collectd
@@ -76,6 +78,9 @@ func NewParser(config *Config) (Parser, error) {
 	case "json":
 		parser, err = NewJSONParser(config.MetricName,
 			config.TagKeys, config.DefaultTags)
+	case "aiservice":
+		parser, err = NewAiserviceParser(config.MetricName,
+			config.TagKeys, config.DefaultTags)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -108,6 +113,18 @@ func NewJSONParser(
 	return parser, nil
 }
 
+func NewAiserviceParser(
+	metricName string,
+	tagKeys []string,
+	defaultTags map[string]string,
+) (Parser, error) {
+	return &aiservice.AiserviceParser{
+		MetricName:  metricName,
+		TagKeys:     tagKeys,
+		DefaultTags: defaultTags,
+	}, nil
+}
+
 func NewNagiosParser() (Parser, error) {
 	return &nagios.NagiosParser{}, nil
 }
@@ -0,0 +1,295 @@
+package converter
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tags to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert. The array may contain globs.
+  ##   <target-type> = [<tag-key>...]
+  [processors.converter.tags]
+    string = []
+    integer = []
+    boolean = []
+    float = []
+
+  ## Fields to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert. The array may contain globs.
+  ##   <target-type> = [<field-key>...]
+  [processors.converter.fields]
+    tag = []
+    string = []
+    integer = []
+    boolean = []
+    float = []
+`
+
+// Conversion lists the keys converted to each type, Tag applies to the
+// fields only
+type Conversion struct {
+	Tag     []string
+	String  []string
+	Integer []string
+	Boolean []string
+	Float   []string
+}
+
+type Converter struct {
+	Tags   *Conversion
+	Fields *Conversion
+
+	compileOnce  sync.Once
+	tagFilters   *conversionFilters
+	fieldFilters *conversionFilters
+	compileErr   error
+}
+
+type conversionFilters struct {
+	Tag     filter.Filter
+	String  filter.Filter
+	Integer filter.Filter
+	Boolean filter.Filter
+	Float   filter.Filter
+}
+
+func (c *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+// Validate checks the globs
+func (c *Converter) Validate() []error {
+	c.compileOnce.Do(c.compile)
+	if c.compileErr != nil {
+		return []error{c.compileErr}
+	}
+	return nil
+}
+
+func (c *Converter) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	c.compileOnce.Do(c.compile)
+	if c.compileErr != nil {
+		return in
+	}
+
+	for _, metric := range in {
+		c.convertTags(metric)
+		c.convertFields(metric)
+	}
+	return in
+}
+
+func (c *Converter) compile() {
+	var err error
+	if c.Tags != nil && len(c.Tags.Tag) > 0 {
+		c.compileErr = fmt.Errorf("Invalid tags conversion: tags cannot be converted to tags")
+	} else if c.tagFilters, err = compileFilters(c.Tags); err != nil {
+		c.compileErr = fmt.Errorf("Invalid tags conversion: %s", err)
+	} else if c.fieldFilters, err = compileFilters(c.Fields); err != nil {
+		c.compileErr = fmt.Errorf("Invalid fields conversion: %s", err)
+	}
+	if c.compileErr != nil {
+		log.Printf("E! %s, the converter is disabled\n", c.compileErr)
+	}
+}
+
+func compileFilters(conv *Conversion) (*conversionFilters, error) {
+	if conv == nil {
+		return &conversionFilters{}, nil
+	}
+
+	var err error
+	cf := &conversionFilters{}
+	if cf.Tag, err = filter.Compile(conv.Tag); err != nil {
+		return nil, err
+	}
+	if cf.String, err = filter.Compile(conv.String); err != nil {
+		return nil, err
+	}
+	if cf.Integer, err = filter.Compile(conv.Integer); err != nil {
+		return nil, err
+	}
+	if cf.Boolean, err = filter.Compile(conv.Boolean); err != nil {
+		return nil, err
+	}
+	if cf.Float, err = filter.Compile(conv.Float); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// convertTags converts the tags to fields, the tags which fail to convert
+// are left unchanged
+func (c *Converter) convertTags(metric telegraf.Metric) {
+	for key, value := range metric.Tags() {
+		var (
+			v  interface{}
+			ok bool
+		)
+		switch {
+		case match(c.tagFilters.String, key):
+			v, ok = value, true
+		case match(c.tagFilters.Integer, key):
+			v, ok = toInteger(value)
+		case match(c.tagFilters.Boolean, key):
+			v, ok = toBool(value)
+		case match(c.tagFilters.Float, key):
+			v, ok = toFloat(value)
+		default:
+			continue
+		}
+
+		if !ok {
+			log.Printf("D! Unable to convert tag %s=%q of %s\n", key, value, metric.Name())
+			continue
+		}
+		metric.RemoveTag(key)
+		metric.AddField(key, v)
+	}
+}
+
+// convertFields converts the fields, the fields which fail to convert are
+// left unchanged
+func (c *Converter) convertFields(metric telegraf.Metric) {
+	for key, value := range metric.Fields() {
+		var (
+			v  interface{}
+			ok bool
+		)
+		switch {
+		case match(c.fieldFilters.Tag, key):
+			fieldToTag(metric, key, value)
+			continue
+		case match(c.fieldFilters.String, key):
+			v, ok = toString(value)
+		case match(c.fieldFilters.Integer, key):
+			v, ok = toInteger(value)
+		case match(c.fieldFilters.Boolean, key):
+			v, ok = toBool(value)
+		case match(c.fieldFilters.Float, key):
+			v, ok = toFloat(value)
+		default:
+			continue
+		}
+
+		if !ok {
+			log.Printf("D! Unable to convert field %s=%v of %s\n", key, value, metric.Name())
+			continue
+		}
+		metric.AddField(key, v)
+	}
+}
+
+// fieldToTag moves the field key to the tags, the last field of a metric is
+// left unchanged
+func fieldToTag(metric telegraf.Metric, key string, value interface{}) {
+	tag, ok := toString(value)
+	if !ok {
+		log.Printf("D! Unable to convert field %s=%v of %s\n", key, value, metric.Name())
+		return
+	}
+	if err := metric.RemoveField(key); err != nil {
+		log.Printf("D! Unable to convert field %s of %s to a tag: %s\n", key, metric.Name(), err)
+		return
+	}
+	metric.AddTag(key, tag)
+}
+
+func match(f filter.Filter, key string) bool {
+	return f != nil && f.Match(key)
+}
+
+func toInteger(v interface{}) (int64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value, true
+	case float64:
+		// float64(math.MaxInt64) is 2^63, out of the int64 range
+		if math.IsNaN(value) || value < float64(math.MinInt64) || value >= float64(math.MaxInt64) {
+			return 0, false
+		}
+		return int64(value), true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if result, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return result, true
+		}
+		if result, err := strconv.ParseFloat(value, 64); err == nil {
+			return toInteger(result)
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		result, err := strconv.ParseFloat(value, 64)
+		return result, err == nil
+	}
+	return 0, false
+}
+
+func toBool(v interface{}) (bool, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	case bool:
+		return value, true
+	case string:
+		result, err := strconv.ParseBool(value)
+		return result, err == nil
+	}
+	return false, false
+}
+
+func toString(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case string:
+		return value, true
+	}
+	return "", false
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
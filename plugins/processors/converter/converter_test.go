@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New("sai_disk", tags, fields, time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func TestConvertTags(t *testing.T) {
+	c := &Converter{Tags: &Conversion{
+		String:  []string{"model"},
+		Integer: []string{"sector_*"},
+		Boolean: []string{"ssd"},
+		Float:   []string{"size_tb"},
+	}}
+	assert.Empty(t, c.Validate())
+
+	m := c.Apply(newMetric(t,
+		map[string]string{
+			"model":       "ST1000",
+			"sector_size": "512",
+			"ssd":         "true",
+			"size_tb":     "1.5",
+			"slot":        "3",
+			"sector_bad":  "n/a",
+		},
+		map[string]interface{}{"value": int64(1)}))[0]
+
+	assert.Equal(t, map[string]string{"slot": "3", "sector_bad": "n/a"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"value":       int64(1),
+		"model":       "ST1000",
+		"sector_size": int64(512),
+		"ssd":         true,
+		"size_tb":     1.5,
+	}, m.Fields())
+}
+
+func TestConvertFields(t *testing.T) {
+	c := &Converter{Fields: &Conversion{
+		Tag:     []string{"wwn"},
+		String:  []string{"status"},
+		Integer: []string{"temperature", "power_on", "broken"},
+		Boolean: []string{"failed"},
+		Float:   []string{"reads"},
+	}}
+
+	m := c.Apply(newMetric(t, nil, map[string]interface{}{
+		"wwn":         "5000c5005f50e6ab",
+		"status":      int64(1),
+		"temperature": 36.6,
+		"power_on":    "0x10",
+		"broken":      "n/a",
+		"failed":      int64(0),
+		"reads":       int64(10),
+	}))[0]
+
+	assert.Equal(t, map[string]string{"wwn": "5000c5005f50e6ab"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"status":      "1",
+		"temperature": int64(36),
+		"power_on":    int64(16),
+		"broken":      "n/a",
+		"failed":      false,
+		"reads":       float64(10),
+	}, m.Fields())
+}
+
+func TestConvertLastFieldToTag(t *testing.T) {
+	c := &Converter{Fields: &Conversion{Tag: []string{"wwn"}}}
+
+	// a metric keeps its last field
+	m := c.Apply(newMetric(t, nil, map[string]interface{}{"wwn": "5000c5005f50e6ab"}))[0]
+	assert.Equal(t, map[string]interface{}{"wwn": "5000c5005f50e6ab"}, m.Fields())
+}
+
+func TestInvalidGlob(t *testing.T) {
+	c := &Converter{Fields: &Conversion{Integer: []string{"a["}}}
+	assert.Len(t, c.Validate(), 1)
+
+	m := newMetric(t, nil, map[string]interface{}{"a[": "1"})
+	assert.Equal(t, "1", c.Apply(m)[0].Fields()["a["])
+}
+
+func TestToIntegerOutOfRange(t *testing.T) {
+	for _, v := range []interface{}{math.NaN(), math.Inf(1), math.Inf(-1), math.Pow(2, 63), "NaN", "1e19"} {
+		_, ok := toInteger(v)
+		assert.False(t, ok, "%v", v)
+	}
+	i, ok := toInteger(-math.Pow(2, 63))
+	assert.True(t, ok)
+	assert.Equal(t, int64(math.MinInt64), i)
+}
+
+func TestInvalidTagsToTags(t *testing.T) {
+	c := &Converter{Tags: &Conversion{Tag: []string{"host"}}}
+	assert.Len(t, c.Validate(), 1)
+}
@@ -0,0 +1,123 @@
+package enum
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Each mapping maps the values of one field or tag with a table
+  # [[processors.enum.mapping]]
+  #   ## Name of the field to map
+  #   field = "smart_health_status"
+  #   ## Name of the tag to map, instead of a field
+  #   # tag = "status"
+  #
+  #   ## Destination field or tag, defaults to the mapped one
+  #   dest = "smart_health_status_code"
+  #
+  #   ## Value of the values missing from the table, they are left unchanged
+  #   ## when it is not set
+  #   default = 0
+  #
+  #   ## Table of mappings, e.g. to the dcai DiskStatusType
+  #   [processors.enum.mapping.value_mappings]
+  #     PASSED = 1
+  #     OK = 1
+  #     FAILED = 2
+  #     WARNING = 3
+  #     CRITICAL = 4
+`
+
+type Mapping struct {
+	Field         string
+	Tag           string
+	Dest          string
+	Default       interface{}
+	ValueMappings map[string]interface{}
+}
+
+type EnumMapper struct {
+	Mappings []Mapping `toml:"mapping"`
+}
+
+func (m *EnumMapper) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *EnumMapper) Description() string {
+	return "Map enum values according to given table."
+}
+
+// Validate checks that each mapping maps one field or tag
+func (m *EnumMapper) Validate() []error {
+	var errs []error
+	for i, mapping := range m.Mappings {
+		if (mapping.Field == "") == (mapping.Tag == "") {
+			errs = append(errs, fmt.Errorf("mapping[%d] must set one of field or tag", i))
+		}
+		if len(mapping.ValueMappings) == 0 {
+			errs = append(errs, fmt.Errorf("mapping[%d] has no value_mappings", i))
+		}
+	}
+	return errs
+}
+
+func (m *EnumMapper) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, mapping := range m.Mappings {
+			if mapping.Field != "" {
+				if value, ok := metric.Fields()[mapping.Field]; ok {
+					if mapped, ok := mapping.lookup(value); ok {
+						metric.AddField(mapping.dest(mapping.Field), mapped)
+					}
+				}
+			}
+			if mapping.Tag != "" {
+				if value, ok := metric.Tags()[mapping.Tag]; ok {
+					if mapped, ok := mapping.lookup(value); ok {
+						metric.AddTag(mapping.dest(mapping.Tag), fmt.Sprint(mapped))
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+// lookup returns the mapped value of value, the values which are not strings
+// or booleans are not mapped
+func (mapping *Mapping) lookup(value interface{}) (interface{}, bool) {
+	var key string
+	switch v := value.(type) {
+	case string:
+		key = v
+	case bool:
+		key = fmt.Sprint(v)
+	default:
+		return nil, false
+	}
+
+	if mapped, ok := mapping.ValueMappings[key]; ok {
+		return mapped, true
+	}
+	if mapping.Default != nil {
+		return mapping.Default, true
+	}
+	return nil, false
+}
+
+func (mapping *Mapping) dest(key string) string {
+	if mapping.Dest != "" {
+		return mapping.Dest
+	}
+	return key
+}
+
+func init() {
+	processors.Add("enum", func() telegraf.Processor {
+		return &EnumMapper{}
+	})
+}
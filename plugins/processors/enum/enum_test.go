@@ -0,0 +1,91 @@
+package enum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/toml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, status string) telegraf.Metric {
+	m, err := metric.New("sai_disk",
+		map[string]string{"disk_wwn": "5000c5005f50e6ab", "transport": "SATA"},
+		map[string]interface{}{
+			"smart_health_status": status,
+			"is_ssd":              true,
+			"size":                int64(1000),
+		},
+		time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func TestFieldMapping(t *testing.T) {
+	e := &EnumMapper{}
+	require.NoError(t, toml.Unmarshal([]byte(`
+[[mapping]]
+  field = "smart_health_status"
+  dest = "status"
+  [mapping.value_mappings]
+    PASSED = 1
+    OK = 1
+    FAILED = 2
+`), e))
+	assert.Empty(t, e.Validate())
+
+	m := e.Apply(newMetric(t, "PASSED"))[0]
+	assert.Equal(t, int64(1), m.Fields()["status"])
+	assert.Equal(t, "PASSED", m.Fields()["smart_health_status"])
+
+	// no default, the values missing from the table are not mapped
+	m = e.Apply(newMetric(t, "UNKNOWN"))[0]
+	assert.False(t, m.HasField("status"))
+}
+
+func TestFieldMappingInPlace(t *testing.T) {
+	e := &EnumMapper{Mappings: []Mapping{{
+		Field:         "smart_health_status",
+		Default:       int64(0),
+		ValueMappings: map[string]interface{}{"OK": int64(1)},
+	}, {
+		Field:         "is_ssd",
+		ValueMappings: map[string]interface{}{"true": "SSD"},
+	}, {
+		// only the strings and booleans are mapped
+		Field:         "size",
+		Default:       int64(-1),
+		ValueMappings: map[string]interface{}{"1000": int64(1)},
+	}}}
+
+	m := e.Apply(newMetric(t, "BROKEN"))[0]
+	assert.Equal(t, map[string]interface{}{
+		"smart_health_status": int64(0),
+		"is_ssd":              "SSD",
+		"size":                int64(1000),
+	}, m.Fields())
+}
+
+func TestTagMapping(t *testing.T) {
+	e := &EnumMapper{Mappings: []Mapping{{
+		Tag:           "transport",
+		Dest:          "transport_code",
+		ValueMappings: map[string]interface{}{"SATA": int64(7)},
+	}}}
+
+	m := e.Apply(newMetric(t, "OK"))[0]
+	assert.Equal(t, "7", m.Tags()["transport_code"])
+	assert.Equal(t, "SATA", m.Tags()["transport"])
+}
+
+func TestValidate(t *testing.T) {
+	e := &EnumMapper{Mappings: []Mapping{
+		{Field: "a", Tag: "b", ValueMappings: map[string]interface{}{"x": 1}},
+		{Field: "a"},
+	}}
+	assert.Len(t, e.Validate(), 2)
+}
@@ -0,0 +1,135 @@
+package regex
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## The tag and field conversions are defined in separate sub-tables, they are
+  ## applied in order. The value is replaced only when pattern matches it.
+  # [[processors.regex.tags]]
+  #   ## Tag to change
+  #   key = "resp_code"
+  #   ## Regular expression to match on a tag value
+  #   pattern = "^(\\d)\\d\\d$"
+  #   ## Replacement, with the capture groups of pattern as ${1}, ${2}...
+  #   replacement = "${1}xx"
+
+  # [[processors.regex.fields]]
+  #   ## String field to change
+  #   key = "request"
+  #   pattern = "^/api(?P<method>/[\\w/]+)\\S*"
+  #   replacement = "${method}"
+  #   ## Write the result to result_key, leaving key unchanged
+  #   result_key = "method"
+`
+
+type converter struct {
+	Key         string
+	Pattern     string
+	Replacement string
+	ResultKey   string
+
+	regex *regexp.Regexp
+}
+
+type Regex struct {
+	Tags   []converter
+	Fields []converter
+
+	compileOnce sync.Once
+}
+
+func (r *Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Regex) Description() string {
+	return "Transform tag and field values with regex pattern"
+}
+
+// Validate checks the patterns
+func (r *Regex) Validate() []error {
+	var errs []error
+	for _, c := range append(r.Tags, r.Fields...) {
+		if c.Key == "" {
+			errs = append(errs, fmt.Errorf("A conversion has an empty key"))
+		}
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid pattern for %s: %s", c.Key, err))
+		}
+	}
+	return errs
+}
+
+func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	r.compileOnce.Do(r.compile)
+
+	for _, metric := range in {
+		for _, c := range r.Tags {
+			if c.regex == nil {
+				continue
+			}
+			if value, ok := metric.Tags()[c.Key]; ok {
+				if result, ok := c.replace(value); ok {
+					metric.AddTag(c.resultKey(), result)
+				}
+			}
+		}
+
+		for _, c := range r.Fields {
+			if c.regex == nil {
+				continue
+			}
+			// only the string fields are changed
+			if value, ok := metric.Fields()[c.Key].(string); ok {
+				if result, ok := c.replace(value); ok {
+					metric.AddField(c.resultKey(), result)
+				}
+			}
+		}
+	}
+	return in
+}
+
+// compile compiles the patterns, the conversions with an invalid one are
+// disabled
+func (r *Regex) compile() {
+	for _, converters := range [][]converter{r.Tags, r.Fields} {
+		for i := range converters {
+			c := &converters[i]
+			regex, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				log.Printf("E! Invalid pattern for %s, the conversion is disabled: %s\n", c.Key, err)
+				continue
+			}
+			c.regex = regex
+		}
+	}
+}
+
+func (c *converter) replace(value string) (string, bool) {
+	if !c.regex.MatchString(value) {
+		return "", false
+	}
+	return c.regex.ReplaceAllString(value, c.Replacement), true
+}
+
+func (c *converter) resultKey() string {
+	if c.ResultKey != "" {
+		return c.ResultKey
+	}
+	return c.Key
+}
+
+func init() {
+	processors.Add("regex", func() telegraf.Processor {
+		return &Regex{}
+	})
+}
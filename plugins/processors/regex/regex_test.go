@@ -0,0 +1,70 @@
+package regex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T) telegraf.Metric {
+	m, err := metric.New("access_log",
+		map[string]string{"verb": "GET", "resp_code": "200"},
+		map[string]interface{}{
+			"request":       "/users/42/",
+			"ignore_number": int64(200),
+		},
+		time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func TestTagConversions(t *testing.T) {
+	r := &Regex{Tags: []converter{
+		{Key: "resp_code", Pattern: "^(\\d)\\d\\d$", Replacement: "${1}xx"},
+		{Key: "verb", Pattern: "^(GET)$", Replacement: "${1}", ResultKey: "method"},
+		// not matching, the tag is unchanged
+		{Key: "verb", Pattern: "^POST$", Replacement: "write"},
+	}}
+	assert.Empty(t, r.Validate())
+
+	m := r.Apply(newMetric(t))[0]
+	assert.Equal(t, map[string]string{
+		"verb":      "GET",
+		"resp_code": "2xx",
+		"method":    "GET",
+	}, m.Tags())
+}
+
+func TestFieldConversions(t *testing.T) {
+	r := &Regex{Fields: []converter{
+		{Key: "request", Pattern: "^/users/(?P<id>\\d+)/$", Replacement: "/users/:id/", ResultKey: "pattern"},
+		{Key: "request", Pattern: "^/users/(\\d+)/$", Replacement: "${1}"},
+		// only the string fields are converted
+		{Key: "ignore_number", Pattern: ".*", Replacement: "x"},
+	}}
+
+	m := r.Apply(newMetric(t))[0]
+	assert.Equal(t, map[string]interface{}{
+		"request":       "42",
+		"pattern":       "/users/:id/",
+		"ignore_number": int64(200),
+	}, m.Fields())
+}
+
+func TestInvalidPattern(t *testing.T) {
+	r := &Regex{Tags: []converter{
+		{Key: "verb", Pattern: "(", Replacement: "x"},
+		{Key: "resp_code", Pattern: "^2", Replacement: "ok"},
+	}}
+	assert.Len(t, r.Validate(), 1)
+
+	// the invalid conversion is disabled
+	m := r.Apply(newMetric(t))[0]
+	assert.Equal(t, "GET", m.Tags()["verb"])
+	assert.Equal(t, "ok00", m.Tags()["resp_code"])
+}
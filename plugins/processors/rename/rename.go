@@ -0,0 +1,101 @@
+package rename
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Each replacement renames one measurement, tag or field to dest. The
+  ## replacements are applied in order.
+  # [[processors.rename.replace]]
+  #   measurement = "network_interface_throughput"
+  #   dest = "throughput"
+
+  # [[processors.rename.replace]]
+  #   tag = "hostname"
+  #   dest = "host"
+
+  # [[processors.rename.replace]]
+  #   field = "lower"
+  #   dest = "min"
+`
+
+type Replace struct {
+	Measurement string
+	Tag         string
+	Field       string
+	Dest        string
+}
+
+type Rename struct {
+	Replaces []Replace `toml:"replace"`
+}
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags, and fields that pass through this filter."
+}
+
+// Validate checks that each replacement renames one thing
+func (r *Rename) Validate() []error {
+	var errs []error
+	for i, replace := range r.Replaces {
+		n := 0
+		for _, name := range []string{replace.Measurement, replace.Tag, replace.Field} {
+			if name != "" {
+				n++
+			}
+		}
+		if n != 1 {
+			errs = append(errs, fmt.Errorf("replace[%d] must set one of measurement, tag or field", i))
+		}
+		if replace.Dest == "" {
+			errs = append(errs, fmt.Errorf("replace[%d] has an empty dest", i))
+		}
+	}
+	return errs
+}
+
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, replace := range r.Replaces {
+			if replace.Dest == "" {
+				continue
+			}
+
+			switch {
+			case replace.Measurement != "":
+				if metric.Name() == replace.Measurement {
+					metric.SetName(replace.Dest)
+				}
+			case replace.Tag != "":
+				if value, ok := metric.Tags()[replace.Tag]; ok {
+					metric.RemoveTag(replace.Tag)
+					metric.AddTag(replace.Dest, value)
+				}
+			case replace.Field != "" && replace.Field != replace.Dest:
+				if value, ok := metric.Fields()[replace.Field]; ok {
+					// the field is added first, a metric keeps a field
+					metric.AddField(replace.Dest, value)
+					if err := metric.RemoveField(replace.Field); err != nil {
+						log.Printf("E! Unable to rename field %s: %s\n", replace.Field, err)
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
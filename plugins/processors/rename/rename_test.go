@@ -0,0 +1,71 @@
+package rename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, name string) telegraf.Metric {
+	m, err := metric.New(name,
+		map[string]string{"hostname": "localhost", "region": "east-1"},
+		map[string]interface{}{"one": int64(1), "two": int64(2)},
+		time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func TestRename(t *testing.T) {
+	r := &Rename{Replaces: []Replace{
+		{Measurement: "foo", Dest: "bar"},
+		{Tag: "hostname", Dest: "host"},
+		{Field: "one", Dest: "first"},
+		{Field: "missing", Dest: "none"},
+	}}
+	assert.Empty(t, r.Validate())
+
+	m := r.Apply(newMetric(t, "foo"))[0]
+	assert.Equal(t, "bar", m.Name())
+	assert.Equal(t, map[string]string{"host": "localhost", "region": "east-1"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"first": int64(1), "two": int64(2)}, m.Fields())
+
+	other := r.Apply(newMetric(t, "baz"))[0]
+	assert.Equal(t, "baz", other.Name())
+	assert.True(t, other.HasTag("host"))
+}
+
+func TestRenameChained(t *testing.T) {
+	// the replacements are applied in order
+	r := &Rename{Replaces: []Replace{
+		{Field: "one", Dest: "two"},
+		{Field: "two", Dest: "three"},
+	}}
+	m := r.Apply(newMetric(t, "foo"))[0]
+	assert.Equal(t, map[string]interface{}{"three": int64(1)}, m.Fields())
+}
+
+func TestRenameFilter(t *testing.T) {
+	rp := models.NewRunningProcessor("rename", &Rename{Replaces: []Replace{
+		{Measurement: "foo", Dest: "bar"},
+	}}, &models.ProcessorConfig{Name: "rename"})
+	rp.Config.Filter.TagPass = []models.TagFilter{{Name: "region", Filter: []string{"west-*"}}}
+	require.NoError(t, rp.Config.Filter.Compile())
+
+	// the metrics excluded by tagpass are unchanged
+	out := rp.Apply(newMetric(t, "foo"))
+	assert.Equal(t, "foo", out[0].Name())
+}
+
+func TestRenameValidate(t *testing.T) {
+	r := &Rename{Replaces: []Replace{
+		{Measurement: "foo", Tag: "bar", Dest: "baz"},
+		{Field: "foo"},
+	}}
+	assert.Len(t, r.Validate(), 2)
+}
@@ -0,0 +1,392 @@
+package topology
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai"
+	"github.com/influxdata/telegraf/dcai/connector/vcsa"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/topology/datacenter"
+	"github.com/influxdata/telegraf/dcai/topology/host/vmware/esxi"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tags holding the kernel name of a local disk, e.g. sda, they get the
+  ## disk_wwn tag. Partitions are mapped to their disk.
+  disk_tags = ["name", "device"]
+
+  ## Tag holding the name of the vSphere virtual machines, hosts and
+  ## datastores, as set by inputs.vsphere
+  vsphere_tag = "name"
+
+  ## Measurements of the agent host, they get its host_domain_id. The other
+  ## measurements, e.g. ping or snmp, describe remote targets and only get
+  ## the vSphere tags.
+  local_measurements = ["cpu", "disk", "diskio", "kernel", "mem", "net", "netstat", "processes", "swap", "system"]
+
+  ## How often the topology is fetched again
+  refresh_interval = "10m"
+
+  ## The vCenters to get the vSphere topology from, as in inputs.vspheretpgy.
+  ## The vSphere metrics are only enriched when they are set.
+  # urls = [
+  #   ["vc1", "192.168.0.1", "john", "qwerad"],
+  # ]
+
+  ## Restrict the metrics enriched, e.g.
+  # namepass = ["diskio", "disk", "net", "virtualmachine", "hostsystem", "datastore"]
+`
+
+// defaultRefreshInterval replaces a refresh_interval which is not positive
+const defaultRefreshInterval = 10 * time.Minute
+
+var (
+	// the seams of the tests
+	fetchAgent           = fetchAgentIdentity
+	fetchVsphereTopology = fetchVcenterTopology
+	getWWN               = disk.GetWWNByKernelName
+	getDiskOfPartition   = disk.GetDiskOfPartition
+)
+
+type Topology struct {
+	DiskTags          []string `toml:"disk_tags"`
+	VsphereTag        string   `toml:"vsphere_tag"`
+	LocalMeasurements []string `toml:"local_measurements"`
+	RefreshInterval   internal.Duration
+	Urls              [][]string
+
+	local filter.Filter
+
+	// the topology is loaded by a goroutine started on the first metric, the
+	// metrics are passed untagged until the first load is done
+	once   sync.Once
+	stop   chan struct{}
+	loaded chan struct{}
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	cache *cache
+}
+
+func (t *Topology) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Topology) Description() string {
+	return "Tag metrics with the dcai identities of their host, disk, virtual machine and datastore."
+}
+
+// Validate checks the vCenter tuples of urls
+func (t *Topology) Validate() []error {
+	var errs []error
+	if t.RefreshInterval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("refresh_interval must be positive, found %s", t.RefreshInterval.Duration))
+	}
+	if _, err := filter.Compile(t.LocalMeasurements); err != nil {
+		errs = append(errs, fmt.Errorf("Invalid local_measurements: %s", err))
+	}
+	if len(t.Urls) > 0 {
+		errs = append(errs, vcsa.ValidateUrls(t.Urls)...)
+	}
+	return errs
+}
+
+func (t *Topology) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	t.once.Do(t.start)
+
+	t.mu.Lock()
+	c := t.cache
+	t.mu.Unlock()
+	if c == nil {
+		return in
+	}
+
+	for _, metric := range in {
+		if len(t.Urls) > 0 && t.enrichVsphere(c, metric) {
+			continue
+		}
+		if t.local != nil && t.local.Match(metric.Name()) {
+			t.enrichLocal(c, metric)
+		}
+	}
+	return in
+}
+
+// Stop ends the refresh of the topology
+func (t *Topology) Stop() {
+	// a processor stopped before its first metric never starts
+	t.once.Do(func() {})
+	if t.stop == nil {
+		return
+	}
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	t.wg.Wait()
+}
+
+// enrichVsphere tags the metrics of a vSphere object, it returns false when
+// the metric is of none
+func (t *Topology) enrichVsphere(c *cache, metric telegraf.Metric) bool {
+	name := strings.ToLower(metric.Tags()[t.VsphereTag])
+	dsName := strings.ToLower(metric.Tags()["ds_name"])
+	for _, vc := range c.vcenters {
+		if vc == nil {
+			continue
+		}
+		if vm, ok := vc.vms[name]; ok {
+			addTag(metric, "vm_domain_id", vm.domainID)
+			addTag(metric, "host_domain_id", vm.hostDomainID)
+			addTag(metric, "cluster_domain_id", c.clusterDomainID)
+			addTag(metric, "datastore", strings.Join(vm.datastores, ","))
+			return true
+		}
+		if hostDomainID, ok := vc.hosts[name]; ok {
+			addTag(metric, "host_domain_id", hostDomainID)
+			addTag(metric, "cluster_domain_id", c.clusterDomainID)
+			return true
+		}
+
+		// inputs.vsphere names the datastores by ds_name
+		ds, ok := vc.datastores[name]
+		if !ok {
+			ds, ok = vc.datastores[dsName]
+		}
+		if ok {
+			addTag(metric, "datastore_domain_id", ds.domainID)
+			addTag(metric, "disk_wwn", strings.Join(ds.wwns, ","))
+			addTag(metric, "cluster_domain_id", c.clusterDomainID)
+			return true
+		}
+	}
+	return false
+}
+
+// enrichLocal tags the metrics of the agent host and its disks
+func (t *Topology) enrichLocal(c *cache, metric telegraf.Metric) {
+	addTag(metric, "host_domain_id", c.hostDomainID)
+	addTag(metric, "cluster_domain_id", c.clusterDomainID)
+	tags := metric.Tags()
+	for _, tag := range t.DiskTags {
+		if name, ok := tags[tag]; ok {
+			if wwn := c.diskWWN(name); wwn != "" {
+				addTag(metric, "disk_wwn", wwn)
+				return
+			}
+		}
+	}
+}
+
+// addTag sets the tag key unless the metric has one already
+func addTag(metric telegraf.Metric, key string, value string) {
+	if value == "" || metric.HasTag(key) {
+		return
+	}
+	metric.AddTag(key, value)
+}
+
+func (t *Topology) start() {
+	// Validate reports the invalid patterns, they match no measurement
+	t.local, _ = filter.Compile(t.LocalMeasurements)
+	if t.RefreshInterval.Duration <= 0 {
+		log.Printf("W! [processors.topology] refresh_interval must be positive, found %s, using %s\n",
+			t.RefreshInterval.Duration, defaultRefreshInterval)
+		t.RefreshInterval.Duration = defaultRefreshInterval
+	}
+	t.stop = make(chan struct{})
+	t.loaded = make(chan struct{})
+	t.wg.Add(1)
+	go t.refresh()
+}
+
+// refresh loads the topology at once and then every refresh_interval until
+// the processor is stopped
+func (t *Topology) refresh() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.RefreshInterval.Duration)
+	defer ticker.Stop()
+
+	previous := newCache()
+	for {
+		c := t.load(previous)
+		t.mu.Lock()
+		t.cache = c
+		t.mu.Unlock()
+		if previous.loaded.IsZero() {
+			close(t.loaded)
+		}
+		previous = c
+
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// load fetches the topology, the parts which fail to be fetched are kept
+// from previous
+func (t *Topology) load(previous *cache) *cache {
+	c := newCache()
+	c.loaded = time.Now()
+
+	var err error
+	if c.hostDomainID, c.clusterDomainID, err = fetchAgent(); err != nil {
+		log.Printf("E! Unable to get the agent host topology: %s\n", err)
+		c.hostDomainID = previous.hostDomainID
+		c.clusterDomainID = previous.clusterDomainID
+	}
+
+	// each vCenter keeps its previous topology when it is unreachable
+	c.vcenters = make([]*vcenter, len(t.Urls))
+	for i, urls := range t.Urls {
+		dcs, err := fetchVsphereTopology(urls)
+		if err != nil {
+			log.Printf("E! Unable to get the vSphere topology from %s: %s\n", urls[0], err)
+			if i < len(previous.vcenters) {
+				c.vcenters[i] = previous.vcenters[i]
+			}
+			continue
+		}
+		c.vcenters[i] = newVcenter(dcs)
+	}
+	return c
+}
+
+func fetchAgentIdentity() (string, string, error) {
+	a, err := dcai.GetDcaiAgent()
+	if err != nil {
+		return "", "", err
+	}
+	h, err := dcai.FetchAgentHostConfig(a.Agenttype, a.TelegrafConfig.Agent.DmidecodePath)
+	if err != nil {
+		return "", "", err
+	}
+	return h.DomainID(), a.GetSaiClusterDomainId(), nil
+}
+
+func fetchVcenterTopology(urls []string) ([]*datacenter.DatacenterConfig, error) {
+	if len(urls) != 4 {
+		return nil, fmt.Errorf("The vCenter configuration is incorrect")
+	}
+	vc, err := vcsa.NewVcsaConnector(urls[0], urls[1], urls[2], urls[3], true)
+	if err != nil {
+		return nil, err
+	}
+	return dcai.FetchVsphereTopology(vc)
+}
+
+type vmInfo struct {
+	domainID     string
+	hostDomainID string
+	datastores   []string
+}
+
+type datastoreInfo struct {
+	domainID string
+	wwns     []string
+}
+
+// cache maps the names found in the metrics to the dcai identities
+type cache struct {
+	loaded          time.Time
+	hostDomainID    string
+	clusterDomainID string
+
+	// the vSphere topology of each of the urls, nil until it is fetched
+	vcenters []*vcenter
+
+	// the disk WWNs are looked up on the first metric of a disk, the names
+	// of the devices which are not disks are mapped to ""
+	disksMu sync.Mutex
+	disks   map[string]string
+}
+
+func newCache() *cache {
+	return &cache{disks: make(map[string]string)}
+}
+
+// vcenter indexes the objects of a vCenter by their lowercased name
+type vcenter struct {
+	vms        map[string]*vmInfo
+	hosts      map[string]string
+	datastores map[string]*datastoreInfo
+}
+
+func newVcenter(dcs []*datacenter.DatacenterConfig) *vcenter {
+	vc := &vcenter{
+		vms:        make(map[string]*vmInfo),
+		hosts:      make(map[string]string),
+		datastores: make(map[string]*datastoreInfo),
+	}
+	for _, dc := range dcs {
+		for _, h := range dc.Hosts {
+			esxiHost, ok := h.(*esxi.EsxiHostConfig)
+			if !ok {
+				continue
+			}
+			vc.hosts[strings.ToLower(esxiHost.Hostname())] = esxiHost.DomainID()
+
+			for _, ds := range esxiHost.Datastores {
+				info := &datastoreInfo{domainID: ds.DomainID()}
+				for _, d := range ds.Disks {
+					info.wwns = append(info.wwns, d.WWN)
+				}
+				vc.datastores[strings.ToLower(ds.Name)] = info
+			}
+
+			for _, vm := range esxiHost.VMs {
+				info := &vmInfo{
+					domainID:     vm.DomainID(),
+					hostDomainID: esxiHost.DomainID(),
+				}
+				for _, ds := range vm.Datastores {
+					info.datastores = append(info.datastores, ds.Name)
+				}
+				vc.vms[strings.ToLower(vm.Name)] = info
+			}
+		}
+	}
+	return vc
+}
+
+// diskWWN returns the WWN of the local disk or partition name, or "" when
+// it is not a disk
+func (c *cache) diskWWN(name string) string {
+	c.disksMu.Lock()
+	defer c.disksMu.Unlock()
+
+	if wwn, ok := c.disks[name]; ok {
+		return wwn
+	}
+	wwn, err := getWWN(getDiskOfPartition(name))
+	if err != nil {
+		wwn = ""
+	}
+	c.disks[name] = wwn
+	return wwn
+}
+
+func init() {
+	processors.Add("topology", func() telegraf.Processor {
+		return &Topology{
+			DiskTags:   []string{"name", "device"},
+			VsphereTag: "name",
+			LocalMeasurements: []string{"cpu", "disk", "diskio", "kernel", "mem",
+				"net", "netstat", "processes", "swap", "system"},
+			RefreshInterval: internal.Duration{Duration: defaultRefreshInterval},
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package topology
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/dcai/hardware/disk"
+	"github.com/influxdata/telegraf/dcai/topology/datacenter"
+	"github.com/influxdata/telegraf/dcai/topology/datastore"
+	"github.com/influxdata/telegraf/dcai/topology/host"
+	"github.com/influxdata/telegraf/dcai/topology/host/vmware/esxi"
+	"github.com/influxdata/telegraf/dcai/topology/virtualmachine"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, name string, tags map[string]string) telegraf.Metric {
+	m, err := metric.New(name, tags, map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func vsphereTopology(t *testing.T) []*datacenter.DatacenterConfig {
+	ds, err := datastore.NewDatastoreInfo("Datastore1", "5a1b2c3d-ds01",
+		[]*disk.DiskInfo{{WWN: "6000c2900000000000000000000000ff"}})
+	require.NoError(t, err)
+	vm, err := virtualmachine.NewVirtualmachineInfo("Web01", "4201-vm01",
+		[]*datastore.DatastoreInfo{ds}, nil)
+	require.NoError(t, err)
+	h, err := esxi.NewEsxiHostConfig("ESX01.local", "4c4c-esx01", "VMware ESXi", "6.5.0",
+		nil, nil, []*virtualmachine.VirtualmachineInfo{vm}, []*datastore.DatastoreInfo{ds}, nil, nil)
+	require.NoError(t, err)
+	dc, err := datacenter.NewDatacenterConfig("DC0", nil, []host.HostConfig{h})
+	require.NoError(t, err)
+	return []*datacenter.DatacenterConfig{dc}
+}
+
+// setup replaces the topology sources, the returned function restores them
+func setup(t *testing.T, agentErr error, vsphereErr error) func() {
+	fetchAgent = func() (string, string, error) {
+		return "agenthost01", "saicluster01", agentErr
+	}
+	fetchVsphereTopology = func(urls []string) ([]*datacenter.DatacenterConfig, error) {
+		if vsphereErr != nil {
+			return nil, vsphereErr
+		}
+		return vsphereTopology(t), nil
+	}
+	getWWN = func(name string) (string, error) {
+		if name == "sda" {
+			return "5000c5005f50e6ab", nil
+		}
+		return "", fmt.Errorf("Cannot find WWN of %s", name)
+	}
+	getDiskOfPartition = func(name string) string {
+		if name == "sda1" {
+			return "sda"
+		}
+		return name
+	}
+
+	return func() {
+		fetchAgent = fetchAgentIdentity
+		fetchVsphereTopology = fetchVcenterTopology
+		getWWN = disk.GetWWNByKernelName
+		getDiskOfPartition = disk.GetDiskOfPartition
+	}
+}
+
+func newTopology() *Topology {
+	return &Topology{
+		DiskTags:          []string{"name", "device"},
+		VsphereTag:        "name",
+		LocalMeasurements: []string{"cpu", "disk", "diskio"},
+		RefreshInterval:   internal.Duration{Duration: time.Hour},
+		Urls:              [][]string{{"vc1", "192.168.0.1", "john", "qwerad"}},
+	}
+}
+
+// started starts the topology and waits for its first load
+func started(tp *Topology) *Topology {
+	tp.once.Do(tp.start)
+	<-tp.loaded
+	return tp
+}
+
+func TestApplyLocal(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	diskio := newMetric(t, "diskio", map[string]string{"name": "sda"})
+	partition := newMetric(t, "disk", map[string]string{"device": "sda1", "path": "/"})
+	loop := newMetric(t, "diskio", map[string]string{"name": "loop0"})
+	cpu := newMetric(t, "cpu", map[string]string{"cpu": "cpu-total"})
+	// the remote targets are not of the agent host
+	ping := newMetric(t, "ping", map[string]string{"url": "www.example.com"})
+
+	tp := started(newTopology())
+	defer tp.Stop()
+	out := tp.Apply(diskio, partition, loop, cpu, ping)
+	require.Len(t, out, 5)
+
+	assert.Equal(t, map[string]string{
+		"name":              "sda",
+		"disk_wwn":          "5000c5005f50e6ab",
+		"host_domain_id":    "agenthost01",
+		"cluster_domain_id": "saicluster01",
+	}, diskio.Tags())
+	assert.Equal(t, "5000c5005f50e6ab", partition.Tags()["disk_wwn"])
+	assert.False(t, loop.HasTag("disk_wwn"))
+	assert.Equal(t, "agenthost01", loop.Tags()["host_domain_id"])
+	assert.Equal(t, "agenthost01", cpu.Tags()["host_domain_id"])
+	assert.Equal(t, "saicluster01", cpu.Tags()["cluster_domain_id"])
+	assert.Equal(t, map[string]string{"url": "www.example.com"}, ping.Tags())
+}
+
+func TestApplyVsphere(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	vm := newMetric(t, "virtualmachine", map[string]string{"name": "web01"})
+	esx := newMetric(t, "hostsystem", map[string]string{"name": "esx01.local"})
+	ds := newMetric(t, "datastore", map[string]string{"ds_name": "Datastore1"})
+	// the tags set by the inputs are kept
+	own := newMetric(t, "virtualmachine", map[string]string{"name": "web01", "datastore": "other"})
+
+	tp := started(newTopology())
+	defer tp.Stop()
+	tp.Apply(vm, esx, ds, own)
+
+	assert.Equal(t, map[string]string{
+		"name":              "web01",
+		"vm_domain_id":      "4201-vm01",
+		"host_domain_id":    "4c4c-esx01",
+		"cluster_domain_id": "saicluster01",
+		"datastore":         "Datastore1",
+	}, vm.Tags())
+	assert.Equal(t, map[string]string{
+		"name":              "esx01.local",
+		"host_domain_id":    "4c4c-esx01",
+		"cluster_domain_id": "saicluster01",
+	}, esx.Tags())
+	assert.Equal(t, map[string]string{
+		"ds_name":             "Datastore1",
+		"datastore_domain_id": "5a1b2c3d-ds01",
+		"disk_wwn":            "6000c2900000000000000000000000ff",
+		"cluster_domain_id":   "saicluster01",
+	}, ds.Tags())
+	assert.Equal(t, "other", own.Tags()["datastore"])
+}
+
+func TestApplyWithoutVcenter(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	tp := newTopology()
+	tp.Urls = nil
+	started(tp)
+	defer tp.Stop()
+
+	vm := newMetric(t, "virtualmachine", map[string]string{"name": "web01"})
+	disk := newMetric(t, "disk", map[string]string{"name": "web01"})
+	tp.Apply(vm, disk)
+	assert.False(t, vm.HasTag("vm_domain_id"))
+	assert.False(t, vm.HasTag("host_domain_id"))
+	assert.Equal(t, "agenthost01", disk.Tags()["host_domain_id"])
+}
+
+func TestApplyBeforeLoad(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	block := make(chan struct{})
+	fetchAgent = func() (string, string, error) {
+		<-block
+		return "agenthost01", "saicluster01", nil
+	}
+
+	tp := newTopology()
+	defer tp.Stop()
+
+	// the metrics are not held while the vCenters are queried
+	m := newMetric(t, "cpu", nil)
+	tp.Apply(m)
+	assert.Empty(t, m.Tags())
+
+	close(block)
+	<-tp.loaded
+	m = newMetric(t, "cpu", nil)
+	tp.Apply(m)
+	assert.Equal(t, "agenthost01", m.Tags()["host_domain_id"])
+}
+
+func TestRefresh(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	var loads int32
+	fetchAgent = func() (string, string, error) {
+		n := atomic.AddInt32(&loads, 1)
+		return fmt.Sprintf("agenthost%02d", n), "saicluster01", nil
+	}
+
+	tp := newTopology()
+	tp.RefreshInterval.Duration = time.Millisecond
+	started(tp)
+	m := newMetric(t, "cpu", nil)
+	for i := 0; i < 100; i++ {
+		m = newMetric(t, "cpu", nil)
+		tp.Apply(m)
+		if m.Tags()["host_domain_id"] != "agenthost01" {
+			break
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	assert.NotEqual(t, "agenthost01", m.Tags()["host_domain_id"])
+
+	// no topology is loaded once stopped
+	tp.Stop()
+	n := atomic.LoadInt32(&loads)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&loads))
+}
+
+func TestRefreshError(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	tp := newTopology()
+	tp.Urls = append(tp.Urls, []string{"vc2", "192.168.0.2", "john", "qwerad"})
+	previous := tp.load(newCache())
+
+	// vc2 keeps its previous topology while vc1 is loaded again
+	setup(t, fmt.Errorf("dmidecode not found"), nil)
+	fetchVsphereTopology = func(urls []string) ([]*datacenter.DatacenterConfig, error) {
+		if urls[0] == "vc2" {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return nil, nil
+	}
+	c := tp.load(previous)
+	assert.Equal(t, "agenthost01", c.hostDomainID)
+	require.Len(t, c.vcenters, 2)
+	assert.Empty(t, c.vcenters[0].vms)
+	assert.Contains(t, c.vcenters[1].vms, "web01")
+}
+
+func TestRefreshIntervalNotPositive(t *testing.T) {
+	defer setup(t, nil, nil)()
+
+	tp := newTopology()
+	tp.RefreshInterval.Duration = 0
+	started(tp)
+	defer tp.Stop()
+	assert.Equal(t, defaultRefreshInterval, tp.RefreshInterval.Duration)
+
+	m := newMetric(t, "cpu", nil)
+	tp.Apply(m)
+	assert.Equal(t, "agenthost01", m.Tags()["host_domain_id"])
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	tp := newTopology()
+	tp.Stop()
+	// the processor is not started once stopped
+	m := newMetric(t, "cpu", nil)
+	tp.Apply(m)
+	assert.Empty(t, m.Tags())
+	assert.Nil(t, tp.stop)
+}
+
+func TestValidate(t *testing.T) {
+	tp := newTopology()
+	assert.Empty(t, tp.Validate())
+
+	tp.Urls = [][]string{{"vc1", ""}}
+	tp.RefreshInterval.Duration = 0
+	tp.LocalMeasurements = []string{"a["}
+	assert.Len(t, tp.Validate(), 3)
+}
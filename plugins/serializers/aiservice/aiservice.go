@@ -0,0 +1,112 @@
+package aiservice
+
+import (
+	ejson "encoding/json"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+// DefaultBatchSize is the number of points of a document posted by the
+// aiservice output
+const DefaultBatchSize = 100
+
+// AiserviceSerializer serializes the metrics as the points posted to the
+// aiservice, a document holding the points of a measurement:
+// {"measurement":"cpu","points":[{"time":"1257894000000000000","host":"a","usage_idle":99}]}
+type AiserviceSerializer struct {
+	// GroupByMeasurement puts the points of a measurement in the same
+	// documents, else a document holds consecutive metrics
+	GroupByMeasurement bool
+	// BatchSize is the maximum number of points of a document
+	BatchSize int
+}
+
+// Block is the metrics of a document
+type Block struct {
+	Measurement string
+	Metrics     []telegraf.Metric
+}
+
+type document struct {
+	Measurement string                   `json:"measurement,omitempty"`
+	Points      []map[string]interface{} `json:"points"`
+}
+
+func (s *AiserviceSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return marshal(metric.Name(), []telegraf.Metric{metric})
+}
+
+// SerializeBatch serializes the metrics as a document per block, one by line
+func (s *AiserviceSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var serialized []byte
+	for _, block := range s.Blocks(metrics) {
+		b, err := marshal(block.Measurement, block.Metrics)
+		if err != nil {
+			return nil, err
+		}
+		serialized = append(serialized, b...)
+	}
+	return serialized, nil
+}
+
+// Blocks splits the metrics in the blocks of at most BatchSize metrics of a
+// measurement. The blocks are ordered by their first metric.
+func (s *AiserviceSerializer) Blocks(metrics []telegraf.Metric) []Block {
+	size := s.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	var blocks []Block
+	// the index of the open block of each measurement
+	open := make(map[string]int)
+	for _, m := range metrics {
+		name := m.Name()
+		i, ok := open[name]
+		if !s.GroupByMeasurement {
+			i = len(blocks) - 1
+			ok = i >= 0 && blocks[i].Measurement == name
+		}
+		if !ok || len(blocks[i].Metrics) >= size {
+			i = len(blocks)
+			open[name] = i
+			blocks = append(blocks, Block{Measurement: name})
+		}
+		blocks[i].Metrics = append(blocks[i].Metrics, m)
+	}
+	return blocks
+}
+
+// Points returns the body posted by the aiservice output for a block,
+// {"points":[...]}, the measurement being in the URL
+func Points(metrics []telegraf.Metric) ([]byte, error) {
+	return ejson.Marshal(document{Points: points(metrics)})
+}
+
+// marshal returns the document of the points of a measurement, on a line
+func marshal(measurement string, metrics []telegraf.Metric) ([]byte, error) {
+	serialized, err := ejson.Marshal(document{Measurement: measurement, Points: points(metrics)})
+	if err != nil {
+		return []byte{}, err
+	}
+	return append(serialized, '\n'), nil
+}
+
+// points merges the tags and the fields of each metric with its time, in
+// nanoseconds as a string
+func points(metrics []telegraf.Metric) []map[string]interface{} {
+	points := make([]map[string]interface{}, 0, len(metrics))
+	for _, metric := range metrics {
+		point := map[string]interface{}{}
+		point["time"] = strconv.FormatInt(metric.Time().UnixNano(), 10)
+		for k, v := range metric.Tags() {
+			point[k] = v
+		}
+		for k, v := range metric.Fields() {
+			point[k] = v
+		}
+		points = append(points, point)
+	}
+	return points
+}
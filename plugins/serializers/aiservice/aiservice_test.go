@@ -0,0 +1,100 @@
+package aiservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	parser "github.com/influxdata/telegraf/plugins/parsers/aiservice"
+)
+
+func newMetric(t *testing.T, name string, value float64) telegraf.Metric {
+	m, err := metric.New(name,
+		map[string]string{"host": "a"},
+		map[string]interface{}{"value": value},
+		time.Unix(0, 1257894000000000000))
+	require.NoError(t, err)
+	return m
+}
+
+func TestSerialize(t *testing.T) {
+	m, err := metric.New("smart",
+		map[string]string{"disk_wwn": "5000c5005f50e6ab"},
+		map[string]interface{}{"temp_c": int64(35), "health_ok": true, "model": "ST4000"},
+		time.Unix(0, 1257894000000000001))
+	require.NoError(t, err)
+
+	s := AiserviceSerializer{GroupByMeasurement: true}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"measurement":"smart","points":[{"disk_wwn":"5000c5005f50e6ab","health_ok":true,"model":"ST4000","temp_c":35,"time":"1257894000000000001"}]}`+"\n",
+		string(buf))
+}
+
+func TestBlocksGroupByMeasurement(t *testing.T) {
+	metrics := []telegraf.Metric{
+		newMetric(t, "cpu", 1),
+		newMetric(t, "mem", 2),
+		newMetric(t, "cpu", 3),
+		newMetric(t, "cpu", 4),
+		newMetric(t, "mem", 5),
+	}
+
+	s := AiserviceSerializer{GroupByMeasurement: true, BatchSize: 2}
+	blocks := s.Blocks(metrics)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, Block{"cpu", []telegraf.Metric{metrics[0], metrics[2]}}, blocks[0])
+	assert.Equal(t, Block{"mem", []telegraf.Metric{metrics[1], metrics[4]}}, blocks[1])
+	assert.Equal(t, Block{"cpu", []telegraf.Metric{metrics[3]}}, blocks[2])
+}
+
+func TestBlocksInOrder(t *testing.T) {
+	metrics := []telegraf.Metric{
+		newMetric(t, "cpu", 1),
+		newMetric(t, "cpu", 2),
+		newMetric(t, "mem", 3),
+		newMetric(t, "cpu", 4),
+	}
+
+	s := AiserviceSerializer{}
+	blocks := s.Blocks(metrics)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, Block{"cpu", metrics[:2]}, blocks[0])
+	assert.Equal(t, Block{"mem", metrics[2:3]}, blocks[1])
+	assert.Equal(t, Block{"cpu", metrics[3:]}, blocks[2])
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		newMetric(t, "cpu", 1),
+		newMetric(t, "mem", 2),
+		newMetric(t, "cpu", 3),
+	}
+
+	s := AiserviceSerializer{GroupByMeasurement: true}
+	buf, err := s.SerializeBatch(metrics)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"measurement":"cpu","points":[{"host":"a","time":"1257894000000000000","value":1},{"host":"a","time":"1257894000000000000","value":3}]}`+"\n"+
+			`{"measurement":"mem","points":[{"host":"a","time":"1257894000000000000","value":2}]}`+"\n",
+		string(buf))
+
+	// a relay parses the measurements back
+	p := parser.AiserviceParser{MetricName: "aiservice"}
+	parsed, err := p.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, parsed, 3)
+	assert.Equal(t, "cpu", parsed[0].Name())
+	assert.Equal(t, "cpu", parsed[1].Name())
+	assert.Equal(t, "mem", parsed[2].Name())
+}
+
+func TestPoints(t *testing.T) {
+	buf, err := Points([]telegraf.Metric{newMetric(t, "cpu", 1.5)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"points":[{"host":"a","time":"1257894000000000000","value":1.5}]}`, string(buf))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 
+	"github.com/influxdata/telegraf/plugins/serializers/aiservice"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
@@ -27,10 +28,19 @@ type Serializer interface {
 	Serialize(metric telegraf.Metric) ([]byte, error)
 }
 
+// BatchSerializer is implemented by the serializers of the data formats
+// grouping several metrics in a document. The outputs writing a batch at once
+// use it rather than concatenating the serialized metrics.
+type BatchSerializer interface {
+	// SerializeBatch takes the metrics of a write and turns them into a byte
+	// buffer, with a newline at the end.
+	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
-	// Dataformat can be one of: influx, graphite, or json
+	// Dataformat can be one of: influx, graphite, json or aiservice
 	DataFormat string
 
 	// Prefix to add to all measurements, only supports Graphite
@@ -42,6 +52,11 @@ type Config struct {
 
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration
+
+	// Group the points by measurement, only supports aiservice
+	AiserviceGroupByMeasurement bool
+	// Maximum number of points of a document, only supports aiservice
+	AiserviceBatchSize int
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -55,6 +70,9 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template)
 	case "json":
 		serializer, err = NewJsonSerializer(config.TimestampUnits)
+	case "aiservice":
+		serializer, err = NewAiserviceSerializer(config.AiserviceGroupByMeasurement,
+			config.AiserviceBatchSize)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -65,6 +83,16 @@ func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
 	return &json.JsonSerializer{TimestampUnits: timestampUnits}, nil
 }
 
+func NewAiserviceSerializer(groupByMeasurement bool, batchSize int) (Serializer, error) {
+	if batchSize <= 0 {
+		batchSize = aiservice.DefaultBatchSize
+	}
+	return &aiservice.AiserviceSerializer{
+		GroupByMeasurement: groupByMeasurement,
+		BatchSize:          batchSize,
+	}, nil
+}
+
 func NewInfluxSerializer() (Serializer, error) {
 	return &influx.InfluxSerializer{}, nil
 }
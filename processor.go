@@ -10,3 +10,10 @@ type Processor interface {
 	// Apply the filter to the given metric
 	Apply(in ...Metric) []Metric
 }
+
+// StoppableProcessor is an optional interface of the processors running a
+// goroutine. Stop is called once the processor is no longer applied, when it
+// is removed by a reload or the agent shuts down.
+type StoppableProcessor interface {
+	Stop()
+}
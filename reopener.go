@@ -0,0 +1,8 @@
+package telegraf
+
+// Reopener is an optional interface of the outputs writing to files. Reopen
+// closes and opens their files again, e.g. after logrotate moved them away.
+// The agent reopens the outputs on SIGUSR1.
+type Reopener interface {
+	Reopen() error
+}
@@ -0,0 +1,10 @@
+package telegraf
+
+// Validator is an optional interface of the plugins. Validate checks the
+// plugin configuration without connecting or gathering, it is run by
+// `telegraf --validate`.
+type Validator interface {
+	// Validate returns the problems of the plugin configuration, or nil
+	// when the plugin is ready to run
+	Validate() []error
+}